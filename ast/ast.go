@@ -25,27 +25,55 @@ type Visitor interface {
 	DoFilter(v *FilterExpr) error
 	DoRange(v *RangeExpr) error
 	DoFunction(v *FunctionStmt) error
+	DoStruct(v *StructStmt) error
+	DoMethod(v *MethodStmt) error
 	DoPrefix(v *PrefixExpr) error
 	DoInfix(v *InfixExpr) error
 	DoIdent(v *Identifier) error
 	DoSymbol(v *SymbolExpr) error
 	DoConditional(v *ConditionalExpr) error
+	DoNullish(v *NullishExpr) error
+	DoIn(v *InExpr) error
+	DoInterval(v *IntervalExpr) error
+	DoMatch(v *MatchExpr) error
 	DoFn(v *Function) error
 	DoCall(v *Call) error
+	DoSpread(v *SpreadExpr) error
 	DoCallMember(v *CallMember) error
 	DoObjectMember(v *ObjectMember) error
 	DoIndex(v *IndexExpr) error
+	DoSlice(v *SliceExpr) error
+	DoTry(v *TryExpr) error
 	DoNull(v *Null) error
 	DoInteger(v *Integer) error
 	DoBoolean(v *Boolean) error
 	DoString(v *String) error
+	DoTemplate(v *TemplateExpr) error
 	DoArray(v *Array) error
 	DoHash(v *Hash) error
 }
 
-type defaultNode struct{}
+// Position : where the node starts in the source, zero if unknown (e.g. decoded from json)
+type Position struct {
+	Line int
+	Col  int
+}
+
+func (this Position) Valid() bool {
+	return this.Line > 0
+}
+
+func (this Position) String() string {
+	return fmt.Sprintf("line: %v, col: %v", this.Line, this.Col)
+}
+
+type defaultNode struct {
+	pos Position
+}
 
 func (this *defaultNode) AsFunction() (*Function, error) { return nil, errNotFunction }
+func (this *defaultNode) Pos() Position                  { return this.pos }
+func (this *defaultNode) SetPos(pos Position)            { this.pos = pos }
 
 type Node interface {
 	Do(v Visitor) error
@@ -54,6 +82,8 @@ type Node interface {
 	String() string
 	Eval(e object.Env) (object.Object, error)
 	AsFunction() (*Function, error)
+	Pos() Position
+	SetPos(pos Position)
 }
 
 type Nodes []Node
@@ -103,10 +133,10 @@ func (this *ExpressionMap) SortedKeys() ExpressionSlice {
 	return keys
 }
 
-func (this *ExpressionMap) encode() interface{} {
-	r := map[string]interface{}{}
-	for k, v := range *this {
-		r[k.String()] = v.Encode()
+func (this *ExpressionMap) encode(keys ExpressionSlice) interface{} {
+	r := object.NewDumpMap()
+	for _, k := range keys {
+		r.Set(k.String(), (*this)[k].Encode())
 	}
 	return r
 }
@@ -148,6 +178,8 @@ func evalPrefix(op *token.Token, right object.Object) (object.Object, error) {
 		return right.CallMember(object.FnNot, object.Objects{})
 	case token.SUB:
 		return right.CallMember(object.FnNeg, object.Objects{})
+	case token.BITNOT:
+		return right.CallMember(object.FnBitNot, object.Objects{})
 	default:
 		err := fmt.Errorf("unsupport op %v(%v)", op.Literal, token.ToString(op.Type))
 		return object.Nil, err
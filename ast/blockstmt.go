@@ -45,3 +45,12 @@ func (this *BlockStmt) String() string {
 func (this *BlockStmt) Eval(e object.Env) (object.Object, error) {
 	return this.Stmt.Eval(e)
 }
+
+// evalTail : the body of a function, a call in tail position is returned instead of being made
+func (this *BlockStmt) evalTail(e object.Env) (object.Object, *object.TailCall, error) {
+	if stmt, ok := this.Stmt.(*ExpressionStmt); ok {
+		return evalTail(stmt.Expr, e)
+	}
+	r, err := this.Stmt.Eval(e)
+	return r, nil, err
+}
@@ -72,9 +72,43 @@ func (this *Call) Eval(e object.Env) (object.Object, error) {
 	if nil != err {
 		return object.Nil, err
 	}
-	args, err := this.Args.eval(e)
+	args, err := evalArgs(this.Args, e)
 	if nil != err {
 		return object.Nil, err
 	}
 	return fn.Call(args)
 }
+
+func (this *Call) evalTail(e object.Env) (object.Object, *object.TailCall, error) {
+	fn, err := this.Func.Eval(e)
+	if nil != err {
+		return object.Nil, nil, err
+	}
+	args, err := evalArgs(this.Args, e)
+	if nil != err {
+		return object.Nil, nil, err
+	}
+	return nil, &object.TailCall{Fn: fn, Args: args}, nil
+}
+
+// evalTail : calls and method calls (also in the branches of a conditional expression) in tail position are made by the caller,
+// the branches are followed in a loop to keep the go stack small
+func evalTail(expr Expression, e object.Env) (object.Object, *object.TailCall, error) {
+	for {
+		switch v := expr.(type) {
+		case *Call:
+			return v.evalTail(e)
+		case *CallMember:
+			return v.evalTail(e)
+		case *ConditionalExpr:
+			r, err := v.Cond.Eval(e)
+			if nil != err {
+				return object.Nil, nil, err
+			}
+			expr, e = v.getCondNode(r.True()), e.NewEnclosedEnv()
+			continue
+		}
+		r, err := expr.Eval(e)
+		return r, nil, err
+	}
+}
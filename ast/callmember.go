@@ -13,9 +13,10 @@ import (
 // CallMember : implement Expression
 type CallMember struct {
 	defaultNode
-	Left Expression
-	Func *Identifier
-	Args ExpressionSlice
+	Left     Expression
+	Func     *Identifier
+	Args     ExpressionSlice
+	Optional bool // left?.func(args)
 }
 
 func (this *CallMember) Do(v Visitor) error {
@@ -23,20 +24,23 @@ func (this *CallMember) Do(v Visitor) error {
 }
 
 func (this *CallMember) Encode() interface{} {
+	v := map[string]interface{}{
+		"left":     this.Left.Encode(),
+		token.Func: this.Func.Encode(),
+		"args":     this.Args.encode(),
+	}
+	encodeOptionalFlag(v, this.Optional)
 	return map[string]interface{}{
-		keyType: typeExprCallmember,
-		keyValue: map[string]interface{}{
-			"left":     this.Left.Encode(),
-			token.Func: this.Func.Encode(),
-			"args":     this.Args.encode(),
-		},
+		keyType:  typeExprCallmember,
+		keyValue: v,
 	}
 }
 func (this *CallMember) Decode(b []byte) error {
 	var v struct {
-		Left JsonNode        `json:"left"`
-		Func JsonNode        `json:"func"`
-		Args json.RawMessage `json:"args"`
+		Left     JsonNode        `json:"left"`
+		Func     JsonNode        `json:"func"`
+		Args     json.RawMessage `json:"args"`
+		Optional bool            `json:"optional"`
 	}
 	var err error
 	if err = json.Unmarshal(b, &v); nil != err {
@@ -54,6 +58,7 @@ func (this *CallMember) Decode(b []byte) error {
 	if nil != err {
 		return function.NewError(err)
 	}
+	this.Optional = v.Optional
 	return nil
 }
 func (this *CallMember) expressionNode() {}
@@ -67,7 +72,7 @@ func (this *CallMember) String() string {
 	}
 
 	out.WriteString(this.Left.String())
-	out.WriteString(".")
+	out.WriteString(memberPeriod(this.Optional))
 	out.WriteString(this.Func.String())
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
@@ -77,13 +82,50 @@ func (this *CallMember) String() string {
 }
 
 func (this *CallMember) Eval(e object.Env) (object.Object, error) {
-	obj, err := this.Left.Eval(e)
+	r, _, err := this.evalLink(e)
+	return r, err
+}
+
+func (this *CallMember) evalLink(e object.Env) (object.Object, bool, error) {
+	obj, short, err := evalLeft(this.Left, e)
 	if nil != err {
-		return object.Nil, err
+		return object.Nil, false, err
+	}
+	if short || (this.Optional && object.IsNull(obj)) {
+		return object.Nil, true, nil
 	}
 	args, err := this.Args.eval(e)
 	if nil != err {
-		return object.Nil, err
+		return object.Nil, false, err
+	}
+	r, err := obj.CallMember(this.Func.Value, args)
+	return r, false, err
+}
+
+// evalTail : a field or method of struct is returned to be called by the caller, other members are called here
+func (this *CallMember) evalTail(e object.Env) (object.Object, *object.TailCall, error) {
+	obj, short, err := evalLeft(this.Left, e)
+	if nil != err {
+		return object.Nil, nil, err
+	}
+	if short || (this.Optional && object.IsNull(obj)) {
+		return object.Nil, nil, nil
+	}
+	args, err := this.Args.eval(e)
+	if nil != err {
+		return object.Nil, nil, err
+	}
+	name := this.Func.Value
+	// index may be the __index__ hook, refer to Struct.CallMember
+	if s, ok := obj.(*object.Struct); ok && !(object.FnIndex == name && 1 == len(args)) {
+		if fn, ok := s.Member(name); ok {
+			// the trampoline calls Function only, the receiver is inserted before the args
+			if m, ok := fn.(*object.Method); ok {
+				fn, args = m.Fn, append(object.Objects{m.Recv}, args...)
+			}
+			return nil, &object.TailCall{Fn: fn, Args: args}, nil
+		}
 	}
-	return obj.CallMember(this.Func.Value, args)
+	r, err := obj.CallMember(name, args)
+	return r, nil, err
 }
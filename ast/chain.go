@@ -0,0 +1,45 @@
+package ast
+
+import "github.com/jobs-github/escript/object"
+
+// link : a.b, a.f() and a[k] are the links of a member chain,
+// the rest of the chain is null once a ?. meets a null left
+type link interface {
+	Expression
+	// evalLink : short is true if the chain is short-circuited
+	evalLink(e object.Env) (r object.Object, short bool, err error)
+}
+
+// evalLeft : the left of a link, which is a link of the same chain as well
+func evalLeft(left Expression, e object.Env) (object.Object, bool, error) {
+	if l, ok := left.(link); ok {
+		return l.evalLink(e)
+	}
+	r, err := left.Eval(e)
+	return r, false, err
+}
+
+// IsOptionalChain : whether e is a link of a member chain which has a ?.
+func IsOptionalChain(e Expression) bool {
+	for {
+		switch v := e.(type) {
+		case *ObjectMember:
+			if v.Optional {
+				return true
+			}
+			e = v.Left
+		case *CallMember:
+			if v.Optional {
+				return true
+			}
+			e = v.Left
+		case *IndexExpr:
+			if v.Optional {
+				return true
+			}
+			e = v.Left
+		default:
+			return false
+		}
+	}
+}
@@ -2,6 +2,8 @@ package ast
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 
 	"github.com/jobs-github/escript/function"
 	"github.com/jobs-github/escript/object"
@@ -11,8 +13,10 @@ import (
 // ConstStmt : implement Statement
 type ConstStmt struct {
 	defaultNode
-	Name  *Identifier
-	Value Expression
+	Name    *Identifier
+	Pattern Pattern   // nil unless destructuring, Name is nil then
+	Type    *TypeExpr // nil if not annotated
+	Value   Expression
 }
 
 func (this *ConstStmt) Do(v Visitor) error {
@@ -20,17 +24,48 @@ func (this *ConstStmt) Do(v Visitor) error {
 }
 
 func (this *ConstStmt) Encode() interface{} {
+	v := map[string]interface{}{
+		"value": this.Value.Encode(),
+	}
+	if nil != this.Pattern {
+		v["pattern"] = this.Pattern.Encode()
+	} else {
+		v["name"] = this.Name.Encode()
+	}
+	if nil != this.Type {
+		v["annotation"] = encodeType(this.Type)
+	}
 	return map[string]interface{}{
-		keyType: typeStmtConst,
-		keyValue: map[string]interface{}{
-			"name":  this.Name.Encode(),
-			"value": this.Value.Encode(),
-		},
+		keyType:  typeStmtConst,
+		keyValue: v,
 	}
 }
 func (this *ConstStmt) Decode(b []byte) error {
+	var v struct {
+		Name    *JsonNode `json:"name"`
+		Pattern *JsonNode `json:"pattern"`
+		Type    string    `json:"annotation"`
+		Value   JsonNode  `json:"value"`
+	}
 	var err error
-	this.Name, this.Value, err = decodeKv(b)
+	if err = json.Unmarshal(b, &v); nil != err {
+		return function.NewError(err)
+	}
+	if nil != v.Pattern {
+		this.Pattern, err = v.Pattern.decodePattern()
+	} else if nil != v.Name {
+		this.Name, err = v.Name.decodeIdent()
+	} else {
+		err = errors.New("const requires name or pattern")
+	}
+	if nil != err {
+		return function.NewError(err)
+	}
+	this.Type, err = decodeType(v.Type)
+	if nil != err {
+		return function.NewError(err)
+	}
+	this.Value, err = v.Value.decodeExpr()
 	if nil != err {
 		return function.NewError(err)
 	}
@@ -42,7 +77,11 @@ func (this *ConstStmt) String() string {
 	var out bytes.Buffer
 	out.WriteString(token.Const)
 	out.WriteString(" ")
-	out.WriteString(this.Name.String())
+	if nil != this.Pattern {
+		out.WriteString(annotate(this.Pattern.String(), this.Type))
+	} else {
+		out.WriteString(annotate(this.Name.String(), this.Type))
+	}
 	out.WriteString(" = ")
 	if nil != this.Value {
 		out.WriteString(this.Value.String())
@@ -52,5 +91,15 @@ func (this *ConstStmt) String() string {
 }
 
 func (this *ConstStmt) Eval(e object.Env) (object.Object, error) {
-	return evalVar(this.Name, this.Value, e)
+	if nil == this.Pattern {
+		return evalVar(this.Name, this.Value, e)
+	}
+	r, err := this.Value.Eval(e)
+	if nil != err {
+		return object.Nil, err
+	}
+	if err := destruct(this.Pattern, r, e); nil != err {
+		return object.Nil, err
+	}
+	return r, nil
 }
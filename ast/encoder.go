@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"path/filepath"
@@ -73,6 +74,18 @@ func (this *JsonNode) decodeExpr() (Expression, error) {
 	return expr, nil
 }
 
+func (this *JsonNode) decodePattern() (Pattern, error) {
+	fn, ok := patternFactory[this.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown pattern type: %v", this.Type)
+	}
+	p := fn()
+	if err := p.Decode(this.Value); nil != err {
+		return nil, function.NewError(err)
+	}
+	return p, nil
+}
+
 func (this *JsonNode) decodeBlockStmt() (*BlockStmt, error) {
 	stmt := this.newBlockStmt()
 	if nil == stmt {
@@ -150,28 +163,57 @@ func (this *JsonNode) newNode() Node {
 	return nil
 }
 
-type JsonMap map[string]JsonNode
+type JsonPair struct {
+	Key   string
+	Value JsonNode
+}
+
+// JsonPairs : members of json object in document order
+type JsonPairs []*JsonPair
 
-func newJsonMap(b []byte) (JsonMap, error) {
-	var m JsonMap
-	if err := json.Unmarshal(b, &m); nil != err {
+func newJsonPairs(b []byte) (JsonPairs, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	if tok, err := d.Token(); nil != err {
+		return nil, function.NewError(err)
+	} else if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("expect object, got `%v`", tok)
+	}
+	pairs := JsonPairs{}
+	for d.More() {
+		tok, err := d.Token()
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		k, ok := tok.(string)
+		if !ok {
+			return nil, fmt.Errorf("expect object key, got `%v`", tok)
+		}
+		pair := &JsonPair{Key: k}
+		if err := d.Decode(&pair.Value); nil != err {
+			return nil, function.NewError(err)
+		}
+		pairs = append(pairs, pair)
+	}
+	if _, err := d.Token(); nil != err {
 		return nil, function.NewError(err)
 	}
-	return m, nil
+	return pairs, nil
 }
 
-func (this *JsonMap) decodeExprMap() (ExpressionMap, error) {
+func (this *JsonPairs) decodeExprMap() (ExpressionMap, ExpressionSlice, error) {
 	m := ExpressionMap{}
-	for k, v := range *this {
-		n, err := v.decodeExpr()
+	keys := ExpressionSlice{}
+	for _, v := range *this {
+		n, err := v.Value.decodeExpr()
 		if nil != err {
-			return nil, function.NewError(err)
+			return nil, nil, function.NewError(err)
 		}
 		s := NewString()
-		s.Value = k
+		s.Value = v.Key
 		m[s] = n
+		keys = append(keys, s)
 	}
-	return m, nil
+	return m, keys, nil
 }
 
 type JsonNodes []JsonNode
@@ -244,10 +286,10 @@ func decodeIdents(b []byte) (IdentifierSlice, error) {
 	return arr.decodeIdents()
 }
 
-func decodeExprMap(b []byte) (ExpressionMap, error) {
-	m, err := newJsonMap(b)
+func decodeExprMap(b []byte) (ExpressionMap, ExpressionSlice, error) {
+	m, err := newJsonPairs(b)
 	if nil != err {
-		return nil, function.NewError(err)
+		return nil, nil, function.NewError(err)
 	}
 	return m.decodeExprMap()
 }
@@ -292,6 +334,21 @@ func decodeExpr(b []byte) (Expression, error) {
 	return expr, nil
 }
 
+// encodeOptional : absent node is encoded as json null
+func encodeOptional(node Expression) interface{} {
+	if nil == node {
+		return nil
+	}
+	return node.Encode()
+}
+
+func decodeOptional(v *JsonNode) (Expression, error) {
+	if nil == v {
+		return nil, nil
+	}
+	return v.decodeExpr()
+}
+
 func decodeKv(b []byte) (*Identifier, Expression, error) {
 	var v struct {
 		Name  JsonNode `json:"name"`
@@ -310,3 +367,10 @@ func decodeKv(b []byte) (*Identifier, Expression, error) {
 	}
 	return name, value, nil
 }
+
+// encodeOptionalFlag : only optional chaining carries the flag
+func encodeOptionalFlag(v map[string]interface{}, optional bool) {
+	if optional {
+		v["optional"] = true
+	}
+}
@@ -9,6 +9,8 @@ const (
 	typeNodeProgram      = "program"
 	typeStmtConst        = token.Const
 	typeStmtFn           = token.Func
+	typeStmtStruct       = token.Struct
+	typeStmtMethod       = "method"
 	typeStmtExpr         = "expr"
 	typeStmtBlock        = "block"
 	typeExprIdent        = "ident"
@@ -25,18 +27,35 @@ const (
 	typeExprBoolean      = object.TypeBool
 	typeExprInteger      = object.TypeInt
 	typeExprString       = object.TypeStr
+	typeExprTemplate     = "template"
 	typeExprCall         = "call"
+	typeExprSpread       = "spread"
 	typeExprCallmember   = "callmember"
 	typeExprObjectmember = "objectmember"
 	typeExprConditional  = "conditional"
+	typeExprNullish      = "nullish"
+	typeExprIn           = token.In
+	typeExprInterval     = object.TypeInterval
+	typeExprMatch        = "match"
 	typeExprHash         = object.TypeHash
 	typeExprIndex        = "index"
+	typeExprSlice        = "slice"
+	typeExprTry          = token.Try
 	typeExprInfix        = "infix"
 	typeExprPrefix       = "prefix"
+
+	typePatternWildcard = "wildcard"
+	typePatternBind     = "bind"
+	typePatternLiteral  = "literal"
+	typePatternAlt      = "alt"
+	typePatternArray    = "array_pattern"
+	typePatternHash     = "hash_pattern"
 )
 
 func NewConst() *ConstStmt             { return &ConstStmt{} }
 func NewFunction() *FunctionStmt       { return &FunctionStmt{} }
+func NewStruct() *StructStmt           { return &StructStmt{} }
+func NewMethod() *MethodStmt           { return &MethodStmt{} }
 func NewExpr() *ExpressionStmt         { return &ExpressionStmt{} }
 func NewBlock() *BlockStmt             { return &BlockStmt{} }
 func NewIdent() *Identifier            { return &Identifier{} }
@@ -52,21 +71,31 @@ func NewNull() *Null                   { return &Null{} }
 func NewBoolean() *Boolean             { return &Boolean{} }
 func NewInteger() *Integer             { return &Integer{} }
 func NewString() *String               { return &String{} }
+func NewTemplate() *TemplateExpr       { return &TemplateExpr{} }
 func NewCall() *Call                   { return &Call{} }
+func NewSpread() *SpreadExpr           { return &SpreadExpr{} }
 func NewCallMember() *CallMember       { return &CallMember{} }
 func NewObjectMember() *ObjectMember   { return &ObjectMember{} }
 func NewConditional() *ConditionalExpr { return &ConditionalExpr{} }
+func NewNullish() *NullishExpr         { return &NullishExpr{} }
+func NewIn() *InExpr                   { return &InExpr{} }
+func NewInterval() *IntervalExpr       { return &IntervalExpr{} }
+func NewMatch() *MatchExpr             { return &MatchExpr{} }
 func NewHash() *Hash                   { return &Hash{} }
 func NewIndex() *IndexExpr             { return &IndexExpr{} }
+func NewSlice() *SliceExpr             { return &SliceExpr{} }
+func NewTry() *TryExpr                 { return &TryExpr{} }
 func NewInfix() *InfixExpr             { return &InfixExpr{} }
 func NewPrefix() *PrefixExpr           { return &PrefixExpr{} }
 
 var (
 	stmtFactory = map[string]func() Statement{
-		typeStmtConst: func() Statement { return NewConst() },
-		typeStmtFn:    func() Statement { return NewFunction() },
-		typeStmtExpr:  func() Statement { return NewExpr() },
-		typeStmtBlock: func() Statement { return NewBlock() },
+		typeStmtConst:  func() Statement { return NewConst() },
+		typeStmtFn:     func() Statement { return NewFunction() },
+		typeStmtStruct: func() Statement { return NewStruct() },
+		typeStmtMethod: func() Statement { return NewMethod() },
+		typeStmtExpr:   func() Statement { return NewExpr() },
+		typeStmtBlock:  func() Statement { return NewBlock() },
 	}
 	exprFactory = map[string]func() Expression{
 		typeExprIdent:        func() Expression { return NewIdent() },
@@ -83,13 +112,29 @@ var (
 		typeExprBoolean:      func() Expression { return NewBoolean() },
 		typeExprInteger:      func() Expression { return NewInteger() },
 		typeExprString:       func() Expression { return NewString() },
+		typeExprTemplate:     func() Expression { return NewTemplate() },
 		typeExprCall:         func() Expression { return NewCall() },
+		typeExprSpread:       func() Expression { return NewSpread() },
 		typeExprCallmember:   func() Expression { return NewCallMember() },
 		typeExprObjectmember: func() Expression { return NewObjectMember() },
 		typeExprConditional:  func() Expression { return NewConditional() },
+		typeExprNullish:      func() Expression { return NewNullish() },
+		typeExprIn:           func() Expression { return NewIn() },
+		typeExprInterval:     func() Expression { return NewInterval() },
+		typeExprMatch:        func() Expression { return NewMatch() },
 		typeExprHash:         func() Expression { return NewHash() },
 		typeExprIndex:        func() Expression { return NewIndex() },
+		typeExprSlice:        func() Expression { return NewSlice() },
+		typeExprTry:          func() Expression { return NewTry() },
 		typeExprInfix:        func() Expression { return NewInfix() },
 		typeExprPrefix:       func() Expression { return NewPrefix() },
 	}
+	patternFactory = map[string]func() Pattern{
+		typePatternWildcard: func() Pattern { return &WildcardPattern{} },
+		typePatternBind:     func() Pattern { return &BindPattern{} },
+		typePatternLiteral:  func() Pattern { return &LiteralPattern{} },
+		typePatternAlt:      func() Pattern { return &AltPattern{} },
+		typePatternArray:    func() Pattern { return &ArrayPattern{} },
+		typePatternHash:     func() Pattern { return &HashPattern{} },
+	}
 )
@@ -68,10 +68,6 @@ func (this *FilterExpr) Eval(e object.Env) (object.Object, error) {
 	if nil != err {
 		return object.Nil, err
 	}
-	arr, err := v.AsArray()
-	if nil != err {
-		return object.Nil, err
-	}
 	cb, err := this.Body.Eval(e)
 	if nil != err {
 		return object.Nil, err
@@ -79,18 +75,18 @@ func (this *FilterExpr) Eval(e object.Env) (object.Object, error) {
 	if !object.IsCallable(cb) {
 		return object.Nil, errNotCallable
 	}
-	if arr.Items == nil || len(arr.Items) < 1 {
-		return object.NewArray(object.Objects{}), nil
-	}
 	r := object.Objects{}
-	for i, item := range arr.Items {
-		v, err := cb.Call(object.Objects{object.NewInteger(int64(i)), item})
+	if err := object.Iterate(v, func(i int64, item object.Object) error {
+		v, err := cb.Call(object.Objects{object.NewInteger(i), item})
 		if nil != err {
-			return object.Nil, err
+			return err
 		}
 		if v.True() {
 			r = append(r, item)
 		}
+		return nil
+	}); nil != err {
+		return object.Nil, err
 	}
 	return object.NewArray(r), nil
 }
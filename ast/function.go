@@ -3,19 +3,47 @@ package ast
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"strings"
 
 	"github.com/jobs-github/escript/function"
 	"github.com/jobs-github/escript/object"
+	"github.com/jobs-github/escript/token"
 )
 
 // Function : implement Expression
 type Function struct {
 	defaultNode
-	Lambda string
-	Name   string
-	Args   IdentifierSlice
-	Body   *BlockStmt
+	Lambda   string
+	Name     string
+	Args     IdentifierSlice
+	Params   PatternSlice    // one pattern per arg, nil if no arg is destructured
+	Defaults ExpressionSlice // one default value per arg (nil if required), nil if no arg has default
+	Rest     *Identifier     // nil if there is no `...rest`
+	Types    []*TypeExpr     // one annotation per arg then the rest (nil if not annotated), nil if nothing is annotated
+	Returns  *TypeExpr       // nil if not annotated
+	Body     *BlockStmt
+}
+
+// ArgType : annotation of the i-th arg, the rest is the last, nil if not annotated
+func (this *Function) ArgType(i int) *TypeExpr {
+	if i < len(this.Types) {
+		return this.Types[i]
+	}
+	return nil
+}
+
+// RestType : annotation of the rest, which is an array
+func (this *Function) RestType() *TypeExpr {
+	if nil == this.Rest {
+		return nil
+	}
+	return this.ArgType(len(this.Args))
+}
+
+// ArgName : hidden name of the i-th arg which is destructured
+func ArgName(i int) string {
+	return fmt.Sprintf("__arg%v__", i)
 }
 
 func (this *Function) Do(v Visitor) error {
@@ -29,6 +57,29 @@ func (this *Function) value() map[string]interface{} {
 		"args":   this.Args.encode(),
 		"body":   this.Body.Encode(),
 	}
+	if nil != this.Params {
+		m["params"] = this.Params.encode()
+	}
+	if nil != this.Defaults {
+		defaults := []interface{}{}
+		for _, d := range this.Defaults {
+			defaults = append(defaults, encodeOptional(d))
+		}
+		m["defaults"] = defaults
+	}
+	if nil != this.Rest {
+		m["rest"] = this.Rest.Encode()
+	}
+	if nil != this.Types {
+		types := []string{}
+		for _, t := range this.Types {
+			types = append(types, encodeType(t))
+		}
+		m["types"] = types
+	}
+	if nil != this.Returns {
+		m["returns"] = encodeType(this.Returns)
+	}
 	return m
 }
 
@@ -40,10 +91,15 @@ func (this *Function) Encode() interface{} {
 }
 func (this *Function) Decode(b []byte) error {
 	var v struct {
-		Lambda string          `json:"lambda"`
-		Name   string          `json:"name"`
-		Args   json.RawMessage `json:"args"`
-		Body   JsonNode        `json:"body"`
+		Lambda   string          `json:"lambda"`
+		Name     string          `json:"name"`
+		Args     json.RawMessage `json:"args"`
+		Params   json.RawMessage `json:"params"`
+		Defaults []*JsonNode     `json:"defaults"`
+		Rest     *JsonNode       `json:"rest"`
+		Types    []string        `json:"types"`
+		Returns  string          `json:"returns"`
+		Body     JsonNode        `json:"body"`
 	}
 	var err error
 	if err = json.Unmarshal(b, &v); nil != err {
@@ -55,6 +111,42 @@ func (this *Function) Decode(b []byte) error {
 	if nil != err {
 		return function.NewError(err)
 	}
+	if nil != v.Params {
+		this.Params = PatternSlice{}
+		if err := this.Params.decode(v.Params); nil != err {
+			return function.NewError(err)
+		}
+	}
+	if nil != v.Defaults {
+		this.Defaults = ExpressionSlice{}
+		for _, d := range v.Defaults {
+			expr, err := decodeOptional(d)
+			if nil != err {
+				return function.NewError(err)
+			}
+			this.Defaults = append(this.Defaults, expr)
+		}
+	}
+	if nil != v.Rest {
+		this.Rest, err = v.Rest.decodeIdent()
+		if nil != err {
+			return function.NewError(err)
+		}
+	}
+	if nil != v.Types {
+		this.Types = []*TypeExpr{}
+		for _, s := range v.Types {
+			t, err := decodeType(s)
+			if nil != err {
+				return function.NewError(err)
+			}
+			this.Types = append(this.Types, t)
+		}
+	}
+	this.Returns, err = decodeType(v.Returns)
+	if nil != err {
+		return function.NewError(err)
+	}
 	this.Body, err = v.Body.decodeBlockStmt()
 	if nil != err {
 		return function.NewError(err)
@@ -70,6 +162,20 @@ func (this *Function) String() string {
 	for _, p := range this.Args {
 		args = append(args, p.String())
 	}
+	if nil != this.Params {
+		args = this.Params.strings()
+	}
+	for i := range args {
+		args[i] = annotate(args[i], this.ArgType(i))
+	}
+	for i, d := range this.Defaults {
+		if nil != d {
+			args[i] = fmt.Sprintf("%v = %v", args[i], d.String())
+		}
+	}
+	if nil != this.Rest {
+		args = append(args, annotate(token.Ellipsis+this.Rest.String(), this.RestType()))
+	}
 	if "" == this.Name {
 		out.WriteString("func ")
 	} else {
@@ -79,26 +185,76 @@ func (this *Function) String() string {
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
 	out.WriteString(")")
+	if nil != this.Returns {
+		out.WriteString(": ")
+		out.WriteString(this.Returns.String())
+	}
 	out.WriteString(this.Body.String())
 
 	return out.String()
 }
 
 func (this *Function) Eval(e object.Env) (object.Object, error) {
+	args := this.Args.Values()
+	if nil != this.Rest {
+		args = append(args, this.Rest.Value)
+	}
 	return object.NewFunction(
 		this.Name,
-		this.Args.Values(),
+		args,
+		this.Arity(),
 		this.evalBody(),
 		e,
 	), nil
 }
 
+// Arity : args before the first default are required
+func (this *Function) Arity() object.Arity {
+	required := len(this.Args)
+	for i, d := range this.Defaults {
+		if nil != d {
+			required = i
+			break
+		}
+	}
+	return object.Arity{Args: len(this.Args), Required: required, Rest: nil != this.Rest}
+}
+
 func (this *Function) AsFunction() (*Function, error) {
 	return this, nil
 }
 
-func (this *Function) evalBody() func(e object.Env) (object.Object, error) {
-	return func(e object.Env) (object.Object, error) {
-		return this.Body.Eval(e)
+// evalBody : the args are bound apart from the body, so that a recursion keeps a small go stack frame per call
+func (this *Function) evalBody() object.EvalBody {
+	return func(e object.Env, argc int) (object.Object, *object.TailCall, error) {
+		if err := this.bindArgs(e, argc); nil != err {
+			return object.Nil, nil, err
+		}
+		return this.Body.evalTail(e)
 	}
 }
+
+// bindArgs : fill the missing args with their default values and destructure the args in order,
+// so that a default value can refer to the args before it
+func (this *Function) bindArgs(e object.Env, argc int) error {
+	for i, arg := range this.Args {
+		if i >= argc && i < len(this.Defaults) && nil != this.Defaults[i] {
+			v, err := this.Defaults[i].Eval(e)
+			if nil != err {
+				return err
+			}
+			e.Set(arg.Value, v)
+		}
+		if i >= len(this.Params) {
+			continue
+		}
+		if _, ok := this.Params[i].(*BindPattern); ok {
+			continue
+		}
+		v, _ := e.Get(arg.Value)
+		if err := destruct(this.Params[i], v, e); nil != err {
+			return err
+		}
+	}
+	return nil
+}
@@ -13,6 +13,26 @@ import (
 type Hash struct {
 	defaultNode
 	Pairs ExpressionMap
+	Keys  ExpressionSlice
+}
+
+// Set : keys are kept in the order of the literal
+func (this *Hash) Set(k Expression, v Expression) {
+	if nil == this.Pairs {
+		this.Pairs = ExpressionMap{}
+	}
+	if _, ok := this.Pairs[k]; !ok {
+		this.Keys = append(this.Keys, k)
+	}
+	this.Pairs[k] = v
+}
+
+// OrderedKeys : falls back to sorted keys when the hash was not built by Set
+func (this *Hash) OrderedKeys() ExpressionSlice {
+	if len(this.Keys) == len(this.Pairs) {
+		return this.Keys
+	}
+	return this.Pairs.SortedKeys()
 }
 
 func (this *Hash) Do(v Visitor) error {
@@ -22,12 +42,12 @@ func (this *Hash) Do(v Visitor) error {
 func (this *Hash) Encode() interface{} {
 	return map[string]interface{}{
 		keyType:  typeExprHash,
-		keyValue: this.Pairs.encode(),
+		keyValue: this.Pairs.encode(this.OrderedKeys()),
 	}
 }
 func (this *Hash) Decode(b []byte) error {
 	var err error
-	this.Pairs, err = decodeExprMap(b)
+	this.Pairs, this.Keys, err = decodeExprMap(b)
 	if nil != err {
 		return function.NewError(err)
 	}
@@ -38,8 +58,8 @@ func (this *Hash) expressionNode() {}
 func (this *Hash) String() string {
 	var out bytes.Buffer
 	items := []string{}
-	for k, v := range this.Pairs {
-		items = append(items, fmt.Sprintf("%v:%v", k.String(), v.String()))
+	for _, k := range this.OrderedKeys() {
+		items = append(items, fmt.Sprintf("%v:%v", k.String(), this.Pairs[k].String()))
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(items, ", "))
@@ -48,21 +68,19 @@ func (this *Hash) String() string {
 }
 
 func (this *Hash) Eval(e object.Env) (object.Object, error) {
-	m := object.HashMap{}
-	for k, v := range this.Pairs {
+	h := object.NewOrderedHash()
+	for _, k := range this.OrderedKeys() {
 		key, err := k.Eval(e)
 		if nil != err {
 			return object.Nil, err
 		}
-		h, err := key.Hash()
+		val, err := this.Pairs[k].Eval(e)
 		if nil != err {
 			return object.Nil, err
 		}
-		val, err := v.Eval(e)
-		if nil != err {
+		if err := h.Set(key, val); nil != err {
 			return object.Nil, err
 		}
-		m[*h] = &object.HashPair{Key: key, Value: val}
 	}
-	return object.NewHash(m), nil
+	return h, nil
 }
@@ -0,0 +1,80 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+)
+
+// InExpr : implement Expression
+type InExpr struct {
+	defaultNode
+	Left  Expression
+	Right Expression // array, hash, string or interval
+	Not   bool       // not in
+}
+
+func (this *InExpr) Do(v Visitor) error {
+	return v.DoIn(this)
+}
+
+func (this *InExpr) Encode() interface{} {
+	return map[string]interface{}{
+		keyType: typeExprIn,
+		keyValue: map[string]interface{}{
+			"left":  this.Left.Encode(),
+			"right": this.Right.Encode(),
+			"not":   this.Not,
+		},
+	}
+}
+func (this *InExpr) Decode(b []byte) error {
+	var v struct {
+		Left  JsonNode `json:"left"`
+		Right JsonNode `json:"right"`
+		Not   bool     `json:"not"`
+	}
+	var err error
+	if err = json.Unmarshal(b, &v); nil != err {
+		return function.NewError(err)
+	}
+	this.Left, err = v.Left.decodeExpr()
+	if nil != err {
+		return function.NewError(err)
+	}
+	this.Right, err = v.Right.decodeExpr()
+	if nil != err {
+		return function.NewError(err)
+	}
+	this.Not = v.Not
+	return nil
+}
+func (this *InExpr) expressionNode() {}
+
+func (this *InExpr) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(this.Left.String())
+	if this.Not {
+		out.WriteString(" not in ")
+	} else {
+		out.WriteString(" in ")
+	}
+	out.WriteString(this.Right.String())
+	out.WriteString(")")
+	return out.String()
+}
+
+func (this *InExpr) Eval(e object.Env) (object.Object, error) {
+	left, err := this.Left.Eval(e)
+	if nil != err {
+		return object.Nil, err
+	}
+	right, err := this.Right.Eval(e)
+	if nil != err {
+		return object.Nil, err
+	}
+	return object.Contains(right, left, this.Not)
+}
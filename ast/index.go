@@ -11,8 +11,9 @@ import (
 // IndexExpr : implement Expression
 type IndexExpr struct {
 	defaultNode
-	Left  Expression // array
-	Index Expression
+	Left     Expression // array
+	Index    Expression
+	Optional bool // left?.[index]
 }
 
 func (this *IndexExpr) Do(v Visitor) error {
@@ -20,18 +21,21 @@ func (this *IndexExpr) Do(v Visitor) error {
 }
 
 func (this *IndexExpr) Encode() interface{} {
+	v := map[string]interface{}{
+		"left":  this.Left.Encode(),
+		"index": this.Index.Encode(),
+	}
+	encodeOptionalFlag(v, this.Optional)
 	return map[string]interface{}{
-		keyType: typeExprIndex,
-		keyValue: map[string]interface{}{
-			"left":  this.Left.Encode(),
-			"index": this.Index.Encode(),
-		},
+		keyType:  typeExprIndex,
+		keyValue: v,
 	}
 }
 func (this *IndexExpr) Decode(b []byte) error {
 	var v struct {
-		Left  JsonNode `json:"left"`
-		Index JsonNode `json:"index"`
+		Left     JsonNode `json:"left"`
+		Index    JsonNode `json:"index"`
+		Optional bool     `json:"optional"`
 	}
 	var err error
 	if err = json.Unmarshal(b, &v); nil != err {
@@ -45,6 +49,7 @@ func (this *IndexExpr) Decode(b []byte) error {
 	if nil != err {
 		return function.NewError(err)
 	}
+	this.Optional = v.Optional
 	return nil
 }
 func (this *IndexExpr) expressionNode() {}
@@ -53,6 +58,9 @@ func (this *IndexExpr) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
 	out.WriteString(this.Left.String())
+	if this.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("[")
 	out.WriteString(this.Index.String())
 	out.WriteString("])")
@@ -60,13 +68,27 @@ func (this *IndexExpr) String() string {
 }
 
 func (this *IndexExpr) Eval(e object.Env) (object.Object, error) {
-	left, err := this.Left.Eval(e)
+	r, _, err := this.evalLink(e)
+	return r, err
+}
+
+func (this *IndexExpr) evalLink(e object.Env) (object.Object, bool, error) {
+	left, short, err := evalLeft(this.Left, e)
 	if nil != err {
-		return object.Nil, err
+		return object.Nil, false, err
+	}
+	if short || (this.Optional && object.IsNull(left)) {
+		return object.Nil, true, nil
 	}
 	idx, err := this.Index.Eval(e)
 	if nil != err {
-		return object.Nil, err
+		return object.Nil, false, err
+	}
+	var r object.Object
+	if this.Optional {
+		r, err = object.OptionalIndex(left, idx)
+	} else {
+		r, err = left.CallMember(object.FnIndex, object.Objects{idx})
 	}
-	return left.CallMember(object.FnIndex, object.Objects{idx})
+	return r, false, err
 }
@@ -1,7 +1,9 @@
 package ast
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 
 	"github.com/jobs-github/escript/function"
@@ -12,6 +14,7 @@ import (
 type Integer struct {
 	defaultNode
 	Value int64
+	Big   *big.Int // literal out of the int64 range
 }
 
 func (this *Integer) Do(v Visitor) error {
@@ -19,6 +22,12 @@ func (this *Integer) Do(v Visitor) error {
 }
 
 func (this *Integer) Encode() interface{} {
+	if nil != this.Big {
+		return map[string]interface{}{
+			keyType:  typeExprInteger,
+			keyValue: this.Big,
+		}
+	}
 	return map[string]interface{}{
 		keyType:  typeExprInteger,
 		keyValue: this.Value,
@@ -28,6 +37,10 @@ func (this *Integer) Decode(b []byte) error {
 	v := function.BytesToString(b)
 	i, err := strconv.ParseInt(v, 10, 64)
 	if nil != err {
+		if n, ok := new(big.Int).SetString(v, 10); ok && errors.Is(err, strconv.ErrRange) {
+			this.Big = n
+			return nil
+		}
 		return function.NewError(err)
 	}
 	this.Value = i
@@ -36,9 +49,19 @@ func (this *Integer) Decode(b []byte) error {
 func (this *Integer) expressionNode() {}
 
 func (this *Integer) String() string {
+	if nil != this.Big {
+		return this.Big.String()
+	}
 	return fmt.Sprintf("%v", this.Value)
 }
 
 func (this *Integer) Eval(e object.Env) (object.Object, error) {
-	return object.NewInteger(this.Value), nil
+	return this.Object(), nil
+}
+
+func (this *Integer) Object() object.Object {
+	if nil != this.Big {
+		return object.NewBigInt(this.Big)
+	}
+	return object.NewInteger(this.Value)
 }
@@ -0,0 +1,80 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+)
+
+// IntervalExpr : implement Expression
+type IntervalExpr struct {
+	defaultNode
+	Start     Expression
+	End       Expression
+	Exclusive bool // start..<end
+}
+
+func (this *IntervalExpr) Do(v Visitor) error {
+	return v.DoInterval(this)
+}
+
+func (this *IntervalExpr) Encode() interface{} {
+	return map[string]interface{}{
+		keyType: typeExprInterval,
+		keyValue: map[string]interface{}{
+			"start":     this.Start.Encode(),
+			"end":       this.End.Encode(),
+			"exclusive": this.Exclusive,
+		},
+	}
+}
+func (this *IntervalExpr) Decode(b []byte) error {
+	var v struct {
+		Start     JsonNode `json:"start"`
+		End       JsonNode `json:"end"`
+		Exclusive bool     `json:"exclusive"`
+	}
+	var err error
+	if err = json.Unmarshal(b, &v); nil != err {
+		return function.NewError(err)
+	}
+	this.Start, err = v.Start.decodeExpr()
+	if nil != err {
+		return function.NewError(err)
+	}
+	this.End, err = v.End.decodeExpr()
+	if nil != err {
+		return function.NewError(err)
+	}
+	this.Exclusive = v.Exclusive
+	return nil
+}
+func (this *IntervalExpr) expressionNode() {}
+
+func (this *IntervalExpr) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(this.Start.String())
+	if this.Exclusive {
+		out.WriteString("..<")
+	} else {
+		out.WriteString("..")
+	}
+	out.WriteString(this.End.String())
+	out.WriteString(")")
+	return out.String()
+}
+
+func (this *IntervalExpr) Eval(e object.Env) (object.Object, error) {
+	start, err := this.Start.Eval(e)
+	if nil != err {
+		return object.Nil, err
+	}
+	end, err := this.End.Eval(e)
+	if nil != err {
+		return object.Nil, err
+	}
+	return object.NewInterval(start, end, this.Exclusive)
+}
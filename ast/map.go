@@ -68,7 +68,7 @@ func (this *MapExpr) Eval(e object.Env) (object.Object, error) {
 	if nil != err {
 		return object.Nil, err
 	}
-	arr, err := v.AsArray()
+	sz, err := object.MapLen(v)
 	if nil != err {
 		return object.Nil, err
 	}
@@ -79,17 +79,16 @@ func (this *MapExpr) Eval(e object.Env) (object.Object, error) {
 	if !object.IsCallable(cb) {
 		return object.Nil, errNotCallable
 	}
-	if arr.Items == nil || len(arr.Items) < 1 {
-		return object.NewArray(object.Objects{}), nil
-	}
-	sz := len(arr.Items)
 	r := make(object.Objects, sz)
-	for i, item := range arr.Items {
-		v, err := cb.Call(object.Objects{object.NewInteger(int64(i)), item})
+	if err := object.Iterate(v, func(i int64, item object.Object) error {
+		v, err := cb.Call(object.Objects{object.NewInteger(i), item})
 		if nil != err {
-			return object.Nil, err
+			return err
 		}
 		r[i] = v
+		return nil
+	}); nil != err {
+		return object.Nil, err
 	}
 	return object.NewArray(r), nil
 }
@@ -0,0 +1,175 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+)
+
+// MatchArm : pattern [if guard] => body
+type MatchArm struct {
+	Pattern Pattern
+	Guard   Expression // nil if the arm has no guard
+	Body    Expression
+}
+
+func (this *MatchArm) encode() interface{} {
+	var guard interface{}
+	if nil != this.Guard {
+		guard = this.Guard.Encode()
+	}
+	return map[string]interface{}{
+		"pattern": this.Pattern.Encode(),
+		"guard":   guard,
+		"body":    this.Body.Encode(),
+	}
+}
+
+func (this *MatchArm) decode(b []byte) error {
+	var v struct {
+		Pattern JsonNode  `json:"pattern"`
+		Guard   *JsonNode `json:"guard"`
+		Body    JsonNode  `json:"body"`
+	}
+	var err error
+	if err = json.Unmarshal(b, &v); nil != err {
+		return function.NewError(err)
+	}
+	this.Pattern, err = v.Pattern.decodePattern()
+	if nil != err {
+		return function.NewError(err)
+	}
+	if nil != v.Guard {
+		this.Guard, err = v.Guard.decodeExpr()
+		if nil != err {
+			return function.NewError(err)
+		}
+	}
+	this.Body, err = v.Body.decodeExpr()
+	if nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+func (this *MatchArm) String() string {
+	var out bytes.Buffer
+	out.WriteString(this.Pattern.String())
+	if nil != this.Guard {
+		out.WriteString(" if ")
+		out.WriteString(this.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(this.Body.String())
+	return out.String()
+}
+
+// eval : false if the pattern or the guard rejects v
+func (this *MatchArm) eval(e object.Env, v object.Object) (object.Object, bool, error) {
+	env := e.NewEnclosedEnv()
+	ok, err := this.Pattern.Match(v, func(name string, val object.Object) {
+		env.Set(name, val)
+	})
+	if nil != err || !ok {
+		return object.Nil, false, err
+	}
+	if nil != this.Guard {
+		g, err := this.Guard.Eval(env)
+		if nil != err {
+			return object.Nil, false, err
+		}
+		if !g.True() {
+			return object.Nil, false, nil
+		}
+	}
+	r, err := this.Body.Eval(env)
+	if nil != err {
+		return object.Nil, false, err
+	}
+	return r, true, nil
+}
+
+type MatchArms []*MatchArm
+
+// MatchExpr : implement Expression
+type MatchExpr struct {
+	defaultNode
+	Value Expression
+	Arms  MatchArms
+}
+
+func (this *MatchExpr) Do(v Visitor) error {
+	return v.DoMatch(this)
+}
+
+func (this *MatchExpr) Encode() interface{} {
+	arms := []interface{}{}
+	for _, arm := range this.Arms {
+		arms = append(arms, arm.encode())
+	}
+	return map[string]interface{}{
+		keyType: typeExprMatch,
+		keyValue: map[string]interface{}{
+			"value": this.Value.Encode(),
+			"arms":  arms,
+		},
+	}
+}
+func (this *MatchExpr) Decode(b []byte) error {
+	var v struct {
+		Value JsonNode          `json:"value"`
+		Arms  []json.RawMessage `json:"arms"`
+	}
+	var err error
+	if err = json.Unmarshal(b, &v); nil != err {
+		return function.NewError(err)
+	}
+	this.Value, err = v.Value.decodeExpr()
+	if nil != err {
+		return function.NewError(err)
+	}
+	this.Arms = MatchArms{}
+	for _, item := range v.Arms {
+		arm := &MatchArm{}
+		if err := arm.decode(item); nil != err {
+			return function.NewError(err)
+		}
+		this.Arms = append(this.Arms, arm)
+	}
+	return nil
+}
+func (this *MatchExpr) expressionNode() {}
+
+func (this *MatchExpr) String() string {
+	var out bytes.Buffer
+	arms := []string{}
+	for _, arm := range this.Arms {
+		arms = append(arms, arm.String())
+	}
+	out.WriteString("match ")
+	out.WriteString(this.Value.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+	return out.String()
+}
+
+func (this *MatchExpr) Eval(e object.Env) (object.Object, error) {
+	v, err := this.Value.Eval(e)
+	if nil != err {
+		return object.Nil, err
+	}
+	for _, arm := range this.Arms {
+		r, ok, err := arm.eval(e, v)
+		if nil != err {
+			return object.Nil, err
+		}
+		if ok {
+			return r, nil
+		}
+	}
+	return object.Nil, object.NoMatch(v)
+}
@@ -0,0 +1,71 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+)
+
+// NullishExpr : implement Expression
+type NullishExpr struct {
+	defaultNode
+	Left  Expression
+	Right Expression // evaluated only when left is null
+}
+
+func (this *NullishExpr) Do(v Visitor) error {
+	return v.DoNullish(this)
+}
+
+func (this *NullishExpr) Encode() interface{} {
+	return map[string]interface{}{
+		keyType: typeExprNullish,
+		keyValue: map[string]interface{}{
+			"left":  this.Left.Encode(),
+			"right": this.Right.Encode(),
+		},
+	}
+}
+func (this *NullishExpr) Decode(b []byte) error {
+	var v struct {
+		Left  JsonNode `json:"left"`
+		Right JsonNode `json:"right"`
+	}
+	var err error
+	if err = json.Unmarshal(b, &v); nil != err {
+		return function.NewError(err)
+	}
+	this.Left, err = v.Left.decodeExpr()
+	if nil != err {
+		return function.NewError(err)
+	}
+	this.Right, err = v.Right.decodeExpr()
+	if nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+func (this *NullishExpr) expressionNode() {}
+
+func (this *NullishExpr) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(this.Left.String())
+	out.WriteString(" ?? ")
+	out.WriteString(this.Right.String())
+	out.WriteString(")")
+	return out.String()
+}
+
+func (this *NullishExpr) Eval(e object.Env) (object.Object, error) {
+	left, err := this.Left.Eval(e)
+	if nil != err {
+		return object.Nil, err
+	}
+	if !object.IsNull(left) {
+		return left, nil
+	}
+	return this.Right.Eval(e)
+}
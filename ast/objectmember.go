@@ -11,8 +11,9 @@ import (
 // ObjectMember : implement Expression
 type ObjectMember struct {
 	defaultNode
-	Left   Expression
-	Member *Identifier
+	Left     Expression
+	Member   *Identifier
+	Optional bool // left?.member
 }
 
 func (this *ObjectMember) Do(v Visitor) error {
@@ -20,18 +21,21 @@ func (this *ObjectMember) Do(v Visitor) error {
 }
 
 func (this *ObjectMember) Encode() interface{} {
+	v := map[string]interface{}{
+		"left":   this.Left.Encode(),
+		"member": this.Member.Encode(),
+	}
+	encodeOptionalFlag(v, this.Optional)
 	return map[string]interface{}{
-		keyType: typeExprObjectmember,
-		keyValue: map[string]interface{}{
-			"left":   this.Left.Encode(),
-			"member": this.Member.Encode(),
-		},
+		keyType:  typeExprObjectmember,
+		keyValue: v,
 	}
 }
 func (this *ObjectMember) Decode(b []byte) error {
 	var v struct {
-		Left   JsonNode `json:"left"`
-		Member JsonNode `json:"member"`
+		Left     JsonNode `json:"left"`
+		Member   JsonNode `json:"member"`
+		Optional bool     `json:"optional"`
 	}
 	var err error
 	if err = json.Unmarshal(b, &v); nil != err {
@@ -45,6 +49,7 @@ func (this *ObjectMember) Decode(b []byte) error {
 	if nil != err {
 		return function.NewError(err)
 	}
+	this.Optional = v.Optional
 	return nil
 }
 func (this *ObjectMember) expressionNode() {}
@@ -53,16 +58,32 @@ func (this *ObjectMember) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(this.Left.String())
-	out.WriteString(".")
+	out.WriteString(memberPeriod(this.Optional))
 	out.WriteString(this.Member.String())
 
 	return out.String()
 }
 
 func (this *ObjectMember) Eval(e object.Env) (object.Object, error) {
-	obj, err := this.Left.Eval(e)
+	r, _, err := this.evalLink(e)
+	return r, err
+}
+
+func (this *ObjectMember) evalLink(e object.Env) (object.Object, bool, error) {
+	obj, short, err := evalLeft(this.Left, e)
 	if nil != err {
-		return object.Nil, err
+		return object.Nil, false, err
+	}
+	if short || (this.Optional && object.IsNull(obj)) {
+		return object.Nil, true, nil
+	}
+	r, err := obj.GetMember(this.Member.Value)
+	return r, false, err
+}
+
+func memberPeriod(optional bool) string {
+	if optional {
+		return "?."
 	}
-	return obj.GetMember(this.Member.Value)
+	return "."
 }
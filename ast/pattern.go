@@ -0,0 +1,372 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+	"github.com/jobs-github/escript/token"
+)
+
+const Wildcard = "_"
+
+// PatternVisitor : patterns are not expressions, the compiler lowers them with its own visitor
+type PatternVisitor interface {
+	DoWildcardPattern(p *WildcardPattern) error
+	DoBindPattern(p *BindPattern) error
+	DoLiteralPattern(p *LiteralPattern) error
+	DoAltPattern(p *AltPattern) error
+	DoArrayPattern(p *ArrayPattern) error
+	DoHashPattern(p *HashPattern) error
+}
+
+// Pattern : the left side of a match arm
+type Pattern interface {
+	Do(v PatternVisitor) error
+	Encode() interface{}
+	Decode(b []byte) error
+	String() string
+	// Match : bind is called for every captured name, the bindings are dropped if v does not match
+	Match(v object.Object, bind func(name string, val object.Object)) (bool, error)
+	// Names : captured names in the order of appearance
+	Names() []string
+}
+
+type PatternSlice []Pattern
+
+func (this *PatternSlice) encode() interface{} {
+	arr := []interface{}{}
+	for _, p := range *this {
+		arr = append(arr, p.Encode())
+	}
+	return arr
+}
+
+func (this *PatternSlice) decode(b []byte) error {
+	var v []JsonNode
+	if err := json.Unmarshal(b, &v); nil != err {
+		return function.NewError(err)
+	}
+	for _, n := range v {
+		p, err := n.decodePattern()
+		if nil != err {
+			return function.NewError(err)
+		}
+		*this = append(*this, p)
+	}
+	return nil
+}
+
+func (this *PatternSlice) names() []string {
+	r := []string{}
+	for _, p := range *this {
+		r = append(r, p.Names()...)
+	}
+	return r
+}
+
+func (this *PatternSlice) strings() []string {
+	r := []string{}
+	for _, p := range *this {
+		r = append(r, p.String())
+	}
+	return r
+}
+
+// literalString : string literals are quoted so that they differ from bindings
+func literalString(e Expression) string {
+	if s, ok := e.(*String); ok {
+		return strconv.Quote(s.Value)
+	}
+	return e.String()
+}
+
+// destruct : bind the names of p in e, error if the shape of v does not fit p
+func destruct(p Pattern, v object.Object, e object.Env) error {
+	ok, err := p.Match(v, func(name string, val object.Object) {
+		e.Set(name, val)
+	})
+	if nil != err {
+		return err
+	}
+	if !ok {
+		return object.CannotDestruct(p.String(), v)
+	}
+	return nil
+}
+
+// WildcardPattern : implement Pattern, `_` matches anything and binds nothing
+type WildcardPattern struct{}
+
+func (this *WildcardPattern) Do(v PatternVisitor) error {
+	return v.DoWildcardPattern(this)
+}
+func (this *WildcardPattern) Encode() interface{} {
+	return map[string]interface{}{
+		keyType: typePatternWildcard,
+	}
+}
+func (this *WildcardPattern) Decode(b []byte) error { return nil }
+func (this *WildcardPattern) String() string        { return Wildcard }
+func (this *WildcardPattern) Names() []string       { return nil }
+func (this *WildcardPattern) Match(v object.Object, bind func(name string, val object.Object)) (bool, error) {
+	return true, nil
+}
+
+// BindPattern : implement Pattern, a name matches anything and binds it
+type BindPattern struct {
+	Name *Identifier
+}
+
+func (this *BindPattern) Do(v PatternVisitor) error {
+	return v.DoBindPattern(this)
+}
+func (this *BindPattern) Encode() interface{} {
+	return map[string]interface{}{
+		keyType:  typePatternBind,
+		keyValue: this.Name.Value,
+	}
+}
+func (this *BindPattern) Decode(b []byte) error {
+	this.Name = NewIdent()
+	return this.Name.Decode(b)
+}
+func (this *BindPattern) String() string  { return this.Name.Value }
+func (this *BindPattern) Names() []string { return []string{this.Name.Value} }
+func (this *BindPattern) Match(v object.Object, bind func(name string, val object.Object)) (bool, error) {
+	bind(this.Name.Value, v)
+	return true, nil
+}
+
+// LiteralPattern : implement Pattern, integer, string, boolean or null compared strictly
+type LiteralPattern struct {
+	Value Expression
+}
+
+func (this *LiteralPattern) Do(v PatternVisitor) error {
+	return v.DoLiteralPattern(this)
+}
+func (this *LiteralPattern) Encode() interface{} {
+	return map[string]interface{}{
+		keyType:  typePatternLiteral,
+		keyValue: this.Value.Encode(),
+	}
+}
+func (this *LiteralPattern) Decode(b []byte) error {
+	var v JsonNode
+	var err error
+	if err = json.Unmarshal(b, &v); nil != err {
+		return function.NewError(err)
+	}
+	this.Value, err = v.decodeExpr()
+	if nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+func (this *LiteralPattern) String() string  { return literalString(this.Value) }
+func (this *LiteralPattern) Names() []string { return nil }
+func (this *LiteralPattern) Match(v object.Object, bind func(name string, val object.Object)) (bool, error) {
+	lit, err := this.Object()
+	if nil != err {
+		return false, err
+	}
+	return object.Equals(lit, v), nil
+}
+
+// Object : literals do not depend on the env
+func (this *LiteralPattern) Object() (object.Object, error) {
+	return this.Value.Eval(nil)
+}
+
+// AltPattern : implement Pattern, `p1 | p2`, alternatives never bind
+type AltPattern struct {
+	Alts PatternSlice
+}
+
+func (this *AltPattern) Do(v PatternVisitor) error {
+	return v.DoAltPattern(this)
+}
+func (this *AltPattern) Encode() interface{} {
+	return map[string]interface{}{
+		keyType:  typePatternAlt,
+		keyValue: this.Alts.encode(),
+	}
+}
+func (this *AltPattern) Decode(b []byte) error {
+	this.Alts = PatternSlice{}
+	return this.Alts.decode(b)
+}
+func (this *AltPattern) String() string  { return strings.Join(this.Alts.strings(), " | ") }
+func (this *AltPattern) Names() []string { return nil }
+func (this *AltPattern) Match(v object.Object, bind func(name string, val object.Object)) (bool, error) {
+	for _, p := range this.Alts {
+		ok, err := p.Match(v, bind)
+		if nil != err {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ArrayPattern : implement Pattern, `[p1, p2]` matches an array of exactly the same length,
+// `[p1, ...rest]` matches an array of at least 1 item and binds the remaining items to rest
+type ArrayPattern struct {
+	Elems PatternSlice
+	Rest  Pattern // nil if there is no `...`, otherwise a bind or `_`
+}
+
+func (this *ArrayPattern) Do(v PatternVisitor) error {
+	return v.DoArrayPattern(this)
+}
+func (this *ArrayPattern) Encode() interface{} {
+	var rest interface{}
+	if nil != this.Rest {
+		rest = this.Rest.Encode()
+	}
+	return map[string]interface{}{
+		keyType: typePatternArray,
+		keyValue: map[string]interface{}{
+			"elems": this.Elems.encode(),
+			"rest":  rest,
+		},
+	}
+}
+func (this *ArrayPattern) Decode(b []byte) error {
+	var v struct {
+		Elems json.RawMessage `json:"elems"`
+		Rest  *JsonNode       `json:"rest"`
+	}
+	if err := json.Unmarshal(b, &v); nil != err {
+		return function.NewError(err)
+	}
+	this.Elems = PatternSlice{}
+	if err := this.Elems.decode(v.Elems); nil != err {
+		return function.NewError(err)
+	}
+	if nil != v.Rest {
+		var err error
+		this.Rest, err = v.Rest.decodePattern()
+		if nil != err {
+			return function.NewError(err)
+		}
+	}
+	return nil
+}
+func (this *ArrayPattern) String() string {
+	items := this.Elems.strings()
+	if nil != this.Rest {
+		items = append(items, token.Ellipsis+this.Rest.String())
+	}
+	return fmt.Sprintf("[%v]", strings.Join(items, ", "))
+}
+func (this *ArrayPattern) Names() []string {
+	names := this.Elems.names()
+	if nil != this.Rest {
+		names = append(names, this.Rest.Names()...)
+	}
+	return names
+}
+func (this *ArrayPattern) Match(v object.Object, bind func(name string, val object.Object)) (bool, error) {
+	sz := len(this.Elems)
+	if !object.IsArrayOf(v, sz, nil != this.Rest) {
+		return false, nil
+	}
+	arr, err := v.AsArray()
+	if nil != err {
+		return false, err
+	}
+	for i, p := range this.Elems {
+		ok, err := p.Match(arr.Items[i], bind)
+		if nil != err || !ok {
+			return false, err
+		}
+	}
+	if nil != this.Rest {
+		rest := make(object.Objects, len(arr.Items)-sz)
+		copy(rest, arr.Items[sz:])
+		return this.Rest.Match(object.NewArray(rest), bind)
+	}
+	return true, nil
+}
+
+// HashPattern : implement Pattern, `{"k": p}` matches a hash which has all the keys, other keys are ignored
+type HashPattern struct {
+	Keys   ExpressionSlice // literals
+	Values PatternSlice
+}
+
+func (this *HashPattern) Do(v PatternVisitor) error {
+	return v.DoHashPattern(this)
+}
+func (this *HashPattern) Encode() interface{} {
+	return map[string]interface{}{
+		keyType: typePatternHash,
+		keyValue: map[string]interface{}{
+			"keys":   this.Keys.encode(),
+			"values": this.Values.encode(),
+		},
+	}
+}
+func (this *HashPattern) Decode(b []byte) error {
+	var v struct {
+		Keys   json.RawMessage `json:"keys"`
+		Values json.RawMessage `json:"values"`
+	}
+	if err := json.Unmarshal(b, &v); nil != err {
+		return function.NewError(err)
+	}
+	var err error
+	this.Keys, err = decodeExprs(v.Keys)
+	if nil != err {
+		return function.NewError(err)
+	}
+	this.Values = PatternSlice{}
+	if err := this.Values.decode(v.Values); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+func (this *HashPattern) String() string {
+	var out bytes.Buffer
+	items := []string{}
+	for i, k := range this.Keys {
+		items = append(items, fmt.Sprintf("%v: %v", literalString(k), this.Values[i].String()))
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(items, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+func (this *HashPattern) Names() []string { return this.Values.names() }
+func (this *HashPattern) Match(v object.Object, bind func(name string, val object.Object)) (bool, error) {
+	if !object.IsHash(v) {
+		return false, nil
+	}
+	for i, k := range this.Keys {
+		key, err := k.Eval(nil)
+		if nil != err {
+			return false, err
+		}
+		found, err := object.Contains(v, key, false)
+		if nil != err || !found.True() {
+			return false, err
+		}
+		val, err := v.CallMember(object.FnIndex, object.Objects{key})
+		if nil != err {
+			return false, err
+		}
+		ok, err := this.Values[i].Match(val, bind)
+		if nil != err || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
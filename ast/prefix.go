@@ -38,7 +38,7 @@ func (this *PrefixExpr) Decode(b []byte) error {
 	if err = json.Unmarshal(b, &v); nil != err {
 		return function.NewError(err)
 	}
-	this.Op, err = token.GetInfixToken(v.Op)
+	this.Op, err = token.GetPrefixToken(v.Op)
 	if nil != err {
 		return function.NewError(err)
 	}
@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 
 	"github.com/jobs-github/escript/function"
 	"github.com/jobs-github/escript/object"
@@ -73,6 +74,9 @@ func (this *RangeExpr) Eval(e object.Env) (object.Object, error) {
 	if !object.IsCallable(fn) {
 		return object.Nil, err
 	}
+	if cnt < 0 {
+		return object.Nil, function.NewError(fmt.Errorf("range with negative count %v", cnt))
+	}
 	r := make(object.Objects, cnt)
 	for i := int64(0); i < cnt; i++ {
 		v, err := fn.Call(object.Objects{object.NewInteger(i)})
@@ -77,10 +77,6 @@ func (this *ReduceExpr) Eval(e object.Env) (object.Object, error) {
 	if nil != err {
 		return object.Nil, err
 	}
-	arr, err := v.AsArray()
-	if nil != err {
-		return object.Nil, err
-	}
 	acc, err := this.Init.Eval(e)
 	if nil != err {
 		return object.Nil, err
@@ -92,15 +88,15 @@ func (this *ReduceExpr) Eval(e object.Env) (object.Object, error) {
 	if !object.IsCallable(cb) {
 		return object.Nil, errNotCallable
 	}
-	if arr.Items == nil || len(arr.Items) < 1 {
-		return acc, nil
-	}
-	for _, item := range arr.Items {
+	if err := object.Iterate(v, func(_ int64, item object.Object) error {
 		v, err := cb.Call(object.Objects{acc, item})
 		if nil != err {
-			return object.Nil, err
+			return err
 		}
 		acc = v
+		return nil
+	}); nil != err {
+		return object.Nil, err
 	}
 	return acc, nil
 }
@@ -0,0 +1,108 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+)
+
+// SliceExpr : implement Expression
+type SliceExpr struct {
+	defaultNode
+	Left  Expression
+	Start Expression // nil if omitted
+	End   Expression // nil if omitted
+	Step  Expression // nil if omitted
+}
+
+func (this *SliceExpr) Do(v Visitor) error {
+	return v.DoSlice(this)
+}
+
+func (this *SliceExpr) Bounds() ExpressionSlice {
+	return ExpressionSlice{this.Start, this.End, this.Step}
+}
+
+func (this *SliceExpr) Encode() interface{} {
+	return map[string]interface{}{
+		keyType: typeExprSlice,
+		keyValue: map[string]interface{}{
+			"left":  this.Left.Encode(),
+			"start": encodeOptional(this.Start),
+			"end":   encodeOptional(this.End),
+			"step":  encodeOptional(this.Step),
+		},
+	}
+}
+func (this *SliceExpr) Decode(b []byte) error {
+	var v struct {
+		Left  JsonNode  `json:"left"`
+		Start *JsonNode `json:"start"`
+		End   *JsonNode `json:"end"`
+		Step  *JsonNode `json:"step"`
+	}
+	var err error
+	if err = json.Unmarshal(b, &v); nil != err {
+		return function.NewError(err)
+	}
+	this.Left, err = v.Left.decodeExpr()
+	if nil != err {
+		return function.NewError(err)
+	}
+	this.Start, err = decodeOptional(v.Start)
+	if nil != err {
+		return function.NewError(err)
+	}
+	this.End, err = decodeOptional(v.End)
+	if nil != err {
+		return function.NewError(err)
+	}
+	this.Step, err = decodeOptional(v.Step)
+	if nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+func (this *SliceExpr) expressionNode() {}
+
+func (this *SliceExpr) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(this.Left.String())
+	out.WriteString("[")
+	if nil != this.Start {
+		out.WriteString(this.Start.String())
+	}
+	out.WriteString(":")
+	if nil != this.End {
+		out.WriteString(this.End.String())
+	}
+	if nil != this.Step {
+		out.WriteString(":")
+		out.WriteString(this.Step.String())
+	}
+	out.WriteString("])")
+	return out.String()
+}
+
+func (this *SliceExpr) Eval(e object.Env) (object.Object, error) {
+	left, err := this.Left.Eval(e)
+	if nil != err {
+		return object.Nil, err
+	}
+	args := object.Objects{}
+	for _, bound := range this.Bounds() {
+		if nil == bound {
+			args = append(args, object.Nil)
+			continue
+		}
+		v, err := bound.Eval(e)
+		if nil != err {
+			return object.Nil, err
+		}
+		args = append(args, v)
+	}
+	return left.CallMember(object.FnSlice, args)
+}
@@ -0,0 +1,84 @@
+package ast
+
+import (
+	"errors"
+
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+	"github.com/jobs-github/escript/token"
+)
+
+var (
+	ErrSpread = errors.New("spread `...` is only allowed in call arguments")
+)
+
+// SpreadExpr : implement Expression, `...arr` passes the items of arr as args
+type SpreadExpr struct {
+	defaultNode
+	Value Expression
+}
+
+func (this *SpreadExpr) Do(v Visitor) error {
+	return v.DoSpread(this)
+}
+
+func (this *SpreadExpr) Encode() interface{} {
+	return map[string]interface{}{
+		keyType:  typeExprSpread,
+		keyValue: this.Value.Encode(),
+	}
+}
+func (this *SpreadExpr) Decode(b []byte) error {
+	var err error
+	this.Value, err = decodeExpr(b)
+	if nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+func (this *SpreadExpr) expressionNode() {}
+
+func (this *SpreadExpr) String() string {
+	return token.Ellipsis + this.Value.String()
+}
+
+// Eval : the items are expanded by the call
+func (this *SpreadExpr) Eval(e object.Env) (object.Object, error) {
+	return object.Nil, function.NewError(ErrSpread)
+}
+
+// HasSpread : whether any of args is spread
+func HasSpread(args ExpressionSlice) bool {
+	for _, a := range args {
+		if _, ok := a.(*SpreadExpr); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// evalArgs : evaluate args, the items of a spread arg are expanded in place
+func evalArgs(args ExpressionSlice, e object.Env) (object.Objects, error) {
+	r := object.Objects{}
+	for _, a := range args {
+		spread, ok := a.(*SpreadExpr)
+		if !ok {
+			v, err := a.Eval(e)
+			if nil != err {
+				return nil, err
+			}
+			r.Append(v)
+			continue
+		}
+		v, err := spread.Value.Eval(e)
+		if nil != err {
+			return nil, err
+		}
+		arr, err := v.AsArray()
+		if nil != err {
+			return nil, object.CannotSpread(v)
+		}
+		r = append(r, arr.Items...)
+	}
+	return r, nil
+}
@@ -0,0 +1,165 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+	"github.com/jobs-github/escript/token"
+)
+
+// StructStmt : implement Statement, `struct Name { fields }`
+type StructStmt struct {
+	defaultNode
+	Name   *Identifier
+	Fields IdentifierSlice
+}
+
+func (this *StructStmt) Do(v Visitor) error {
+	return v.DoStruct(this)
+}
+
+func (this *StructStmt) Encode() interface{} {
+	return map[string]interface{}{
+		keyType: typeStmtStruct,
+		keyValue: map[string]interface{}{
+			"name":   this.Name.Encode(),
+			"fields": this.Fields.encode(),
+		},
+	}
+}
+func (this *StructStmt) Decode(b []byte) error {
+	var v struct {
+		Name   JsonNode        `json:"name"`
+		Fields json.RawMessage `json:"fields"`
+	}
+	var err error
+	if err = json.Unmarshal(b, &v); nil != err {
+		return function.NewError(err)
+	}
+	this.Name, err = v.Name.decodeIdent()
+	if nil != err {
+		return function.NewError(err)
+	}
+	this.Fields, err = decodeIdents(v.Fields)
+	if nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+func (this *StructStmt) statementNode() {}
+
+func (this *StructStmt) String() string {
+	var out bytes.Buffer
+	out.WriteString(token.Struct)
+	out.WriteString(" ")
+	out.WriteString(this.Name.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(this.Fields.Values(), ", "))
+	out.WriteString(" };")
+	return out.String()
+}
+
+// Object : a new struct type, methods are added to it by MethodStmt
+func (this *StructStmt) Object() *object.StructType {
+	return object.NewStructType(this.Name.Value, this.Fields.Values())
+}
+
+func (this *StructStmt) Eval(e object.Env) (object.Object, error) {
+	t := this.Object()
+	e.Set(this.Name.Value, t)
+	return t, nil
+}
+
+// MethodStmt : implement Statement, `func (recv Type) name(args) {...}`
+type MethodStmt struct {
+	defaultNode
+	Recv  *Identifier
+	Type  *Identifier
+	Value *Function // Name is the name of method, Args excludes the receiver
+}
+
+func (this *MethodStmt) Do(v Visitor) error {
+	return v.DoMethod(this)
+}
+
+func (this *MethodStmt) Encode() interface{} {
+	return map[string]interface{}{
+		keyType: typeStmtMethod,
+		keyValue: map[string]interface{}{
+			"recv":  this.Recv.Encode(),
+			"type":  this.Type.Encode(),
+			"value": this.Value.Encode(),
+		},
+	}
+}
+func (this *MethodStmt) Decode(b []byte) error {
+	var v struct {
+		Recv  JsonNode `json:"recv"`
+		Type  JsonNode `json:"type"`
+		Value JsonNode `json:"value"`
+	}
+	var err error
+	if err = json.Unmarshal(b, &v); nil != err {
+		return function.NewError(err)
+	}
+	this.Recv, err = v.Recv.decodeIdent()
+	if nil != err {
+		return function.NewError(err)
+	}
+	this.Type, err = v.Type.decodeIdent()
+	if nil != err {
+		return function.NewError(err)
+	}
+	this.Value, err = v.Value.decodeFn()
+	if nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+func (this *MethodStmt) statementNode() {}
+
+func (this *MethodStmt) String() string {
+	var out bytes.Buffer
+	out.WriteString("func (")
+	out.WriteString(this.Recv.String())
+	out.WriteString(" ")
+	out.WriteString(this.Type.String())
+	out.WriteString(") ")
+	out.WriteString(this.Value.String())
+	out.WriteString(";")
+	return out.String()
+}
+
+// Fn : the method as a function which takes the receiver as its first arg
+func (this *MethodStmt) Fn() *Function {
+	fn := *this.Value
+	fn.Args = append(IdentifierSlice{this.Recv}, this.Value.Args...)
+	if nil != this.Value.Params {
+		fn.Params = append(PatternSlice{&BindPattern{Name: this.Recv}}, this.Value.Params...)
+	}
+	if nil != this.Value.Defaults {
+		fn.Defaults = append(ExpressionSlice{nil}, this.Value.Defaults...)
+	}
+	if nil != this.Value.Types {
+		fn.Types = append([]*TypeExpr{{Name: this.Type.Value}}, this.Value.Types...)
+	}
+	return &fn
+}
+
+func (this *MethodStmt) Eval(e object.Env) (object.Object, error) {
+	t, err := this.Type.Eval(e)
+	if nil != err {
+		return object.Nil, err
+	}
+	fn, err := this.Fn().Eval(e)
+	if nil != err {
+		return object.Nil, err
+	}
+	if err := object.DefineMethod(t, this.Value.Name, fn); nil != err {
+		return object.Nil, err
+	}
+	return fn, nil
+}
@@ -0,0 +1,65 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+)
+
+var (
+	templateEscaper = strings.NewReplacer("`", "\\`", "${", "\\${")
+)
+
+// TemplateExpr : implement Expression
+type TemplateExpr struct {
+	defaultNode
+	Parts ExpressionSlice // string literals and `${}` expressions
+}
+
+func (this *TemplateExpr) Do(v Visitor) error {
+	return v.DoTemplate(this)
+}
+
+func (this *TemplateExpr) Encode() interface{} {
+	return map[string]interface{}{
+		keyType:  typeExprTemplate,
+		keyValue: this.Parts.encode(),
+	}
+}
+
+func (this *TemplateExpr) Decode(b []byte) error {
+	var err error
+	this.Parts, err = decodeExprs(b)
+	if nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+func (this *TemplateExpr) expressionNode() {}
+
+func (this *TemplateExpr) String() string {
+	var out bytes.Buffer
+	out.WriteString("`")
+	for _, v := range this.Parts {
+		if s, ok := v.(*String); ok {
+			out.WriteString(templateEscaper.Replace(s.Value))
+		} else {
+			out.WriteString("${")
+			out.WriteString(v.String())
+			out.WriteString("}")
+		}
+	}
+	out.WriteString("`")
+	return out.String()
+}
+
+func (this *TemplateExpr) Eval(e object.Env) (object.Object, error) {
+	parts, err := this.Parts.eval(e)
+	if nil != err {
+		return object.Nil, err
+	}
+	return object.Concat(parts)
+}
@@ -0,0 +1,104 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+)
+
+// TryExpr : implement Expression
+type TryExpr struct {
+	defaultNode
+	Try   *BlockStmt
+	Name  *Identifier // nil if error is not bound
+	Catch *BlockStmt
+}
+
+func (this *TryExpr) Do(v Visitor) error {
+	return v.DoTry(this)
+}
+
+// CatchFn : catch block as a function which takes the error as its argument
+func (this *TryExpr) CatchFn() *Function {
+	fn := NewFn()
+	fn.Args = IdentifierSlice{}
+	if nil != this.Name {
+		fn.Args = append(fn.Args, this.Name)
+	} else {
+		ident := NewIdent()
+		ident.Value = "__err__"
+		fn.Args = append(fn.Args, ident)
+	}
+	fn.Body = this.Catch
+	return fn
+}
+
+func (this *TryExpr) Encode() interface{} {
+	var name interface{}
+	if nil != this.Name {
+		name = this.Name.Encode()
+	}
+	return map[string]interface{}{
+		keyType: typeExprTry,
+		keyValue: map[string]interface{}{
+			"try":   this.Try.Encode(),
+			"name":  name,
+			"catch": this.Catch.Encode(),
+		},
+	}
+}
+func (this *TryExpr) Decode(b []byte) error {
+	var v struct {
+		Try   JsonNode  `json:"try"`
+		Name  *JsonNode `json:"name"`
+		Catch JsonNode  `json:"catch"`
+	}
+	var err error
+	if err = json.Unmarshal(b, &v); nil != err {
+		return function.NewError(err)
+	}
+	this.Try, err = v.Try.decodeBlockStmt()
+	if nil != err {
+		return function.NewError(err)
+	}
+	if nil != v.Name {
+		this.Name, err = v.Name.decodeIdent()
+		if nil != err {
+			return function.NewError(err)
+		}
+	}
+	this.Catch, err = v.Catch.decodeBlockStmt()
+	if nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+func (this *TryExpr) expressionNode() {}
+
+func (this *TryExpr) String() string {
+	var out bytes.Buffer
+	out.WriteString("try ")
+	out.WriteString(this.Try.String())
+	out.WriteString(" catch ")
+	if nil != this.Name {
+		out.WriteString("(")
+		out.WriteString(this.Name.String())
+		out.WriteString(") ")
+	}
+	out.WriteString(this.Catch.String())
+	return out.String()
+}
+
+func (this *TryExpr) Eval(e object.Env) (object.Object, error) {
+	r, err := this.Try.Eval(e.NewEnclosedEnv())
+	if nil == err {
+		return r, nil
+	}
+	env := e.NewEnclosedEnv()
+	if nil != this.Name {
+		env.Set(this.Name.Value, object.ToError(err))
+	}
+	return this.Catch.Eval(env)
+}
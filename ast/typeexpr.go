@@ -0,0 +1,64 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jobs-github/escript/function"
+)
+
+// TypeExpr : type annotation, `int`, `array<int>`, `hash<string>`, a struct name..., only consulted by the checker
+type TypeExpr struct {
+	Name string
+	Elem *TypeExpr // element of array or value of hash, nil if not given
+}
+
+func (this *TypeExpr) String() string {
+	if nil == this.Elem {
+		return this.Name
+	}
+	return fmt.Sprintf("%v<%v>", this.Name, this.Elem.String())
+}
+
+// ParseType : the inverse of TypeExpr.String, used to decode the annotations from json
+func ParseType(s string) (*TypeExpr, error) {
+	s = strings.TrimSpace(s)
+	idx := strings.Index(s, "<")
+	if idx < 0 {
+		if "" == s {
+			return nil, function.NewError(fmt.Errorf("invalid type `%v`", s))
+		}
+		return &TypeExpr{Name: s}, nil
+	}
+	if !strings.HasSuffix(s, ">") {
+		return nil, function.NewError(fmt.Errorf("invalid type `%v`", s))
+	}
+	elem, err := ParseType(s[idx+1 : len(s)-1])
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	return &TypeExpr{Name: strings.TrimSpace(s[:idx]), Elem: elem}, nil
+}
+
+// encodeType : an optional annotation is encoded as string, "" if not given
+func encodeType(t *TypeExpr) string {
+	if nil == t {
+		return ""
+	}
+	return t.String()
+}
+
+func decodeType(s string) (*TypeExpr, error) {
+	if "" == s {
+		return nil, nil
+	}
+	return ParseType(s)
+}
+
+// annotate : `name: type`
+func annotate(s string, t *TypeExpr) string {
+	if nil == t {
+		return s
+	}
+	return fmt.Sprintf("%v: %v", s, t.String())
+}
@@ -3,6 +3,7 @@ package builtin
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 
@@ -71,6 +72,8 @@ var (
 		newSymbol("sprintf", builtinSprintf),
 		newSymbol("loads", builtinLoads),
 		newSymbol("dumps", builtinDumps),
+		newSymbol("error", builtinError),
+		newSymbol("throw", builtinThrow),
 	}
 	builtins = builtinSymbolTable.newSymbolTable()
 )
@@ -88,20 +91,92 @@ func unquote(input string) string {
 	return input
 }
 
+// formatArg : typed value so that verbs like %d %05d %x %t %q work as go,
+// an integer is formatted as a float by the float verbs
+func formatArg(v object.Object, verb rune) (interface{}, error) {
+	switch obj := v.(type) {
+	case *object.Integer:
+		if isFloatVerb(verb) {
+			return float64(obj.Value), nil
+		}
+		return obj.Value, nil
+	case *object.BigInt:
+		if isFloatVerb(verb) {
+			return new(big.Float).SetInt(obj.Value), nil
+		}
+		return obj.Value, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+	}
+	return object.ToString(v)
+}
+
+func isFloatVerb(verb rune) bool {
+	return strings.ContainsRune("eEfFgG", verb)
+}
+
+// formatVerbs : the verb of each of the argc arguments, 0 if an argument is not used by a verb,
+// an argument index like %[2]d and a width or precision from the arguments like %*d are counted as fmt does
+func formatVerbs(format string, argc int) []rune {
+	r := make([]rune, argc)
+	arg := 0
+	for i := 0; i < len(format); i++ {
+		if '%' != format[i] {
+			continue
+		}
+		for i++; i < len(format); i++ {
+			c := format[i]
+			switch {
+			case '[' == c:
+				end := strings.IndexByte(format[i:], ']')
+				if end < 0 {
+					return r
+				}
+				if n, err := strconv.Atoi(format[i+1 : i+end]); nil == err {
+					arg = n - 1
+				}
+				i += end
+				continue
+			case '*' == c:
+				arg++
+				continue
+			case strings.IndexByte("+-# 0.", c) >= 0 || ('0' <= c && c <= '9'):
+				continue
+			}
+			if '%' != c {
+				if arg >= 0 && arg < argc {
+					r[arg] = rune(c)
+				}
+				arg++
+			}
+			break
+		}
+	}
+	return r
+}
+
 func newFormatArgs(entry string, args object.Objects) (*formatArgs, error) {
 	argc := len(args)
 	if argc < 2 {
 		return nil, fmt.Errorf("%v takes at least 2 arguments (%v given)", entry, argc)
 	}
-	s := []interface{}{}
-	for i := 1; i < argc; i++ {
-		s = append(s, args[i].String())
-	}
 	format := args[0]
 	if !object.IsString(format) {
 		return nil, fmt.Errorf("%v the first argument should be string (%v given)", entry, object.Typeof(format))
 	}
-	return &formatArgs{format: unquote(format.String()), args: s}, nil
+	f := unquote(format.String())
+	verbs := formatVerbs(f, argc-1)
+	s := []interface{}{}
+	for i := 1; i < argc; i++ {
+		v, err := formatArg(args[i], verbs[i-1])
+		if nil != err {
+			return nil, err
+		}
+		s = append(s, v)
+	}
+	return &formatArgs{format: f, args: s}, nil
 }
 
 // implement
@@ -118,7 +193,20 @@ func builtinStr(args object.Objects) (object.Object, error) {
 	if argc != 1 {
 		return object.Nil, fmt.Errorf("str() takes exactly one argument (%v given)", argc)
 	}
-	return object.NewString(args[0].String()), nil
+	s, err := object.ToString(args[0])
+	if nil != err {
+		return object.Nil, err
+	}
+	return object.NewString(s), nil
+}
+
+// concat : the string forms of args joined
+func concat(args object.Objects) (string, error) {
+	r, err := object.Concat(args)
+	if nil != err {
+		return "", err
+	}
+	return r.String(), nil
 }
 
 func builtinPrint(args object.Objects) (object.Object, error) {
@@ -126,11 +214,11 @@ func builtinPrint(args object.Objects) (object.Object, error) {
 	if argc == 0 {
 		return object.NewString(""), nil
 	}
-	var s strings.Builder
-	for _, arg := range args {
-		s.WriteString(arg.String())
+	s, err := concat(args)
+	if nil != err {
+		return object.Nil, err
 	}
-	fmt.Print(s.String())
+	fmt.Print(s)
 	return object.NewString(""), nil
 }
 
@@ -139,11 +227,11 @@ func builtinPrintln(args object.Objects) (object.Object, error) {
 	if argc == 0 {
 		return object.NewString(""), nil
 	}
-	var s strings.Builder
-	for _, arg := range args {
-		s.WriteString(arg.String())
+	s, err := concat(args)
+	if nil != err {
+		return object.Nil, err
 	}
-	fmt.Println(s.String())
+	fmt.Println(s)
 	return object.NewString(""), nil
 }
 
@@ -157,7 +245,7 @@ func builtinPrintf(args object.Objects) (object.Object, error) {
 }
 
 func builtinSprintf(args object.Objects) (object.Object, error) {
-	r, err := newFormatArgs("printf()", args)
+	r, err := newFormatArgs("sprintf()", args)
 	if nil != err {
 		return object.Nil, err
 	}
@@ -195,3 +283,22 @@ func builtinDumps(args object.Objects) (object.Object, error) {
 	}
 	return object.NewString(function.BytesToString(b)), nil
 }
+
+func builtinError(args object.Objects) (object.Object, error) {
+	argc := len(args)
+	if argc != 1 {
+		return object.Nil, fmt.Errorf("error() takes exactly one argument (%v given)", argc)
+	}
+	return object.NewError(args[0].String()), nil
+}
+
+func builtinThrow(args object.Objects) (object.Object, error) {
+	argc := len(args)
+	if argc != 1 {
+		return object.Nil, fmt.Errorf("throw() takes exactly one argument (%v given)", argc)
+	}
+	if e, ok := args[0].(*object.Error); ok {
+		return object.Nil, e
+	}
+	return object.Nil, object.NewError(args[0].String())
+}
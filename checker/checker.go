@@ -0,0 +1,203 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jobs-github/escript/ast"
+	"github.com/jobs-github/escript/builtin"
+)
+
+// Error : a type mismatch found by the checker
+type Error struct {
+	Pos ast.Position // zero if unknown, e.g. the ast is decoded from json
+	Msg string
+}
+
+func (this *Error) Error() string {
+	if !this.Pos.Valid() {
+		return this.Msg
+	}
+	return fmt.Sprintf("%v, %v", this.Msg, this.Pos.String())
+}
+
+// Errors : all the mismatches in the order of the source
+type Errors []*Error
+
+func (this Errors) Error() string {
+	msgs := []string{}
+	for _, err := range this {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Check : infer the types of node and report the mismatches with the annotations,
+// builtins and object methods, unannotated args and unknown symbols are of any type.
+// The error is Errors if there is any mismatch.
+func Check(node ast.Node) error {
+	c := newChecker()
+	c.check(node)
+	if len(c.errs) > 0 {
+		return c.errs
+	}
+	return nil
+}
+
+func newChecker() *checker {
+	return &checker{
+		scope:   newScope(nil),
+		structs: map[string]*structInfo{},
+		typ:     anyType,
+	}
+}
+
+// checker : implement ast.Visitor, every Do sets typ as the type of the node
+type checker struct {
+	scope   *scope
+	structs map[string]*structInfo
+	typ     *Type
+	pos     ast.Position // position of the innermost node which has one
+	errs    Errors
+}
+
+// check : type of node
+func (this *checker) check(node ast.Node) *Type {
+	saved := this.pos
+	if node.Pos().Valid() {
+		this.pos = node.Pos()
+	}
+	this.typ = anyType
+	node.Do(this)
+	this.pos = saved
+	return this.typ
+}
+
+func (this *checker) report(format string, args ...interface{}) {
+	this.errs = append(this.errs, &Error{Pos: this.pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+// reportAt : like report, at node if it has a position
+func (this *checker) reportAt(node ast.Node, format string, args ...interface{}) {
+	saved := this.pos
+	if node.Pos().Valid() {
+		this.pos = node.Pos()
+	}
+	this.report(format, args...)
+	this.pos = saved
+}
+
+func (this *checker) enterScope() {
+	this.scope = newScope(this.scope)
+}
+
+func (this *checker) leaveScope() {
+	this.scope = this.scope.outer
+}
+
+// lookup : symbols shadow the builtins, unknown symbols are of any type
+func (this *checker) lookup(name string) *Type {
+	if t, ok := this.scope.get(name); ok {
+		return t
+	}
+	if sig, ok := builtins[name]; ok && builtin.IsBuiltin(name) {
+		return funcOf(sig)
+	}
+	return anyType
+}
+
+// resolve : type of annotation, any if it is not given
+func (this *checker) resolve(t *ast.TypeExpr) *Type {
+	if nil == t {
+		return anyType
+	}
+	name := t.Name
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+	var elem *Type
+	if nil != t.Elem {
+		if !elemTypes[name] {
+			this.report("type `%v` takes no element type", t.String())
+			return anyType
+		}
+		elem = this.resolve(t.Elem)
+	}
+	if basicTypes[name] {
+		return &Type{Name: name, Elem: elem}
+	}
+	if info, ok := this.structs[name]; ok {
+		return info.instance()
+	}
+	this.report("unknown type `%v`", t.String())
+	return anyType
+}
+
+// signature : signature of fn by its annotations
+func (this *checker) signature(fn *ast.Function) *signature {
+	sig := &signature{
+		required: fn.Arity().Required,
+		returns:  this.resolve(fn.Returns),
+	}
+	for i := range fn.Args {
+		sig.args = append(sig.args, this.resolve(fn.ArgType(i)))
+	}
+	if nil != fn.Rest {
+		sig.rest = this.resolve(fn.RestType())
+		if !sig.rest.isAny() && !sig.rest.is(typeArray) {
+			this.report("rest `%v` should be array, got %v", fn.Rest.Value, sig.rest.String())
+			sig.rest = arrayOf(anyType)
+		}
+	}
+	return sig
+}
+
+// expect : report if a value of got is used where want is expected
+func (this *checker) expect(node ast.Node, want *Type, got *Type, what string) {
+	if !assignable(want, got) {
+		this.reportAt(node, "%v expects %v, got %v", what, want.String(), got.String())
+	}
+}
+
+// declare : structs and methods are known before any statement is checked,
+// so that a function may call a method which is declared after it
+func (this *checker) declare(stmts ast.StatementSlice) {
+	for _, stmt := range stmts {
+		if v, ok := stmt.(*ast.StructStmt); ok {
+			this.structs[v.Name.Value] = newStructInfo(v.Name.Value, v.Fields.Values())
+		}
+	}
+	saved := this.pos
+	for _, stmt := range stmts {
+		if v, ok := stmt.(*ast.MethodStmt); ok {
+			if info, ok := this.structs[v.Type.Value]; ok && !info.hasField(v.Value.Name) {
+				this.pos = v.Pos()
+				info.methods[v.Value.Name] = this.signature(v.Value)
+			}
+		}
+	}
+	this.pos = saved
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{outer: outer, m: map[string]*Type{}}
+}
+
+// scope : types of symbols
+type scope struct {
+	outer *scope
+	m     map[string]*Type
+}
+
+func (this *scope) get(name string) (*Type, bool) {
+	for s := this; nil != s; s = s.outer {
+		if t, ok := s.m[name]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func (this *scope) set(name string, t *Type) {
+	this.m[name] = t
+}
@@ -0,0 +1,148 @@
+package checker
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jobs-github/escript/ast"
+	"github.com/jobs-github/escript/parser"
+)
+
+func parseProgram(t *testing.T, code string) ast.Node {
+	p, err := parser.New(code)
+	if nil != err {
+		t.Fatal(err)
+	}
+	program, err := p.ParseProgram()
+	if nil != err {
+		t.Fatal(err)
+	}
+	return program
+}
+
+func TestCheck(t *testing.T) {
+	decl := `
+	struct Point { x, y };
+	func (p Point) scale(k: int): Point { Point(p.x * k, p.y * k) };
+	func (p Point) __add__(o: Point): Point { Point(p.x + o.x, p.y + o.y) };
+	func add(x: int, y: int = 1): int { x + y };
+	func join(sep: string, ...items: array<string>): string { sep };
+	`
+	tests := []string{
+		`add(1, 2) + 3`,
+		`add(1) * add(2, 3) - true`,
+		`const n: int = add(1);`,
+		`const a: array<int> = [1, 2, 3]; a[0] + a.first() + a.len()`,
+		`const h: hash<array<int>> = {"a": [1]}; h["a"][0] + 1`,
+		`const s: string = "a" + str(1) + type(1) + dumps([1]);`,
+		`const e: error = error("x"); e.message() + "!"`,
+		`join(",", "a", "b")`,
+		`null?.x.y(1)[0]`,
+		`join(",", ...["a"])`,
+		`Point(1, 2).scale(3).x`,
+		`(Point(1, 2) + Point(3, 4)).scale(2)`,
+		`Point(1, 2) == Point(1, 2)`,
+		`const f = func(x: int): int { x * 2 }; f(1) + 1`,
+		`const f: func = add; f("a")`,
+		`func fact(n: int): int { (n < 2) ? 1 : n * fact(n - 1) }; fact(5)`,
+		`map([1, 2], func(i, x) { x * 2 })[0] + 1`,
+		`const u = loads("{}"); u + 1`,
+		`try { 1 / 0 } catch (e) { e.message() }`,
+		`match 1 { 1 => "a", _ => "b" } + "c"`,
+		"`${1}` + \"a\"",
+		`const p: Point = Point(1, 2); p.y`,
+		`-true + ~1 + !"a"`,
+		`1 in [1] && "a" in "abc" && 1 in 1..3`,
+		`"abc"[1:] + "abc"[0]`,
+		`null ?? 1`,
+		`1 + null`,
+		`1..3 + 1`,
+	}
+	for _, code := range tests {
+		if err := Check(parseProgram(t, decl+code)); nil != err {
+			t.Fatalf("`%v` unexpected error: %v", code, err)
+		}
+	}
+	errs := []struct {
+		input string
+		want  string
+	}{
+		{`add("a")`, "arg 1 of `add` expects int, got string, line: 7, col: 6"},
+		{`add()`, "`add` takes 1..2 arguments (0 given)"},
+		{`add(1, 2, 3)`, "`add` takes 1..2 arguments (3 given)"},
+		{`const n: string = add(1);`, "const `n` expects string, got int"},
+		{`func f(x: int): string { x }`, "result of `f` expects string, got int"},
+		{`func f(x: int = "a") { x }`, "default value of `x` expects int, got string"},
+		{`func f(...x: int) { x }`, "rest `x` should be array, got int"},
+		{`const a: array<int> = ["a"];`, "const `a` expects array<int>, got array<string>"},
+		{`const a: array<int> = [1]; a.foo()`, "no attribute 'foo' in array<int>"},
+		{`const a: array<int> = [1]; a.push()`, "`push()` of array<int> takes 1 arguments (0 given)"},
+		{`const a: array<int> = [1]; a["x"]`, "index of array<int> expects int, got string"},
+		{`const a: array<int> = [1]; a[0] + "x"`, "unsupported op `+` between int and string"},
+		{`const h: hash<int> = {"a": "b"};`, "const `h` expects hash<int>, got hash<string>"},
+		{`"a" - "b"`, "unsupported op `-` between string and string"},
+		{`[1] < [2]`, "unsupported op `<` between array<int> and array<int>"},
+		{`-"a"`, "unsupported op `-` for string"},
+		{`1[0]`, "int does not support index"},
+		{`1[0:1]`, "int does not support slice"},
+		{`1 in 2`, "unsupported op `in` for int"},
+		{`1..[2]`, "end of interval expects int, got array<int>"},
+		{`1(2)`, "`1` is not callable (int)"},
+		{`join(",", 1)`, "arg 2 of `join` expects string, got int"},
+		{`str(1, 2)`, "`str` takes 1 arguments (2 given)"},
+		{`loads(1)`, "arg 1 of `loads` expects string, got int"},
+		{`Point(1)`, "`Point` takes 2 arguments (1 given)"},
+		{`Point(1, 2).z`, "no attribute 'z' in Point"},
+		{`Point(1, 2)?.z`, "no attribute 'z' in Point"},
+		{`{"name": 1}?.name`, "no attribute 'name' in hash"},
+		{`Point(1, 2).scale("a")`, "arg 1 of `Point.scale` expects int, got string"},
+		{`Point(1, 2) - Point(1, 2)`, "unsupported op `-` for Point"},
+		{`Point(1, 2)[0]`, "Point does not support index"},
+		{`func (p Point) x() { 1 }`, "struct Point has both field and method named `x`"},
+		{`const q = 1; func (p q) f() { 1 }`, "cannot define method `f` on int `q`"},
+		{`const n: Foo = 1;`, "unknown type `Foo`"},
+		{`const n: int<int> = 1;`, "type `int<int>` takes no element type"},
+		{`const f = func(x: int) { x }; f(add(1) > 0)`, "arg 1 of `f` expects int, got bool"},
+		{`const s: string = "a"; s.len() + s`, "unsupported op `+` between int and string"},
+	}
+	for _, tt := range errs {
+		err := Check(parseProgram(t, decl+tt.input))
+		if nil == err {
+			t.Fatalf("`%v` expect error", tt.input)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("`%v` expect error `%v`, got `%v`", tt.input, tt.want, err)
+		}
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	code := "const a: int = \"a\";\nconst b: string = 1;\n\"x\" * 2"
+	err := Check(parseProgram(t, code))
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("expect Errors, got %v", err)
+	}
+	if len(errs) != 3 {
+		t.Fatalf("expect 3 errors, got %v", errs)
+	}
+	for i, line := range []int{1, 2, 3} {
+		if errs[i].Pos.Line != line {
+			t.Fatalf("error %v expect line %v, got %v", i, line, errs[i].Pos)
+		}
+	}
+	// positions are lost by json, the errors are still reported
+	b, err := json.Marshal(parseProgram(t, code).Encode())
+	if nil != err {
+		t.Fatal(err)
+	}
+	node, err := ast.Decode(b)
+	if nil != err {
+		t.Fatal(err)
+	}
+	err = Check(node)
+	if errs, ok := err.(Errors); !ok || len(errs) != 3 || errs[0].Pos.Valid() {
+		t.Fatalf("unexpected errors: %v", err)
+	}
+}
@@ -0,0 +1,328 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/jobs-github/escript/object"
+)
+
+// names of types, a struct instance is typed by the name of its struct
+const (
+	typeAny      = "any"
+	typeInt      = "int"
+	typeStr      = "string"
+	typeBool     = "bool"
+	typeNull     = "null"
+	typeArray    = "array"
+	typeHash     = "hash"
+	typeFunc     = "func"
+	typeError    = "error"
+	typeInterval = "interval"
+)
+
+var (
+	anyType      = &Type{Name: typeAny}
+	intType      = &Type{Name: typeInt}
+	strType      = &Type{Name: typeStr}
+	boolType     = &Type{Name: typeBool}
+	nullType     = &Type{Name: typeNull}
+	errorType    = &Type{Name: typeError}
+	intervalType = &Type{Name: typeInterval}
+	funcType     = &Type{Name: typeFunc}
+)
+
+// aliases : the names of object.Typeof are accepted by annotations too
+var aliases = map[string]string{
+	"integer": typeInt,
+	"boolean": typeBool,
+}
+
+var elemTypes = map[string]bool{
+	typeArray: true,
+	typeHash:  true,
+}
+
+var basicTypes = map[string]bool{
+	typeAny:      true,
+	typeInt:      true,
+	typeStr:      true,
+	typeBool:     true,
+	typeNull:     true,
+	typeArray:    true,
+	typeHash:     true,
+	typeFunc:     true,
+	typeError:    true,
+	typeInterval: true,
+}
+
+// Type : inferred or annotated type of an expression
+type Type struct {
+	Name   string
+	Elem   *Type       // element of array or value of hash, nil means any
+	Sig    *signature  // nil if the func is unknown
+	Struct *structInfo // struct instance, or the struct itself if Name is func
+}
+
+func arrayOf(elem *Type) *Type {
+	return &Type{Name: typeArray, Elem: elem}
+}
+
+func hashOf(elem *Type) *Type {
+	return &Type{Name: typeHash, Elem: elem}
+}
+
+func funcOf(sig *signature) *Type {
+	return &Type{Name: typeFunc, Sig: sig}
+}
+
+func (this *Type) String() string {
+	if nil == this.Elem {
+		return this.Name
+	}
+	return fmt.Sprintf("%v<%v>", this.Name, this.Elem.String())
+}
+
+func (this *Type) isAny() bool {
+	return typeAny == this.Name
+}
+
+func (this *Type) is(name string) bool {
+	return name == this.Name
+}
+
+// numeric : boolean is promoted to integer by arithmetic
+func (this *Type) numeric() bool {
+	return this.is(typeInt) || this.is(typeBool)
+}
+
+func (this *Type) isStruct() bool {
+	return nil != this.Struct && !this.is(typeFunc)
+}
+
+// elem : element type, any if unknown
+func (this *Type) elem() *Type {
+	if nil == this.Elem {
+		return anyType
+	}
+	return this.Elem
+}
+
+// assignable : a value of got can be used where want is expected
+func assignable(want *Type, got *Type) bool {
+	if want.isAny() || got.isAny() {
+		return true
+	}
+	if want.Name != got.Name {
+		return false
+	}
+	if nil == want.Elem || nil == got.Elem {
+		return true
+	}
+	return assignable(want.Elem, got.Elem)
+}
+
+// join : the type of an expression which yields either a or b
+func join(a *Type, b *Type) *Type {
+	if a.isAny() || b.isAny() || a.Name != b.Name {
+		return anyType
+	}
+	if nil == a.Elem || nil == b.Elem {
+		return &Type{Name: a.Name, Struct: a.Struct}
+	}
+	if a == b {
+		return a
+	}
+	return &Type{Name: a.Name, Elem: join(a.Elem, b.Elem), Struct: a.Struct}
+}
+
+// signature : args, the rest and the result of a func, any if not annotated
+type signature struct {
+	args     []*Type
+	required int
+	rest     *Type // type of the rest array, nil if there is no rest
+	returns  *Type
+}
+
+func newSignature(returns *Type, args ...*Type) *signature {
+	return &signature{args: args, required: len(args), returns: returns}
+}
+
+func variadic(returns *Type, args ...*Type) *signature {
+	sig := newSignature(returns, args...)
+	sig.rest = arrayOf(anyType)
+	return sig
+}
+
+// arity : `2`, `1..2` or `1..` like the runtime error
+func (this *signature) arity() string {
+	if nil != this.rest {
+		return fmt.Sprintf("%v..", this.required)
+	}
+	if this.required == len(this.args) {
+		return fmt.Sprintf("%v", this.required)
+	}
+	return fmt.Sprintf("%v..%v", this.required, len(this.args))
+}
+
+// argType : type of the i-th arg, nil if there are too many args
+func (this *signature) argType(i int) *Type {
+	if i < len(this.args) {
+		return this.args[i]
+	}
+	if nil != this.rest {
+		return this.rest.elem()
+	}
+	return nil
+}
+
+// structInfo : fields and methods of a struct, methods are declared before any statement is checked
+type structInfo struct {
+	name    string
+	fields  []string
+	methods map[string]*signature
+}
+
+func newStructInfo(name string, fields []string) *structInfo {
+	return &structInfo{name: name, fields: fields, methods: map[string]*signature{}}
+}
+
+func (this *structInfo) hasField(name string) bool {
+	for _, field := range this.fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// instance : type of the values constructed by the struct
+func (this *structInfo) instance() *Type {
+	return &Type{Name: this.name, Struct: this}
+}
+
+// constructor : the struct is called with the values of fields in order
+func (this *structInfo) constructor() *Type {
+	args := []*Type{}
+	for range this.fields {
+		args = append(args, anyType)
+	}
+	t := funcOf(newSignature(this.instance(), args...))
+	t.Struct = this
+	return t
+}
+
+// builtins : signatures of builtin functions
+var builtins = map[string]*signature{
+	"type":    newSignature(strType, anyType),
+	"str":     newSignature(strType, anyType),
+	"print":   variadic(strType),
+	"println": variadic(strType),
+	"printf":  variadic(strType, strType, anyType),
+	"sprintf": variadic(strType, strType, anyType),
+	"loads":   newSignature(anyType, strType),
+	"dumps":   newSignature(strType, anyType),
+	"error":   newSignature(errorType, anyType),
+	"throw":   newSignature(anyType, anyType),
+}
+
+// method : builtin method of objects, the result may depend on the receiver
+type method struct {
+	min     int
+	max     int
+	returns func(recv *Type) *Type
+}
+
+func returns(t *Type) func(recv *Type) *Type {
+	return func(recv *Type) *Type { return t }
+}
+
+func returnsElem(recv *Type) *Type { return recv.elem() }
+func returnsRecv(recv *Type) *Type { return recv }
+
+var (
+	methodLen      = &method{0, 0, returns(intType)}
+	methodNot      = &method{0, 0, returns(boolType)}
+	methodNeg      = &method{0, 0, returns(intType)}
+	methodInt      = &method{0, 0, returns(intType)}
+	methodBitNot   = &method{0, 0, returns(intType)}
+	methodContains = &method{1, 1, returns(boolType)}
+	methodFirst    = &method{0, 0, returnsElem}
+	methodLast     = &method{0, 0, returnsElem}
+	methodIndex    = &method{1, 1, returnsElem}
+	methodSlice    = &method{1, 3, returnsRecv}
+)
+
+// methods : builtin methods by the type of receiver, refer to objectBuiltins of each object
+var methods = map[string]map[string]*method{
+	typeInt: {
+		object.FnNot:    methodNot,
+		object.FnNeg:    methodNeg,
+		object.FnInt:    methodInt,
+		object.FnBitNot: methodBitNot,
+	},
+	typeBool: {
+		object.FnNot: methodNot,
+		object.FnNeg: methodNeg,
+		object.FnInt: methodInt,
+	},
+	typeStr: {
+		object.FnLen:      methodLen,
+		object.FnIndex:    &method{1, 1, returns(strType)},
+		object.FnNot:      methodNot,
+		object.FnInt:      methodInt,
+		object.FnSlice:    methodSlice,
+		object.FnContains: methodContains,
+	},
+	typeArray: {
+		object.FnLen:      methodLen,
+		object.FnIndex:    methodIndex,
+		object.FnNot:      methodNot,
+		object.FnFirst:    methodFirst,
+		object.FnLast:     methodLast,
+		object.FnTail:     &method{0, 0, returnsRecv},
+		object.FnPush:     &method{1, 1, returnsRecv},
+		object.FnSlice:    methodSlice,
+		object.FnContains: methodContains,
+	},
+	typeHash: {
+		object.FnLen:      methodLen,
+		object.FnIndex:    methodIndex,
+		object.FnNot:      methodNot,
+		object.FnKeys:     &method{0, 0, returns(arrayOf(anyType))},
+		object.FnContains: methodContains,
+	},
+	typeInterval: {
+		object.FnLen:      methodLen,
+		object.FnIndex:    &method{1, 1, returns(intType)},
+		object.FnNot:      methodNot,
+		object.FnFirst:    &method{0, 0, returns(intType)},
+		object.FnLast:     &method{0, 0, returns(intType)},
+		object.FnSlice:    methodSlice,
+		object.FnContains: methodContains,
+	},
+	typeError: {
+		object.FnNot:     methodNot,
+		object.FnMessage: &method{0, 0, returns(strType)},
+	},
+	typeNull: {
+		object.FnNot: methodNot,
+	},
+	typeFunc: {
+		object.FnNot: methodNot,
+	},
+}
+
+// lookupMethod : builtin method of t, ok is false if t is unknown
+func lookupMethod(t *Type, name string) (*method, bool) {
+	if t.isStruct() {
+		if object.FnNot == name {
+			return methodNot, true
+		}
+		return nil, true
+	}
+	m, ok := methods[t.Name]
+	if !ok {
+		return nil, false
+	}
+	return m[name], true
+}
@@ -0,0 +1,637 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/jobs-github/escript/ast"
+	"github.com/jobs-github/escript/object"
+	"github.com/jobs-github/escript/token"
+)
+
+// prefixMethods : prefix operators are object methods, refer to ast.evalPrefix
+var prefixMethods = map[token.TokenType]string{
+	token.NOT:    object.FnNot,
+	token.SUB:    object.FnNeg,
+	token.BITNOT: object.FnBitNot,
+}
+
+var arithOps = map[token.TokenType]bool{
+	token.ADD:    true,
+	token.SUB:    true,
+	token.MUL:    true,
+	token.DIV:    true,
+	token.MOD:    true,
+	token.POW:    true,
+	token.BITAND: true,
+	token.BITOR:  true,
+	token.BITXOR: true,
+	token.SHL:    true,
+	token.SHR:    true,
+}
+
+var compareOps = map[token.TokenType]bool{
+	token.LT:  true,
+	token.GT:  true,
+	token.LEQ: true,
+	token.GEQ: true,
+}
+
+// hooks : operator hooks of struct, refer to object.Struct.calcHook
+var hooks = map[token.TokenType]string{
+	token.ADD: "__add__",
+	token.SUB: "__sub__",
+	token.MUL: "__mul__",
+	token.DIV: "__div__",
+	token.MOD: "__mod__",
+	token.EQ:  "__eq__",
+	token.NEQ: "__eq__",
+	token.LT:  "__lt__",
+	token.GT:  "__lt__",
+	token.LEQ: "__lt__",
+	token.GEQ: "__lt__",
+}
+
+const hookIndex = "__index__"
+
+func fnName(fn *ast.Function) string {
+	if "" != fn.Name {
+		return fn.Name
+	}
+	if "" != fn.Lambda {
+		return fn.Lambda
+	}
+	return token.Func
+}
+
+// function : check the body of fn with its args bound to sig
+func (this *checker) function(fn *ast.Function, sig *signature) *Type {
+	this.enterScope()
+	defer this.leaveScope()
+	for i, arg := range fn.Args {
+		this.scope.set(arg.Value, sig.args[i])
+		if i < len(fn.Defaults) && nil != fn.Defaults[i] {
+			t := this.check(fn.Defaults[i])
+			this.expect(fn.Defaults[i], sig.args[i], t, fmt.Sprintf("default value of `%v`", arg.Value))
+		}
+		if i < len(fn.Params) {
+			if _, ok := fn.Params[i].(*ast.BindPattern); !ok {
+				this.bind(fn.Params[i])
+			}
+		}
+	}
+	if nil != fn.Rest {
+		this.scope.set(fn.Rest.Value, sig.rest)
+	}
+	body := this.check(fn.Body)
+	r := *sig
+	if nil != fn.Returns {
+		this.expect(fn.Body, sig.returns, body, fmt.Sprintf("result of `%v`", fnName(fn)))
+	} else {
+		r.returns = body
+	}
+	return funcOf(&r)
+}
+
+// bind : names captured by pattern are of any type
+func (this *checker) bind(p ast.Pattern) {
+	for _, name := range p.Names() {
+		this.scope.set(name, anyType)
+	}
+}
+
+// checkArgs : the args after a spread are not checked
+func (this *checker) checkArgs(name string, sig *signature, args ast.ExpressionSlice, types []*Type) {
+	spread := -1
+	for i, arg := range args {
+		if _, ok := arg.(*ast.SpreadExpr); ok {
+			spread = i
+			break
+		}
+	}
+	argc := len(args)
+	if spread < 0 && (argc < sig.required || (nil == sig.rest && argc > len(sig.args))) {
+		this.report("`%v` takes %v arguments (%v given)", name, sig.arity(), argc)
+		return
+	}
+	for i, t := range types {
+		want := sig.argType(i)
+		if i == spread || nil == want {
+			break
+		}
+		this.expect(args[i], want, t, fmt.Sprintf("arg %v of `%v`", i+1, name))
+	}
+}
+
+// checkMethod : call the builtin method m of recv
+func (this *checker) checkMethod(recv *Type, name string, m *method, argc int) *Type {
+	if argc < m.min || argc > m.max {
+		n := fmt.Sprintf("%v", m.min)
+		if m.min != m.max {
+			n = fmt.Sprintf("%v..%v", m.min, m.max)
+		}
+		this.report("`%v()` of %v takes %v arguments (%v given)", name, recv.String(), n, argc)
+	}
+	return m.returns(recv)
+}
+
+func (this *checker) checkExprs(exprs ast.ExpressionSlice) []*Type {
+	types := []*Type{}
+	for _, expr := range exprs {
+		types = append(types, this.check(expr))
+	}
+	return types
+}
+
+func (this *checker) noAttribute(name string, t *Type) {
+	this.report("no attribute '%v' in %v", name, t.String())
+}
+
+// returnsOf : result type of a func
+func returnsOf(t *Type) *Type {
+	if nil == t.Sig {
+		return anyType
+	}
+	return t.Sig.returns
+}
+
+func (this *checker) DoProgram(v *ast.Program) error {
+	this.declare(v.Stmts)
+	for _, stmt := range v.Stmts {
+		this.check(stmt)
+	}
+	return nil
+}
+
+func (this *checker) DoConst(v *ast.ConstStmt) error {
+	t := this.check(v.Value)
+	if nil != v.Type {
+		want := this.resolve(v.Type)
+		name := v.String()
+		if nil != v.Name {
+			name = v.Name.Value
+		}
+		this.expect(v.Value, want, t, fmt.Sprintf("const `%v`", name))
+		t = want
+	}
+	if nil != v.Pattern {
+		this.bind(v.Pattern)
+	} else {
+		this.scope.set(v.Name.Value, t)
+	}
+	this.typ = t
+	return nil
+}
+
+func (this *checker) DoBlock(v *ast.BlockStmt) error {
+	this.typ = this.check(v.Stmt)
+	return nil
+}
+
+func (this *checker) DoExpr(v *ast.ExpressionStmt) error {
+	this.typ = this.check(v.Expr)
+	return nil
+}
+
+func (this *checker) DoLoop(v *ast.LoopExpr) error {
+	this.check(v.Cnt)
+	this.check(v.Body)
+	this.typ = anyType
+	return nil
+}
+
+func (this *checker) DoMap(v *ast.MapExpr) error {
+	this.check(v.Arr)
+	body := this.check(v.Body)
+	this.typ = arrayOf(returnsOf(body))
+	return nil
+}
+
+func (this *checker) DoReduce(v *ast.ReduceExpr) error {
+	this.check(v.Arr)
+	body := this.check(v.Body)
+	init := this.check(v.Init)
+	this.typ = join(init, returnsOf(body))
+	return nil
+}
+
+func (this *checker) DoFilter(v *ast.FilterExpr) error {
+	arr := this.check(v.Arr)
+	this.check(v.Body)
+	if arr.is(typeArray) {
+		this.typ = arr
+	} else {
+		this.typ = arrayOf(anyType)
+	}
+	return nil
+}
+
+func (this *checker) DoRange(v *ast.RangeExpr) error {
+	this.check(v.Cnt)
+	this.check(v.Body)
+	this.typ = anyType
+	return nil
+}
+
+func (this *checker) DoFunction(v *ast.FunctionStmt) error {
+	sig := this.signature(v.Value)
+	// the function may call itself
+	this.scope.set(v.Name.Value, funcOf(sig))
+	t := this.function(v.Value, sig)
+	this.scope.set(v.Name.Value, t)
+	this.typ = t
+	return nil
+}
+
+func (this *checker) DoStruct(v *ast.StructStmt) error {
+	info, ok := this.structs[v.Name.Value]
+	if !ok {
+		info = newStructInfo(v.Name.Value, v.Fields.Values())
+		this.structs[v.Name.Value] = info
+	}
+	t := info.constructor()
+	this.scope.set(v.Name.Value, t)
+	this.typ = t
+	return nil
+}
+
+func (this *checker) DoMethod(v *ast.MethodStmt) error {
+	name := v.Value.Name
+	recv := anyType
+	var sig *signature
+	t := this.lookup(v.Type.Value)
+	if info := t.Struct; nil != info && t.is(typeFunc) {
+		recv = info.instance()
+		if info.hasField(name) {
+			this.report("struct %v has both field and method named `%v`", info.name, name)
+		} else if sig = info.methods[name]; nil == sig {
+			sig = this.signature(v.Value)
+			info.methods[name] = sig
+		}
+	} else if !t.isAny() {
+		this.report("cannot define method `%v` on %v `%v`", name, t.String(), v.Type.Value)
+	}
+	if nil == sig {
+		sig = this.signature(v.Value)
+	}
+	this.enterScope()
+	this.scope.set(v.Recv.Value, recv)
+	this.typ = this.function(v.Value, sig)
+	this.leaveScope()
+	return nil
+}
+
+func (this *checker) DoPrefix(v *ast.PrefixExpr) error {
+	right := this.check(v.Right)
+	name := prefixMethods[v.Op.Type]
+	m, known := lookupMethod(right, name)
+	if !known {
+		if v.Op.TypeIs(token.NOT) {
+			this.typ = boolType
+		}
+		return nil
+	}
+	if nil == m {
+		this.report("unsupported op `%v` for %v", v.Op.Literal, right.String())
+		return nil
+	}
+	this.typ = m.returns(right)
+	return nil
+}
+
+func (this *checker) DoInfix(v *ast.InfixExpr) error {
+	left := this.check(v.Left)
+	right := this.check(v.Right)
+	this.typ = this.infix(v.Op, left, right)
+	return nil
+}
+
+// infix : only the operands of known types are checked, null, interval and any are left to the runtime
+func (this *checker) infix(op *token.Token, left *Type, right *Type) *Type {
+	t := op.Type
+	if left.isStruct() {
+		return this.infixStruct(op, left)
+	}
+	if token.EQ == t || token.NEQ == t {
+		return boolType
+	}
+	if token.AND == t || token.OR == t {
+		if left.is(typeBool) && right.is(typeBool) {
+			return boolType
+		}
+		return anyType
+	}
+	unchecked := func(t *Type) bool {
+		return t.isAny() || t.is(typeNull) || t.is(typeInterval)
+	}
+	if unchecked(left) || unchecked(right) {
+		if compareOps[t] {
+			return boolType
+		}
+		return anyType
+	}
+	if left.numeric() && right.numeric() {
+		if compareOps[t] {
+			return boolType
+		}
+		if arithOps[t] {
+			return intType
+		}
+	}
+	if left.is(typeStr) && right.is(typeStr) {
+		if compareOps[t] {
+			return boolType
+		}
+		if token.ADD == t {
+			return strType
+		}
+	}
+	this.report("unsupported op `%v` between %v and %v", op.Literal, left.String(), right.String())
+	return anyType
+}
+
+func (this *checker) infixStruct(op *token.Token, left *Type) *Type {
+	if name, ok := hooks[op.Type]; ok {
+		if sig, ok := left.Struct.methods[name]; ok {
+			if arithOps[op.Type] {
+				return sig.returns
+			}
+			return boolType
+		}
+	}
+	switch op.Type {
+	case token.EQ, token.NEQ:
+		return boolType
+	case token.AND, token.OR:
+		return anyType
+	default:
+		this.report("unsupported op `%v` for %v", op.Literal, left.String())
+		return anyType
+	}
+}
+
+func (this *checker) DoIdent(v *ast.Identifier) error {
+	this.typ = this.lookup(v.Value)
+	return nil
+}
+
+func (this *checker) DoSymbol(v *ast.SymbolExpr) error {
+	this.typ = anyType
+	return nil
+}
+
+func (this *checker) DoConditional(v *ast.ConditionalExpr) error {
+	this.check(v.Cond)
+	yes := this.check(v.Yes)
+	no := this.check(v.No)
+	this.typ = join(yes, no)
+	return nil
+}
+
+func (this *checker) DoNullish(v *ast.NullishExpr) error {
+	left := this.check(v.Left)
+	right := this.check(v.Right)
+	if left.is(typeNull) {
+		this.typ = right
+	} else {
+		this.typ = join(left, right)
+	}
+	return nil
+}
+
+func (this *checker) DoIn(v *ast.InExpr) error {
+	this.check(v.Left)
+	right := this.check(v.Right)
+	if m, known := lookupMethod(right, object.FnContains); known && nil == m && !right.isStruct() {
+		this.report("unsupported op `%v` for %v", token.In, right.String())
+	}
+	this.typ = boolType
+	return nil
+}
+
+func (this *checker) DoInterval(v *ast.IntervalExpr) error {
+	this.expect(v.Start, intType, this.check(v.Start), "start of interval")
+	this.expect(v.End, intType, this.check(v.End), "end of interval")
+	this.typ = intervalType
+	return nil
+}
+
+func (this *checker) DoMatch(v *ast.MatchExpr) error {
+	this.check(v.Value)
+	var r *Type
+	for _, arm := range v.Arms {
+		this.enterScope()
+		this.bind(arm.Pattern)
+		if nil != arm.Guard {
+			this.check(arm.Guard)
+		}
+		t := this.check(arm.Body)
+		this.leaveScope()
+		if nil == r {
+			r = t
+		} else {
+			r = join(r, t)
+		}
+	}
+	if nil == r {
+		r = anyType
+	}
+	this.typ = r
+	return nil
+}
+
+func (this *checker) DoFn(v *ast.Function) error {
+	this.typ = this.function(v, this.signature(v))
+	return nil
+}
+
+func (this *checker) DoCall(v *ast.Call) error {
+	fn := this.check(v.Func)
+	types := this.checkExprs(v.Args)
+	this.typ = anyType
+	if nil != fn.Sig {
+		this.checkArgs(v.Func.String(), fn.Sig, v.Args, types)
+		this.typ = fn.Sig.returns
+	} else if !fn.isAny() && !fn.is(typeFunc) {
+		this.report("`%v` is not callable (%v)", v.Func.String(), fn.String())
+	}
+	return nil
+}
+
+func (this *checker) DoSpread(v *ast.SpreadExpr) error {
+	this.typ = this.check(v.Value)
+	return nil
+}
+
+func (this *checker) DoCallMember(v *ast.CallMember) error {
+	left := this.check(v.Left)
+	types := this.checkExprs(v.Args)
+	name := v.Func.Value
+	this.typ = anyType
+	// left?.name resolves the member as left.name does
+	if v.Optional && left.is(typeNull) {
+		return nil
+	}
+	if left.isStruct() {
+		if left.Struct.hasField(name) {
+			return nil
+		}
+		if sig, ok := left.Struct.methods[name]; ok {
+			this.checkArgs(fmt.Sprintf("%v.%v", left.Struct.name, name), sig, v.Args, types)
+			this.typ = sig.returns
+			return nil
+		}
+	}
+	m, known := lookupMethod(left, name)
+	if !known {
+		return nil
+	}
+	if nil == m {
+		this.noAttribute(name, left)
+		return nil
+	}
+	this.typ = this.checkMethod(left, name, m, len(v.Args))
+	return nil
+}
+
+func (this *checker) DoObjectMember(v *ast.ObjectMember) error {
+	left := this.check(v.Left)
+	name := v.Member.Value
+	this.typ = anyType
+	// left?.name resolves the member as left.name does
+	if v.Optional && left.is(typeNull) {
+		return nil
+	}
+	if left.isStruct() {
+		if left.Struct.hasField(name) {
+			return nil
+		}
+		if sig, ok := left.Struct.methods[name]; ok {
+			this.typ = funcOf(sig)
+			return nil
+		}
+	}
+	m, known := lookupMethod(left, name)
+	if !known {
+		return nil
+	}
+	if nil == m {
+		this.noAttribute(name, left)
+		return nil
+	}
+	this.typ = funcType
+	return nil
+}
+
+func (this *checker) DoIndex(v *ast.IndexExpr) error {
+	left := this.check(v.Left)
+	idx := this.check(v.Index)
+	this.typ = anyType
+	if v.Optional {
+		return nil
+	}
+	if left.isStruct() {
+		if sig, ok := left.Struct.methods[hookIndex]; ok {
+			this.typ = sig.returns
+			return nil
+		}
+	}
+	m, known := lookupMethod(left, object.FnIndex)
+	if !known {
+		return nil
+	}
+	if nil == m {
+		this.report("%v does not support index", left.String())
+		return nil
+	}
+	if !left.is(typeHash) {
+		this.expect(v.Index, intType, idx, fmt.Sprintf("index of %v", left.String()))
+	}
+	this.typ = m.returns(left)
+	return nil
+}
+
+func (this *checker) DoSlice(v *ast.SliceExpr) error {
+	left := this.check(v.Left)
+	for _, bound := range []ast.Expression{v.Start, v.End, v.Step} {
+		if nil != bound {
+			this.expect(bound, intType, this.check(bound), "slice bound")
+		}
+	}
+	this.typ = anyType
+	m, known := lookupMethod(left, object.FnSlice)
+	if !known {
+		return nil
+	}
+	if nil == m {
+		this.report("%v does not support slice", left.String())
+		return nil
+	}
+	this.typ = m.returns(left)
+	return nil
+}
+
+func (this *checker) DoTry(v *ast.TryExpr) error {
+	t := this.check(v.Try)
+	this.enterScope()
+	if nil != v.Name {
+		this.scope.set(v.Name.Value, errorType)
+	}
+	c := this.check(v.Catch)
+	this.leaveScope()
+	this.typ = join(t, c)
+	return nil
+}
+
+func (this *checker) DoNull(v *ast.Null) error {
+	this.typ = nullType
+	return nil
+}
+
+func (this *checker) DoInteger(v *ast.Integer) error {
+	this.typ = intType
+	return nil
+}
+
+func (this *checker) DoBoolean(v *ast.Boolean) error {
+	this.typ = boolType
+	return nil
+}
+
+func (this *checker) DoString(v *ast.String) error {
+	this.typ = strType
+	return nil
+}
+
+func (this *checker) DoTemplate(v *ast.TemplateExpr) error {
+	this.checkExprs(v.Parts)
+	this.typ = strType
+	return nil
+}
+
+func (this *checker) DoArray(v *ast.Array) error {
+	var elem *Type
+	for _, t := range this.checkExprs(v.Items) {
+		if nil == elem {
+			elem = t
+		} else {
+			elem = join(elem, t)
+		}
+	}
+	this.typ = arrayOf(elem)
+	return nil
+}
+
+func (this *checker) DoHash(v *ast.Hash) error {
+	var elem *Type
+	for _, k := range v.OrderedKeys() {
+		this.check(k)
+		t := this.check(v.Pairs[k])
+		if nil == elem {
+			elem = t
+		} else {
+			elem = join(elem, t)
+		}
+	}
+	this.typ = hashOf(elem)
+	return nil
+}
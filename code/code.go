@@ -11,6 +11,8 @@ import (
 
 var (
 	errUnsupportedWidth = errors.New("unsupported width")
+	// ErrOverflow : an operand is out of the range of its width, refer to MakeWide
+	ErrOverflow = errors.New("operand overflow")
 )
 
 type Opcode byte
@@ -60,59 +62,135 @@ const (
 	OpAnd
 	OpOr
 	OpIndex
+	OpSlice
+	OpTry
+	OpEndTry
+	OpCatch
+	OpJumpWhenNull
+	OpJumpWhenNotNull
+	OpIndexOptional
+	OpConcat
+	OpBitNot
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShl
+	OpShr
+	OpPow
+	OpIn
+	OpNotIn
+	OpInterval
+	OpMatchValue
+	OpMatchArray
+	OpMatchHash
+	OpMatchTable
+	OpNoMatch
+	OpDestructFail
+	OpArgMissing
+	OpCallSpread
+	OpTailCall
+	OpTailCallSpread
+	OpGetMember
+	OpMethod
+	OpAddConst
+	OpSubConst
+	OpGetLocal2
+	OpCmpJump
+	OpCallBuiltin
+	OpCallObjectFn
+	OpWide
 	OpPlaceholder
 )
 
 var (
 	definitions = map[Opcode]*Definition{
-		OpArray:         {"OpArray", []int{2}},
-		OpArrayReserve:  {"OpArrayReserve", []int{}},
-		OpArrayNew:      {"OpArrayNew", []int{1}},
-		OpArraySet:      {"OpArraySet", []int{1, 1}},
-		OpArrayAppend:   {"OpArrayAppend", []int{1}},
-		OpArrayLen:      {"OpArrayLen", []int{}},
-		OpClosure:       {"OpClosure", []int{2, 1}},
-		OpConst:         {"OpConst", []int{2}},
-		OpHash:          {"OpHash", []int{2}},
-		OpJumpWhenFalse: {"OpJumpWhenFalse", []int{2}},
-		OpJump:          {"OpJump", []int{2}},
-		OpSymbol:        {"OpSymbol", []int{2}},
-		OpGetGlobal:     {"OpGetGlobal", []int{2}},
-		OpSetGlobal:     {"OpSetGlobal", []int{2}},
-		OpGetBuiltin:    {"OpGetBuiltin", []int{1}},
-		OpGetObjectFn:   {"OpGetObjectFn", []int{1}},
-		OpGetLocal:      {"OpGetLocal", []int{1}},
-		OpSetLocal:      {"OpSetLocal", []int{1}},
-		OpIncLocal:      {"OpIncLocal", []int{1}},
-		OpCall:          {"OpCall", []int{1}},
-		OpGetFree:       {"OpGetFree", []int{1}},
-		OpGetLambda:     {"OpGetLambda", []int{1}},
-		OpReturn:        {"OpReturn", []int{}},
-		OpPop:           {"OpPop", []int{}},
-		OpTrue:          {"OpTrue", []int{}},
-		OpFalse:         {"OpFalse", []int{}},
-		OpNull:          {"OpNull", []int{}},
-		OpNot:           {"OpNot", []int{}},
-		OpNeg:           {"OpNeg", []int{}},
-		OpAdd:           {"OpAdd", []int{}},
-		OpSub:           {"OpSub", []int{}},
-		OpMul:           {"OpMul", []int{}},
-		OpDiv:           {"OpDiv", []int{}},
-		OpMod:           {"OpMod", []int{}},
-		OpLt:            {"OpLt", []int{}},
-		OpGt:            {"OpGt", []int{}},
-		OpEq:            {"OpEq", []int{}},
-		OpNeq:           {"OpNeq", []int{}},
-		OpLeq:           {"OpLeq", []int{}},
-		OpGeq:           {"OpGeq", []int{}},
-		OpAnd:           {"OpAnd", []int{}},
-		OpOr:            {"OpOr", []int{}},
-		OpIndex:         {"OpIndex", []int{}},
-		OpPlaceholder:   {"OpPlaceholder", []int{}},
+		OpArray:           {"OpArray", []int{2}},
+		OpArrayReserve:    {"OpArrayReserve", []int{}},
+		OpArrayNew:        {"OpArrayNew", []int{1}},
+		OpArraySet:        {"OpArraySet", []int{1, 1}},
+		OpArrayAppend:     {"OpArrayAppend", []int{1}},
+		OpArrayLen:        {"OpArrayLen", []int{}},
+		OpClosure:         {"OpClosure", []int{2, 1}},
+		OpConst:           {"OpConst", []int{2}},
+		OpHash:            {"OpHash", []int{2}},
+		OpJumpWhenFalse:   {"OpJumpWhenFalse", []int{2}},
+		OpJump:            {"OpJump", []int{2}},
+		OpSymbol:          {"OpSymbol", []int{2}},
+		OpGetGlobal:       {"OpGetGlobal", []int{2}},
+		OpSetGlobal:       {"OpSetGlobal", []int{2}},
+		OpGetBuiltin:      {"OpGetBuiltin", []int{1}},
+		OpGetObjectFn:     {"OpGetObjectFn", []int{1}},
+		OpGetLocal:        {"OpGetLocal", []int{1}},
+		OpSetLocal:        {"OpSetLocal", []int{1}},
+		OpIncLocal:        {"OpIncLocal", []int{1}}, // local = local + 1, the slot is rebound to a new integer
+		OpCall:            {"OpCall", []int{1}},
+		OpGetFree:         {"OpGetFree", []int{1}},
+		OpGetLambda:       {"OpGetLambda", []int{1}},
+		OpReturn:          {"OpReturn", []int{}},
+		OpPop:             {"OpPop", []int{}},
+		OpTrue:            {"OpTrue", []int{}},
+		OpFalse:           {"OpFalse", []int{}},
+		OpNull:            {"OpNull", []int{}},
+		OpNot:             {"OpNot", []int{}},
+		OpNeg:             {"OpNeg", []int{}},
+		OpAdd:             {"OpAdd", []int{}},
+		OpSub:             {"OpSub", []int{}},
+		OpMul:             {"OpMul", []int{}},
+		OpDiv:             {"OpDiv", []int{}},
+		OpMod:             {"OpMod", []int{}},
+		OpLt:              {"OpLt", []int{}},
+		OpGt:              {"OpGt", []int{}},
+		OpEq:              {"OpEq", []int{}},
+		OpNeq:             {"OpNeq", []int{}},
+		OpLeq:             {"OpLeq", []int{}},
+		OpGeq:             {"OpGeq", []int{}},
+		OpAnd:             {"OpAnd", []int{}},
+		OpOr:              {"OpOr", []int{}},
+		OpIndex:           {"OpIndex", []int{}},
+		OpSlice:           {"OpSlice", []int{}},
+		OpTry:             {"OpTry", []int{2}},
+		OpEndTry:          {"OpEndTry", []int{}},
+		OpCatch:           {"OpCatch", []int{}},
+		OpJumpWhenNull:    {"OpJumpWhenNull", []int{2}},
+		OpJumpWhenNotNull: {"OpJumpWhenNotNull", []int{2}},
+		OpIndexOptional:   {"OpIndexOptional", []int{}},
+		OpConcat:          {"OpConcat", []int{2}},
+		OpBitNot:          {"OpBitNot", []int{}},
+		OpBitAnd:          {"OpBitAnd", []int{}},
+		OpBitOr:           {"OpBitOr", []int{}},
+		OpBitXor:          {"OpBitXor", []int{}},
+		OpShl:             {"OpShl", []int{}},
+		OpShr:             {"OpShr", []int{}},
+		OpPow:             {"OpPow", []int{}},
+		OpIn:              {"OpIn", []int{}},
+		OpNotIn:           {"OpNotIn", []int{}},
+		OpInterval:        {"OpInterval", []int{1}},
+		OpMatchValue:      {"OpMatchValue", []int{}},
+		OpMatchArray:      {"OpMatchArray", []int{2, 1}},
+		OpMatchHash:       {"OpMatchHash", []int{}},
+		OpMatchTable:      {"OpMatchTable", []int{2, 2}},
+		OpNoMatch:         {"OpNoMatch", []int{}},
+		OpDestructFail:    {"OpDestructFail", []int{2}},
+		OpArgMissing:      {"OpArgMissing", []int{1}},
+		OpCallSpread:      {"OpCallSpread", []int{1}},
+		OpTailCall:        {"OpTailCall", []int{1}},
+		OpTailCallSpread:  {"OpTailCallSpread", []int{1}},
+		OpGetMember:       {"OpGetMember", []int{2}},
+		OpMethod:          {"OpMethod", []int{2}},
+		OpAddConst:        {"OpAddConst", []int{2}},
+		OpSubConst:        {"OpSubConst", []int{2}},
+		OpGetLocal2:       {"OpGetLocal2", []int{1, 1}},
+		OpCmpJump:         {"OpCmpJump", []int{1, 2}},
+		OpCallBuiltin:     {"OpCallBuiltin", []int{1, 1}},
+		OpCallObjectFn:    {"OpCallObjectFn", []int{1, 1, 2}}, // object fn, args, inline cache
+		OpWide:            {"OpWide", []int{}},                // prefix, the operands of the next instruction are twice as wide
+		OpPlaceholder:     {"OpPlaceholder", []int{}},
 	}
+	wideDefinitions = widen(definitions)
 	prefixCodePairs = tokenCodePairs{
 		{token.Not, OpNot},
 		{token.Neg, OpNeg},
+		{token.BitNot, OpBitNot},
 	}
 	infixCodePairs = tokenCodePairs{
 		{token.Add, OpAdd},
@@ -128,6 +206,12 @@ var (
 		{token.Geq, OpGeq},
 		{token.And, OpAnd},
 		{token.Or, OpOr},
+		{token.BitAnd, OpBitAnd},
+		{token.BitOr, OpBitOr},
+		{token.BitXor, OpBitXor},
+		{token.Shl, OpShl},
+		{token.Shr, OpShr},
+		{token.Pow, OpPow},
 	}
 	prefixCodeMap = prefixCodePairs.newMap()
 	infixCodeMap  = infixCodePairs.newMap()
@@ -190,18 +274,27 @@ func (this *Instructions) String() string {
 	var out bytes.Buffer
 	sz := len(*this)
 	for i := 0; i < sz; {
-		d, err := Lookup(Opcode((*this)[i]))
+		// OpWide is listed with the instruction it prefixes
+		op, prefix, lookup := Opcode((*this)[i]), 0, Lookup
+		if OpWide == op && i+1 < sz {
+			op, prefix, lookup = Opcode((*this)[i+1]), 1, LookupWide
+		}
+		d, err := lookup(op)
 		if nil != err {
 			fmt.Fprintf(&out, "ERROR: %v\n", err)
 			continue
 		}
-		r, err := DecodeOperands(d, (*this)[i+1:])
+		r, err := DecodeOperands(d, (*this)[i+1+prefix:])
 		if nil != err {
 			fmt.Fprintf(&out, "ERROR: %v\n", err)
 			continue
 		}
-		fmt.Fprintf(&out, "%04d %s\n", i, this.format(d, r.Value))
-		i = i + 1 + r.Pos
+		if prefix > 0 {
+			fmt.Fprintf(&out, "%04d OpWide %s\n", i, this.format(d, r.Value))
+		} else {
+			fmt.Fprintf(&out, "%04d %s\n", i, this.format(d, r.Value))
+		}
+		i = i + 1 + prefix + r.Pos
 	}
 	return out.String()
 }
@@ -218,6 +311,8 @@ func (this *Instructions) format(d *Definition, operands []int) string {
 		return fmt.Sprintf("%s %d", d.Name, operands[0])
 	case 2:
 		return fmt.Sprintf("%s %d %d", d.Name, operands[0], operands[1])
+	case 3:
+		return fmt.Sprintf("%s %d %d %d", d.Name, operands[0], operands[1], operands[2])
 	}
 	return fmt.Sprintf("ERROR: unsupport format for %s\n", d.Name)
 }
@@ -257,26 +352,83 @@ func Lookup(op Opcode) (*Definition, error) {
 	return v, nil
 }
 
+// LookupWide : definition of op after the OpWide prefix
+func LookupWide(op Opcode) (*Definition, error) {
+	v, ok := wideDefinitions[op]
+	if !ok {
+		if d, err := Lookup(op); nil == err {
+			return nil, fmt.Errorf("%v can not be widened", d.Name)
+		}
+		return nil, fmt.Errorf("undefined opcode: %v", op)
+	}
+	return v, nil
+}
+
+// narrowOnly : the fused opcodes are never widened, they are emitted only if the operands fit
+var narrowOnly = map[Opcode]bool{
+	OpAddConst:     true,
+	OpSubConst:     true,
+	OpGetLocal2:    true,
+	OpCmpJump:      true,
+	OpCallBuiltin:  true,
+	OpCallObjectFn: true,
+}
+
+// widen : the definitions with every operand twice as wide, refer to narrowOnly
+func widen(m map[Opcode]*Definition) map[Opcode]*Definition {
+	r := map[Opcode]*Definition{}
+	for op, d := range m {
+		if narrowOnly[op] || OpWide == op {
+			continue
+		}
+		widths := make([]int, len(d.OperandWidths))
+		for i, w := range d.OperandWidths {
+			widths[i] = 2 * w
+		}
+		r[op] = &Definition{Name: d.Name, OperandWidths: widths}
+	}
+	return r
+}
+
+// MaxOperand : the largest operand of width bytes
+func MaxOperand(width int) int {
+	return 1<<(8*width) - 1
+}
+
+// Make : errors.Is(err, ErrOverflow) if an operand is out of the range of its width
 func Make(op Opcode, operands ...int) (Instructions, error) {
 	v, err := Lookup(op)
 	if nil != err {
 		return nil, err
 	}
+	return encode(Instructions{byte(op)}, v, operands)
+}
 
-	sz := 1
-	for _, w := range v.OperandWidths {
+// MakeWide : op prefixed by OpWide, the operands are twice as wide as the ones of Make
+// (e.g. 2 bytes for the locals and 4 bytes for the constants)
+func MakeWide(op Opcode, operands ...int) (Instructions, error) {
+	v, err := LookupWide(op)
+	if nil != err {
+		return nil, err
+	}
+	return encode(Instructions{byte(OpWide), byte(op)}, v, operands)
+}
+
+func encode(prefix Instructions, d *Definition, operands []int) (Instructions, error) {
+	sz := len(prefix)
+	for _, w := range d.OperandWidths {
 		sz = sz + w
 	}
 
 	instruction := make(Instructions, sz)
-	instruction[0] = byte(op)
+	copy(instruction, prefix)
 
-	offset := 1
+	offset := len(prefix)
 	for i, o := range operands {
-		width := v.OperandWidths[i]
-		err := encodeOperand(o, v.OperandWidths[i], instruction[offset:])
+		width := d.OperandWidths[i]
+		err := encodeOperand(o, width, instruction[offset:])
 		if nil != err {
-			return nil, err
+			return nil, fmt.Errorf("%v: %w", d.Name, err)
 		}
 		offset += width
 	}
@@ -285,7 +437,13 @@ func Make(op Opcode, operands ...int) (Instructions, error) {
 }
 
 func encodeOperand(operand int, width int, b []byte) error {
+	if operand < 0 || operand > MaxOperand(width) {
+		return fmt.Errorf("%w, %v out of %v byte(s)", ErrOverflow, operand, width)
+	}
 	switch width {
+	case 4:
+		binary.BigEndian.PutUint32(b, uint32(operand))
+		return nil
 	case 2:
 		binary.BigEndian.PutUint16(b, uint16(operand))
 		return nil
@@ -297,6 +455,10 @@ func encodeOperand(operand int, width int, b []byte) error {
 	}
 }
 
+func DecodeUint32(b []byte) uint32 {
+	return binary.BigEndian.Uint32(b)
+}
+
 func DecodeUint16(b []byte) uint16 {
 	return binary.BigEndian.Uint16(b)
 }
@@ -307,6 +469,8 @@ func DecodeUint8(b []byte) uint8 {
 
 func decodeOperand(width int, b []byte) (int, error) {
 	switch width {
+	case 4:
+		return int(DecodeUint32(b)), nil
 	case 2:
 		return int(DecodeUint16(b)), nil
 	case 1:
@@ -1,6 +1,7 @@
 package code
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -12,6 +13,14 @@ func newCode(op Opcode, operands ...int) Instructions {
 	return r
 }
 
+func newWideCode(op Opcode, operands ...int) Instructions {
+	r, err := MakeWide(op, operands...)
+	if nil != err {
+		return Instructions{}
+	}
+	return r
+}
+
 func TestMake(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -22,6 +31,7 @@ func TestMake(t *testing.T) {
 		{"case_1", OpConst, []int{65534}, Instructions{byte(OpConst), 255, 254}},
 		{"case_2", OpAdd, []int{}, Instructions{byte(OpAdd)}},
 		{"case_3", OpClosure, []int{65534, 255}, Instructions{byte(OpClosure), 255, 254, 255}},
+		{"case_4", OpCmpJump, []int{int(OpLt), 65534}, Instructions{byte(OpCmpJump), byte(OpLt), 255, 254}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -100,3 +110,62 @@ func TestReadOperands(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeWide(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       Opcode
+		operands []int
+		want     Instructions
+	}{
+		{"case_1", OpConst, []int{70000}, Instructions{byte(OpWide), byte(OpConst), 0, 1, 17, 112}},
+		{"case_2", OpGetLocal, []int{300}, Instructions{byte(OpWide), byte(OpGetLocal), 1, 44}},
+		{"case_3", OpClosure, []int{65536, 256}, Instructions{byte(OpWide), byte(OpClosure), 0, 1, 0, 0, 1, 0}},
+		{"case_4", OpGetBuiltin, []int{256}, Instructions{byte(OpWide), byte(OpGetBuiltin), 1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Make(tt.op, tt.operands...); !errors.Is(err, ErrOverflow) {
+				t.Fatalf("expect ErrOverflow, got: %v", err)
+			}
+			ins, err := MakeWide(tt.op, tt.operands...)
+			if nil != err {
+				t.Fatal(err)
+			}
+			if string(ins) != string(tt.want) {
+				t.Fatalf("want %v, got %v", tt.want, ins)
+			}
+			d, err := LookupWide(tt.op)
+			if nil != err {
+				t.Fatal(err)
+			}
+			r, err := DecodeOperands(d, ins[2:])
+			if nil != err {
+				t.Fatal(err)
+			}
+			for i, want := range tt.operands {
+				if r.Value[i] != want {
+					t.Errorf("operand wrong, want: %v, got: %v", want, r.Value[i])
+				}
+			}
+		})
+	}
+	if _, err := MakeWide(OpConst, 1<<32); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("expect ErrOverflow, got: %v", err)
+	}
+	if _, err := Make(OpJump, -1); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("expect ErrOverflow, got: %v", err)
+	}
+	if _, err := MakeWide(OpCallBuiltin, 256, 1); nil == err {
+		t.Fatal("expect OpCallBuiltin can not be widened")
+	}
+	ins := append(newCode(OpAdd), newWideCode(OpConst, 70000)...)
+	ins = append(ins, newCode(OpPop)...)
+	want := `0000 OpAdd
+0001 OpWide OpConst 70000
+0007 OpPop
+`
+	if got := ins.String(); got != want {
+		t.Errorf("wrong instruction\nwant: %v\ngot: %v", want, got)
+	}
+}
@@ -16,6 +16,7 @@ func newBytecode(i code.Instructions) Bytecode {
 		instructions: i,
 		lastIns:      encodedInstruction{},
 		prevLastIns:  encodedInstruction{},
+		far:          map[int]int{},
 	}
 }
 
@@ -37,6 +38,8 @@ type Bytecode interface {
 	setLastInstruction(op code.Opcode, pos int)
 	lastCode() code.Opcode
 	prevLastCode() code.Opcode
+	setFarJump(pos int, target int)
+	farJumps() map[int]int
 }
 
 // bytecode : implement Bytecode
@@ -44,6 +47,7 @@ type bytecode struct {
 	instructions code.Instructions
 	lastIns      encodedInstruction
 	prevLastIns  encodedInstruction
+	far          map[int]int // pos => target of the jumps back-patched beyond 2 bytes, refer to widenJumps
 }
 
 func (this *bytecode) Instructions() code.Instructions {
@@ -97,6 +101,14 @@ func (this *bytecode) prevLastCode() code.Opcode {
 	return this.prevLastIns.op
 }
 
+func (this *bytecode) setFarJump(pos int, target int) {
+	this.far[pos] = target
+}
+
+func (this *bytecode) farJumps() map[int]int {
+	return this.far
+}
+
 // scopeBytecode : implement Bytecode
 type scopeBytecode struct {
 	scopes     []Bytecode
@@ -151,3 +163,11 @@ func (this *scopeBytecode) lastCode() code.Opcode {
 func (this *scopeBytecode) prevLastCode() code.Opcode {
 	return this.scopeCode().prevLastCode()
 }
+
+func (this *scopeBytecode) setFarJump(pos int, target int) {
+	this.scopeCode().setFarJump(pos, target)
+}
+
+func (this *scopeBytecode) farJumps() map[int]int {
+	return this.scopeCode().farJumps()
+}
@@ -1,20 +1,54 @@
 package compiler
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/jobs-github/escript/ast"
 	"github.com/jobs-github/escript/code"
 	"github.com/jobs-github/escript/function"
 	"github.com/jobs-github/escript/object"
 )
 
+// MaxInlineCaches : the call sites of builtin methods beyond it are not cached, refer to OpCallObjectFn
+const MaxInlineCaches = 0x10000
+
+// MaxGlobals : size of the globals of the vm
+const MaxGlobals = 0x10000
+
+// unpatched : operand of a forward jump until changeOperand
+const unpatched = 0
+
+// limits : what the first operand of an instruction counts, for the error of a program beyond the wide operands
+var limits = map[code.Opcode]string{
+	code.OpConst:       "constants",
+	code.OpGetGlobal:   "globals",
+	code.OpSetGlobal:   "globals",
+	code.OpGetLocal:    "locals",
+	code.OpSetLocal:    "locals",
+	code.OpIncLocal:    "locals",
+	code.OpGetFree:     "free variables",
+	code.OpCall:        "arguments",
+	code.OpTailCall:    "arguments",
+	code.OpGetBuiltin:  "builtins",
+	code.OpGetObjectFn: "object functions",
+	code.OpArray:       "array items",
+	code.OpHash:        "hash pairs",
+}
+
 type Compiler interface {
 	Compile(node ast.Node) error
 	Bytecode() Bytecode
 	Constants() object.Objects
+	Debug() *DebugInfo
+	Peephole() error
 
 	enterScope()
-	leaveScope() Bytecode
+	leaveScope() (Bytecode, error)
 	addConst(obj object.Object) int
+	addFunc(fn object.Object, info *FuncInfo) int
+	addInlineCache() (int, bool)
+	funcInfo(name string) *FuncInfo
 	// return pos before encode
 	encode(op code.Opcode, operands ...int) (int, error)
 	pos() int
@@ -26,6 +60,8 @@ type Compiler interface {
 	resolve(key string) (*Symbol, error)
 	symbols() int
 	freeSymbols() Symbols
+	snapshot() map[string]*Symbol
+	restore(saved map[string]*Symbol)
 }
 
 func Make(s SymbolTable, consts object.Objects) Compiler {
@@ -33,6 +69,7 @@ func Make(s SymbolTable, consts object.Objects) Compiler {
 		st:        s,
 		b:         newScopeBytecode(newBytecode(code.Instructions{})),
 		constants: consts,
+		funcs:     map[int]*FuncInfo{},
 	}
 }
 
@@ -45,10 +82,22 @@ type compilerImpl struct {
 	st        SymbolTable
 	b         Bytecode
 	constants object.Objects
+	funcs     map[int]*FuncInfo
+	caches    int
 }
 
 func (this *compilerImpl) Compile(node ast.Node) error {
-	return node.Do(newVisitor(this, nil))
+	if err := node.Do(newVisitor(this, nil)); nil != err {
+		return err
+	}
+	if len(this.b.farJumps()) > 0 {
+		b, err := this.relax(this.b.scopeCode())
+		if nil != err {
+			return function.NewError(err)
+		}
+		this.b = newScopeBytecode(b)
+	}
+	return nil
 }
 
 func (this *compilerImpl) Bytecode() Bytecode {
@@ -59,14 +108,42 @@ func (this *compilerImpl) Constants() object.Objects {
 	return this.constants
 }
 
+// Debug : names of the symbols, which are lost by the bytecode
+func (this *compilerImpl) Debug() *DebugInfo {
+	return &DebugInfo{Globals: this.st.names(), Funcs: this.funcs}
+}
+
+// Peephole : fuse the compiled instructions, refer to Peephole
+func (this *compilerImpl) Peephole() error {
+	b, err := Peephole(this.b, this.constants)
+	if nil != err {
+		return function.NewError(err)
+	}
+	this.b = newScopeBytecode(b)
+	return nil
+}
+
 func (this *compilerImpl) enterScope() {
 	this.b.enterScope()
 	this.st = this.st.newEnclosed()
 }
 
-func (this *compilerImpl) leaveScope() Bytecode {
+func (this *compilerImpl) leaveScope() (Bytecode, error) {
 	this.st = this.st.outer()
-	return this.b.leaveScope()
+	return this.relax(this.b.leaveScope())
+}
+
+// relax : widen the jumps of b if any of them is back-patched beyond 2 bytes, refer to widenJumps
+func (this *compilerImpl) relax(b Bytecode) (Bytecode, error) {
+	far := b.farJumps()
+	if 0 == len(far) {
+		return b, nil
+	}
+	ins, err := widenJumps(b.Instructions(), far, this.constants)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	return newBytecode(ins), nil
 }
 
 func (this *compilerImpl) addConst(obj object.Object) int {
@@ -74,10 +151,42 @@ func (this *compilerImpl) addConst(obj object.Object) int {
 	return len(this.constants) - 1
 }
 
+// addInlineCache : slot of the inline cache of a call site, false if the slots are used up
+func (this *compilerImpl) addInlineCache() (int, bool) {
+	if this.caches >= MaxInlineCaches {
+		return -1, false
+	}
+	this.caches++
+	return this.caches - 1, true
+}
+
+// addFunc : add the ByteFunc as constant, info is the names of it
+func (this *compilerImpl) addFunc(fn object.Object, info *FuncInfo) int {
+	idx := this.addConst(fn)
+	this.funcs[idx] = info
+	return idx
+}
+
+// funcInfo : names of the function being compiled, call it before leaveScope
+func (this *compilerImpl) funcInfo(name string) *FuncInfo {
+	info := &FuncInfo{Name: name, Locals: this.st.names()}
+	for _, s := range this.st.freeSymbols() {
+		info.Frees = append(info.Frees, s.Name)
+	}
+	return info
+}
+
+// encode : the instruction is prefixed by OpWide if an operand overflows its width
 func (this *compilerImpl) encode(op code.Opcode, operands ...int) (int, error) {
+	if (code.OpGetGlobal == op || code.OpSetGlobal == op) && operands[0] >= MaxGlobals {
+		return -1, fmt.Errorf("too many globals, the limit is %v", MaxGlobals)
+	}
 	ins, err := code.Make(op, operands...)
+	if errors.Is(err, code.ErrOverflow) {
+		ins, err = code.MakeWide(op, operands...)
+	}
 	if nil != err {
-		return -1, function.NewError(err)
+		return -1, function.NewError(tooMany(op, operands, err))
 	}
 	lastPos := this.addInstruction(ins)
 	this.b.setLastInstruction(op, lastPos)
@@ -88,9 +197,15 @@ func (this *compilerImpl) pos() int {
 	return len(this.b.Instructions())
 }
 
+// changeOperand : back-patch the jump at opPos, a target beyond 2 bytes is kept aside
+// until the scope is left, refer to relax
 func (this *compilerImpl) changeOperand(opPos int, operand int) error {
 	op := this.b.opCode(opPos)
 	newIns, err := code.Make(op, operand)
+	if errors.Is(err, code.ErrOverflow) {
+		this.b.setFarJump(opPos, operand)
+		return nil
+	}
 	if nil != err {
 		return function.NewError(err)
 	}
@@ -98,6 +213,16 @@ func (this *compilerImpl) changeOperand(opPos int, operand int) error {
 	return nil
 }
 
+// tooMany : err of an operand which overflows even if it is wide
+func tooMany(op code.Opcode, operands []int, err error) error {
+	what, ok := limits[op]
+	if !ok || !errors.Is(err, code.ErrOverflow) {
+		return err
+	}
+	def, _ := code.LookupWide(op)
+	return fmt.Errorf("too many %v, %v exceeds %v", what, operands[0], code.MaxOperand(def.OperandWidths[0]))
+}
+
 func (this *compilerImpl) addInstruction(ins []byte) int {
 	return this.b.addInstruction(ins)
 }
@@ -121,3 +246,11 @@ func (this *compilerImpl) symbols() int {
 func (this *compilerImpl) freeSymbols() Symbols {
 	return this.st.freeSymbols()
 }
+
+func (this *compilerImpl) snapshot() map[string]*Symbol {
+	return this.st.snapshot()
+}
+
+func (this *compilerImpl) restore(saved map[string]*Symbol) {
+	this.st.restore(saved)
+}
@@ -1,7 +1,9 @@
 package compiler
 
 import (
+	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/jobs-github/escript/ast"
@@ -669,6 +671,42 @@ func Test_IndexExpr(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func Test_SliceExpr(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			"case_1",
+			"[1,2,3][1:]",
+			[]interface{}{1, 2, 3, 1},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpConst, 1),
+				newCode(code.OpConst, 2),
+				newCode(code.OpArray, 3),
+				newCode(code.OpConst, 3),
+				newCode(code.OpNull),
+				newCode(code.OpNull),
+				newCode(code.OpSlice),
+				newCode(code.OpPop),
+			},
+		},
+		{
+			"case_2",
+			`"abc"[::-1]`,
+			[]interface{}{"abc", 1},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpNull),
+				newCode(code.OpNull),
+				newCode(code.OpConst, 1),
+				newCode(code.OpNeg),
+				newCode(code.OpSlice),
+				newCode(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
 func Test_Functions(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -693,6 +731,324 @@ func Test_Functions(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func Test_FunctionArgs(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			"case_1",
+			"func(x, y = 1) { y }",
+			[]interface{}{
+				1,
+				[]code.Instructions{
+					newCode(code.OpArgMissing, 1),
+					newCode(code.OpJumpWhenFalse, 10),
+					newCode(code.OpConst, 0),
+					newCode(code.OpSetLocal, 1),
+					newCode(code.OpGetLocal, 1),
+					newCode(code.OpReturn),
+				},
+			},
+			[]code.Instructions{
+				newCode(code.OpClosure, 1),
+				newCode(code.OpPop),
+			},
+		},
+		{
+			"case_2",
+			"str(...[1], 2)",
+			[]interface{}{1, 2},
+			[]code.Instructions{
+				newCode(code.OpGetBuiltin, 1),
+				newCode(code.OpConst, 0),
+				newCode(code.OpArray, 1),
+				newCode(code.OpConst, 1),
+				newCode(code.OpArray, 1),
+				newCode(code.OpCallSpread, 2),
+				newCode(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func Test_TryExpr(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			"case_1",
+			"try { 1 } catch (e) { e }",
+			[]interface{}{
+				1,
+				[]code.Instructions{
+					newCode(code.OpGetLocal, 0),
+					newCode(code.OpReturn),
+				},
+			},
+			[]code.Instructions{
+				newCode(code.OpTry, 10),
+				newCode(code.OpConst, 0),
+				newCode(code.OpEndTry),
+				newCode(code.OpJump, 17),
+				newCode(code.OpClosure, 1, 0),
+				newCode(code.OpCatch),
+				newCode(code.OpCall, 1),
+				newCode(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func Test_NullSafe(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			"case_1",
+			`"s" ?? 1`,
+			[]interface{}{"s", 1},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpJumpWhenNotNull, 9),
+				newCode(code.OpConst, 1),
+				newCode(code.OpPop),
+			},
+		},
+		{
+			"case_2",
+			`"s"?.[1]`,
+			[]interface{}{"s", 1},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpJumpWhenNull, 10),
+				newCode(code.OpConst, 1),
+				newCode(code.OpIndexOptional),
+				newCode(code.OpPop),
+			},
+		},
+		{
+			"case_3",
+			`"s"?.a`,
+			[]interface{}{"s", "a"},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpJumpWhenNull, 9),
+				newCode(code.OpGetMember, 1),
+				newCode(code.OpPop),
+			},
+		},
+		{
+			"case_4",
+			`"s"?.len()`,
+			[]interface{}{"s"},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpJumpWhenNull, 11),
+				newCode(code.OpCallObjectFn, 0, 0, 0),
+				newCode(code.OpPop),
+			},
+		},
+		{
+			"case_5",
+			`"s"?.a.b[1]`,
+			[]interface{}{"s", "a", "b", 1},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpJumpWhenNull, 16),
+				newCode(code.OpGetMember, 1),
+				newCode(code.OpGetMember, 2),
+				newCode(code.OpConst, 3),
+				newCode(code.OpIndex),
+				newCode(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func Test_InExpr(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			"case_1",
+			`"a" in "abc"`,
+			[]interface{}{"a", "abc"},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpConst, 1),
+				newCode(code.OpIn),
+				newCode(code.OpPop),
+			},
+		},
+		{
+			"case_2",
+			`"a" not in "abc"`,
+			[]interface{}{"a", "abc"},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpConst, 1),
+				newCode(code.OpNotIn),
+				newCode(code.OpPop),
+			},
+		},
+		{
+			"case_3",
+			`1..10`,
+			[]interface{}{1, 10},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpConst, 1),
+				newCode(code.OpInterval, 0),
+				newCode(code.OpPop),
+			},
+		},
+		{
+			"case_4",
+			`5 in 1..<10`,
+			[]interface{}{5, 1, 10},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpConst, 1),
+				newCode(code.OpConst, 2),
+				newCode(code.OpInterval, 1),
+				newCode(code.OpIn),
+				newCode(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func Test_MatchExpr(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			"case_1",
+			`match 1 { 1 => "a", _ => "b" }`,
+			// the last constant is the jump table: {1: 6}
+			[]interface{}{1, "a", "b", nil},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpJump, 19),
+				newCode(code.OpConst, 1),
+				newCode(code.OpJump, 24),
+				newCode(code.OpPop),
+				newCode(code.OpConst, 2),
+				newCode(code.OpJump, 24),
+				newCode(code.OpMatchTable, 3, 12),
+				newCode(code.OpPop),
+			},
+		},
+		{
+			"case_2",
+			`match 1 { x if x > 0 => x }`,
+			[]interface{}{1, 0},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpSetGlobal, 0),
+				newCode(code.OpGetGlobal, 0),
+				newCode(code.OpSetGlobal, 1),
+				newCode(code.OpGetGlobal, 1),
+				newCode(code.OpConst, 1),
+				newCode(code.OpGt),
+				newCode(code.OpJumpWhenFalse, 28),
+				newCode(code.OpGetGlobal, 1),
+				newCode(code.OpJump, 32),
+				newCode(code.OpGetGlobal, 0),
+				newCode(code.OpNoMatch),
+				newCode(code.OpPop),
+			},
+		},
+		{
+			"case_3",
+			`match [1] { [2] => 0 }`,
+			[]interface{}{1, 0, 2, 0},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpArray, 1),
+				newCode(code.OpSetGlobal, 0),
+				newCode(code.OpGetGlobal, 0),
+				newCode(code.OpMatchArray, 1, 0),
+				newCode(code.OpJumpWhenFalse, 39),
+				newCode(code.OpGetGlobal, 0),
+				newCode(code.OpConst, 1),
+				newCode(code.OpIndex),
+				newCode(code.OpConst, 2),
+				newCode(code.OpMatchValue),
+				newCode(code.OpJumpWhenFalse, 39),
+				newCode(code.OpConst, 3),
+				newCode(code.OpJump, 43),
+				newCode(code.OpGetGlobal, 0),
+				newCode(code.OpNoMatch),
+				newCode(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func Test_DestructConst(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			"case_1",
+			`const [a] = [1];`,
+			[]interface{}{1, 0, "[a]"},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpArray, 1),
+				newCode(code.OpSetGlobal, 0),
+				newCode(code.OpGetGlobal, 0),
+				newCode(code.OpMatchArray, 1, 0),
+				newCode(code.OpJumpWhenFalse, 32),
+				newCode(code.OpGetGlobal, 0),
+				newCode(code.OpConst, 1),
+				newCode(code.OpIndex),
+				newCode(code.OpSetGlobal, 1),
+				newCode(code.OpJump, 38),
+				newCode(code.OpGetGlobal, 0),
+				newCode(code.OpDestructFail, 2),
+			},
+		},
+		{
+			"case_2",
+			`const [..._] = [];`,
+			[]interface{}{"[..._]"},
+			[]code.Instructions{
+				newCode(code.OpArray, 0),
+				newCode(code.OpSetGlobal, 0),
+				newCode(code.OpGetGlobal, 0),
+				newCode(code.OpMatchArray, 0, 1),
+				newCode(code.OpJumpWhenFalse, 19),
+				newCode(code.OpJump, 25),
+				newCode(code.OpGetGlobal, 0),
+				newCode(code.OpDestructFail, 0),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func Test_TemplateExpr(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			"case_1",
+			"`a${1}b`",
+			[]interface{}{"a", 1, "b"},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpConst, 1),
+				newCode(code.OpConst, 2),
+				newCode(code.OpConcat, 3),
+				newCode(code.OpPop),
+			},
+		},
+		{
+			"case_2",
+			"``",
+			[]interface{}{},
+			[]code.Instructions{
+				newCode(code.OpConcat, 0),
+				newCode(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
 func Test_Scopes(t *testing.T) {
 	c := New()
 	b := c.Bytecode()
@@ -988,7 +1344,7 @@ func Test_RecursiveFunctions(t *testing.T) {
 					newCode(code.OpGetLocal, 0),
 					newCode(code.OpConst, 0),
 					newCode(code.OpSub),
-					newCode(code.OpCall, 1),
+					newCode(code.OpTailCall, 1),
 					newCode(code.OpReturn),
 				},
 				1,
@@ -1006,6 +1362,96 @@ func Test_RecursiveFunctions(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func Test_TailCall(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			"case_1",
+			`
+			const f = func(x) {
+				x ? f(x) : 1
+			};
+			`,
+			[]interface{}{
+				1,
+				[]code.Instructions{
+					newCode(code.OpGetLocal, 0),
+					newCode(code.OpJumpWhenFalse, 12),
+					newCode(code.OpGetLambda),
+					newCode(code.OpGetLocal, 0),
+					newCode(code.OpTailCall, 1),
+					newCode(code.OpReturn),
+					newCode(code.OpConst, 0),
+					newCode(code.OpReturn),
+				},
+			},
+			[]code.Instructions{
+				newCode(code.OpClosure, 1, 0),
+				newCode(code.OpSetGlobal, 0),
+			},
+		},
+		{
+			"case_2",
+			`
+			const f = func(...xs) {
+				f(...xs)
+			};
+			`,
+			[]interface{}{
+				[]code.Instructions{
+					newCode(code.OpGetLambda),
+					newCode(code.OpGetLocal, 0),
+					newCode(code.OpTailCallSpread, 1),
+					newCode(code.OpReturn),
+				},
+			},
+			[]code.Instructions{
+				newCode(code.OpClosure, 0, 0),
+				newCode(code.OpSetGlobal, 0),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func Test_Struct(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			"case_1",
+			`
+			struct P { x };
+			func (p P) get() { p.x };
+			P(1).get();
+			`,
+			[]interface{}{
+				nil,
+				"x",
+				[]code.Instructions{
+					newCode(code.OpGetLocal, 0),
+					newCode(code.OpGetMember, 1),
+					newCode(code.OpReturn),
+				},
+				"get",
+				1,
+				"get",
+			},
+			[]code.Instructions{
+				newCode(code.OpConst, 0),
+				newCode(code.OpSetGlobal, 0),
+				newCode(code.OpGetGlobal, 0),
+				newCode(code.OpClosure, 2, 0),
+				newCode(code.OpMethod, 3),
+				newCode(code.OpGetGlobal, 0),
+				newCode(code.OpConst, 4),
+				newCode(code.OpCall, 1),
+				newCode(code.OpGetMember, 5),
+				newCode(code.OpCall, 0),
+				newCode(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
 func Test_ResolveFunctionName(t *testing.T) {
 	g := NewSymbolTable(nil)
 	g.defineLambda("a")
@@ -1019,3 +1465,311 @@ func Test_ResolveFunctionName(t *testing.T) {
 		t.Fatalf("want %s to resolve to %+v, got %+v, err: %v", want.Name, want, r, err)
 	}
 }
+
+func Test_Marshal(t *testing.T) {
+	p, err := parser.New(`
+	struct P { x, y };
+	const f = func(a, b = 2, ...c) { P(a, b) };
+	const n = 123456789012345678901234567890;
+	match f(1).x { 1 => "a", true => "b", null => "c", _ => "d" }
+	`)
+	if nil != err {
+		t.Fatal(err)
+	}
+	program, err := p.ParseProgram()
+	if nil != err {
+		t.Fatal(err)
+	}
+	c := New()
+	if err := c.Compile(program); nil != err {
+		t.Fatal(err)
+	}
+	b, err := Marshal(c.Bytecode(), c.Constants())
+	if nil != err {
+		t.Fatal(err)
+	}
+	bytecode, consts, err := Unmarshal(b)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := testInstructions([]code.Instructions{c.Bytecode().Instructions()}, bytecode.Instructions()); nil != err {
+		t.Fatal(err)
+	}
+	want := c.Constants()
+	if len(consts) != len(want) {
+		t.Fatalf("expect %v constants, got %v", len(want), len(consts))
+	}
+	for i, obj := range want {
+		if object.Typeof(obj) != object.Typeof(consts[i]) || obj.String() != consts[i].String() {
+			switch v := obj.(type) {
+			case *object.ByteFunc:
+				fn, ok := consts[i].(*object.ByteFunc)
+				if ok && v.Arity == fn.Arity && v.Locals == fn.Locals && bytes.Equal(v.Ins, fn.Ins) {
+					continue
+				}
+			}
+			t.Fatalf("constant %v expect %v, got %v", i, obj.String(), consts[i].String())
+		}
+	}
+	if _, err := Marshal(c.Bytecode(), object.Objects{object.NewArray(nil)}); nil == err {
+		t.Fatal("expect error for array constant")
+	}
+}
+
+func Test_Unmarshal(t *testing.T) {
+	concat := func(items ...code.Instructions) code.Instructions {
+		r := code.Instructions{}
+		for _, ins := range items {
+			r = append(r, ins...)
+		}
+		return r
+	}
+	one := object.NewInteger(1)
+	fn := func(ins code.Instructions, locals int) object.Object {
+		return object.NewByteFunc(ins, object.Arity{}, locals)
+	}
+	tests := []struct {
+		ins    code.Instructions
+		consts object.Objects
+		want   string
+	}{
+		{concat(newCode(code.OpConst, 0), newCode(code.OpJump, 6)), object.Objects{one}, ""},
+		{newCode(code.OpConst, 0)[:2], object.Objects{one}, "OpConst truncated"},
+		{code.Instructions{0xfe}, nil, "0000"},
+		{newCode(code.OpConst, 1), object.Objects{one}, "refers to constant 1 of 1"},
+		{newCode(code.OpAddConst, 0), nil, "refers to constant 0 of 0"},
+		{newCode(code.OpClosure, 0, 0), object.Objects{one}, "refers to constant 0 of type integer"},
+		{newCode(code.OpGetBuiltin, 0xff), nil, "refers to builtin 255"},
+		{newCode(code.OpGetLocal, 0), nil, "refers to local 0 of 0"},
+		{concat(newCode(code.OpConst, 0), newCode(code.OpJump, 7)), object.Objects{one}, "jumps to 7"},
+		{concat(newCode(code.OpConst, 0), newCode(code.OpJump, 1)), object.Objects{one}, "jumps to 1"},
+		{newCode(code.OpConst, 0), object.Objects{fn(newCode(code.OpGetLocal, 1), 1)}, "refers to local 1 of 1"},
+		{newCode(code.OpConst, 0), object.Objects{fn(newCode(code.OpConst, 1), 0)}, "function 0: 0000 OpConst"},
+		{newCode(code.OpConst, 0), object.Objects{object.NewByteFunc(nil, object.Arity{Required: 1}, 1)}, "required 1"},
+		{newCode(code.OpPop), nil, "OpPop pops 1 of 0 values"},
+		{concat(newCode(code.OpConst, 0), newCode(code.OpCall, 1)), object.Objects{one}, "OpCall pops 2 of 1 values"},
+		{newCode(code.OpClosure, 0, 2), object.Objects{fn(nil, 0)}, "OpClosure pops 2 of 0 values"},
+		{concat(newCode(code.OpClosure, 0, 0), newCode(code.OpPop)), object.Objects{fn(newCode(code.OpGetFree, 0), 0)}, "refers to free variable 0 of 0"},
+		{concat(newCode(code.OpConst, 1), newCode(code.OpClosure, 0, 1), newCode(code.OpClosure, 0, 0)),
+			object.Objects{fn(nil, 0), one}, "closed with 1 and 0 free variables"},
+		{newCode(code.OpEndTry), nil, "OpEndTry out of a try block"},
+		{concat(newCode(code.OpTrue), newCode(code.OpJumpWhenFalse, 5), newCode(code.OpNull), newCode(code.OpNull)), nil,
+			"0005 reached with 0 and 1 values"},
+	}
+	for i, tt := range tests {
+		b, err := Marshal(newBytecode(tt.ins), tt.consts)
+		if nil != err {
+			t.Fatal(err)
+		}
+		_, _, err = Unmarshal(b)
+		if "" == tt.want {
+			if nil != err {
+				t.Fatalf("i: %v, err: %v", i, err)
+			}
+			continue
+		}
+		if nil == err {
+			t.Fatalf("i: %v, expect error", i)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("i: %v, expect error `%v`, got `%v`", i, tt.want, err)
+		}
+	}
+}
+
+func Test_Disassemble(t *testing.T) {
+	p, err := parser.New(`
+	const k = 1;
+	func add(x, y = 2) { x + y + k };
+	const f = func(a) { func(b) { a + b } };
+	map([1], func(i, v) { v }).len() + add(1)
+	`)
+	if nil != err {
+		t.Fatal(err)
+	}
+	program, err := p.ParseProgram()
+	if nil != err {
+		t.Fatal(err)
+	}
+	c := New()
+	if err := c.Compile(program); nil != err {
+		t.Fatal(err)
+	}
+	s := Disassemble(c.Bytecode(), c.Constants(), c.Debug())
+	wants := []string{
+		"globals: 0 k, 1 add, 2 f",
+		"OpSetGlobal 1                  ; add",
+		"fn#2 add: args 2, required 1, locals 2",
+		"locals: 0 x, 1 y",
+		"OpArgMissing 1                 ; y",
+		"OpJumpWhenFalse 10             ; to 0010",
+		">> 0010 OpGetLocal 0                   ; x",
+		"frees: 0 a",
+		"OpGetFree 0                    ; a",
+		"OpClosure 3 1                  ; fn#3 <lambda>, 1 frees",
+		"fn#6 <map>: args 3, required 3, locals 3",
+		"OpCallObjectFn 0 0 0           ; len, 0 args, cache 0",
+	}
+	for _, want := range wants {
+		if !strings.Contains(s, want) {
+			t.Fatalf("expect `%v` in\n%v", want, s)
+		}
+	}
+	// names are unknown without debug info
+	s = Disassemble(c.Bytecode(), c.Constants(), nil)
+	if !strings.Contains(s, "fn#2: args 2") || strings.Contains(s, "; add") {
+		t.Fatalf("unexpected disassembly\n%v", s)
+	}
+	// broken instructions are reported instead of looping
+	s = Disassemble(newBytecode(code.Instructions{byte(code.OpConst), 0}), nil, nil)
+	if !strings.Contains(s, "ERROR: 0000 OpConst truncated") {
+		t.Fatalf("unexpected disassembly\n%v", s)
+	}
+}
+
+func Test_Peephole(t *testing.T) {
+	concat := func(items ...[]byte) code.Instructions {
+		r := code.Instructions{}
+		for _, item := range items {
+			r = append(r, item...)
+		}
+		return r
+	}
+	mustMake := func(op code.Opcode, operands ...int) []byte {
+		b, err := code.Make(op, operands...)
+		if nil != err {
+			t.Fatal(err)
+		}
+		return b
+	}
+	table := object.NewOrderedHash()
+	if err := table.Set(object.NewInteger(1), object.NewInteger(28)); nil != err {
+		t.Fatal(err)
+	}
+	consts := object.Objects{object.NewInteger(1), table}
+	ins := concat(
+		mustMake(code.OpGetLocal, 0),       // 0000
+		mustMake(code.OpGetLocal, 1),       // 0002
+		mustMake(code.OpLt),                // 0004
+		mustMake(code.OpJumpWhenFalse, 17), // 0005
+		mustMake(code.OpGetLocal, 0),       // 0008
+		mustMake(code.OpConst, 0),          // 0010
+		mustMake(code.OpSub),               // 0013
+		mustMake(code.OpJump, 23),          // 0014
+		mustMake(code.OpGetBuiltin, 1),     // 0017
+		mustMake(code.OpGetLocal, 0),       // 0019
+		mustMake(code.OpCall, 1),           // 0021
+		mustMake(code.OpMatchTable, 1, 28), // 0023
+		mustMake(code.OpConst, 0),          // 0028
+		mustMake(code.OpReturn),            // 0031
+	)
+	b, err := Peephole(newBytecode(ins), consts)
+	if nil != err {
+		t.Fatal(err)
+	}
+	expected := concat(
+		mustMake(code.OpGetLocal2, 0, 1),             // 0000
+		mustMake(code.OpCmpJump, int(code.OpLt), 15), // 0003, the dropped OpGetBuiltin moves to its first arg
+		mustMake(code.OpGetLocal, 0),                 // 0007
+		mustMake(code.OpSubConst, 0),                 // 0009
+		mustMake(code.OpJump, 20),                    // 0012
+		mustMake(code.OpGetLocal, 0),                 // 0015
+		mustMake(code.OpCallBuiltin, 1, 1),           // 0017
+		mustMake(code.OpMatchTable, 1, 25),           // 0020
+		mustMake(code.OpConst, 0),                    // 0025
+		mustMake(code.OpReturn),                      // 0028
+	)
+	if got := b.Instructions(); !bytes.Equal(expected, got) {
+		t.Fatalf("expect\n%v\ngot\n%v", expected.String(), got.String())
+	}
+	moved, err := object.ToInteger(consts[1].(*object.Hash).Items()[0].Value)
+	if nil != err || 25 != moved {
+		t.Fatalf("match table is not moved, %v", consts[1].String())
+	}
+	// a jump target is never fused into the previous instruction
+	ins = concat(
+		mustMake(code.OpGetLocal, 0),      // 0000
+		mustMake(code.OpJumpWhenFalse, 8), // 0002
+		mustMake(code.OpConst, 0),         // 0005
+		mustMake(code.OpGetLocal, 0),      // 0008
+		mustMake(code.OpReturn),           // 0010
+	)
+	b, err = Peephole(newBytecode(ins), consts)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if got := b.Instructions(); !bytes.Equal(ins, got) {
+		t.Fatalf("expect\n%v\ngot\n%v", ins.String(), got.String())
+	}
+}
+
+// wideProgram : more than 255 args, locals and frees, and functions whose jumps are beyond 2 bytes
+func wideProgram(n int, terms int) string {
+	args, vals := []string{}, []string{}
+	for i := 0; i < n; i++ {
+		args = append(args, fmt.Sprintf("a%v", i))
+		vals = append(vals, fmt.Sprintf("%v", i))
+	}
+	big := "x" + strings.Repeat(" + 1 + x", terms)
+	return fmt.Sprintf(`
+	func f(%v) { a%v - a0 };
+	func g(%v) { func() { %v } };
+	func h(x) { (x > 0) ? (%v) : -1 };
+	func m(x) { match x { 0 => "zero", 1 => %v, _ => "other" } };
+	[f(%v), g(%v)(), h(1), h(0), m(0), m(1), m(2)]
+	`, strings.Join(args, ", "), n-1, strings.Join(args, ", "), strings.Join(args, " + "), big, big,
+		strings.Join(vals, ", "), strings.Join(vals, ", "))
+}
+
+func Test_Wide(t *testing.T) {
+	p, err := parser.New(wideProgram(300, 12000))
+	if nil != err {
+		t.Fatal(err)
+	}
+	program, err := p.ParseProgram()
+	if nil != err {
+		t.Fatal(err)
+	}
+	c := New()
+	if err := c.Compile(program); nil != err {
+		t.Fatal(err)
+	}
+	s := Disassemble(c.Bytecode(), c.Constants(), c.Debug())
+	wants := []string{
+		"OpWide OpGetLocal 299",
+		"OpWide OpCall 300",
+		"OpWide OpClosure",
+		"OpWide OpGetFree 299",
+		"OpWide OpJumpWhenFalse",
+		"OpWide OpJump ",
+		"OpWide OpMatchTable",
+	}
+	for _, want := range wants {
+		if !strings.Contains(s, want) {
+			t.Fatalf("expect `%v`", want)
+		}
+	}
+	if strings.Contains(s, "ERROR") {
+		t.Fatal("unexpected error in disassembly")
+	}
+	if err := c.Peephole(); nil != err {
+		t.Fatal(err)
+	}
+
+	// the globals are limited by the vm
+	var b strings.Builder
+	for i := 0; i <= MaxGlobals; i++ {
+		fmt.Fprintf(&b, "const g%v = 0;\n", i)
+	}
+	p, err = parser.New(b.String())
+	if nil != err {
+		t.Fatal(err)
+	}
+	program, err = p.ParseProgram()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := New().Compile(program); nil == err || !strings.Contains(err.Error(), "too many globals") {
+		t.Fatalf("expect too many globals, got: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package compiler
+
+import (
+	"github.com/jobs-github/escript/ast"
+	"github.com/jobs-github/escript/code"
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+)
+
+const (
+	destructValue = "__destruct__"
+)
+
+func (this *visitor) doConstPattern(v *ast.ConstStmt) error {
+	if err := v.Value.Do(this); nil != err {
+		return function.NewError(err)
+	}
+	s := this.c.define(destructValue)
+	if _, err := this.doStoreSymbol(s); nil != err {
+		return function.NewError(err)
+	}
+	return this.doDestruct(v.Pattern, s)
+}
+
+// destruct bytecode format
+//
+//	     pattern----------|  every mismatch jumps to the error
+//	|----OpJump           |
+//	|    value<-----------|
+//	|    OpDestructFail
+//	|--->...
+func (this *visitor) doDestruct(p ast.Pattern, s *Symbol) error {
+	load := func() error {
+		_, err := this.doLoadSymbol(s)
+		return err
+	}
+	fails := []int{}
+	if err := p.Do(&patternVisitor{v: this, load: load, fails: &fails}); nil != err {
+		return function.NewError(err)
+	}
+	if 0 == len(fails) {
+		return nil
+	}
+	posEnd, err := this.c.encode(code.OpJump, unpatched)
+	if nil != err {
+		return function.NewError(err)
+	}
+	// back-patching
+	for _, pos := range fails {
+		if err := this.c.changeOperand(pos, this.c.pos()); nil != err {
+			return function.NewError(err)
+		}
+	}
+	if err := load(); nil != err {
+		return function.NewError(err)
+	}
+	idx := this.c.addConst(object.NewString(p.String()))
+	if _, err := this.c.encode(code.OpDestructFail, idx); nil != err {
+		return function.NewError(err)
+	}
+	// back-patching
+	if err := this.c.changeOperand(posEnd, this.c.pos()); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
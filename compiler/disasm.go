@@ -0,0 +1,245 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/jobs-github/escript/builtin"
+	"github.com/jobs-github/escript/code"
+	"github.com/jobs-github/escript/object"
+)
+
+// FuncInfo : names of a compiled function, the synthetic loop closures are named like `<map>`
+type FuncInfo struct {
+	Name   string
+	Locals []string // by index, args first
+	Frees  []string // by index
+}
+
+// DebugInfo : names of the symbols, the instructions refer to them by index only
+type DebugInfo struct {
+	Globals []string
+	Funcs   map[int]*FuncInfo // by index of the ByteFunc constant
+}
+
+// Disassemble : listing of the constant pool, the main instructions and every function constant,
+// debug is nil if the names are unknown (e.g. the bytecode is loaded from .esc)
+func Disassemble(b Bytecode, consts object.Objects, debug *DebugInfo) string {
+	d := &disassembler{consts: consts, debug: debug}
+	builtin.Traverse(func(i int, name string) { d.builtins = append(d.builtins, name) })
+	object.Traverse(func(i int, name string) { d.objectFns = append(d.objectFns, name) })
+	return d.run(b.Instructions())
+}
+
+type disassembler struct {
+	consts    object.Objects
+	debug     *DebugInfo
+	builtins  []string
+	objectFns []string
+	fn        *FuncInfo // function being disassembled, nil for main
+	out       bytes.Buffer
+}
+
+func (this *disassembler) run(main code.Instructions) string {
+	if len(this.consts) > 0 {
+		this.out.WriteString("constants:\n")
+		for i, c := range this.consts {
+			// `struct P { x, y }` is typed by itself
+			if s, t := this.constant(i), object.Typeof(c); strings.HasPrefix(s, t) {
+				fmt.Fprintf(&this.out, "  %4d %v\n", i, s)
+			} else {
+				fmt.Fprintf(&this.out, "  %4d %v %v\n", i, t, s)
+			}
+		}
+	}
+	this.out.WriteString("main:\n")
+	if nil != this.debug && len(this.debug.Globals) > 0 {
+		fmt.Fprintf(&this.out, "  globals: %v\n", indexed(this.debug.Globals))
+	}
+	this.instructions(main)
+	for i, c := range this.consts {
+		fn, ok := c.(*object.ByteFunc)
+		if !ok {
+			continue
+		}
+		this.fn = this.funcInfo(i)
+		fmt.Fprintf(&this.out, "\n%v: args %v, required %v", this.funcName(i), fn.Args, fn.Required)
+		if fn.Rest {
+			this.out.WriteString(", rest")
+		}
+		fmt.Fprintf(&this.out, ", locals %v\n", fn.Locals)
+		if nil != this.fn && len(this.fn.Locals) > 0 {
+			fmt.Fprintf(&this.out, "  locals: %v\n", indexed(this.fn.Locals))
+		}
+		if nil != this.fn && len(this.fn.Frees) > 0 {
+			fmt.Fprintf(&this.out, "  frees: %v\n", indexed(this.fn.Frees))
+		}
+		this.instructions(fn.Ins)
+	}
+	return this.out.String()
+}
+
+func (this *disassembler) funcInfo(idx int) *FuncInfo {
+	if nil == this.debug {
+		return nil
+	}
+	return this.debug.Funcs[idx]
+}
+
+// funcName : `fn#2 add`
+func (this *disassembler) funcName(idx int) string {
+	if info := this.funcInfo(idx); nil != info {
+		return fmt.Sprintf("fn#%v %v", idx, info.Name)
+	}
+	return fmt.Sprintf("fn#%v", idx)
+}
+
+func (this *disassembler) constant(idx int) string {
+	if idx < 0 || idx >= len(this.consts) {
+		return fmt.Sprintf("<invalid constant %v>", idx)
+	}
+	switch v := this.consts[idx].(type) {
+	case *object.String:
+		return fmt.Sprintf("%q", v.Value)
+	case *object.ByteFunc:
+		return this.funcName(idx)
+	default:
+		return v.String()
+	}
+}
+
+// decoded : an instruction and its operands, pos is the one of OpWide if it is wide
+type decoded struct {
+	pos      int
+	op       code.Opcode
+	def      *code.Definition
+	operands []int
+	wide     bool
+}
+
+func decode(ins code.Instructions) ([]*decoded, error) {
+	r := []*decoded{}
+	for i := 0; i < len(ins); {
+		op, prefix, lookup := code.Opcode(ins[i]), 0, code.Lookup
+		if code.OpWide == op && i+1 < len(ins) {
+			op, prefix, lookup = code.Opcode(ins[i+1]), 1, code.LookupWide
+		}
+		def, err := lookup(op)
+		if nil != err {
+			return r, fmt.Errorf("%04d %v", i, err)
+		}
+		width := 0
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		if i+1+prefix+width > len(ins) {
+			return r, fmt.Errorf("%04d %v truncated", i, def.Name)
+		}
+		operands, err := code.DecodeOperands(def, ins[i+1+prefix:])
+		if nil != err {
+			return r, fmt.Errorf("%04d %v", i, err)
+		}
+		r = append(r, &decoded{pos: i, op: op, def: def, operands: operands.Value, wide: prefix > 0})
+		i += 1 + prefix + width
+	}
+	return r, nil
+}
+
+func (this *disassembler) instructions(ins code.Instructions) {
+	items, err := decode(ins)
+	targets := jumpTargets(items, this.consts)
+	for _, item := range items {
+		marker := ""
+		if targets[item.pos] {
+			marker = ">>"
+		}
+		line := fmt.Sprintf("  %2s %04d %v", marker, item.pos, format(item))
+		if comment := this.comment(item); "" != comment {
+			line = fmt.Sprintf("%-40s ; %v", line, comment)
+		}
+		this.out.WriteString(line)
+		this.out.WriteString("\n")
+	}
+	if nil != err {
+		fmt.Fprintf(&this.out, "  ERROR: %v\n", err)
+	}
+}
+
+func format(item *decoded) string {
+	s := []string{item.def.Name}
+	if item.wide {
+		s = append([]string{"OpWide"}, s...)
+	}
+	for _, operand := range item.operands {
+		s = append(s, fmt.Sprintf("%v", operand))
+	}
+	return strings.Join(s, " ")
+}
+
+// comment : meaning of the operands
+func (this *disassembler) comment(item *decoded) string {
+	switch item.op {
+	case code.OpConst, code.OpAddConst, code.OpSubConst, code.OpSymbol, code.OpGetMember, code.OpMethod, code.OpDestructFail:
+		return this.constant(item.operands[0])
+	case code.OpClosure:
+		return fmt.Sprintf("%v, %v frees", this.funcName(item.operands[0]), item.operands[1])
+	case code.OpGetGlobal, code.OpSetGlobal:
+		if nil != this.debug {
+			return nameAt(this.debug.Globals, item.operands[0])
+		}
+	case code.OpGetLocal, code.OpSetLocal, code.OpIncLocal, code.OpArgMissing:
+		if nil != this.fn {
+			return nameAt(this.fn.Locals, item.operands[0])
+		}
+	case code.OpGetLocal2:
+		if nil != this.fn {
+			return fmt.Sprintf("%v, %v", nameAt(this.fn.Locals, item.operands[0]), nameAt(this.fn.Locals, item.operands[1]))
+		}
+	case code.OpGetFree:
+		if nil != this.fn {
+			return nameAt(this.fn.Frees, item.operands[0])
+		}
+	case code.OpGetLambda:
+		if nil != this.fn {
+			return this.fn.Name
+		}
+	case code.OpGetBuiltin:
+		return nameAt(this.builtins, item.operands[0])
+	case code.OpCallBuiltin:
+		return fmt.Sprintf("%v, %v args", nameAt(this.builtins, item.operands[0]), item.operands[1])
+	case code.OpCmpJump:
+		def, err := code.Lookup(code.Opcode(item.operands[0]))
+		if nil != err {
+			return fmt.Sprintf("<invalid comparison %v>, to %04d", item.operands[0], item.operands[1])
+		}
+		return fmt.Sprintf("%v, to %04d", def.Name, item.operands[1])
+	case code.OpGetObjectFn:
+		return nameAt(this.objectFns, item.operands[0])
+	case code.OpCallObjectFn:
+		return fmt.Sprintf("%v, %v args, cache %v", nameAt(this.objectFns, item.operands[0]), item.operands[1], item.operands[2])
+	case code.OpJump, code.OpJumpWhenFalse, code.OpJumpWhenNull, code.OpJumpWhenNotNull:
+		return fmt.Sprintf("to %04d", item.operands[0])
+	case code.OpTry:
+		return fmt.Sprintf("catch at %04d", item.operands[0])
+	case code.OpMatchTable:
+		return fmt.Sprintf("table %v, default to %04d", this.constant(item.operands[0]), item.operands[1])
+	}
+	return ""
+}
+
+func nameAt(names []string, idx int) string {
+	if idx < 0 || idx >= len(names) {
+		return ""
+	}
+	return names[idx]
+}
+
+// indexed : `0 x, 1 y`
+func indexed(names []string) string {
+	s := []string{}
+	for i, name := range names {
+		s = append(s, fmt.Sprintf("%v %v", i, name))
+	}
+	return strings.Join(s, ", ")
+}
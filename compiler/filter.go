@@ -39,7 +39,7 @@ func (this *visitor) DoFilter(v *ast.FilterExpr) error {
 		arr:          arr,
 		res:          res,
 	}
-	if err := this.doLoop(l, v.Body, i, arr); nil != err {
+	if err := this.doLoop("<filter>", l, v.Body, i, arr); nil != err {
 		return function.NewError(err)
 	}
 	// push 0
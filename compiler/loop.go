@@ -28,19 +28,21 @@ func (this *visitor) doBody(l loop, body ast.Expression) error {
 	return nil
 }
 
-func (this *visitor) doLoop(l loop, body ast.Expression, i *ast.Identifier, up *ast.Identifier) error {
+// doLoop : name is the name of the synthetic closure in the disassembly
+func (this *visitor) doLoop(name string, l loop, body ast.Expression, i *ast.Identifier, up *ast.Identifier) error {
 	this.c.enterScope()
 
 	// args
 	si := this.c.define(i.Value)
 	this.c.define(up.Value)
 	l.prepare()
+	args := this.c.symbols()
 
 	startPos, err := this.doCond(l, i)
 	if nil != err {
 		return function.NewError(err)
 	}
-	endPos, err := this.c.encode(code.OpJumpWhenFalse, -1)
+	endPos, err := this.c.encode(code.OpJumpWhenFalse, unpatched)
 	if nil != err {
 		return function.NewError(err)
 	}
@@ -65,10 +67,14 @@ func (this *visitor) doLoop(l loop, body ast.Expression, i *ast.Identifier, up *
 		return function.NewError(err)
 	}
 	symbols := this.c.symbols()
-	r := this.c.leaveScope()
+	info := this.c.funcInfo(name)
+	r, err := this.c.leaveScope()
+	if nil != err {
+		return function.NewError(err)
+	}
 
-	fn := object.NewByteFunc(r.Instructions(), symbols)
-	idx := this.c.addConst(fn)
+	fn := object.NewByteFunc(r.Instructions(), object.NewArity(args), symbols)
+	idx := this.c.addFunc(fn, info)
 	if _, err := this.c.encode(code.OpClosure, idx, 0); nil != err {
 		return function.NewError(err)
 	}
@@ -166,7 +172,7 @@ func (this *visitor) DoLoop(v *ast.LoopExpr) error {
 		i:            i,
 		cnt:          cnt,
 	}
-	if err := this.doLoop(l, v.Body, i, cnt); nil != err {
+	if err := this.doLoop("<loop>", l, v.Body, i, cnt); nil != err {
 		return function.NewError(err)
 	}
 
@@ -41,7 +41,7 @@ func (this *visitor) DoMap(v *ast.MapExpr) error {
 		arr:          arr,
 		res:          res,
 	}
-	if err := this.doLoop(l, v.Body, i, arr); nil != err {
+	if err := this.doLoop("<map>", l, v.Body, i, arr); nil != err {
 		return function.NewError(err)
 	}
 	// push 0
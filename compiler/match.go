@@ -0,0 +1,431 @@
+package compiler
+
+import (
+	"github.com/jobs-github/escript/ast"
+	"github.com/jobs-github/escript/code"
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+)
+
+const (
+	matchValue = "__match__"
+)
+
+// MatchExpr bytecode format
+//
+//	     value
+//	     OpSetGlobal/OpSetLocal __match__
+//	     pattern------------------|  every mismatch jumps to the next arm
+//	     guard                    |
+//	     OpJumpWhenFalse----------|
+//	     body                     |
+//	|----OpJump                   |
+//	|    pattern...<--------------|
+//	|    OpGetGlobal/OpGetLocal __match__
+//	|    OpNoMatch
+//	|--->...
+//
+// bindings of an arm are visible only inside the arm
+func (this *visitor) DoMatch(v *ast.MatchExpr) error {
+	saved := this.c.snapshot()
+	defer this.c.restore(saved)
+
+	if table := newJumpTable(v); nil != table {
+		return this.doMatchTable(v, table)
+	}
+	if err := v.Value.Do(this); nil != err {
+		return function.NewError(err)
+	}
+	s := this.c.define(matchValue)
+	if _, err := this.doStoreSymbol(s); nil != err {
+		return function.NewError(err)
+	}
+	load := func() error {
+		_, err := this.doLoadSymbol(s)
+		return err
+	}
+	ends := []int{}
+	for _, arm := range v.Arms {
+		pos, err := this.doMatchArm(arm, load)
+		if nil != err {
+			return function.NewError(err)
+		}
+		ends = append(ends, pos)
+	}
+	if err := load(); nil != err {
+		return function.NewError(err)
+	}
+	if _, err := this.c.encode(code.OpNoMatch); nil != err {
+		return function.NewError(err)
+	}
+	// back-patching
+	for _, pos := range ends {
+		if err := this.c.changeOperand(pos, this.c.pos()); nil != err {
+			return function.NewError(err)
+		}
+	}
+	return nil
+}
+
+// doMatchArm : return the pos of OpJump to the end of match
+func (this *visitor) doMatchArm(arm *ast.MatchArm, load func() error) (int, error) {
+	saved := this.c.snapshot()
+	defer this.c.restore(saved)
+
+	fails := []int{}
+	pv := &patternVisitor{v: this, load: load, fails: &fails}
+	if err := arm.Pattern.Do(pv); nil != err {
+		return -1, function.NewError(err)
+	}
+	if nil != arm.Guard {
+		if err := arm.Guard.Do(this.enclosed(optionEncodeNothing)); nil != err {
+			return -1, function.NewError(err)
+		}
+		if err := pv.fail(); nil != err {
+			return -1, function.NewError(err)
+		}
+	}
+	if err := arm.Body.Do(this.enclosed(optionEncodeNothing)); nil != err {
+		return -1, function.NewError(err)
+	}
+	posEnd, err := this.c.encode(code.OpJump, unpatched)
+	if nil != err {
+		return -1, function.NewError(err)
+	}
+	// back-patching
+	for _, pos := range fails {
+		if err := this.c.changeOperand(pos, this.c.pos()); nil != err {
+			return -1, function.NewError(err)
+		}
+	}
+	return posEnd, nil
+}
+
+// patternVisitor : implement ast.PatternVisitor, leave nothing on the stack
+type patternVisitor struct {
+	v     *visitor
+	load  func() error // push the value to match
+	fails *[]int       // OpJumpWhenFalse to the next arm
+}
+
+func (this *patternVisitor) fail() error {
+	pos, err := this.v.c.encode(code.OpJumpWhenFalse, unpatched)
+	if nil != err {
+		return function.NewError(err)
+	}
+	*this.fails = append(*this.fails, pos)
+	return nil
+}
+
+// child : visitor of the value which load pushes after the parent value
+func (this *patternVisitor) child(load func() error) *patternVisitor {
+	return &patternVisitor{
+		v: this.v,
+		load: func() error {
+			if err := this.load(); nil != err {
+				return err
+			}
+			return load()
+		},
+		fails: this.fails,
+	}
+}
+
+func (this *patternVisitor) DoWildcardPattern(p *ast.WildcardPattern) error {
+	return nil
+}
+
+func (this *patternVisitor) DoBindPattern(p *ast.BindPattern) error {
+	if err := this.load(); nil != err {
+		return function.NewError(err)
+	}
+	s := this.v.c.define(p.Name.Value)
+	if _, err := this.v.doStoreSymbol(s); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+func (this *patternVisitor) DoLiteralPattern(p *ast.LiteralPattern) error {
+	if err := this.load(); nil != err {
+		return function.NewError(err)
+	}
+	if err := p.Value.Do(this.v); nil != err {
+		return function.NewError(err)
+	}
+	if _, err := this.v.c.encode(code.OpMatchValue); nil != err {
+		return function.NewError(err)
+	}
+	return this.fail()
+}
+
+// AltPattern bytecode format
+//
+//	     alt 1------------|
+//	|----OpJump           |
+//	|    alt 2...<--------|
+//	|--->...
+func (this *patternVisitor) DoAltPattern(p *ast.AltPattern) error {
+	oks := []int{}
+	last := len(p.Alts) - 1
+	for i, alt := range p.Alts {
+		if i == last {
+			if err := alt.Do(this); nil != err {
+				return function.NewError(err)
+			}
+			break
+		}
+		fails := []int{}
+		if err := alt.Do(&patternVisitor{v: this.v, load: this.load, fails: &fails}); nil != err {
+			return function.NewError(err)
+		}
+		pos, err := this.v.c.encode(code.OpJump, unpatched)
+		if nil != err {
+			return function.NewError(err)
+		}
+		oks = append(oks, pos)
+		// back-patching
+		for _, pos := range fails {
+			if err := this.v.c.changeOperand(pos, this.v.c.pos()); nil != err {
+				return function.NewError(err)
+			}
+		}
+	}
+	// back-patching
+	for _, pos := range oks {
+		if err := this.v.c.changeOperand(pos, this.v.c.pos()); nil != err {
+			return function.NewError(err)
+		}
+	}
+	return nil
+}
+
+func (this *patternVisitor) DoArrayPattern(p *ast.ArrayPattern) error {
+	if err := this.load(); nil != err {
+		return function.NewError(err)
+	}
+	rest := 0
+	if nil != p.Rest {
+		rest = 1
+	}
+	if _, err := this.v.c.encode(code.OpMatchArray, len(p.Elems), rest); nil != err {
+		return function.NewError(err)
+	}
+	if err := this.fail(); nil != err {
+		return function.NewError(err)
+	}
+	for i, elem := range p.Elems {
+		idx := object.NewInteger(int64(i))
+		pv := this.child(func() error {
+			if _, err := this.v.doConst(idx); nil != err {
+				return err
+			}
+			_, err := this.v.c.encode(code.OpIndex)
+			return err
+		})
+		if err := elem.Do(pv); nil != err {
+			return function.NewError(err)
+		}
+	}
+	if nil == p.Rest {
+		return nil
+	}
+	// value[n:]
+	start := object.NewInteger(int64(len(p.Elems)))
+	pv := this.child(func() error {
+		if _, err := this.v.doConst(start); nil != err {
+			return err
+		}
+		for _, op := range []code.Opcode{code.OpNull, code.OpNull, code.OpSlice} {
+			if _, err := this.v.c.encode(op); nil != err {
+				return err
+			}
+		}
+		return nil
+	})
+	if err := p.Rest.Do(pv); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+func (this *patternVisitor) DoHashPattern(p *ast.HashPattern) error {
+	if err := this.load(); nil != err {
+		return function.NewError(err)
+	}
+	if _, err := this.v.c.encode(code.OpMatchHash); nil != err {
+		return function.NewError(err)
+	}
+	if err := this.fail(); nil != err {
+		return function.NewError(err)
+	}
+	for i, k := range p.Keys {
+		key := k
+		// key in value
+		if err := key.Do(this.v); nil != err {
+			return function.NewError(err)
+		}
+		if err := this.load(); nil != err {
+			return function.NewError(err)
+		}
+		if _, err := this.v.c.encode(code.OpIn); nil != err {
+			return function.NewError(err)
+		}
+		if err := this.fail(); nil != err {
+			return function.NewError(err)
+		}
+		pv := this.child(func() error {
+			if err := key.Do(this.v); nil != err {
+				return err
+			}
+			_, err := this.v.c.encode(code.OpIndex)
+			return err
+		})
+		if err := p.Values[i].Do(pv); nil != err {
+			return function.NewError(err)
+		}
+	}
+	return nil
+}
+
+// jumpTable : literal arms without guard, and an optional `_` as the last arm
+type jumpTable struct {
+	keys       []object.Objects // keys of each literal arm
+	defaultArm *ast.MatchArm
+}
+
+func literalKey(p ast.Pattern) (object.Object, bool) {
+	lit, ok := p.(*ast.LiteralPattern)
+	if !ok {
+		return nil, false
+	}
+	v, err := lit.Object()
+	if nil != err {
+		return nil, false
+	}
+	if _, err := v.Hash(); nil != err {
+		return nil, false
+	}
+	return v, true
+}
+
+func literalKeys(p ast.Pattern) (object.Objects, bool) {
+	if alt, ok := p.(*ast.AltPattern); ok {
+		keys := object.Objects{}
+		for _, item := range alt.Alts {
+			k, ok := literalKey(item)
+			if !ok {
+				return nil, false
+			}
+			keys = append(keys, k)
+		}
+		return keys, true
+	}
+	k, ok := literalKey(p)
+	if !ok {
+		return nil, false
+	}
+	return object.Objects{k}, true
+}
+
+// newJumpTable : nil if the arms can not be dispatched by a table
+func newJumpTable(v *ast.MatchExpr) *jumpTable {
+	table := &jumpTable{keys: []object.Objects{}}
+	arms := v.Arms
+	last := arms[len(arms)-1]
+	if _, ok := last.Pattern.(*ast.WildcardPattern); ok && nil == last.Guard {
+		table.defaultArm = last
+		arms = arms[:len(arms)-1]
+	}
+	if len(arms) < 1 {
+		return nil
+	}
+	for _, arm := range arms {
+		if nil != arm.Guard {
+			return nil
+		}
+		keys, ok := literalKeys(arm.Pattern)
+		if !ok {
+			return nil
+		}
+		table.keys = append(table.keys, keys)
+	}
+	return table
+}
+
+// MatchExpr bytecode format, jump table
+//
+//	     value
+//	     OpJump-------------------|
+//	     body 1<--------------|   |
+//	|----OpJump               |   |
+//	|    body 2...<-----------|   |
+//	|    OpPop<---------------|   |  default, OpNoMatch if there is no `_`
+//	|    body _               |   |
+//	|----OpJump               |   |
+//	|    OpMatchTable---------|<--|  pop value & jump to the arm if found
+//	|--->...
+func (this *visitor) doMatchTable(v *ast.MatchExpr, table *jumpTable) error {
+	if err := v.Value.Do(this); nil != err {
+		return function.NewError(err)
+	}
+	posDispatch, err := this.c.encode(code.OpJump, unpatched)
+	if nil != err {
+		return function.NewError(err)
+	}
+	targets := object.NewOrderedHash()
+	ends := []int{}
+	doBody := func(body ast.Expression) error {
+		if err := body.Do(this.enclosed(optionEncodeNothing)); nil != err {
+			return err
+		}
+		pos, err := this.c.encode(code.OpJump, unpatched)
+		if nil != err {
+			return err
+		}
+		ends = append(ends, pos)
+		return nil
+	}
+	for i, keys := range table.keys {
+		pos := object.NewInteger(int64(this.c.pos()))
+		for _, k := range keys {
+			// the first arm wins
+			if _, ok := targets.Get(k); !ok {
+				if err := targets.Set(k, pos); nil != err {
+					return function.NewError(err)
+				}
+			}
+		}
+		if err := doBody(v.Arms[i].Body); nil != err {
+			return function.NewError(err)
+		}
+	}
+	posDefault := this.c.pos()
+	if nil != table.defaultArm {
+		if _, err := this.c.encode(code.OpPop); nil != err {
+			return function.NewError(err)
+		}
+		if err := doBody(table.defaultArm.Body); nil != err {
+			return function.NewError(err)
+		}
+	} else {
+		if _, err := this.c.encode(code.OpNoMatch); nil != err {
+			return function.NewError(err)
+		}
+	}
+	// back-patching
+	if err := this.c.changeOperand(posDispatch, this.c.pos()); nil != err {
+		return function.NewError(err)
+	}
+	idx := this.c.addConst(targets)
+	if _, err := this.c.encode(code.OpMatchTable, idx, posDefault); nil != err {
+		return function.NewError(err)
+	}
+	// back-patching
+	for _, pos := range ends {
+		if err := this.c.changeOperand(pos, this.c.pos()); nil != err {
+			return function.NewError(err)
+		}
+	}
+	return nil
+}
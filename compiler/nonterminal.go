@@ -17,6 +17,9 @@ func (this *visitor) DoProgram(v *ast.Program) error {
 }
 
 func (this *visitor) DoConst(v *ast.ConstStmt) error {
+	if nil != v.Pattern {
+		return this.doConstPattern(v)
+	}
 	return this.doBind(v.Name, v.Value)
 }
 
@@ -28,6 +31,9 @@ func (this *visitor) DoBlock(v *ast.BlockStmt) error {
 }
 
 func (this *visitor) DoExpr(v *ast.ExpressionStmt) error {
+	if optionEncodeReturn == this.optionExpr() {
+		return this.doTail(v.Expr)
+	}
 	if err := v.Expr.Do(this); nil != err {
 		return function.NewError(err)
 	}
@@ -39,11 +45,62 @@ func (this *visitor) DoExpr(v *ast.ExpressionStmt) error {
 		if _, err := this.c.encode(code.OpPop); nil != err {
 			return function.NewError(err)
 		}
-	case optionEncodeReturn:
-		if _, err := this.c.encode(code.OpReturn); nil != err {
+	}
+	return nil
+}
+
+// doTail : expression in tail position of a function body, every path ends with OpReturn,
+// a call is made by OpTailCall which reuses the frame of the caller
+func (this *visitor) doTail(expr ast.Expression) error {
+	switch v := expr.(type) {
+	case *ast.Call:
+		if err := this.doCallWith(v.Func, v.Args, code.OpTailCall, code.OpTailCallSpread); nil != err {
+			return function.NewError(err)
+		}
+	case *ast.CallMember:
+		if err := this.doCallMemberWith(v, code.OpTailCall, code.OpTailCallSpread); nil != err {
+			return function.NewError(err)
+		}
+	case *ast.ConditionalExpr:
+		return this.doTailConditional(v)
+	default:
+		if err := expr.Do(this); nil != err {
 			return function.NewError(err)
 		}
 	}
+	// a builtin called by OpTailCall leaves its result on the stack
+	if _, err := this.c.encode(code.OpReturn); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+// ConditionalExpr bytecode format, tail position
+//
+//	cond
+//	OpJumpWhenFalse--|
+//	Yes              |
+//	OpReturn         |
+//	No<--------------|
+//	OpReturn
+func (this *visitor) doTailConditional(v *ast.ConditionalExpr) error {
+	if err := v.Cond.Do(this); nil != err {
+		return function.NewError(err)
+	}
+	posJumpWhenFalse, err := this.c.encode(code.OpJumpWhenFalse, unpatched)
+	if nil != err {
+		return function.NewError(err)
+	}
+	if err := this.doTail(v.Yes); nil != err {
+		return function.NewError(err)
+	}
+	// back-patching
+	if err := this.c.changeOperand(posJumpWhenFalse, this.c.pos()); nil != err {
+		return function.NewError(err)
+	}
+	if err := this.doTail(v.No); nil != err {
+		return function.NewError(err)
+	}
 	return nil
 }
 
@@ -51,6 +108,36 @@ func (this *visitor) DoFunction(v *ast.FunctionStmt) error {
 	return this.doBind(v.Name, v.Value)
 }
 
+func (this *visitor) DoStruct(v *ast.StructStmt) error {
+	s := this.c.define(v.Name.Value)
+	if _, err := this.doConst(v.Object()); nil != err {
+		return function.NewError(err)
+	}
+	if _, err := this.doStoreSymbol(s); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+// MethodStmt bytecode format
+//
+//	type
+//	closure
+//	OpMethod name
+func (this *visitor) DoMethod(v *ast.MethodStmt) error {
+	if err := v.Type.Do(this); nil != err {
+		return function.NewError(err)
+	}
+	if err := v.Fn().Do(this); nil != err {
+		return function.NewError(err)
+	}
+	idx := this.c.addConst(object.NewString(v.Value.Name))
+	if _, err := this.c.encode(code.OpMethod, idx); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
 func (this *visitor) DoPrefix(v *ast.PrefixExpr) error {
 	if err := v.Right.Do(this); nil != err {
 		return function.NewError(err)
@@ -94,14 +181,14 @@ func (this *visitor) DoConditional(v *ast.ConditionalExpr) error {
 	if err := v.Cond.Do(this); nil != err {
 		return function.NewError(err)
 	}
-	posJumpWhenFalse, err := this.c.encode(code.OpJumpWhenFalse, -1)
+	posJumpWhenFalse, err := this.c.encode(code.OpJumpWhenFalse, unpatched)
 	if nil != err {
 		return function.NewError(err)
 	}
 	if err := v.Yes.Do(this.enclosed(optionEncodeNothing)); nil != err {
 		return function.NewError(err)
 	}
-	posJump, err := this.c.encode(code.OpJump, -1)
+	posJump, err := this.c.encode(code.OpJump, unpatched)
 	if nil != err {
 		return function.NewError(err)
 	}
@@ -119,6 +206,58 @@ func (this *visitor) DoConditional(v *ast.ConditionalExpr) error {
 	return nil
 }
 
+func (this *visitor) DoNullish(v *ast.NullishExpr) error {
+	if err := v.Left.Do(this); nil != err {
+		return function.NewError(err)
+	}
+	posJump, err := this.c.encode(code.OpJumpWhenNotNull, unpatched)
+	if nil != err {
+		return function.NewError(err)
+	}
+	if err := v.Right.Do(this.enclosed(optionEncodeNothing)); nil != err {
+		return function.NewError(err)
+	}
+	// back-patching
+	if err := this.c.changeOperand(posJump, this.c.pos()); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+func (this *visitor) DoIn(v *ast.InExpr) error {
+	if err := v.Left.Do(this); nil != err {
+		return function.NewError(err)
+	}
+	if err := v.Right.Do(this); nil != err {
+		return function.NewError(err)
+	}
+	op := code.OpIn
+	if v.Not {
+		op = code.OpNotIn
+	}
+	if _, err := this.c.encode(op); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+func (this *visitor) DoInterval(v *ast.IntervalExpr) error {
+	if err := v.Start.Do(this); nil != err {
+		return function.NewError(err)
+	}
+	if err := v.End.Do(this); nil != err {
+		return function.NewError(err)
+	}
+	exclusive := 0
+	if v.Exclusive {
+		exclusive = 1
+	}
+	if _, err := this.c.encode(code.OpInterval, exclusive); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
 func (this *visitor) DoFn(v *ast.Function) error {
 	this.c.enterScope()
 
@@ -127,8 +266,15 @@ func (this *visitor) DoFn(v *ast.Function) error {
 		this.c.defineLambda(v.Lambda)
 	}
 
+	args := []*Symbol{}
 	for _, a := range v.Args {
-		this.c.define(a.Value)
+		args = append(args, this.c.define(a.Value))
+	}
+	if nil != v.Rest {
+		this.c.define(v.Rest.Value)
+	}
+	if err := this.doArgs(v, args); nil != err {
+		return function.NewError(err)
 	}
 
 	if err := v.Body.Do(this.enclosed(optionEncodeReturn)); nil != err {
@@ -138,7 +284,11 @@ func (this *visitor) DoFn(v *ast.Function) error {
 	// after compiled a function’s body, capture the FreeSymbols before leave scope
 	freeSymbols := this.c.freeSymbols()
 	symbols := this.c.symbols()
-	r := this.c.leaveScope()
+	info := this.c.funcInfo(funcName(v))
+	r, err := this.c.leaveScope()
+	if nil != err {
+		return function.NewError(err)
+	}
 
 	// vm will put the free variables on to the stack
 	// waiting to be merged with an ByteFunc into an Closure.
@@ -148,8 +298,8 @@ func (this *visitor) DoFn(v *ast.Function) error {
 		}
 	}
 
-	fn := object.NewByteFunc(r.Instructions(), symbols)
-	idx := this.c.addConst(fn)
+	fn := object.NewByteFunc(r.Instructions(), v.Arity(), symbols)
+	idx := this.c.addFunc(fn, info)
 	// not OpConst here
 	if _, err := this.c.encode(code.OpClosure, idx, len(freeSymbols)); nil != err {
 		return function.NewError(err)
@@ -157,16 +307,102 @@ func (this *visitor) DoFn(v *ast.Function) error {
 	return nil
 }
 
+// funcName : name of the function in the disassembly
+func funcName(v *ast.Function) string {
+	if v.Lambda != "" {
+		return v.Lambda
+	}
+	if v.Name != "" {
+		return v.Name
+	}
+	return "<lambda>"
+}
+
+// doArgs : fill the missing args with their default values and destructure the args in order
+//
+//	OpArgMissing i
+//	OpJumpWhenFalse--|
+//	default          |
+//	OpSetLocal i     |
+//	...<-------------|
+func (this *visitor) doArgs(v *ast.Function, args []*Symbol) error {
+	for i, s := range args {
+		if i < len(v.Defaults) && nil != v.Defaults[i] {
+			if _, err := this.c.encode(code.OpArgMissing, i); nil != err {
+				return function.NewError(err)
+			}
+			pos, err := this.c.encode(code.OpJumpWhenFalse, unpatched)
+			if nil != err {
+				return function.NewError(err)
+			}
+			if err := v.Defaults[i].Do(this); nil != err {
+				return function.NewError(err)
+			}
+			if _, err := this.doStoreSymbol(s); nil != err {
+				return function.NewError(err)
+			}
+			// back-patching
+			if err := this.c.changeOperand(pos, this.c.pos()); nil != err {
+				return function.NewError(err)
+			}
+		}
+		if i >= len(v.Params) {
+			continue
+		}
+		if _, ok := v.Params[i].(*ast.BindPattern); ok {
+			continue
+		}
+		if err := this.doDestruct(v.Params[i], s); nil != err {
+			return function.NewError(err)
+		}
+	}
+	return nil
+}
+
 func (this *visitor) doCall(fn ast.Expression, args ast.ExpressionSlice) error {
+	return this.doCallWith(fn, args, code.OpCall, code.OpCallSpread)
+}
+
+func (this *visitor) doCallWith(fn ast.Expression, args ast.ExpressionSlice, opCall code.Opcode, opSpread code.Opcode) error {
 	if err := fn.Do(this); nil != err {
 		return function.NewError(err)
 	}
+	return this.doCallArgs(args, opCall, opSpread)
+}
+
+// doCallArgs : the callee is on the stack
+func (this *visitor) doCallArgs(args ast.ExpressionSlice, opCall code.Opcode, opSpread code.Opcode) error {
+	if ast.HasSpread(args) {
+		return this.doCallSpread(args, opSpread)
+	}
 	for _, a := range args {
 		if err := a.Do(this); nil != err {
 			return function.NewError(err)
 		}
 	}
-	if _, err := this.c.encode(code.OpCall, len(args)); nil != err {
+	if _, err := this.c.encode(opCall, len(args)); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+// doCallSpread : every arg is pushed as an array, a spread arg is pushed as is
+func (this *visitor) doCallSpread(args ast.ExpressionSlice, op code.Opcode) error {
+	for _, a := range args {
+		if spread, ok := a.(*ast.SpreadExpr); ok {
+			if err := spread.Value.Do(this); nil != err {
+				return function.NewError(err)
+			}
+			continue
+		}
+		if err := a.Do(this); nil != err {
+			return function.NewError(err)
+		}
+		if _, err := this.c.encode(code.OpArray, 1); nil != err {
+			return function.NewError(err)
+		}
+	}
+	if _, err := this.c.encode(op, len(args)); nil != err {
 		return function.NewError(err)
 	}
 	return nil
@@ -176,32 +412,188 @@ func (this *visitor) DoCall(v *ast.Call) error {
 	return this.doCall(v.Func, v.Args)
 }
 
+func (this *visitor) DoSpread(v *ast.SpreadExpr) error {
+	return function.NewError(ast.ErrSpread)
+}
+
 func (this *visitor) DoCallMember(v *ast.CallMember) error {
-	if err := v.Left.Do(this.enclosed(optionEncodeNothing)); nil != err {
+	return this.doCallMemberWith(v, code.OpCall, code.OpCallSpread)
+}
+
+// doCallMemberWith : opCall and opSpread call a method which is not builtin, e.g. OpTailCall in tail position
+func (this *visitor) doCallMemberWith(v *ast.CallMember, opCall code.Opcode, opSpread code.Opcode) error {
+	jumps, err := this.linkCallMember(v, opCall, opSpread)
+	if nil != err {
 		return function.NewError(err)
 	}
-	return this.doCall(v.Func, v.Args)
+	return this.doChainEnd(jumps)
 }
 
-func (this *visitor) DoObjectMember(v *ast.ObjectMember) error {
-	if err := v.Left.Do(this.enclosed(optionEncodeNothing)); nil != err {
+// doLink : compile a link of a member chain (a.b, a.f(), a[k]), the jumps of ?. on a null left are returned,
+// the outermost link patches them to its end, refer to ast.IsOptionalChain
+func (this *visitor) doLink(e ast.Expression) ([]int, error) {
+	switch v := e.(type) {
+	case *ast.ObjectMember:
+		return this.linkObjectMember(v)
+	case *ast.CallMember:
+		return this.linkCallMember(v, code.OpCall, code.OpCallSpread)
+	case *ast.IndexExpr:
+		return this.linkIndex(v)
+	}
+	return nil, e.Do(this)
+}
+
+// doLinkLeft : the left of a link, the null left of left?.x stays on the stack as the result of the chain
+func (this *visitor) doLinkLeft(left ast.Expression, optional bool) ([]int, error) {
+	jumps, err := this.enclosed(optionEncodeNothing).(*visitor).doLink(left)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	if !optional {
+		return jumps, nil
+	}
+	pos, err := this.c.encode(code.OpJumpWhenNull, unpatched)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	return append(jumps, pos), nil
+}
+
+// doChainEnd : back-patching
+func (this *visitor) doChainEnd(jumps []int) error {
+	for _, pos := range jumps {
+		if err := this.c.changeOperand(pos, this.c.pos()); nil != err {
+			return function.NewError(err)
+		}
+	}
+	return nil
+}
+
+func (this *visitor) linkCallMember(v *ast.CallMember, opCall code.Opcode, opSpread code.Opcode) ([]int, error) {
+	jumps, err := this.doLinkLeft(v.Left, v.Optional)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	if err := this.doCallMember(v, opCall, opSpread); nil != err {
+		return nil, function.NewError(err)
+	}
+	return jumps, nil
+}
+
+func (this *visitor) doCallMember(v *ast.CallMember, opCall code.Opcode, opSpread code.Opcode) error {
+	if ok, err := this.doCallObjectFn(v); ok || nil != err {
+		return err
+	}
+	if err := this.doMember(v.Func); nil != err {
 		return function.NewError(err)
 	}
-	if err := v.Member.Do(this); nil != err {
+	return this.doCallArgs(v.Args, opCall, opSpread)
+}
+
+// doCallObjectFn : a builtin method is called by OpCallObjectFn, which caches the method
+// per receiver type, false if the call is not made by it (e.g. spread args)
+func (this *visitor) doCallObjectFn(v *ast.CallMember) (bool, error) {
+	if !object.IsObjectFn(v.Func.Value) || ast.HasSpread(v.Args) {
+		return false, nil
+	}
+	s, err := this.c.resolve(v.Func.Value)
+	if nil != err || ScopeObjectFn != s.Scope {
+		return false, nil
+	}
+	// OpCallObjectFn is never widened
+	if s.Index > code.MaxOperand(1) || len(v.Args) > code.MaxOperand(1) {
+		return false, nil
+	}
+	slot, ok := this.c.addInlineCache()
+	if !ok {
+		return false, nil
+	}
+	for _, a := range v.Args {
+		if err := a.Do(this); nil != err {
+			return false, function.NewError(err)
+		}
+	}
+	if _, err := this.c.encode(code.OpCallObjectFn, s.Index, len(v.Args), slot); nil != err {
+		return false, function.NewError(err)
+	}
+	return true, nil
+}
+
+// doMember : builtin methods are resolved by index, fields and methods of struct by name
+func (this *visitor) doMember(name *ast.Identifier) error {
+	if object.IsObjectFn(name.Value) {
+		return name.Do(this)
+	}
+	idx := this.c.addConst(object.NewString(name.Value))
+	if _, err := this.c.encode(code.OpGetMember, idx); nil != err {
 		return function.NewError(err)
 	}
 	return nil
 }
 
-func (this *visitor) DoIndex(v *ast.IndexExpr) error {
-	if err := v.Left.Do(this); nil != err {
+func (this *visitor) DoObjectMember(v *ast.ObjectMember) error {
+	jumps, err := this.linkObjectMember(v)
+	if nil != err {
 		return function.NewError(err)
 	}
-	if err := v.Index.Do(this); nil != err {
+	return this.doChainEnd(jumps)
+}
+
+// linkObjectMember : left?.member resolves the member as left.member does
+func (this *visitor) linkObjectMember(v *ast.ObjectMember) ([]int, error) {
+	jumps, err := this.doLinkLeft(v.Left, v.Optional)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	if err := this.doMember(v.Member); nil != err {
+		return nil, function.NewError(err)
+	}
+	return jumps, nil
+}
+
+func (this *visitor) DoSlice(v *ast.SliceExpr) error {
+	if err := v.Left.Do(this); nil != err {
 		return function.NewError(err)
 	}
-	if _, err := this.c.encode(code.OpIndex); nil != err {
+	for _, bound := range v.Bounds() {
+		if nil == bound {
+			if _, err := this.c.encode(code.OpNull); nil != err {
+				return function.NewError(err)
+			}
+			continue
+		}
+		if err := bound.Do(this); nil != err {
+			return function.NewError(err)
+		}
+	}
+	if _, err := this.c.encode(code.OpSlice); nil != err {
 		return function.NewError(err)
 	}
 	return nil
 }
+
+func (this *visitor) DoIndex(v *ast.IndexExpr) error {
+	jumps, err := this.linkIndex(v)
+	if nil != err {
+		return function.NewError(err)
+	}
+	return this.doChainEnd(jumps)
+}
+
+func (this *visitor) linkIndex(v *ast.IndexExpr) ([]int, error) {
+	jumps, err := this.doLinkLeft(v.Left, v.Optional)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	if err := v.Index.Do(this); nil != err {
+		return nil, function.NewError(err)
+	}
+	op := code.OpIndex
+	if v.Optional {
+		op = code.OpIndexOptional
+	}
+	if _, err := this.c.encode(op); nil != err {
+		return nil, function.NewError(err)
+	}
+	return jumps, nil
+}
@@ -0,0 +1,295 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/jobs-github/escript/code"
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+)
+
+// Peephole : replace the hot sequences of the main instructions and every function in consts
+// with the fused opcodes, the function constants are rewritten in place.
+//
+//	OpConst i; OpAdd                  => OpAddConst i
+//	OpConst i; OpSub                  => OpSubConst i
+//	OpGetLocal a; OpGetLocal b        => OpGetLocal2 a b
+//	OpEq; OpJumpWhenFalse pos         => OpCmpJump OpEq pos (OpNeq, OpLt, OpGt, OpLeq, OpGeq as well)
+//	OpGetBuiltin i; args...; OpCall n => args...; OpCallBuiltin i n (OpTailCall as well)
+//
+// an instruction which is jumped to is never fused into the previous one
+func Peephole(b Bytecode, consts object.Objects) (Bytecode, error) {
+	for _, c := range consts {
+		if fn, ok := c.(*object.ByteFunc); ok {
+			ins, err := peephole(fn.Ins, consts)
+			if nil != err {
+				return nil, function.NewError(err)
+			}
+			fn.Ins = ins
+		}
+	}
+	ins, err := peephole(b.Instructions(), consts)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	return newBytecode(ins), nil
+}
+
+var cmpCodes = map[code.Opcode]bool{
+	code.OpEq:  true,
+	code.OpNeq: true,
+	code.OpLt:  true,
+	code.OpGt:  true,
+	code.OpLeq: true,
+	code.OpGeq: true,
+}
+
+// rewrite : an instruction of the output, drop removes the original one
+type rewrite struct {
+	op       code.Opcode
+	operands []int
+	from     int // position of the first original instruction
+	drop     bool
+	wide     bool
+}
+
+func peephole(ins code.Instructions, consts object.Objects) (code.Instructions, error) {
+	items, err := decode(ins)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	targets := jumpTargets(items, consts)
+	builtinCalls := matchBuiltinCalls(items, targets)
+
+	out := []*rewrite{}
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+		r := &rewrite{op: item.op, operands: item.operands, from: item.pos, wide: item.wide}
+		if argc, ok := builtinCalls[i]; ok {
+			if argc < 0 {
+				r.drop = true
+			} else {
+				r.op, r.operands = code.OpCallBuiltin, []int{items[argc].operands[0], item.operands[0]}
+			}
+			out = append(out, r)
+			continue
+		}
+		if i+1 < len(items) && !targets[items[i+1].pos] {
+			if _, ok := builtinCalls[i+1]; !ok {
+				if fused := fuse(item, items[i+1]); nil != fused {
+					fused.from = item.pos
+					out = append(out, fused)
+					i++
+					continue
+				}
+			}
+		}
+		out = append(out, r)
+	}
+	return relocate(ins, items, out, consts)
+}
+
+// fuse : nil if a and b can not be fused, the wide instructions are never fused
+func fuse(a *decoded, b *decoded) *rewrite {
+	if a.wide || b.wide {
+		return nil
+	}
+	switch {
+	case code.OpConst == a.op && code.OpAdd == b.op:
+		return &rewrite{op: code.OpAddConst, operands: a.operands}
+	case code.OpConst == a.op && code.OpSub == b.op:
+		return &rewrite{op: code.OpSubConst, operands: a.operands}
+	case code.OpGetLocal == a.op && code.OpGetLocal == b.op:
+		return &rewrite{op: code.OpGetLocal2, operands: []int{a.operands[0], b.operands[0]}}
+	case cmpCodes[a.op] && code.OpJumpWhenFalse == b.op:
+		return &rewrite{op: code.OpCmpJump, operands: []int{int(a.op), b.operands[0]}}
+	}
+	return nil
+}
+
+// jumpTargets : positions which are jumped to, refer to jumpOperand
+func jumpTargets(items []*decoded, consts object.Objects) map[int]bool {
+	r := map[int]bool{}
+	for _, item := range items {
+		if i := jumpOperand(item.op); i >= 0 {
+			r[item.operands[i]] = true
+		}
+		if code.OpMatchTable == item.op {
+			if table, ok := matchTable(item, consts); ok {
+				for _, pair := range table.Items() {
+					if pos, err := object.ToInteger(pair.Value); nil == err {
+						r[int(pos)] = true
+					}
+				}
+			}
+		}
+	}
+	return r
+}
+
+// jumpOperand : index of the operand which is a position, -1 if there is none
+func jumpOperand(op code.Opcode) int {
+	switch op {
+	case code.OpJump, code.OpJumpWhenFalse, code.OpJumpWhenNull, code.OpJumpWhenNotNull, code.OpTry:
+		return 0
+	case code.OpMatchTable, code.OpCmpJump:
+		return 1
+	}
+	return -1
+}
+
+func matchTable(item *decoded, consts object.Objects) (*object.Hash, bool) {
+	idx := item.operands[0]
+	if idx >= len(consts) {
+		return nil, false
+	}
+	table, ok := consts[idx].(*object.Hash)
+	return table, ok
+}
+
+// stackEffect : change of the stack size by the instruction, ok is false for jumps
+// and the instructions which are not expected between a builtin and its call
+func stackEffect(item *decoded) (int, bool) {
+	switch item.op {
+	case code.OpConst, code.OpGetLocal, code.OpGetGlobal, code.OpGetFree, code.OpGetLambda,
+		code.OpTrue, code.OpFalse, code.OpNull, code.OpSymbol, code.OpGetBuiltin:
+		return 1, true
+	case code.OpNot, code.OpNeg, code.OpBitNot, code.OpGetMember, code.OpGetObjectFn:
+		return 0, true
+	case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod,
+		code.OpLt, code.OpGt, code.OpEq, code.OpNeq, code.OpLeq, code.OpGeq,
+		code.OpAnd, code.OpOr,
+		code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShl, code.OpShr, code.OpPow,
+		code.OpIndex, code.OpIn, code.OpNotIn:
+		return -1, true
+	case code.OpArray, code.OpConcat:
+		return 1 - item.operands[0], true
+	case code.OpHash:
+		return 1 - 2*item.operands[0], true
+	case code.OpCall:
+		return -item.operands[0], true
+	case code.OpCallObjectFn:
+		return -item.operands[1], true
+	case code.OpClosure:
+		return 1 - item.operands[1], true
+	}
+	return 0, false
+}
+
+// matchBuiltinCalls : OpGetBuiltin => -1 (dropped), its OpCall or OpTailCall => index of the OpGetBuiltin,
+// the args between them must be straight-line code
+func matchBuiltinCalls(items []*decoded, targets map[int]bool) map[int]int {
+	r := map[int]int{}
+	for i, item := range items {
+		if code.OpGetBuiltin != item.op || item.wide {
+			continue
+		}
+		depth := 0
+		for j := i + 1; j < len(items); j++ {
+			next := items[j]
+			if targets[next.pos] {
+				break
+			}
+			if (code.OpCall == next.op || code.OpTailCall == next.op) && !next.wide && next.operands[0] == depth {
+				r[i] = -1
+				r[j] = i
+				break
+			}
+			effect, ok := stackEffect(next)
+			if !ok {
+				break
+			}
+			if depth += effect; depth < 0 {
+				break
+			}
+		}
+	}
+	return r
+}
+
+func lookup(r *rewrite) (*code.Definition, error) {
+	if r.wide {
+		return code.LookupWide(r.op)
+	}
+	return code.Lookup(r.op)
+}
+
+// relocate : encode out and move the jumps to the new positions
+func relocate(ins code.Instructions, items []*decoded, out []*rewrite, consts object.Objects) (code.Instructions, error) {
+	positions := map[int]int{}
+	pos := 0
+	for _, r := range out {
+		positions[r.from] = pos
+		if r.drop {
+			continue
+		}
+		def, err := lookup(r)
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		pos++
+		if r.wide {
+			pos++
+		}
+		for _, w := range def.OperandWidths {
+			pos += w
+		}
+	}
+	positions[len(ins)] = pos
+	moved := func(old int) (int, error) {
+		if v, ok := positions[old]; ok {
+			return v, nil
+		}
+		return -1, fmt.Errorf("jump to %04d which is fused", old)
+	}
+
+	r := code.Instructions{}
+	for _, w := range out {
+		if w.drop {
+			continue
+		}
+		operands := append([]int{}, w.operands...)
+		if i := jumpOperand(w.op); i >= 0 {
+			v, err := moved(operands[i])
+			if nil != err {
+				return nil, function.NewError(err)
+			}
+			operands[i] = v
+		}
+		encode := code.Make
+		if w.wide {
+			encode = code.MakeWide
+		}
+		b, err := encode(w.op, operands...)
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		r = append(r, b...)
+	}
+	// the positions of match tables are moved as well
+	for _, item := range items {
+		if code.OpMatchTable != item.op {
+			continue
+		}
+		table, ok := matchTable(item, consts)
+		if !ok {
+			continue
+		}
+		h := object.NewOrderedHash()
+		for _, pair := range table.Items() {
+			old, err := object.ToInteger(pair.Value)
+			if nil != err {
+				return nil, function.NewError(err)
+			}
+			v, err := moved(int(old))
+			if nil != err {
+				return nil, function.NewError(err)
+			}
+			if err := h.Set(pair.Key, object.NewInteger(int64(v))); nil != err {
+				return nil, function.NewError(err)
+			}
+		}
+		consts[item.operands[0]] = h
+	}
+	return r, nil
+}
@@ -40,7 +40,7 @@ func (this *visitor) DoRange(v *ast.RangeExpr) error {
 		cnt:          cnt,
 		res:          res,
 	}
-	if err := this.doLoop(l, v.Body, i, cnt); nil != err {
+	if err := this.doLoop("<range>", l, v.Body, i, cnt); nil != err {
 		return function.NewError(err)
 	}
 	// push 0
@@ -42,7 +42,7 @@ func (this *visitor) DoReduce(v *ast.ReduceExpr) error {
 		arr:          arr,
 		res:          res,
 	}
-	if err := this.doLoop(l, v.Body, i, arr); nil != err {
+	if err := this.doLoop("<reduce>", l, v.Body, i, arr); nil != err {
 		return function.NewError(err)
 	}
 	// push 0
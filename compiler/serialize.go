@@ -0,0 +1,354 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/big"
+
+	"github.com/jobs-github/escript/code"
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+)
+
+const (
+	SuffixBytecode = ".esc"
+
+	// BytecodeVersion : bump it whenever the opcodes or the layout below change
+	BytecodeVersion uint16 = 5
+)
+
+// layout of the precompiled code, integers are big endian:
+//
+//	magic[4] version[2] checksum[4] body
+//	body  : instructions consts
+//	consts: count (tag value)...
+//
+// the checksum is the crc32 (IEEE) of body,
+// lengths and counts are uvarint, integers are varint
+var bytecodeMagic = []byte{0x1b, 'E', 'S', 'C'}
+
+const headerSize = 10
+
+// tags of the constants
+const (
+	constNull byte = iota
+	constBoolean
+	constInteger
+	constBigInt
+	constString
+	constByteFunc
+	constStructType
+	constHash
+)
+
+// Marshal : encode the instructions of b and the constant pool
+func Marshal(b Bytecode, consts object.Objects) ([]byte, error) {
+	w := &bytecodeWriter{}
+	w.writeBytes(b.Instructions())
+	w.writeUvarint(uint64(len(consts)))
+	for _, c := range consts {
+		if err := w.writeConst(c); nil != err {
+			return nil, function.NewError(err)
+		}
+	}
+	body := w.buf.Bytes()
+	header := make([]byte, headerSize)
+	copy(header, bytecodeMagic)
+	binary.BigEndian.PutUint16(header[4:], BytecodeVersion)
+	binary.BigEndian.PutUint32(header[6:], crc32.ChecksumIEEE(body))
+	return append(header, body...), nil
+}
+
+// Unmarshal : the inverse of Marshal, the data is validated by the magic, version and checksum,
+// and the instructions by verify
+func Unmarshal(data []byte) (Bytecode, object.Objects, error) {
+	if len(data) < headerSize || !bytes.Equal(data[:4], bytecodeMagic) {
+		return nil, nil, function.NewError(fmt.Errorf("invalid bytecode, bad magic"))
+	}
+	if v := binary.BigEndian.Uint16(data[4:]); v != BytecodeVersion {
+		return nil, nil, function.NewError(fmt.Errorf("unsupported bytecode version %v, expect %v", v, BytecodeVersion))
+	}
+	body := data[headerSize:]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(data[6:]) {
+		return nil, nil, function.NewError(fmt.Errorf("invalid bytecode, checksum mismatch"))
+	}
+	r := &bytecodeReader{r: bytes.NewReader(body)}
+	ins, err := r.readBytes()
+	if nil != err {
+		return nil, nil, function.NewError(err)
+	}
+	n, err := r.readUvarint()
+	if nil != err {
+		return nil, nil, function.NewError(err)
+	}
+	consts := object.Objects{}
+	for i := uint64(0); i < n; i++ {
+		c, err := r.readConst()
+		if nil != err {
+			return nil, nil, function.NewError(err)
+		}
+		consts = append(consts, c)
+	}
+	if r.r.Len() > 0 {
+		return nil, nil, function.NewError(fmt.Errorf("invalid bytecode, %v trailing bytes", r.r.Len()))
+	}
+	if err := verify(ins, consts); nil != err {
+		return nil, nil, function.NewError(err)
+	}
+	return newBytecode(ins), consts, nil
+}
+
+// bytecodeWriter : body of the precompiled code
+type bytecodeWriter struct {
+	buf bytes.Buffer
+}
+
+func (this *bytecodeWriter) writeUvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	this.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (this *bytecodeWriter) writeVarint(v int64) {
+	var b [binary.MaxVarintLen64]byte
+	this.buf.Write(b[:binary.PutVarint(b[:], v)])
+}
+
+func (this *bytecodeWriter) writeBytes(b []byte) {
+	this.writeUvarint(uint64(len(b)))
+	this.buf.Write(b)
+}
+
+func (this *bytecodeWriter) writeString(s string) {
+	this.writeBytes([]byte(s))
+}
+
+func (this *bytecodeWriter) writeBool(v bool) {
+	if v {
+		this.buf.WriteByte(1)
+	} else {
+		this.buf.WriteByte(0)
+	}
+}
+
+func (this *bytecodeWriter) writeConst(obj object.Object) error {
+	switch v := obj.(type) {
+	case *object.Null:
+		this.buf.WriteByte(constNull)
+	case *object.Boolean:
+		this.buf.WriteByte(constBoolean)
+		this.writeBool(v.Value)
+	case *object.Integer:
+		this.buf.WriteByte(constInteger)
+		this.writeVarint(v.Value)
+	case *object.BigInt:
+		this.buf.WriteByte(constBigInt)
+		this.writeString(v.Value.String())
+	case *object.String:
+		this.buf.WriteByte(constString)
+		this.writeString(v.Value)
+	case *object.ByteFunc:
+		this.buf.WriteByte(constByteFunc)
+		this.writeBytes(v.Ins)
+		this.writeUvarint(uint64(v.Args))
+		this.writeUvarint(uint64(v.Required))
+		this.writeBool(v.Rest)
+		this.writeUvarint(uint64(v.Locals))
+	case *object.StructType:
+		// methods are defined when the code runs
+		this.buf.WriteByte(constStructType)
+		this.writeString(v.Name)
+		this.writeUvarint(uint64(len(v.Fields)))
+		for _, field := range v.Fields {
+			this.writeString(field)
+		}
+	case *object.Hash:
+		items := v.Items()
+		this.buf.WriteByte(constHash)
+		this.writeUvarint(uint64(len(items)))
+		for _, pair := range items {
+			if err := this.writeConst(pair.Key); nil != err {
+				return function.NewError(err)
+			}
+			if err := this.writeConst(pair.Value); nil != err {
+				return function.NewError(err)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported constant %v (%v)", obj.String(), object.Typeof(obj))
+	}
+	return nil
+}
+
+// bytecodeReader : the inverse of bytecodeWriter
+type bytecodeReader struct {
+	r *bytes.Reader
+}
+
+func (this *bytecodeReader) readUvarint() (uint64, error) {
+	v, err := binary.ReadUvarint(this.r)
+	if nil != err {
+		return 0, function.NewError(err)
+	}
+	return v, nil
+}
+
+func (this *bytecodeReader) readVarint() (int64, error) {
+	v, err := binary.ReadVarint(this.r)
+	if nil != err {
+		return 0, function.NewError(err)
+	}
+	return v, nil
+}
+
+func (this *bytecodeReader) readInt() (int, error) {
+	v, err := this.readUvarint()
+	if nil != err {
+		return 0, function.NewError(err)
+	}
+	return int(v), nil
+}
+
+func (this *bytecodeReader) readBytes() ([]byte, error) {
+	n, err := this.readUvarint()
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	if n > uint64(this.r.Len()) {
+		return nil, function.NewError(io.ErrUnexpectedEOF)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(this.r, b); nil != err {
+		return nil, function.NewError(err)
+	}
+	return b, nil
+}
+
+func (this *bytecodeReader) readString() (string, error) {
+	b, err := this.readBytes()
+	if nil != err {
+		return "", function.NewError(err)
+	}
+	return string(b), nil
+}
+
+func (this *bytecodeReader) readBool() (bool, error) {
+	b, err := this.r.ReadByte()
+	if nil != err {
+		return false, function.NewError(err)
+	}
+	return 0 != b, nil
+}
+
+func (this *bytecodeReader) readConst() (object.Object, error) {
+	tag, err := this.r.ReadByte()
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	switch tag {
+	case constNull:
+		return object.Nil, nil
+	case constBoolean:
+		v, err := this.readBool()
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		return object.ToBoolean(v), nil
+	case constInteger:
+		v, err := this.readVarint()
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		return object.NewInteger(v), nil
+	case constBigInt:
+		s, err := this.readString()
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		v, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, function.NewError(fmt.Errorf("invalid big integer `%v`", s))
+		}
+		return object.NewBigInt(v), nil
+	case constString:
+		s, err := this.readString()
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		return object.NewString(s), nil
+	case constByteFunc:
+		return this.readByteFunc()
+	case constStructType:
+		return this.readStructType()
+	case constHash:
+		return this.readHash()
+	default:
+		return nil, function.NewError(fmt.Errorf("unknown constant tag %v", tag))
+	}
+}
+
+func (this *bytecodeReader) readByteFunc() (object.Object, error) {
+	ins, err := this.readBytes()
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	arity := object.Arity{}
+	if arity.Args, err = this.readInt(); nil != err {
+		return nil, function.NewError(err)
+	}
+	if arity.Required, err = this.readInt(); nil != err {
+		return nil, function.NewError(err)
+	}
+	if arity.Rest, err = this.readBool(); nil != err {
+		return nil, function.NewError(err)
+	}
+	locals, err := this.readInt()
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	return object.NewByteFunc(code.Instructions(ins), arity, locals), nil
+}
+
+func (this *bytecodeReader) readStructType() (object.Object, error) {
+	name, err := this.readString()
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	n, err := this.readUvarint()
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	fields := []string{}
+	for i := uint64(0); i < n; i++ {
+		field, err := this.readString()
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		fields = append(fields, field)
+	}
+	return object.NewStructType(name, fields), nil
+}
+
+func (this *bytecodeReader) readHash() (object.Object, error) {
+	n, err := this.readUvarint()
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	h := object.NewOrderedHash()
+	for i := uint64(0); i < n; i++ {
+		k, err := this.readConst()
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		v, err := this.readConst()
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		if err := h.Set(k, v); nil != err {
+			return nil, function.NewError(err)
+		}
+	}
+	return h, nil
+}
@@ -53,6 +53,7 @@ func NewSymbolTable(parent SymbolTable) SymbolTable {
 		m:      map[string]*Symbol{},
 		sz:     0,
 		frees:  Symbols{},
+		slots:  []string{},
 	}
 	// TODO: conflict between builtin & object
 	builtin.Traverse(func(i int, name string) {
@@ -73,8 +74,11 @@ type SymbolTable interface {
 	defineObjectFn(index int, name string) *Symbol
 	resolve(key string) (*Symbol, error)
 	freeSymbols() Symbols
+	names() []string
 	defineFree(orginal *Symbol) *Symbol
 	defineLambda(name string) *Symbol
+	snapshot() map[string]*Symbol
+	restore(saved map[string]*Symbol)
 }
 
 // symbolTable : implement SymbolTable
@@ -83,6 +87,7 @@ type symbolTable struct {
 	m      map[string]*Symbol
 	sz     int
 	frees  Symbols
+	slots  []string // names by index, a slot keeps its first name
 }
 
 func (this *symbolTable) newEnclosed() SymbolTable {
@@ -106,6 +111,7 @@ func (this *symbolTable) define(key string) *Symbol {
 	}
 	this.m[key] = s
 	this.sz++
+	this.slots = append(this.slots, key)
 	return s
 }
 
@@ -148,6 +154,11 @@ func (this *symbolTable) freeSymbols() Symbols {
 	return this.frees
 }
 
+// names : names of the globals or locals by index
+func (this *symbolTable) names() []string {
+	return this.slots
+}
+
 func (this *symbolTable) defineFree(orginal *Symbol) *Symbol {
 	this.frees = append(this.frees, orginal)
 	symbol := newSymbol(orginal.Name, ScopeFree, len(this.frees)-1)
@@ -160,3 +171,25 @@ func (this *symbolTable) defineLambda(name string) *Symbol {
 	this.m[name] = s
 	return s
 }
+
+func (this *symbolTable) snapshot() map[string]*Symbol {
+	saved := make(map[string]*Symbol, len(this.m))
+	for k, v := range this.m {
+		saved[k] = v
+	}
+	return saved
+}
+
+// restore : names defined after the snapshot go out of scope, their slots stay reserved
+func (this *symbolTable) restore(saved map[string]*Symbol) {
+	for k, v := range this.m {
+		if v.Scope != ScopeGlobal && v.Scope != ScopeLocal {
+			continue
+		}
+		if prev, ok := saved[k]; ok {
+			this.m[k] = prev
+		} else {
+			delete(this.m, k)
+		}
+	}
+}
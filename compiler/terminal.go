@@ -36,7 +36,7 @@ func (this *visitor) DoNull(v *ast.Null) error {
 }
 
 func (this *visitor) DoInteger(v *ast.Integer) error {
-	_, err := this.doConst(object.NewInteger(v.Value))
+	_, err := this.doConst(v.Object())
 	return err
 }
 
@@ -52,6 +52,18 @@ func (this *visitor) DoString(v *ast.String) error {
 	return err
 }
 
+func (this *visitor) DoTemplate(v *ast.TemplateExpr) error {
+	for _, e := range v.Parts {
+		if err := e.Do(this); nil != err {
+			return function.NewError(err)
+		}
+	}
+	if _, err := this.c.encode(code.OpConcat, len(v.Parts)); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
 func (this *visitor) DoArray(v *ast.Array) error {
 	// pattern: compile data first, op last
 	for _, e := range v.Items {
@@ -66,7 +78,7 @@ func (this *visitor) DoArray(v *ast.Array) error {
 }
 
 func (this *visitor) DoHash(v *ast.Hash) error {
-	keys := v.Pairs.SortedKeys()
+	keys := v.OrderedKeys()
 	for _, k := range keys {
 		if err := k.Do(this); nil != err {
 			return function.NewError(err)
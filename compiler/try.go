@@ -0,0 +1,53 @@
+package compiler
+
+import (
+	"github.com/jobs-github/escript/ast"
+	"github.com/jobs-github/escript/code"
+	"github.com/jobs-github/escript/function"
+)
+
+// TryExpr bytecode format
+//
+//	     OpTry----------------|
+//	     Try                  |
+//	     OpEndTry             |
+//	|----OpJump               |
+//	|    Catch (closure)<-----|
+//	|    OpCatch
+//	|    OpCall 1
+//	|--->...
+func (this *visitor) DoTry(v *ast.TryExpr) error {
+	posTry, err := this.c.encode(code.OpTry, unpatched)
+	if nil != err {
+		return function.NewError(err)
+	}
+	if err := v.Try.Do(this.enclosed(optionEncodeNothing)); nil != err {
+		return function.NewError(err)
+	}
+	if _, err := this.c.encode(code.OpEndTry); nil != err {
+		return function.NewError(err)
+	}
+	posJump, err := this.c.encode(code.OpJump, unpatched)
+	if nil != err {
+		return function.NewError(err)
+	}
+	// back-patching
+	if err := this.c.changeOperand(posTry, this.c.pos()); nil != err {
+		return function.NewError(err)
+	}
+	// catch block is called as a function with the error as its argument
+	if err := v.CatchFn().Do(this); nil != err {
+		return function.NewError(err)
+	}
+	if _, err := this.c.encode(code.OpCatch); nil != err {
+		return function.NewError(err)
+	}
+	if _, err := this.c.encode(code.OpCall, 1); nil != err {
+		return function.NewError(err)
+	}
+	// back-patching
+	if err := this.c.changeOperand(posJump, this.c.pos()); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/jobs-github/escript/builtin"
+	"github.com/jobs-github/escript/code"
+	"github.com/jobs-github/escript/object"
+)
+
+// constOperands : the instructions whose first operand is an index of the constants,
+// with the check of the type the vm asserts, nil if any type is fine
+var constOperands = map[code.Opcode]func(obj object.Object) bool{
+	code.OpConst:        nil,
+	code.OpSymbol:       nil,
+	code.OpGetMember:    nil,
+	code.OpMethod:       nil,
+	code.OpAddConst:     nil,
+	code.OpSubConst:     nil,
+	code.OpClosure:      func(obj object.Object) bool { _, ok := obj.(*object.ByteFunc); return ok },
+	code.OpMatchTable:   func(obj object.Object) bool { _, ok := obj.(*object.Hash); return ok },
+	code.OpDestructFail: func(obj object.Object) bool { _, ok := obj.(*object.String); return ok },
+}
+
+// unit : instructions of main or of a function constant
+type unit struct {
+	name   string
+	ins    code.Instructions
+	items  []*decoded
+	locals int
+	frees  int // -1 if no instruction closes the function
+	main   bool
+}
+
+// flow : the values on the stack above the locals and the try blocks entered, before an instruction
+type flow struct {
+	depth    int
+	handlers int
+}
+
+// verify : the checksum only guards against corruption, so the instructions of main and every function
+// are decoded, every index and position is checked and the stack is traced before the vm trusts them
+func verify(main code.Instructions, consts object.Objects) error {
+	units := []*unit{{name: "main", ins: main, main: true}}
+	funcs := map[int]*unit{}
+	for i, c := range consts {
+		fn, ok := c.(*object.ByteFunc)
+		if !ok {
+			continue
+		}
+		if fn.Required > fn.Args || fn.Args > fn.Locals || (fn.Rest && fn.Args >= fn.Locals) {
+			return fmt.Errorf("invalid bytecode, function %v: args %v, required %v, rest %v, locals %v",
+				i, fn.Args, fn.Required, fn.Rest, fn.Locals)
+		}
+		u := &unit{name: fmt.Sprintf("function %v", i), ins: fn.Ins, locals: fn.Locals, frees: -1}
+		units = append(units, u)
+		funcs[i] = u
+	}
+	for _, u := range units {
+		items, err := decode(u.ins)
+		if nil != err {
+			return fmt.Errorf("invalid bytecode, %v: %v", u.name, err)
+		}
+		u.items = items
+	}
+	// the free variables of a function are pushed by the instructions which close it
+	for _, u := range units {
+		for _, item := range u.items {
+			if code.OpClosure != item.op {
+				continue
+			}
+			fn, ok := funcs[item.operands[0]]
+			if !ok {
+				continue
+			}
+			if fn.frees >= 0 && fn.frees != item.operands[1] {
+				return fmt.Errorf("invalid bytecode, %v is closed with %v and %v free variables", fn.name, fn.frees, item.operands[1])
+			}
+			fn.frees = item.operands[1]
+		}
+	}
+	for _, u := range units {
+		if err := u.verifyOperands(consts); nil != err {
+			return fmt.Errorf("invalid bytecode, %v: %v", u.name, err)
+		}
+		if err := u.verifyStack(consts); nil != err {
+			return fmt.Errorf("invalid bytecode, %v: %v", u.name, err)
+		}
+	}
+	return nil
+}
+
+func (this *unit) verifyOperands(consts object.Objects) error {
+	// a jump to the end finishes the instructions
+	boundaries := map[int]bool{len(this.ins): true}
+	for _, item := range this.items {
+		boundaries[item.pos] = true
+	}
+	builtins, objectFns := 0, 0
+	builtin.Traverse(func(i int, name string) { builtins++ })
+	object.Traverse(func(i int, name string) { objectFns++ })
+	check := func(item *decoded, idx int, limit int, what string) error {
+		if idx >= limit {
+			return fmt.Errorf("%04d %v refers to %v %v of %v", item.pos, item.def.Name, what, idx, limit)
+		}
+		return nil
+	}
+	for _, item := range this.items {
+		if item.wide && fused(item.op) {
+			return fmt.Errorf("%04d %v with wide operands", item.pos, item.def.Name)
+		}
+		var err error
+		switch item.op {
+		case code.OpWide:
+			err = fmt.Errorf("%04d %v without an instruction", item.pos, item.def.Name)
+
+		case code.OpReturn, code.OpTailCall, code.OpTailCallSpread, code.OpArgMissing:
+			if this.main {
+				err = fmt.Errorf("%04d %v out of a function", item.pos, item.def.Name)
+			}
+		case code.OpGetGlobal, code.OpSetGlobal:
+			err = check(item, item.operands[0], MaxGlobals, "global")
+		case code.OpGetLocal, code.OpSetLocal, code.OpIncLocal, code.OpArrayAppend, code.OpArraySet:
+			err = check(item, item.operands[0], this.locals, "local")
+		case code.OpGetLocal2:
+			if err = check(item, item.operands[0], this.locals, "local"); nil == err {
+				err = check(item, item.operands[1], this.locals, "local")
+			}
+		case code.OpGetFree:
+			err = check(item, item.operands[0], this.frees, "free variable")
+		case code.OpGetBuiltin, code.OpCallBuiltin:
+			err = check(item, item.operands[0], builtins, "builtin")
+		case code.OpGetObjectFn:
+			err = check(item, item.operands[0], objectFns, "object function")
+		case code.OpCallObjectFn:
+			if err = check(item, item.operands[0], objectFns, "object function"); nil == err {
+				err = check(item, item.operands[2], MaxInlineCaches, "inline cache")
+			}
+		}
+		if nil != err {
+			return err
+		}
+		if typed, ok := constOperands[item.op]; ok {
+			idx := item.operands[0]
+			if err := check(item, idx, len(consts), "constant"); nil != err {
+				return err
+			}
+			if nil != typed && !typed(consts[idx]) {
+				return fmt.Errorf("%04d %v refers to constant %v of type %v", item.pos, item.def.Name, idx, object.Typeof(consts[idx]))
+			}
+		}
+		positions := []int{}
+		if i := jumpOperand(item.op); i >= 0 {
+			positions = append(positions, item.operands[i])
+		}
+		if code.OpMatchTable == item.op {
+			table, _ := matchTable(item, consts)
+			for _, pair := range table.Items() {
+				pos, err := object.ToInteger(pair.Value)
+				if nil != err {
+					return fmt.Errorf("%04d %v %v", item.pos, item.def.Name, err)
+				}
+				positions = append(positions, int(pos))
+			}
+		}
+		for _, pos := range positions {
+			if pos < 0 || !boundaries[pos] {
+				return fmt.Errorf("%04d %v jumps to %v which is not an instruction", item.pos, item.def.Name, pos)
+			}
+		}
+	}
+	return nil
+}
+
+// verifyStack : follow every path of the instructions, which must never pop more values than pushed
+// or leave a try block not entered, and must agree on the stack where they meet
+func (this *unit) verifyStack(consts object.Objects) error {
+	at := map[int]int{}
+	for i, item := range this.items {
+		at[item.pos] = i
+	}
+	type edge struct {
+		pos int
+		f   flow
+	}
+	states := map[int]flow{0: {}}
+	pending := []int{0}
+	for len(pending) > 0 {
+		pos := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		i, ok := at[pos]
+		if !ok {
+			continue
+		}
+		item := this.items[i]
+		in := states[pos]
+		pop, push := stackUsage(item)
+		if in.depth < pop {
+			return fmt.Errorf("%04d %v pops %v of %v values", item.pos, item.def.Name, pop, in.depth)
+		}
+		out := flow{depth: in.depth - pop + push, handlers: in.handlers}
+		next := len(this.ins)
+		if i+1 < len(this.items) {
+			next = this.items[i+1].pos
+		}
+		edges := []edge{}
+		switch item.op {
+		case code.OpReturn, code.OpTailCall, code.OpTailCallSpread, code.OpNoMatch, code.OpDestructFail:
+		case code.OpJump:
+			edges = append(edges, edge{item.operands[0], out})
+		case code.OpJumpWhenFalse, code.OpJumpWhenNull, code.OpCmpJump:
+			edges = append(edges, edge{next, out}, edge{item.operands[jumpOperand(item.op)], out})
+		case code.OpJumpWhenNotNull:
+			// the value is kept when it jumps
+			edges = append(edges, edge{item.operands[0], out}, edge{next, flow{out.depth - 1, out.handlers}})
+		case code.OpMatchTable:
+			// the value is kept for the default arm
+			edges = append(edges, edge{item.operands[1], out})
+			table, _ := matchTable(item, consts)
+			for _, pair := range table.Items() {
+				target, _ := object.ToInteger(pair.Value)
+				edges = append(edges, edge{int(target), flow{out.depth - 1, out.handlers}})
+			}
+		case code.OpTry:
+			// the stack is restored when it is caught
+			edges = append(edges, edge{next, flow{out.depth, out.handlers + 1}}, edge{item.operands[0], in})
+		case code.OpEndTry:
+			if in.handlers < 1 {
+				return fmt.Errorf("%04d %v out of a try block", item.pos, item.def.Name)
+			}
+			edges = append(edges, edge{next, flow{out.depth, out.handlers - 1}})
+		default:
+			edges = append(edges, edge{next, out})
+		}
+		for _, e := range edges {
+			if e.pos >= len(this.ins) {
+				continue
+			}
+			if f, ok := states[e.pos]; ok {
+				if f != e.f {
+					return fmt.Errorf("%04d reached with %v and %v values on the stack, in %v and %v try blocks",
+						e.pos, f.depth, e.f.depth, f.handlers, e.f.handlers)
+				}
+				continue
+			}
+			states[e.pos] = e.f
+			pending = append(pending, e.pos)
+		}
+	}
+	return nil
+}
+
+// stackUsage : the values an instruction pops and pushes, refer to vm.exec,
+// a call pushes the result when the callee returns
+func stackUsage(item *decoded) (int, int) {
+	switch item.op {
+	case code.OpConst, code.OpGetLocal, code.OpGetGlobal, code.OpGetFree, code.OpGetLambda,
+		code.OpTrue, code.OpFalse, code.OpNull, code.OpSymbol, code.OpGetBuiltin, code.OpArgMissing, code.OpCatch:
+		return 0, 1
+	case code.OpGetLocal2:
+		return 0, 2
+	case code.OpNot, code.OpNeg, code.OpBitNot, code.OpGetMember, code.OpGetObjectFn,
+		code.OpAddConst, code.OpSubConst, code.OpArrayLen, code.OpMatchArray, code.OpMatchHash,
+		code.OpJumpWhenNull, code.OpJumpWhenNotNull, code.OpMatchTable:
+		return 1, 1
+	case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod,
+		code.OpLt, code.OpGt, code.OpEq, code.OpNeq, code.OpLeq, code.OpGeq,
+		code.OpAnd, code.OpOr,
+		code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShl, code.OpShr, code.OpPow,
+		code.OpIndex, code.OpIndexOptional, code.OpIn, code.OpNotIn, code.OpInterval, code.OpMatchValue:
+		return 2, 1
+	case code.OpSlice:
+		return 4, 1
+	case code.OpArrayNew, code.OpArrayReserve:
+		return 1, 2
+	case code.OpArrayAppend, code.OpArraySet, code.OpMethod, code.OpCmpJump:
+		return 2, 0
+	case code.OpSetLocal, code.OpSetGlobal, code.OpPop, code.OpJumpWhenFalse,
+		code.OpReturn, code.OpNoMatch, code.OpDestructFail:
+		return 1, 0
+	case code.OpArray, code.OpConcat:
+		return item.operands[0], 1
+	case code.OpHash:
+		return 2 * item.operands[0], 1
+	case code.OpCall, code.OpCallSpread:
+		return item.operands[0] + 1, 1
+	case code.OpTailCall, code.OpTailCallSpread:
+		return item.operands[0] + 1, 0
+	case code.OpCallBuiltin:
+		return item.operands[1], 1
+	case code.OpCallObjectFn:
+		return item.operands[1] + 1, 1
+	case code.OpClosure:
+		return item.operands[1], 1
+	}
+	return 0, 0
+}
+
+// fused : the instructions of the peephole pass, whose operands are never wide
+func fused(op code.Opcode) bool {
+	switch op {
+	case code.OpGetLocal2, code.OpCmpJump, code.OpCallBuiltin, code.OpCallObjectFn:
+		return true
+	}
+	return false
+}
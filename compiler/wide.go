@@ -0,0 +1,31 @@
+package compiler
+
+import (
+	"github.com/jobs-github/escript/code"
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+)
+
+// widenJumps : a function whose jumps are back-patched beyond 2 bytes (far is pos => target of them)
+// has every jump prefixed by OpWide, so that the targets fit after the instructions are moved
+func widenJumps(ins code.Instructions, far map[int]int, consts object.Objects) (code.Instructions, error) {
+	items, err := decode(ins)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	out := make([]*rewrite, 0, len(items))
+	for _, item := range items {
+		r := &rewrite{op: item.op, operands: item.operands, from: item.pos, wide: item.wide}
+		if i := jumpOperand(item.op); i >= 0 {
+			if target, ok := far[item.pos]; ok {
+				r.operands = append([]int{}, item.operands...)
+				r.operands[i] = target
+			}
+			if _, err := code.LookupWide(item.op); nil == err {
+				r.wide = true
+			}
+		}
+		out = append(out, r)
+	}
+	return relocate(ins, items, out, consts)
+}
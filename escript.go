@@ -1,12 +1,18 @@
 package escript
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/jobs-github/escript/ast"
 	"github.com/jobs-github/escript/object"
 
+	"github.com/jobs-github/escript/checker"
 	"github.com/jobs-github/escript/compiler"
 	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/optimizer"
 	"github.com/jobs-github/escript/parser"
+	"github.com/jobs-github/escript/regvm"
 	"github.com/jobs-github/escript/vm"
 )
 
@@ -15,6 +21,7 @@ type RunnableType uint
 const (
 	RunnableTypeInterpreter RunnableType = iota
 	RunnableTypeVM
+	RunnableTypeRegisterVM
 )
 
 type Runnable interface {
@@ -32,24 +39,132 @@ func NewInterpreter(code string) (Runnable, error) {
 }
 
 func NewState(code string) (Runnable, error) {
+	return NewStateWithOptions(code)
+}
+
+// NewStateWithOptions : NewState, the AST and the bytecode are optimized unless NoOptimize is given,
+// Ast of the Runnable is the optimized one
+func NewStateWithOptions(code string, opts ...Option) (Runnable, error) {
 	node, err := LoadAst(code)
 	if nil != err {
 		return nil, function.NewError(err)
 	}
-	consts := object.Objects{}
-	globals := vm.NewGlobals()
-	st := compiler.NewSymbolTable(nil)
-	c := compiler.Make(st, consts)
-	if err := c.Compile(node); nil != err {
-		return nil, function.NewError(err)
+	o := newOptions(opts)
+	if o.register {
+		if err := optimize(node, o); nil != err {
+			return nil, function.NewError(err)
+		}
+		b, err := regvm.Compile(node)
+		if nil == err {
+			return &registerMachine{node: node, state: regvm.New(b)}, nil
+		}
+		if !errors.Is(err, regvm.ErrUnsupported) {
+			return nil, function.NewError(err)
+		}
+		// the AST is optimized already
+		opts = append(opts, noOptimizeAst())
 	}
-	s := vm.Make(c.Bytecode(), c.Constants(), globals)
+	c, err := compile(node, opts)
 	if nil != err {
 		return nil, function.NewError(err)
 	}
+	s := vm.Make(c.Bytecode(), c.Constants(), vm.NewGlobals())
 	return &virtualMachine{node: node, state: s}, nil
 }
 
+// Option : option of the compiler
+type Option func(o *options)
+
+type options struct {
+	noOptimize    bool
+	noOptimizeAst bool
+	register      bool
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// NoOptimize : compile the AST as it is parsed and skip the peephole pass,
+// refer to optimizer.Optimize and compiler.Peephole
+func NoOptimize() Option {
+	return func(o *options) { o.noOptimize = true }
+}
+
+// Register : run on the register vm, the code out of its subset (refer to regvm.Compile)
+// falls back to the stack vm, Type of the Runnable tells which one is used
+func Register() Option {
+	return func(o *options) { o.register = true }
+}
+
+func noOptimizeAst() Option {
+	return func(o *options) { o.noOptimizeAst = true }
+}
+
+// Compile : precompile code into the format of compiler.Marshal, refer to LoadBytecode
+func Compile(code string, opts ...Option) ([]byte, error) {
+	node, err := LoadAst(code)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	c, err := compile(node, opts)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	return compiler.Marshal(c.Bytecode(), c.Constants())
+}
+
+// LoadBytecode : run the precompiled code on vm without the compiler, Ast of the Runnable is nil
+func LoadBytecode(b []byte) (Runnable, error) {
+	bytecode, consts, err := compiler.Unmarshal(b)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	s := vm.Make(bytecode, consts, vm.NewGlobals())
+	return &virtualMachine{node: nil, state: s, loaded: true}, nil
+}
+
+// Disassemble : listing of the bytecode which code compiles to, refer to compiler.Disassemble
+func Disassemble(code string, opts ...Option) (string, error) {
+	node, err := LoadAst(code)
+	if nil != err {
+		return "", function.NewError(err)
+	}
+	c, err := compile(node, opts)
+	if nil != err {
+		return "", function.NewError(err)
+	}
+	return compiler.Disassemble(c.Bytecode(), c.Constants(), c.Debug()), nil
+}
+
+func optimize(node ast.Node, o *options) error {
+	if o.noOptimize || o.noOptimizeAst {
+		return nil
+	}
+	return optimizer.Optimize(node)
+}
+
+func compile(node ast.Node, opts []Option) (compiler.Compiler, error) {
+	o := newOptions(opts)
+	if err := optimize(node, o); nil != err {
+		return nil, function.NewError(err)
+	}
+	c := compiler.Make(compiler.NewSymbolTable(nil), object.Objects{})
+	if err := c.Compile(node); nil != err {
+		return nil, function.NewError(err)
+	}
+	if !o.noOptimize {
+		if err := c.Peephole(); nil != err {
+			return nil, function.NewError(err)
+		}
+	}
+	return c, nil
+}
+
 // interpreter : implement Runnable
 type interpreter struct {
 	node ast.Node
@@ -69,8 +184,9 @@ func (this *interpreter) Run(s object.Symbols) (object.Object, error) {
 
 // virtualMachine : implement Runnable
 type virtualMachine struct {
-	node  ast.Node
-	state vm.VM
+	node   ast.Node
+	state  vm.VM
+	loaded bool // by LoadBytecode
 }
 
 func (this *virtualMachine) Type() RunnableType {
@@ -81,13 +197,49 @@ func (this *virtualMachine) Ast() ast.Node {
 	return this.node
 }
 
-func (this *virtualMachine) Run(s object.Symbols) (object.Object, error) {
+func (this *virtualMachine) Run(s object.Symbols) (r object.Object, err error) {
+	if this.loaded {
+		// the bytecode is verified when it is loaded, which cannot foresee every value at runtime,
+		// a malformed file fails with an error rather than crashes the host
+		defer func() {
+			if e := recover(); nil != e {
+				r, err = nil, fmt.Errorf("invalid bytecode, %v", e)
+			}
+		}()
+	}
 	if err := this.state.Run(s); nil != err {
 		return nil, err
 	}
 	return this.state.LastPopped(), nil
 }
 
+// registerMachine : implement Runnable
+type registerMachine struct {
+	node  ast.Node
+	state regvm.VM
+}
+
+func (this *registerMachine) Type() RunnableType {
+	return RunnableTypeRegisterVM
+}
+
+func (this *registerMachine) Ast() ast.Node {
+	return this.node
+}
+
+func (this *registerMachine) Run(s object.Symbols) (object.Object, error) {
+	return this.state.Run(s)
+}
+
+// Check : report the type errors of code before it runs, refer to checker.Check
+func Check(code string) error {
+	node, err := LoadAst(code)
+	if nil != err {
+		return function.NewError(err)
+	}
+	return checker.Check(node)
+}
+
 func LoadAst(code string) (ast.Node, error) {
 	p, err := parser.New(code)
 	if nil != err {
@@ -1,11 +1,21 @@
 package escript
 
 import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"math/big"
+	"math/rand"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jobs-github/escript/object"
 	"github.com/jobs-github/escript/parser"
+	"github.com/jobs-github/escript/token"
 )
 
 func TestEvalExpr(t *testing.T) {
@@ -45,6 +55,29 @@ func TestEvalExpr(t *testing.T) {
 		{`const arr = [1,2,4]; arr.tail().tail()`, []int64{4}},
 		{`const arr = [1,2,4]; arr.push(8)`, []int64{1, 2, 4, 8}},
 
+		{`const arr = [1,2,4,8]; arr[1:3]`, []int64{2, 4}},
+		{`const arr = [1,2,4,8]; arr[:-1]`, []int64{1, 2, 4}},
+		{`const arr = [1,2,4,8]; arr[-2:]`, []int64{4, 8}},
+		{`const arr = [1,2,4,8]; arr[::-2]`, []int64{8, 2}},
+		{`const arr = [1,2,4,8]; arr.slice(1)`, []int64{2, 4, 8}},
+		{`const arr = [1,2,4,8]; arr[-1]`, 8},
+		{`"hello"[1:-1]`, "ell"},
+		{`"hello"[::-1]`, "olleh"},
+		{`"hello"[-1]`, "o"},
+		{`6 & 3`, 2},
+		{`6 | 3`, 7},
+		{`6 ^ 3`, 5},
+		{`~5`, -6},
+		{`1 << 10`, 1024},
+		{`-16 >> 2`, -4},
+		{`2 ** 10`, 1024},
+		{`2 ** 3 ** 2`, 512},
+		{`-2 ** 2`, -4},
+		{`(-2) ** 3`, -8},
+		{`1 | 2 << 2 & 12`, 9},
+		{`const flags = 1 | 4; (flags & 4) != 0`, true},
+		{`const m = 255; (m >> 4) ^ (m & 15)`, 0},
+
 		{`"123"[1]`, "2"},
 		{`const s = "123"; s[2]`, "3"},
 
@@ -233,6 +266,43 @@ func testEval(input string, s object.Symbols) (object.Object, error) {
 	return program.Eval(env)
 }
 
+// backends : the interpreter, the stack vm with and without optimization, the register vm and the vm loaded from bytecode
+var backends = []func(code string) (Runnable, error){
+	NewInterpreter,
+	NewState,
+	func(code string) (Runnable, error) { return NewStateWithOptions(code, NoOptimize()) },
+	func(code string) (Runnable, error) { return NewStateWithOptions(code, Register()) },
+	func(code string) (Runnable, error) {
+		b, err := Compile(code)
+		if nil != err {
+			return nil, err
+		}
+		return LoadBytecode(b)
+	},
+}
+
+// testAllBackends : run each of the tests, prefixed by decl, on all backends
+func testAllBackends(t *testing.T, decl string, s object.Symbols, tests []struct {
+	input    string
+	expected interface{}
+}) {
+	for i, tt := range tests {
+		for j, fn := range backends {
+			r, err := fn(decl + tt.input)
+			if nil != err {
+				t.Fatalf("i: %v, j: %v, err: %v", i, j, err)
+			}
+			res, err := r.Run(s)
+			if nil != err {
+				t.Fatalf("i: %v, j: %v, type: %v, err: %v", i, j, r.Type(), err)
+			}
+			if !testEvalObject(t, res, tt.expected) {
+				t.Fatalf("i: %v, j: %v, type: %v", i, j, r.Type())
+			}
+		}
+	}
+}
+
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	result, ok := obj.(*object.Integer)
 	if !ok {
@@ -481,6 +551,31 @@ func TestHash(t *testing.T) {
 	}
 }
 
+func TestHashOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`str({"z": 1, "a": 2, "m": 3})`, `{z: 1, a: 2, m: 3}`},
+		{`str({"z": 1, "a": 2, "z": 3})`, `{z: 3, a: 2}`},
+		{`str({"z": 1, "a": 2, "m": 3}.keys())`, `[z, a, m]`},
+		{`dumps({"z": 1, "a": {"y": true, "b": null}, "m": [3]})`, `{"z":1,"a":{"y":true,"b":null},"m":[3]}`},
+		{`dumps(loads("{\"z\": 1, \"a\": 2, \"m\": 3}"))`, `{"z":1,"a":2,"m":3}`},
+		{`str(loads("{\"z\": 1, \"a\": 2}").keys())`, `[z, a]`},
+	}
+	for i, tt := range tests {
+		for j := 0; j < 8; j++ {
+			evaluated, err := testEval(tt.input, nil)
+			if nil != err {
+				t.Fatalf("i: %v, err: %v", i, err)
+			}
+			if !testStringObject(t, evaluated, tt.expected) {
+				t.Fatalf("i: %v", i)
+			}
+		}
+	}
+}
+
 func TestSymbol(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -601,3 +696,1152 @@ func TestSymbolBool(t *testing.T) {
 		}
 	}
 }
+
+func TestNullSafe(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`null ?? 1`, 1},
+		{`0 ?? 1`, 0},
+		{`false ?? 1`, false},
+		{`null ?? null ?? "x"`, "x"},
+		{`1 ?? throw("never")`, 1},
+		{`const h = {"a": {"b": 2}}; h?.["a"]?.["b"]`, 2},
+		{`const h = {"a": {"b": 2}}; h?.["x"]?.["b"]`, object.Nil},
+		{`const h = {"a": 1}; h?.["a"] + (h?.["x"] ?? 10)`, 11},
+		{`null?.a`, object.Nil},
+		{`null?.[0]`, object.Nil},
+		{`[1, 2]?.[5] ?? -1`, -1},
+		{`[1, 2]?.[-1]`, 2},
+		{`"abc"?.[9] ?? "z"`, "z"},
+		{`null?.len()`, object.Nil},
+		{`[1, 2, 3]?.len()`, 3},
+		{`const h = loads("{\"user\": {\"name\": null}}"); h?.["user"]?.["name"] ?? "anonymous"`, "anonymous"},
+		{`const h = loads("{\"user\": null}"); h?.["user"]?.["name"] ?? "anonymous"`, "anonymous"},
+		{`null?.a.b`, object.Nil},
+		{`{"a": null}["a"]?.b.c`, object.Nil},
+		{`const x = null; x?.a.b(1)[0]`, object.Nil},
+		{`const x = null; [x?.a.b ?? 1, x?.[0].y ?? 2, x?.len().z ?? 3]`, []int64{1, 2, 3}},
+		{`struct P { a }; func f(x) { x?.a.len() }; [f(null) ?? 0, f(P("ab"))]`, []int64{0, 2}},
+		{`struct P { x }; const p = null; [P(1)?.x, p?.x.y ?? 2]`, []int64{1, 2}},
+		{`const h = {"a": 1}; [str(h?.len()), h?.keys()[0]]`, []string{"1", "a"}},
+	}
+	testAllBackends(t, "", nil, tests)
+	// ?. resolves a member as . does, the key of a hash is not a member
+	for _, code := range []string{
+		`null.len()`, `{"a": 1}["x"]`, `{"a": 1}?.["a"].len()`,
+		`{"name": 1}?.name`, `{"a": null}?.a.b`, `const x = {"a": null}; x?.a.b`,
+	} {
+		for _, fn := range backends {
+			r, err := fn(code)
+			if nil != err {
+				t.Fatal(err)
+			}
+			if _, err := r.Run(nil); nil == err {
+				t.Fatalf("`%v` expect error, type: %v", code, r.Type())
+			}
+		}
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"``", ""},
+		{"`abc`", "abc"},
+		{"const a = 1; const b = \"x\"; `${a}-${b}`", "1-x"},
+		{"`sum: ${1 + 2 * 3}!`", "sum: 7!"},
+		{"`${[1, 2]} ${null} ${true}`", "[1, 2] null true"},
+		{"const h = {\"k\": {\"v\": 3}}; `v=${h[\"k\"][\"v\"]}`", "v=3"},
+		{"`${ {\"a\": 1}[\"a\"] }`", "1"},
+		{"`outer ${`inner ${1 + 1}`}`", "outer inner 2"},
+		{"`\\${1} \\` ${2}`", "${1} ` 2"},
+		{"func f(x) { `<${x}>` }; map([1, 2], func(i, x) { f(x) })", []string{"<1>", "<2>"}},
+		{`sprintf("%05d|%x|%t|%v", 42, 255, true, "s")`, "00042|ff|true|s"},
+		{`sprintf("%v %v %d", null, [1], -3)`, "null [1] -3"},
+		{`sprintf("%05.2f|%.1e|%g|%d%%|%v", 3, 25, -2, 7, 3)`, "03.00|2.5e+01|-2|7%|3"},
+		{`sprintf("%[2]d %[1]f", 1, 2)`, "2 1.000000"},
+		{`sprintf("%*d|%.1f", 3, 4, 5)`, "  4|5.0"},
+		{`sprintf("%.1f", 2 ** 70)`, "1180591620717411303424.0"},
+	}
+	testAllBackends(t, "", nil, tests)
+	for _, code := range []string{"`abc", "`${1 + }`", "`${}`", "`${1 2}`", "`${1`"} {
+		if _, err := NewState(code); nil == err {
+			t.Fatalf("`%v` expect error", code)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	code := "#!/usr/bin/env escript\n// the answer\nconst a = 84; /* half */ a // divided by\n/ 2"
+	for _, fn := range backends {
+		r, err := fn(code)
+		if nil != err {
+			t.Fatal(err)
+		}
+		res, err := r.Run(nil)
+		if nil != err {
+			t.Fatalf("type: %v, err: %v", r.Type(), err)
+		}
+		if !testEvalObject(t, res, 42) {
+			t.Fatalf("type: %v", r.Type())
+		}
+	}
+}
+
+func TestBigInt(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`str(9223372036854775807 + 1)`, "9223372036854775808"},
+		{`str(-9223372036854775807 - 2)`, "-9223372036854775809"},
+		{`str(4294967296 * 4294967296)`, "18446744073709551616"},
+		{`str(2 ** 100)`, "1267650600228229401496703205376"},
+		{`str(1 << 70)`, "1180591620717411303424"},
+		{`type(2 ** 64)`, "bigint"},
+		{`type(2 ** 64 - 2 ** 64)`, "integer"},
+		{`2 ** 64 / 2 ** 60`, 16},
+		{`(2 ** 64 + 5) % 10`, 1},
+		{`(1 << 70) >> 68`, 4},
+		{`2 ** 64 > 9223372036854775807`, true},
+		{`2 ** 64 == 18446744073709551616`, true},
+		{`str(-(-9223372036854775807 - 1))`, "9223372036854775808"},
+		{`str(123456789012345678901234567890)`, "123456789012345678901234567890"},
+		{`-123456789012345678901234567890 < 0`, true},
+		{`const h = {18446744073709551616: "big"}; h[2 ** 64]`, "big"},
+		{`str("99999999999999999999".int())`, "99999999999999999999"},
+		{`dumps(loads("{\"n\":123456789012345678901234567890}"))`, `{"n":123456789012345678901234567890}`},
+		{`func fact(n) { n < 2 ? 1 : n * fact(n - 1) }; str(fact(25))`, "15511210043330985984000000"},
+		{`try { 1 / 0 } catch (e) { e.message() }`, "integer division by zero"},
+		{`try { 1 % 0 } catch (e) { e.message() }`, "integer division by zero"},
+		{`try { 2 ** 64 / 0 } catch (e) { e.message() }`, "integer division by zero"},
+		{`sprintf("%d", 9223372036854775807 + 1)`, "9223372036854775808"},
+		{`(2 ** 16777215) >> 16777214`, 2},
+		{`try { 2 ** 4000000000 } catch (e) { e.message() }`, "exponent too large: 4000000000, result > 16777216 bits"},
+		{`try { 3 ** 16777216 } catch (e) { e.message() }`, "exponent too large: 16777216, result > 16777216 bits"},
+		{`try { (2 ** 64) ** 300000 } catch (e) { e.message() }`, "exponent too large: 300000, result > 16777216 bits"},
+		{`(-1) ** 4000000000`, 1},
+		{`0 ** 4000000000`, 0},
+		{`sprintf("%x|%v|%22d|%d", 2 ** 64, -(2 ** 64), 2 ** 64, 1)`, "10000000000000000|-18446744073709551616|  18446744073709551616|1"},
+	}
+	testAllBackends(t, "", nil, tests)
+	// OpIncLocal steps the loop counters as `+` does
+	for _, v := range []int64{math.MaxInt64 - 1, math.MaxInt64} {
+		r, err := (&object.Integer{Value: v}).Succ()
+		if nil != err {
+			t.Fatal(err)
+		}
+		if want := new(big.Int).Add(big.NewInt(v), big.NewInt(1)).String(); want != r.String() {
+			t.Fatalf("expect %v, got %v", want, r)
+		}
+	}
+}
+
+func TestIn(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"CA" in ["US", "CA"]`, true},
+		{`"MX" in ["US", "CA"]`, false},
+		{`"MX" not in ["US", "CA"]`, true},
+		{`1 in ["1", true]`, false},
+		{`[1] in [[1], [2]]`, true},
+		{`"a" in {"a": 1}`, true},
+		{`"b" not in {"a": 1}`, true},
+		{`"ell" in "hello"`, true},
+		{`"" in ""`, true},
+		{`5 in 1..10`, true},
+		{`10 in 1..10`, true},
+		{`10 in 1..<10`, false},
+		{`0 not in 1..10`, true},
+		{`"1" in 1..10`, false},
+		{`const n = 3; n + 1 in n..n * 2`, true},
+		{`1 < 2 in [true]`, true},
+		{`"c" in "a" + "bc"`, true},
+		{`const v = "x"; v in ["x"] && v not in ["y"]`, true},
+		{`const f = func(x) { x.not() }; f(0)`, true},
+	}
+	testAllBackends(t, "", nil, tests)
+	for _, code := range []string{`1 in 1`, `1 in null`, `1 in "123"`, `[1] in {"a": 1}`} {
+		for _, fn := range backends {
+			r, err := fn(code)
+			if nil != err {
+				t.Fatal(err)
+			}
+			if _, err := r.Run(nil); nil == err {
+				t.Fatalf("`%v` expect error, type: %v", code, r.Type())
+			}
+		}
+	}
+}
+
+func TestInterval(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`(1..10).len()`, 10},
+		{`(1..<10).len()`, 9},
+		{`(5..1).len()`, 0},
+		{`(1..<1).len()`, 0},
+		{`(1..10)[0]`, 1},
+		{`(1..10)[-1]`, 10},
+		{`(1..<10).last()`, 9},
+		{`(3..5).first()`, 3},
+		{`str(1..10)`, "1..10"},
+		{`str(1..<10)`, "1..<10"},
+		{`type(1..2)`, "interval"},
+		{`1..3 == 1..3`, true},
+		{`1..3 == 1..<3`, false},
+		{`(1..<1).not()`, true},
+		{`map(1..<5, func(i, x) { x * x })`, []int64{1, 4, 9, 16}},
+		{`filter(1..6, func(i, x) { x % 2 == 0 })`, []int64{2, 4, 6}},
+		{`reduce(1..4, func(acc, x) { acc + x }, 0)`, 10},
+		{`(0..9).slice(2, 5)`, []int64{2, 3, 4}},
+		{`const n = 4; (n - 1..n + 1).len()`, 3},
+		{`(1..1000000000000)[999999999999]`, 1000000000000},
+		{`500000000000 in 1..1000000000000`, true},
+		{`const a = -9223372036854775807 - 1; const r = a..9223372036854775807; str([r.not(), r.first() == a, r.last(), r[-2], r[1] == a + 1])`, "[false, true, 9223372036854775807, 9223372036854775806, true]"},
+		{`const a = -9223372036854775807 - 1; const r = a..<9223372036854775807; str([r.last(), 0 in r])`, "[9223372036854775806, true]"},
+		{`(0..9223372036854775806).len()`, 9223372036854775807},
+		{`(0..9223372036854775806)[-1]`, 9223372036854775806},
+		{`str(0..9223372036854775806)`, "0..9223372036854775806"},
+		{`(9223372036854775806..9223372036854775807).len()`, 2},
+		{`reduce(1..20000, func(acc, x) { acc + x }, 0)`, 200010000},
+		{`filter(1..<10, func(i, x) { x % 3 == 0 })`, []int64{3, 6, 9}},
+		{`map(3..5, func(i, x) { i * x })`, []int64{0, 4, 10}},
+		{`map(1..<1, func(i, x) { x })`, []int64{}},
+	}
+	testAllBackends(t, "", nil, tests)
+	huge := `const r = (-9223372036854775807 - 1)..9223372036854775807;`
+	for _, code := range []string{
+		`1.."2"`, `(1..3)[3]`, `(1..<1).first()`, `1..2 ** 64`,
+		huge + `r.len()`, huge + `r[1:3]`, huge + `map(r, func(i, x) { x })`,
+		`map(0..9223372036854775806, func(i, x) { x })`, huge + `reduce(r, func(acc, x) { acc }, 0)`, huge + `filter(r, func(i, x) { true })`,
+		`dumps(1..3)`, `(0..9223372036854775806)[9223372036854775807]`,
+	} {
+		for _, fn := range backends {
+			r, err := fn(code)
+			if nil != err {
+				t.Fatal(err)
+			}
+			if _, err := r.Run(nil); nil == err {
+				t.Fatalf("`%v` expect error, type: %v", code, r.Type())
+			} else if strings.HasPrefix(code, huge) && !strings.Contains(err.Error(), "len > 9223372036854775807") {
+				t.Fatalf("`%v` expect the length in error, got `%v`", code, err)
+			}
+		}
+	}
+}
+
+func TestSliceStep(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`[1, 2, 3][1::9223372036854775807]`, []int64{2}},
+		{`[1, 2, 3][::9223372036854775807]`, []int64{1}},
+		{`"abc"[2::9223372036854775807]`, "c"},
+		{`(1..5)[1::9223372036854775807]`, []int64{2}},
+		{`[1, 2, 3][1::-9223372036854775807 - 1]`, []int64{2}},
+		{`"abc"[::-9223372036854775807]`, "c"},
+		{`(1..5)[::-9223372036854775807 - 1]`, []int64{5}},
+		{`[1, 2, 3, 4, 5][::2]`, []int64{1, 3, 5}},
+		{`[1, 2, 3, 4, 5][-1:0:-2]`, []int64{5, 3}},
+	}
+	testAllBackends(t, "", nil, tests)
+	errs := []struct {
+		input string
+		want  string
+	}{
+		{`[1, 2, 3][-5]`, "idx: -5"},
+		{`[1, 2, 3][3]`, "idx: 3, len: 3"},
+		{`"abc"[-4]`, "idx: -4"},
+		{`(1..3)[-4]`, "idx: -4"},
+	}
+	for _, tt := range errs {
+		for _, fn := range backends {
+			r, err := fn(tt.input)
+			if nil != err {
+				t.Fatal(err)
+			}
+			_, err = r.Run(nil)
+			if nil == err {
+				t.Fatalf("`%v` expect error, type: %v", tt.input, r.Type())
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("`%v` expect error `%v`, got `%v`, type: %v", tt.input, tt.want, err, r.Type())
+			}
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	fn := `const f = func(v) {
+		match v {
+			1 => "one",
+			"a" | "b" => "ab",
+			[_, [z, _]] => z,
+			[x, y] => x + y,
+			{"type": "circle", "r": r} => r * r * 3,
+			{"type": t} if t > 1 => t,
+			{"type": t} => -t,
+			-1 | null => "neg",
+			_ => "other",
+		}
+	};`
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{fn + `f(1)`, "one"},
+		{fn + `f("a")`, "ab"},
+		{fn + `f("b")`, "ab"},
+		{fn + `f([1, 2])`, 3},
+		{fn + `f([1, [2, 3]])`, 2},
+		{fn + `f([1, 2, 3])`, "other"},
+		{fn + `f({"type": "circle", "r": 2})`, 12},
+		{fn + `f({"type": 3, "extra": 0})`, 3},
+		{fn + `f({"type": 1})`, -1},
+		{fn + `f(-1)`, "neg"},
+		{fn + `f(null)`, "neg"},
+		{fn + `f(true)`, "other"},
+		{fn + `f("1")`, "other"},
+		{fn + `f({"kind": 1})`, "other"},
+		{`match 3 { 1 | 2 => "low", 3 => "three", _ => "high" }`, "three"},
+		{`match 9 { 1 | 2 => "low", 3 => "three", _ => "high" }`, "high"},
+		{`match "b" { "a" => 1, "b" | "a" => 2 }`, 2},
+		{`match true { 1 => "int", true => "bool" }`, "bool"},
+		{`match 1 + 1 { n if n > 1 => n * 10, n => n }`, 20},
+		{`const x = 1; const y = match [5, 6] { [x, y] => x * y }; [x, y]`, []int64{1, 30}},
+		{`const g = func(a, b) { match [a, b] { [0, _] | [_, 0] => 0, [m, n] => m + n } }; g(2, 3)`, 5},
+	}
+	testAllBackends(t, "", nil, tests)
+	for _, code := range []string{`match 1 { 2 => 2 }`, `match [1] { [x, y] => x }`, `match 1 { true => 1, "1" => 2 }`} {
+		for _, fn := range backends {
+			r, err := fn(code)
+			if nil != err {
+				t.Fatal(err)
+			}
+			if _, err := r.Run(nil); nil == err {
+				t.Fatalf("`%v` expect error, type: %v", code, r.Type())
+			}
+		}
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	s := object.Symbols{
+		"fail": func() (object.Object, error) { return object.Nil, errors.New("symbol failed") },
+	}
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 + 1 } catch (e) { 0 }`, 2},
+		{`try { {"k": 1}["x"] } catch (e) { -1 }`, -1},
+		{`try { "abc".int() } catch { 0 }`, 0},
+		{`try { $fail } catch (e) { e.message() }`, "symbol failed"},
+		{`try { throw("boom") } catch (e) { e.message() }`, "boom"},
+		{`try { throw(error("boom")) } catch (e) { type(e) }`, "error"},
+		{`const e1 = error("x"); try { throw(e1) } catch (e) { e == e1 }`, true},
+		{`func f(x) { x > 0 ? f(x - 1) : throw("bottom") }; try { f(10) } catch (e) { str(e) }`, "bottom"},
+		{`const v = 10; try { [1][5] } catch { v * 2 }`, 20},
+		{`try { try { throw("inner") } catch (e) { throw("outer") } } catch (e) { e.message() }`, "outer"},
+		{`[try { 1 } catch { 0 }, try { [][0] } catch { 2 }]`, []int64{1, 2}},
+		{`map([1, 0, 2], func(i, x) { try { 10 / (x == 0 ? throw("zero") : x) } catch { -1 } })`, []int64{10, -1, 5}},
+	}
+	testAllBackends(t, "", s, tests)
+	r, err := NewState(`throw("uncaught")`)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if _, err := r.Run(nil); nil == err {
+		t.Fatal("expect error")
+	}
+}
+
+// TestTryStackOverflow : the stack vm runs out of frames in deep recursion, which is caught like any other error,
+// the interpreter and the register vm go deeper
+func TestTryStackOverflow(t *testing.T) {
+	tests := []struct {
+		input    string
+		deep     interface{}
+		overflow interface{}
+	}{
+		{`const f = func(n) { n == 0 ? 0 : try { f(n - 1) } catch (e) { -1 } }; f(3000)`, 0, -1},
+		{`const f = func(n) { n == 0 ? 0 : f(n - 1) ?? 1 }; try { f(3000) } catch (e) { e.message() }`, 0, "stack overflow"},
+		{`const f = func(n) { n == 0 ? 0 : (f(n - 1) ?? 1) + 1 }; try { f(3000) } catch (e) { e.message() }`, 3000, "stack overflow"},
+	}
+	for i, tt := range tests {
+		for j, fn := range backends {
+			r, err := fn(tt.input)
+			if nil != err {
+				t.Fatalf("i: %v, j: %v, err: %v", i, j, err)
+			}
+			res, err := r.Run(nil)
+			if nil != err {
+				t.Fatalf("i: %v, j: %v, type: %v, err: %v", i, j, r.Type(), err)
+			}
+			expected := tt.deep
+			if RunnableTypeVM == r.Type() {
+				expected = tt.overflow
+			}
+			if !testEvalObject(t, res, expected) {
+				t.Fatalf("i: %v, j: %v, type: %v", i, j, r.Type())
+			}
+		}
+	}
+}
+
+func TestDestruct(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`const [a, b] = [1, 2]; a + b`, 3},
+		{`const [a, ...rest] = [1, 2, 3]; rest`, []int64{2, 3}},
+		{`const [a, ...rest] = [1]; rest.len()`, 0},
+		{`const [_, [b, _]] = [1, [2, 3]]; b`, 2},
+		{`const [..._] = []; 1`, 1},
+		{`const {name, age: years} = {"name": "bob", "age": 3}; name + str(years)`, "bob3"},
+		{`const {"a": [x, ...y]} = {"a": [1, 2], "b": 0}; x * 10 + y[0]`, 12},
+		{`const [x, y] = [1, 2]; const [y2, x2] = [y, x]; [x2, y2]`, []int64{1, 2}},
+		{`const f = func([a, b], c) { a * b + c }; f([2, 3], 4)`, 10},
+		{`const f = func({x, y}) { x - y }; f({"y": 1, "x": 3})`, 2},
+		{`func f(n, [h, ...t]) { (t.len() == 0) ? n + h : f(n + h, t) }; f(0, [1, 2, 3])`, 6},
+		{`map([[1, 2], [3, 4]], func(i, [a, b]) { a * b })`, []int64{2, 12}},
+		{`const f = func([a]) { func() { a } }; f([7])()`, 7},
+	}
+	testAllBackends(t, "", nil, tests)
+	errs := []struct {
+		input string
+		want  string
+	}{
+		{`const [a, b] = [1]`, "cannot destructure array `[1]` with `[a, b]`"},
+		{`const [a, ...b] = []`, "cannot destructure array `[]` with `[a, ...b]`"},
+		{`const {a} = 1`, "cannot destructure integer `1` with `{\"a\": a}`"},
+		{`const {a} = {"b": 1}`, "cannot destructure hash"},
+		{`const f = func([a]) { a }; f("a")`, "cannot destructure string `a` with `[a]`"},
+	}
+	for _, tt := range errs {
+		for _, fn := range backends {
+			r, err := fn(tt.input)
+			if nil != err {
+				t.Fatal(err)
+			}
+			_, err = r.Run(nil)
+			if nil == err {
+				t.Fatalf("`%v` expect error, type: %v", tt.input, r.Type())
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("`%v` expect error `%v`, got `%v`, type: %v", tt.input, tt.want, err, r.Type())
+			}
+		}
+	}
+}
+
+func TestFunctionArgs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`const f = func(x, y = 10) { x + y }; f(1)`, 11},
+		{`const f = func(x, y = 10) { x + y }; f(1, 2)`, 3},
+		{`const f = func(x, y = x * 2, z = y + 1) { [x, y, z] }; f(1)`, []int64{1, 2, 3}},
+		{`const f = func(x, y = x * 2, z = y + 1) { [x, y, z] }; f(1, 5)`, []int64{1, 5, 6}},
+		{`const f = func(x = null) { x ?? 7 }; f(null)`, 7},
+		{`const f = func(x, ...rest) { rest }; f(1, 2, 3)`, []int64{2, 3}},
+		{`const f = func(x, ...rest) { rest.len() }; f(1)`, 0},
+		{`const f = func(...all) { all }; f(1, 2)`, []int64{1, 2}},
+		{`const f = func(x, y = 2, ...rest) { x + y + rest.len() }; f(1)`, 3},
+		{`const f = func(x, y = 2, ...rest) { x + y + rest.len() }; f(1, 1, 1, 1)`, 4},
+		{`const f = func(a, b, c) { a * 100 + b * 10 + c }; f(...[1, 2, 3])`, 123},
+		{`const f = func(a, b, c) { a * 100 + b * 10 + c }; f(1, ...[2], ...[], 3)`, 123},
+		{`const f = func(a, b, c) { a * 100 + b * 10 + c }; f(...1..3)`, 123},
+		{`const f = func(...xs) { xs }; const g = func(...ys) { f(0, ...ys) }; g(1, 2)`, []int64{0, 1, 2}},
+		{`sprintf(...["%v-%v", 1, 2])`, "1-2"},
+		{`const f = func(a, [b, c] = [a, a + 1]) { a + b + c }; f(1)`, 4},
+		{`const f = func(a, [b, c] = [a, a + 1]) { a + b + c }; f(1, [10, 20])`, 31},
+		{`func sum(acc, ...xs) { (xs.len() == 0) ? acc : sum(acc + xs[0], ...xs[1:]) }; sum(0, 1, 2, 3)`, 6},
+		{`const k = 5; const f = func(x = k) { func(y = x) { y } }; f()()`, 5},
+		{`map([1, 2], func(i, x, scale = 10) { x * scale })`, []int64{10, 20}},
+	}
+	testAllBackends(t, "", nil, tests)
+	errs := []struct {
+		input string
+		want  string
+	}{
+		{`const f = func(x, y = 1) { x }; f()`, "1..2"},
+		{`const f = func(x, y = 1) { x }; f(1, 2, 3)`, "1..2"},
+		{`const f = func(x, ...rest) { x }; f()`, "1+"},
+		{`const f = func(x) { x }; f(...1)`, "cannot spread integer `1` into arguments"},
+		{`const f = func(x) { x }; f(...{"a": 1})`, "cannot spread hash"},
+	}
+	for _, tt := range errs {
+		for _, fn := range backends {
+			r, err := fn(tt.input)
+			if nil != err {
+				t.Fatal(err)
+			}
+			_, err = r.Run(nil)
+			if nil == err {
+				t.Fatalf("`%v` expect error, type: %v", tt.input, r.Type())
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("`%v` expect error `%v`, got `%v`, type: %v", tt.input, tt.want, err, r.Type())
+			}
+		}
+	}
+}
+
+func TestTailCall(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`func count(n, acc) { (n == 0) ? acc : count(n - 1, acc + 1) }; count(20000, 0)`, 20000},
+		{`func f(n) { (n > 10) ? ((n % 2 == 0) ? f(n - 1) : f(n - 3)) : n }; f(20000)`, 8},
+		{`func sum(acc, ...xs) { (xs.len() == 0) ? acc : sum(acc + xs[0], ...xs[1:]) }; sum(0, ...1..2000)`, 2001000},
+		{`
+		func fill(n, arr) { (n == 0) ? arr : fill(n - 1, arr.push(n)) };
+		func my_reduce(arr, result, fn) { (arr.len() == 0) ? result : my_reduce(arr.tail(), fn(result, arr.first()), fn) };
+		my_reduce(fill(1500, []), 0, func(x, y) { x + y })
+		`, 1125750},
+		{`func f(x) { str(x) }; f(1)`, "1"},
+		{`const mk = func(k) { func(x) { x + k } }; func g(x) { mk(10)(x) }; g(1)`, 11},
+		{`const a = func(x, y = x + 1, ...rest) { [x, y, rest.len()] }; const b = func(p, q, r) { a(p) }; b(1, 2, 3)`, []int64{1, 2, 0}},
+		{`func f(n) { (n == 0) ? "done" : try { f(n - 1) } catch (e) { e } }; f(100)`, "done"},
+		{`struct C { k }; func (c C) walk(n) { (n == 0) ? c.k : c.walk(n - 1) }; C(7).walk(100000)`, 7},
+		{`struct C { k }; func (c C) walk(n, acc) { (n == 0) ? acc : C(c.k)?.walk(n - 1, acc + c.k) }; C(2).walk(100000, 0)`, 200000},
+		{`struct C { k }; func (c C) walk(n, ...xs) { (n == 0) ? c.k + xs.len() : c.walk(n - 1, n) }; C(3).walk(20000)`, 4},
+		{`struct Op { f }; const op = Op(func(n) { (n == 0) ? "end" : op.f(n - 1) }); op.f(100000)`, "end"},
+	}
+	testAllBackends(t, "", nil, tests)
+	errs := []struct {
+		input string
+		want  string
+	}{
+		{`func f(x) { (x == 0) ? f() : f(x - 1) }; f(3)`, "1"},
+	}
+	for _, tt := range errs {
+		for _, fn := range backends {
+			r, err := fn(tt.input)
+			if nil != err {
+				t.Fatal(err)
+			}
+			_, err = r.Run(nil)
+			if nil == err {
+				t.Fatalf("`%v` expect error, type: %v", tt.input, r.Type())
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("`%v` expect error `%v`, got `%v`, type: %v", tt.input, tt.want, err, r.Type())
+			}
+		}
+	}
+}
+
+// TestRecursionDepth : a go stack overflow cannot be recovered, the interpreter must keep its frames small
+func TestRecursionDepth(t *testing.T) {
+	r, err := NewInterpreter(`func f(n) { (n == 0) ? 0 : 1 + f(n - 1) }; f(1000000)`)
+	if nil != err {
+		t.Fatal(err)
+	}
+	res, err := r.Run(nil)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !testEvalObject(t, res, 1000000) {
+		t.Fatal(res)
+	}
+}
+
+// TestStackOverflow : the frames of the stack vm may run out before its stack (e.g. the peephole pass
+// shrinks the frames), which is a stack overflow as well, the other backends go deeper
+func TestStackOverflow(t *testing.T) {
+	code := `const f = func(n) { n == 0 ? 0 : f(n - 1) + 1 }; f(1500)`
+	for j, fn := range backends {
+		r, err := fn(code)
+		if nil != err {
+			t.Fatal(err)
+		}
+		res, err := r.Run(nil)
+		if RunnableTypeVM != r.Type() {
+			if nil != err || !testEvalObject(t, res, 1500) {
+				t.Fatalf("j: %v, type: %v, err: %v", j, r.Type(), err)
+			}
+		} else if nil == err || !strings.Contains(err.Error(), "stack overflow") {
+			t.Fatalf("j: %v, expect stack overflow, got `%v`", j, err)
+		}
+	}
+}
+
+func TestStruct(t *testing.T) {
+	decl := `
+	struct Point { x, y };
+	func (p Point) dist2() { p.x * p.x + p.y * p.y };
+	func (p Point) add(o, scale = 1) { Point(p.x + o.x * scale, p.y + o.y * scale) };
+	func (p Point) sum(...rest) { p.x + p.y + rest.len() };
+	`
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`Point(3, 4).x`, 3},
+		{`const p = Point(3, 4); p.y`, 4},
+		{`Point(3, 4).dist2()`, 25},
+		{`Point(3, 4).add(Point(1, 1)).x`, 4},
+		{`Point(3, 4).add(Point(1, 1), 10).dist2()`, 13*13 + 14*14},
+		{`Point(3, 4).sum(1, 2)`, 9},
+		{`const f = Point(3, 4).dist2; f()`, 25},
+		{`Point(3, 4) == Point(3, 4)`, true},
+		{`Point(3, 4) == Point(4, 3)`, false},
+		{`Point(3, 4) != [3, 4]`, true},
+		{`Point(3, 4) == Point`, false},
+		{`type(Point(3, 4))`, "Point"},
+		{`type(Point)`, "struct"},
+		{`str(Point(3, [4]))`, "Point{x: 3, y: [4]}"},
+		{`dumps(Point(3, {"a": "b"}))`, `{"x":3,"y":{"a":"b"}}`},
+		{`Point(null, 4).x?.len()`, &object.Null{}},
+		{`Point(3, 4)?.dist2()`, 25},
+		{`!Point(3, 4)`, false},
+		{`map([1, 2], func(i, x) { Point(x, x).dist2() })`, []int64{2, 8}},
+		{`match Point(3, 4) { _ => 1 }`, 1},
+		{`struct Op { f }; Op(func(x) { x * 2 }).f(3)`, 6},
+		{`struct Empty {}; str(Empty())`, "Empty{}"},
+		{`func count(p, n) { (n == 0) ? p : count(p.add(Point(1, 0)), n - 1) }; count(Point(0, 0), 2000).x`, 2000},
+	}
+	testAllBackends(t, decl, nil, tests)
+	errs := []struct {
+		input string
+		want  string
+	}{
+		{`Point(1)`, "Point() takes exactly 2 arguments (1 given)"},
+		{`Point(1, 2).z`, "no attribute 'z' in Point"},
+		{`Point(1, 2).z()`, "no attribute 'z' in Point"},
+		{`func (p Point) x() { 1 }`, "struct Point has both field and method named `x`"},
+		{`const q = 1; func (p q) f() { 1 }`, "cannot define method `f` on integer `1`"},
+		{`Point(1, 2).add()`, "2..3"},
+		{`{Point(1, 2): 1}`, "unsupported"},
+	}
+	for _, tt := range errs {
+		for _, fn := range backends {
+			r, err := fn(decl + tt.input)
+			if nil != err {
+				t.Fatal(err)
+			}
+			_, err = r.Run(nil)
+			if nil == err {
+				t.Fatalf("`%v` expect error, type: %v", tt.input, r.Type())
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("`%v` expect error `%v`, got `%v`, type: %v", tt.input, tt.want, err, r.Type())
+			}
+		}
+	}
+}
+
+func TestOperatorHooks(t *testing.T) {
+	decl := `
+	struct Vec { x, y };
+	func (v Vec) __add__(o) { Vec(v.x + o.x, v.y + o.y) };
+	func (v Vec) __sub__(o) { v + o * -1 };
+	func (v Vec) __mul__(k) { Vec(v.x * k, v.y * k) };
+	func (v Vec) __div__(k) { try { Vec(v.x / k, v.y / k) } catch (e) { Vec(0, 0) } };
+	func (v Vec) __mod__(k) { Vec(v.x % k, v.y % k) };
+	func (v Vec) __eq__(o) { (type(o) == "Vec") ? (v.x == o.x && v.y == o.y) : false };
+	func (v Vec) __lt__(o) { v.x * v.x + v.y * v.y < o.x * o.x + o.y * o.y };
+	func (v Vec) __index__(i) { (i == 0) ? v.x : v.y };
+	func (v Vec) __str__() { "<" + str(v.x) + "," + str(v.y) + ">" };
+	`
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`(Vec(1, 2) + Vec(3, 4)).x`, 4},
+		{`(Vec(1, 2) - Vec(3, 5)).y`, -3},
+		{`(Vec(1, 2) * 3).y`, 6},
+		{`(Vec(4, 6) / 2).x`, 2},
+		{`(Vec(4, 6) / 0).x`, 0},
+		{`(Vec(4, 7) % 3).y`, 1},
+		{`Vec(1, 2) == Vec(1, 2)`, true},
+		{`Vec(1, 2) == 1`, false},
+		{`Vec(1, 2) != Vec(2, 1)`, true},
+		{`Vec(1, 2) < Vec(3, 4)`, true},
+		{`Vec(1, 2) > Vec(3, 4)`, false},
+		{`Vec(1, 2) <= Vec(2, 1)`, true},
+		{`Vec(1, 2) >= Vec(3, 4)`, false},
+		{`Vec(5, 6)[1]`, 6},
+		{`str(Vec(1, 2))`, "<1,2>"},
+		{`str([Vec(1, 2)])`, "[<1,2>]"},
+		{`reduce([Vec(1, 1), Vec(2, 2), Vec(3, 3)], func(acc, v) { acc + v }, Vec(0, 0))[0]`, 6},
+		{`const k = 10; func (v Vec) scale() { v * k }; Vec(1, 2).scale()[1]`, 20},
+		{`func sum(v, n) { (n == 0) ? v : sum(v + Vec(1, 1), n - 1) }; sum(Vec(0, 0), 500)[0]`, 500},
+		{`struct Plain { x }; Plain(1) == Plain(1)`, true},
+		{`try { Vec(1, 2) % 0 } catch (e) { e.message() }`, "integer division by zero"},
+		{`struct M { v }; func (m M) __eq__(o) { m.v % 10 == o.v % 10 }; str([M(1) == M(11), [M(1)] == [M(11)], [M(1)] != [M(2)], M(1) in [M(2), M(21)], M(1) not in [M(2)], {"a": M(1)} == {"a": M(11)}, [M(1)].contains(M(31))])`, "[true, true, true, true, true, true, true]"},
+		{`struct B { v }; func (b B) __eq__(o) { throw("bad eq") }; str([1, try { [B(1)] == [B(1)] } catch (e) { e.message() }, try { B(1) in [B(2)] } catch (e) { e.message() }, 4])`, "[1, bad eq, bad eq, 4]"},
+		{`struct S { v }; func (s S) __str__() { throw("bad str") }; [try { str(S(1)) } catch (e) { e.message() }, try { str([1, {"k": S(1)}]) } catch (e) { e.message() }, try { sprintf("%v", S(1)) } catch (e) { e.message() }, try { ` + "`${S(1)}`" + ` } catch (e) { e.message() }]`, []string{"bad str", "bad str", "bad str", "bad str"}},
+	}
+	testAllBackends(t, decl, nil, tests)
+	errs := []struct {
+		input string
+		want  string
+	}{
+		{`Vec(1, 2) % 0`, "integer division by zero"},
+		{`1 + Vec(1, 2)`, "invalid operation"},
+		{`Vec(1, 2) + 1`, "no attribute 'x' in integer"},
+		{`struct S { v }; func (s S) __str__() { throw("bad str") }; str(S(1))`, "bad str"},
+		{`struct B { v }; func (b B) __eq__(o) { [][1] }; [B(1)] == [B(1)]`, "array is empty"},
+	}
+	for _, tt := range errs {
+		for _, fn := range backends {
+			r, err := fn(decl + tt.input)
+			if nil != err {
+				t.Fatal(err)
+			}
+			_, err = r.Run(nil)
+			if nil == err {
+				t.Fatalf("`%v` expect error, type: %v", tt.input, r.Type())
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("`%v` expect error `%v`, got `%v`, type: %v", tt.input, tt.want, err, r.Type())
+			}
+		}
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	decl := `
+	struct Point { x, y };
+	func (p Point) scale(k: int): Point { Point(p.x * k, p.y * k) };
+	func add(x: int, y: int = 1): int { x + y };
+	func count(...items: array<string>): int { items.len() };
+	`
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`add(1, 2)`, 3},
+		{`const n: int = add(1); n`, 2},
+		{`const [a, b]: array<int> = [1, 2]; a + b`, 3},
+		{`count("a", "b")`, 2},
+		{`Point(1, 2).scale(add(2)).y`, 6},
+		{`const f = func(x: hash<int>): array<int> { [x["a"]] }; f({"a": 1})`, []int64{1}},
+	}
+	for i, tt := range tests {
+		if err := Check(decl + tt.input); nil != err {
+			t.Fatalf("i: %v, check: %v", i, err)
+		}
+	}
+	testAllBackends(t, decl, nil, tests)
+	errs := []struct {
+		input string
+		want  string
+	}{
+		{`add("1")`, "arg 1 of `add` expects int, got string, line: 6, col: 6"},
+		{`const s: string = count();`, "const `s` expects string, got int"},
+		{`Point(1, 2).scale([1])`, "arg 1 of `Point.scale` expects int, got array<int>"},
+	}
+	for _, tt := range errs {
+		err := Check(decl + tt.input)
+		if nil == err {
+			t.Fatalf("`%v` expect error", tt.input)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("`%v` expect error `%v`, got `%v`", tt.input, tt.want, err)
+		}
+	}
+}
+
+func TestBytecode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`1 + 2 * 3`, 7},
+		{`"a" + "b"`, "ab"},
+		{`123456789012345678901234567890 - 123456789012345678901234567889`, 1},
+		{`const a = [1, 2, 3]; a[1:]`, []int64{2, 3}},
+		{`const h = {"a": 1, "b": 2}; h["b"]`, 2},
+		{`func add(x, y = 1) { x + y }; add(1) + add(1, 2)`, 5},
+		{`func sum(...xs) { reduce(xs, func(acc, x) { acc + x }, 0) }; sum(1, 2, 3)`, 6},
+		{`const f = func(x) { func(y) { x + y } }; f(1)(2)`, 3},
+		{`func fact(n, acc) { (n < 2) ? acc : fact(n - 1, acc * n) }; fact(5, 1)`, 120},
+		{`match "b" { "a" => 1, "b" => 2, _ => 3 }`, 2},
+		{`match null { 1 => 1, true => 2, null => 3, _ => 4 }`, 3},
+		{`try { throw("x") } catch (e) { e.message() }`, "x"},
+		{`struct P { x, y }; func (p P) sum() { p.x + p.y }; P(1, 2).sum()`, 3},
+		{`const [a, b] = [1, 2]; a + b`, 3},
+		{`null`, object.Nil},
+		{`true && !false`, true},
+	}
+	for i, tt := range tests {
+		b, err := Compile(tt.input)
+		if nil != err {
+			t.Fatalf("i: %v, err: %v", i, err)
+		}
+		r, err := LoadBytecode(b)
+		if nil != err {
+			t.Fatalf("i: %v, err: %v", i, err)
+		}
+		if r.Type() != RunnableTypeVM || nil != r.Ast() {
+			t.Fatalf("i: %v, unexpected runnable", i)
+		}
+		res, err := r.Run(nil)
+		if nil != err {
+			t.Fatalf("i: %v, err: %v", i, err)
+		}
+		if !testEvalObject(t, res, tt.expected) {
+			t.Fatalf("i: %v", i)
+		}
+	}
+
+	b, err := Compile(`$s + 1`)
+	if nil != err {
+		t.Fatal(err)
+	}
+	r, err := LoadBytecode(b)
+	if nil != err {
+		t.Fatal(err)
+	}
+	res, err := r.Run(object.Symbols{
+		"s": func() (object.Object, error) { return object.NewInteger(1), nil },
+	})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !testEvalObject(t, res, 2) {
+		t.Fatal(res)
+	}
+
+	corrupt := func(pos int, v byte) []byte {
+		c := append([]byte{}, b...)
+		c[pos] = v
+		return c
+	}
+	errs := []struct {
+		input []byte
+		want  string
+	}{
+		{nil, "bad magic"},
+		{[]byte("const a = 1;"), "bad magic"},
+		{corrupt(5, 0xff), "unsupported bytecode version"},
+		{corrupt(len(b)-1, b[len(b)-1]^0xff), "checksum mismatch"},
+		{b[:len(b)-1], "checksum mismatch"},
+	}
+	for i, tt := range errs {
+		_, err := LoadBytecode(tt.input)
+		if nil == err {
+			t.Fatalf("i: %v, expect error", i)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("i: %v, expect error `%v`, got `%v`", i, tt.want, err)
+		}
+	}
+}
+
+// TestBytecodeMalformed : a body with a valid checksum but random bytes must fail to load or run with an error
+func TestBytecodeMalformed(t *testing.T) {
+	inputs := []string{
+		`const f = func(a, b = 2) { func(x) { x + a + b } }; f(1)(2) + f(3, 4)(5)`,
+		`const h = {"a": [1, 2, 3], "b": "s"}; try { h["a"][1:] + [h?.b] } catch (e) { [e.message()] }`,
+		`struct P { x, y }; func (p P) sum() { p.x + p.y }; match P(1, 2).sum() { 3 => "three", [a, b] => a, _ => "other" }`,
+		"const s = \"ab\"; `${s}-${s.len()}` + sprintf(\"%v\", s ?? 1)",
+		`reduce(filter(map([1, 2, 3], func(i, x) { x * i }), func(i, x) { x > 0 }), func(acc, x) { acc + x }, 0)`,
+		`const f = func(...xs) { xs.len() > 1 ? f(...xs[1:]) : xs }; f(1, 2, 3)[0] in 1..5`,
+	}
+	header := 10 // magic[4] version[2] checksum[4], refer to compiler.Marshal
+	rnd := rand.New(rand.NewSource(1))
+	for i, input := range inputs {
+		b, err := Compile(input)
+		if nil != err {
+			t.Fatalf("i: %v, err: %v", i, err)
+		}
+		for round := 0; round < 500; round++ {
+			c := append([]byte{}, b...)
+			for n := 1 + rnd.Intn(3); n > 0; n-- {
+				c[header+rnd.Intn(len(c)-header)] = byte(rnd.Intn(256))
+			}
+			binary.BigEndian.PutUint32(c[6:], crc32.ChecksumIEEE(c[header:]))
+			r, err := LoadBytecode(c)
+			if nil != err {
+				continue
+			}
+			// a malformed loop may never end, which is not checked
+			done := make(chan interface{}, 1)
+			go func() {
+				defer func() { done <- recover() }()
+				r.Run(nil)
+			}()
+			select {
+			case e := <-done:
+				if nil != e {
+					t.Fatalf("i: %v, round: %v, panic: %v", i, round, e)
+				}
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+func TestOptimizer(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`(1 > 0) ? (1 + 1) : (10 % 3)`, 2},
+		{`const a = 2; const b = a * 3; func f(x) { x + b }; f(a)`, 8},
+		{`const a = 1; func f(a) { a }; f(5) + a`, 6},
+		{`const n = 3; map([1, 2], func(i, x) { x * n })`, []int64{3, 6}},
+		{`const s = "ab"; s + "c" + str(s.len())`, "abc2"},
+		{"const n = 2; `${n}${n + 1}`", "23"},
+		{`const a = null; a ?? "x"`, "x"},
+		{`const k = "a"; {k: 1}["a"]`, 1},
+		{`const x = 1; match 1 { x => x + 1, _ => 0 }`, 2},
+		{`9223372036854775807 + 1 - 1`, 9223372036854775807},
+	}
+	for i, tt := range tests {
+		for _, opts := range [][]Option{nil, {NoOptimize()}} {
+			r, err := NewStateWithOptions(tt.input, opts...)
+			if nil != err {
+				t.Fatalf("i: %v, err: %v", i, err)
+			}
+			res, err := r.Run(nil)
+			if nil != err {
+				t.Fatalf("i: %v, err: %v", i, err)
+			}
+			if !testEvalObject(t, res, tt.expected) {
+				t.Fatalf("i: %v, options: %v", i, len(opts))
+			}
+		}
+	}
+	// failures are raised when the code runs
+	for _, opts := range [][]Option{nil, {NoOptimize()}} {
+		r, err := NewStateWithOptions(`const a = 0; 1 / a`, opts...)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if _, err := r.Run(nil); nil == err || !strings.Contains(err.Error(), "division by zero") {
+			t.Fatalf("expect division by zero, got %v", err)
+		}
+	}
+	optimized, err := Disassemble(`(1 > 0) ? (1 + 1) : (10 % 3)`)
+	if nil != err {
+		t.Fatal(err)
+	}
+	plain, err := Disassemble(`(1 > 0) ? (1 + 1) : (10 % 3)`, NoOptimize())
+	if nil != err {
+		t.Fatal(err)
+	}
+	if strings.Contains(optimized, "OpJumpWhenFalse") || !strings.Contains(plain, "OpJumpWhenFalse") {
+		t.Fatalf("unexpected disassembly\n%v\n%v", optimized, plain)
+	}
+}
+
+func TestPeephole(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`func f(x) { (x < 2) ? x : f(x - 1) + f(x - 2) }; f(10)`, 55},
+		{`func f(a, b) { (a == b) ? "eq" : str(a - b) }; f(1, 1) + f(3, 1)`, "eq2"},
+		{`func f(x) { match x { 1 => "a", 2 => "b", _ => str(x + 1) } }; f(1) + f(2) + f(3)`, "ab4"},
+		{`func f(x) { try { 10 / x } catch (e) { -1 } }; f(0) + f(5)`, 1},
+		{`func f(a) { sprintf("%v-%v", type(a), str(a[0] + 1)) }; f([1, 2])`, "array-2"},
+		{`reduce([1, 2, 3], func(acc, x) { (x >= 2) ? acc + x : acc }, 0)`, 5},
+		{`func f(x) { x?.y ?? (x != null) }; f(null)`, false},
+	}
+	for i, tt := range tests {
+		for _, opts := range [][]Option{nil, {NoOptimize()}} {
+			r, err := NewStateWithOptions(tt.input, opts...)
+			if nil != err {
+				t.Fatalf("i: %v, err: %v", i, err)
+			}
+			res, err := r.Run(nil)
+			if nil != err {
+				t.Fatalf("i: %v, err: %v", i, err)
+			}
+			if !testEvalObject(t, res, tt.expected) {
+				t.Fatalf("i: %v, options: %v", i, len(opts))
+			}
+		}
+	}
+	s, err := Disassemble(`func f(a, b) { (a < b) ? str(a) : b - 1 }`)
+	if nil != err {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"OpGetLocal2 0 1", "OpCmpJump", "OpCallBuiltin", "OpSubConst"} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("expect `%v` in\n%v", want, s)
+		}
+	}
+}
+
+func TestRegisterVM(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+		typ      RunnableType
+	}{
+		{`func f(x) { (x < 2) ? x : f(x - 1) + f(x - 2) }; f(10)`, 55, RunnableTypeRegisterVM},
+		{`reduce(map([1, 2, 3], func(i, x) { x * x }), func(acc, x) { acc + x }, 0)`, 14, RunnableTypeRegisterVM},
+		{`filter(range(6, func(i) { i }), func(i, x) { x % 2 == 0 })`, []int64{0, 2, 4}, RunnableTypeRegisterVM},
+		{`func add(a) { func(b) { a + b } }; add(1)(2)`, 3, RunnableTypeRegisterVM},
+		{"const s = \"b\"; `a${s}c${s.len()}`", "abc1", RunnableTypeRegisterVM},
+		{`const h = {"a": [1, 2]}; h?.["b"]?.[0] ?? h["a"][1:][0]`, 2, RunnableTypeRegisterVM},
+		{`func f(x) { x?.len().y[0] ?? 1 }; f(null) + f([1]?.[5])`, 2, RunnableTypeRegisterVM},
+		{`match 2 { 1 => "a", _ => "b" }`, "b", RunnableTypeVM},
+		{`struct P { x }; P(1).x`, 1, RunnableTypeVM},
+		{`func f(x, y = 1) { x + y }; f(1)`, 2, RunnableTypeVM},
+	}
+	for i, tt := range tests {
+		for _, opts := range [][]Option{{Register()}, {Register(), NoOptimize()}} {
+			r, err := NewStateWithOptions(tt.input, opts...)
+			if nil != err {
+				t.Fatalf("i: %v, err: %v", i, err)
+			}
+			if tt.typ != r.Type() {
+				t.Fatalf("i: %v, expect type %v, got %v", i, tt.typ, r.Type())
+			}
+			res, err := r.Run(nil)
+			if nil != err {
+				t.Fatalf("i: %v, err: %v", i, err)
+			}
+			if !testEvalObject(t, res, tt.expected) {
+				t.Fatalf("i: %v, options: %v", i, len(opts))
+			}
+		}
+	}
+	r, err := NewStateWithOptions(`func f(x) { x }; f(1, 2)`, Register())
+	if nil != err {
+		t.Fatal(err)
+	}
+	if _, err := r.Run(nil); nil == err {
+		t.Fatal("expect wrong number of arguments")
+	}
+}
+
+func TestAllocs(t *testing.T) {
+	add := &token.Token{Type: token.ADD, Literal: "+"}
+	lt := &token.Token{Type: token.LT, Literal: "<"}
+	a, b := object.NewInteger(1), object.NewInteger(2)
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{"add", func() { a.Calc(add, b) }},
+		{"lt", func() { a.Calc(lt, b) }},
+		{"not", func() { a.CallMember(object.FnNot, nil) }},
+		{"neg", func() { b.CallMember(object.FnNeg, nil) }},
+		{"bool", func() { object.True.CallMember(object.FnNot, nil) }},
+	}
+	for _, tt := range tests {
+		if n := testing.AllocsPerRun(100, tt.fn); n > 0 {
+			t.Fatalf("%v: expect no allocation, got %v", tt.name, n)
+		}
+	}
+	if object.NewInteger(7) != object.NewInteger(7) || object.NewBoolean(true) != object.True {
+		t.Fatal("expect small integers and booleans to be shared")
+	}
+	r, err := NewState(`const a = [1, 2, 3]; loop(2, func(i) { a.len() }); range(3, func(i) { i + 1 })`)
+	if nil != err {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		res, err := r.Run(nil)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if !testIntegerSliceObject(t, res, []int64{1, 2, 3}) {
+			t.Fatalf("round %v", i)
+		}
+	}
+	if object.NewInteger(0).String() != "0" || object.NewInteger(1).String() != "1" {
+		t.Fatal("expect the shared integers to be unchanged")
+	}
+}
+
+func TestInlineCache(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`func f(x) { x.len() }; [f([1, 2]), f("abc"), f({"a": 1}), f(1..5), f([])]`, []int64{2, 3, 1, 5, 0}},
+		{`map([[1], "ab", [1, 2, 3], "abcd"], func(i, x) { x.len() + i })`, []int64{1, 3, 5, 7}},
+		{`struct P { len }; func (p P) first() { 10 }; func f(x) { x.len() + x.first() }; f([1, 2]) + f(P(func() { 5 }))`, 18},
+		{`func f(x) { x?.first() ?? -1 }; [f([3]), f(null), f(1..2)]`, []int64{3, -1, 1}},
+	}
+	testAllBackends(t, "", nil, tests)
+	r, err := NewState(`func f(x) { x.len() }; f(1)`)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if _, err := r.Run(nil); nil == err || !strings.Contains(err.Error(), "no attribute 'len'") {
+		t.Fatalf("expect no attribute error, got: %v", err)
+	}
+	cache := &object.InlineCache{}
+	arr := object.NewArray(object.Objects{object.NewInteger(1)})
+	if n := testing.AllocsPerRun(100, func() { cache.Call(arr, object.FnLen, nil) }); n > 0 {
+		t.Fatalf("expect no allocation, got %v", n)
+	}
+}
+
+// wideProgram : more than 255 args, locals and frees, and functions whose jumps are beyond 2 bytes
+func wideProgram(n int, terms int) string {
+	args, vals := []string{}, []string{}
+	for i := 0; i < n; i++ {
+		args = append(args, fmt.Sprintf("a%v", i))
+		vals = append(vals, fmt.Sprintf("%v", i))
+	}
+	big := "x" + strings.Repeat(" + 1 + x", terms)
+	return fmt.Sprintf(`
+	func f(%v) { a%v - a0 };
+	func g(%v) { func() { %v } };
+	func h(x) { (x > 0) ? (%v) : -1 };
+	func m(x) { match x { 0 => "zero", 1 => %v, _ => "other" } };
+	[f(%v), g(%v)(), h(1), h(0), m(0), m(1), m(2)]
+	`, strings.Join(args, ", "), n-1, strings.Join(args, ", "), strings.Join(args, " + "), big, big,
+		strings.Join(vals, ", "), strings.Join(vals, ", "))
+}
+
+// constsProgram : more than 65535 constants, which are not folded since x is unknown
+func constsProgram(n int) string {
+	var b strings.Builder
+	b.WriteString("func f(x) { x")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, " + %v", i)
+	}
+	b.WriteString(" }; f(0)")
+	return b.String()
+}
+
+func TestWideOperands(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{wideProgram(300, 10000), "[299, 44850, 20001, -1, zero, 20001, other]"},
+		{constsProgram(66000), "2177967000"},
+	}
+	for i, tt := range tests {
+		for j, fn := range backends {
+			r, err := fn(tt.input)
+			if nil != err {
+				t.Fatalf("i: %v, j: %v, err: %v", i, j, err)
+			}
+			res, err := r.Run(nil)
+			if nil != err {
+				t.Fatalf("i: %v, j: %v, err: %v", i, j, err)
+			}
+			if tt.expected != res.String() {
+				t.Fatalf("i: %v, j: %v, type: %v, want: %v, got: %v", i, j, r.Type(), tt.expected, res)
+			}
+		}
+	}
+}
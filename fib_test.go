@@ -23,6 +23,33 @@ const (
 	};
 	fib(3);
 	`
+
+	// go test -run NONE -bench Fib
+	BENCH_FIB_CODE = `
+	func fib(x) {
+		(x < 2) ? x : fib(x - 1) + fib(x - 2)
+	};
+	fib(20);
+	`
+
+	// go test -run NONE -bench State
+	BENCH_MAPREDUCE_CODE = `
+	const xs = range(1000, func(i) { i });
+	reduce(
+		filter(map(xs, func(i, x) { x * x + i }), func(i, x) { x % 3 == 0 }),
+		func(acc, x) { acc + x },
+		0
+	);
+	`
+
+	BENCH_STRING_CODE = `
+	func pad(s, n) { (s.len() < n) ? pad(s + ".", n) : s };
+	reduce(
+		range(200, func(i) { ` + "`${pad(str(i), 8)}:${type(i)}`" + ` }),
+		func(acc, x) { (acc.len() < 4096) ? acc + x : x },
+		""
+	);
+	`
 )
 
 var (
@@ -46,6 +73,20 @@ func newVM(node ast.Node) vm.VM {
 	return vm.New(c.Bytecode(), c.Constants())
 }
 
+// newBytecode : the compiled code, the peephole pass is applied if peephole is true
+func newBytecode(code string, peephole bool) (compiler.Bytecode, object.Objects) {
+	c := compiler.New()
+	if err := c.Compile(newAst(code)); nil != err {
+		panic(err)
+	}
+	if peephole {
+		if err := c.Peephole(); nil != err {
+			panic(err)
+		}
+	}
+	return c.Bytecode(), c.Constants()
+}
+
 func TestFibExpr(t *testing.T) {
 	r, err := BENCH_AST.Eval(object.NewEnv(nil))
 	if err != nil {
@@ -62,3 +103,105 @@ func TestFibVM(t *testing.T) {
 	r := BENCH_VM.LastPopped()
 	t.Logf("result: %v", r)
 }
+
+func TestFibPeephole(t *testing.T) {
+	for _, peephole := range []bool{false, true} {
+		b, consts := newBytecode(BENCH_FIB_CODE, peephole)
+		machine := vm.New(b, consts)
+		if err := machine.Run(nil); nil != err {
+			t.Fatal(err)
+		}
+		if r := machine.LastPopped(); "6765" != r.String() {
+			t.Fatalf("peephole %v, expect 6765, got %v", peephole, r)
+		}
+	}
+}
+
+func BenchmarkFibExpr(b *testing.B) {
+	node := newAst(BENCH_FIB_CODE)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := node.Eval(object.NewEnv(nil)); nil != err {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkFibVM(b *testing.B, peephole bool) {
+	ins, consts := newBytecode(BENCH_FIB_CODE, peephole)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := vm.New(ins, consts).Run(nil); nil != err {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFibVM(b *testing.B) {
+	benchmarkFibVM(b, false)
+}
+
+func BenchmarkFibVMPeephole(b *testing.B) {
+	benchmarkFibVM(b, true)
+}
+
+// TestRegisterVMBench : the benchmark scripts give the same results on the interpreter and the register vm
+func TestRegisterVMBench(t *testing.T) {
+	for _, code := range []string{BENCH_FIB_CODE, BENCH_MAPREDUCE_CODE, BENCH_STRING_CODE} {
+		want, err := newAst(code).Eval(object.NewEnv(nil))
+		if nil != err {
+			t.Fatal(err)
+		}
+		r, err := NewStateWithOptions(code, Register())
+		if nil != err {
+			t.Fatal(err)
+		}
+		if RunnableTypeRegisterVM != r.Type() {
+			t.Fatalf("expect the register vm, got %v", r.Type())
+		}
+		got, err := r.Run(nil)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if want.String() != got.String() {
+			t.Fatalf("want %v, got %v", want, got)
+		}
+	}
+}
+
+// benchmarkState : the code is compiled and run by a new state in every round
+func benchmarkState(b *testing.B, code string, opts ...Option) {
+	for i := 0; i < b.N; i++ {
+		r, err := NewStateWithOptions(code, opts...)
+		if nil != err {
+			b.Fatal(err)
+		}
+		if _, err := r.Run(nil); nil != err {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStateFib(b *testing.B) {
+	benchmarkState(b, BENCH_FIB_CODE)
+}
+
+func BenchmarkStateFibRegister(b *testing.B) {
+	benchmarkState(b, BENCH_FIB_CODE, Register())
+}
+
+func BenchmarkStateMapReduce(b *testing.B) {
+	benchmarkState(b, BENCH_MAPREDUCE_CODE)
+}
+
+func BenchmarkStateMapReduceRegister(b *testing.B) {
+	benchmarkState(b, BENCH_MAPREDUCE_CODE, Register())
+}
+
+func BenchmarkStateString(b *testing.B) {
+	benchmarkState(b, BENCH_STRING_CODE)
+}
+
+func BenchmarkStateStringRegister(b *testing.B) {
+	benchmarkState(b, BENCH_STRING_CODE, Register())
+}
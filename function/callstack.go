@@ -42,7 +42,7 @@ func GetFunc() string {
 // @newErr: could be nil
 func MakeError(lastErr error, newErr error, skip int) error {
 	callstack := GetCallStack(skip, newErr)
-	return fmt.Errorf("%v|%v", callstack.ToString(), lastErr.Error())
+	return fmt.Errorf("%v|%w", callstack.ToString(), lastErr)
 }
 
 func NewError(err error) error {
@@ -107,6 +107,13 @@ func parseNumber(s string) (object.Object, string, error) {
 	s = s[i:]
 	v, err := strconv.ParseInt(ns, 10, 64)
 	if nil != err {
+		if errors.Is(err, strconv.ErrRange) {
+			r, err := object.ParseInteger(ns, 10)
+			if nil != err {
+				return nil, s, function.NewError(err)
+			}
+			return r, s, nil
+		}
 		return nil, s, function.NewError(err)
 	}
 	return object.NewInteger(v), s, nil
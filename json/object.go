@@ -23,9 +23,9 @@ func (this *objectDecoder) parseObject(s string, depth int, maxDepth int) (objec
 	if len(s) == 0 {
 		return nil, s, function.NewError(errMissingObjectEnd)
 	}
-	m := object.HashMap{}
+	m := object.NewOrderedHash()
 	if s[0] == '}' {
-		return object.NewHash(m), s[1:], nil
+		return m, s[1:], nil
 	}
 	var key object.Object
 	var val object.Object
@@ -39,10 +39,6 @@ func (this *objectDecoder) parseObject(s string, depth int, maxDepth int) (objec
 		if nil != err {
 			return nil, s, function.NewError(err)
 		}
-		h, err := key.Hash()
-		if nil != err {
-			return nil, s, function.NewError(err)
-		}
 		s = skipWS(s)
 		if len(s) == 0 || s[0] != ':' {
 			return nil, s, function.NewError(errObjectKeySeparator)
@@ -54,7 +50,9 @@ func (this *objectDecoder) parseObject(s string, depth int, maxDepth int) (objec
 			return nil, s, function.NewError(err)
 		}
 
-		m[*h] = &object.HashPair{Key: key, Value: val}
+		if err := m.Set(key, val); nil != err {
+			return nil, s, function.NewError(err)
+		}
 
 		s = skipWS(s)
 		if len(s) == 0 {
@@ -69,5 +67,5 @@ func (this *objectDecoder) parseObject(s string, depth int, maxDepth int) (objec
 		}
 		return nil, s, function.NewError(errObjectValSeparator)
 	}
-	return object.NewHash(m), s[1:], nil
+	return m, s[1:], nil
 }
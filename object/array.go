@@ -10,17 +10,7 @@ import (
 )
 
 func NewArray(items Objects) Object {
-	obj := &Array{Items: items}
-	obj.fns = objectBuiltins{
-		FnLen:   obj.builtinLen,
-		FnIndex: obj.builtinIndex,
-		FnNot:   obj.builtinNot,
-		FnFirst: obj.builtinFirst,
-		FnLast:  obj.builtinLast,
-		FnTail:  obj.builtinTail,
-		FnPush:  obj.builtinPush,
-	}
-	return obj
+	return &Array{Items: items}
 }
 
 // Array : implement Object
@@ -29,16 +19,42 @@ type Array struct {
 	Items Objects
 }
 
+// arrayMethods : builtin methods of Array
+var arrayMethods = methods{
+	FnLen:      arrayMethod((*Array).builtinLen),
+	FnIndex:    arrayMethod((*Array).builtinIndex),
+	FnNot:      arrayMethod((*Array).builtinNot),
+	FnFirst:    arrayMethod((*Array).builtinFirst),
+	FnLast:     arrayMethod((*Array).builtinLast),
+	FnTail:     arrayMethod((*Array).builtinTail),
+	FnPush:     arrayMethod((*Array).builtinPush),
+	FnSlice:    arrayMethod((*Array).builtinSlice),
+	FnContains: arrayMethod((*Array).builtinContains),
+}
+
+func arrayMethod(fn func(*Array, Objects) (Object, error)) method {
+	return func(this Object, args Objects) (Object, error) { return fn(this.(*Array), args) }
+}
+
 func (this *Array) String() string {
+	s, _ := this.format(plainString)
+	return s
+}
+
+func (this *Array) format(fn stringer) (string, error) {
 	var out bytes.Buffer
 	items := []string{}
 	for _, v := range this.Items {
-		items = append(items, v.String())
+		s, err := fn(v)
+		if nil != err {
+			return "", err
+		}
+		items = append(items, s)
 	}
 	out.WriteString("[")
 	out.WriteString(strings.Join(items, ", "))
 	out.WriteString("]")
-	return out.String()
+	return out.String(), nil
 }
 
 func (this *Array) Dump() (interface{}, error) {
@@ -61,11 +77,15 @@ func (this *Array) Calc(op *token.Token, right Object) (Object, error) {
 }
 
 func (this *Array) CallMember(name string, args Objects) (Object, error) {
-	return callMember(this, this.fns, name, args)
+	return callMember(this, arrayMethods, name, args)
 }
 
 func (this *Array) GetMember(name string) (Object, error) {
-	return getMember(this, this.fns, name)
+	return getMember(this, arrayMethods, name)
+}
+
+func (this *Array) builtinMethods() methods {
+	return arrayMethods
 }
 
 func (this *Array) AsArray() (*Array, error) {
@@ -114,7 +134,8 @@ func (this *Array) equalArray(other *Array) error {
 	for i := 0; i < szSrc; i++ {
 		src := this.Items[i]
 		dst := other.Items[i]
-		if err := dst.equal(src); nil != err {
+		// the item of the left array first, for the __eq__ hook
+		if err := src.equal(dst); nil != err {
 			return err
 		}
 	}
@@ -181,6 +202,27 @@ func (this *Array) builtinIndex(args Objects) (Object, error) {
 	return indexofArray(this.Items, idx)
 }
 
+func (this *Array) builtinSlice(args Objects) (Object, error) {
+	return sliceArray(this.Items, args)
+}
+
+func (this *Array) builtinContains(args Objects) (Object, error) {
+	argc := len(args)
+	if argc != 1 {
+		return Nil, fmt.Errorf("contains() takes exactly one argument (%v given)", argc)
+	}
+	for _, item := range this.Items {
+		err := args[0].equal(item)
+		if nil == err {
+			return True, nil
+		}
+		if isHookError(err) {
+			return Nil, err
+		}
+	}
+	return False, nil
+}
+
 func (this *Array) builtinNot(args Objects) (Object, error) {
 	argc := len(args)
 	if argc != 0 {
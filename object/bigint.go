@@ -0,0 +1,253 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/token"
+)
+
+func newBigInt(v *big.Int) *BigInt {
+	return &BigInt{
+		Value: v,
+	}
+}
+
+// NewBigInt : integer if v fits in int64, otherwise bigint
+func NewBigInt(v *big.Int) Object {
+	if v.IsInt64() {
+		return NewInteger(v.Int64())
+	}
+	return newBigInt(v)
+}
+
+// ParseInteger : integer or bigint, base is the same as strconv.ParseInt
+func ParseInteger(s string, base int) (Object, error) {
+	v, ok := new(big.Int).SetString(s, base)
+	if !ok {
+		return Nil, fmt.Errorf("could not parse %v as integer", s)
+	}
+	return NewBigInt(v), nil
+}
+
+// BigInt : implement Object, always out of the int64 range
+type BigInt struct {
+	defaultObject
+	Value *big.Int
+}
+
+// bigIntMethods : builtin methods of BigInt
+var bigIntMethods = methods{
+	FnNot:    bigIntMethod((*BigInt).builtinNot),
+	FnNeg:    bigIntMethod((*BigInt).builtinNeg),
+	FnInt:    bigIntMethod((*BigInt).builtinInt),
+	FnBitNot: bigIntMethod((*BigInt).builtinBitNot),
+}
+
+func bigIntMethod(fn func(*BigInt, Objects) (Object, error)) method {
+	return func(this Object, args Objects) (Object, error) { return fn(this.(*BigInt), args) }
+}
+
+// Succ : this + 1 as a new bigint, a loop counter stays a bigint once Integer.Succ promotes it
+func (this *BigInt) Succ() (Object, error) {
+	return calcBig(tokenAdd, this, newInteger(1))
+}
+
+func (this *BigInt) String() string {
+	return this.Value.String()
+}
+
+func (this *BigInt) Hash() (*HashKey, error) {
+	return &HashKey{Type: this.getType(), Value: hash64(this.Value.Bytes())}, nil
+}
+
+func (this *BigInt) Dump() (interface{}, error) {
+	return this.Value, nil
+}
+
+func (this *BigInt) Calc(op *token.Token, right Object) (Object, error) {
+	return right.calcBigInt(op, this)
+}
+
+func (this *BigInt) CallMember(name string, args Objects) (Object, error) {
+	return callMember(this, bigIntMethods, name, args)
+}
+
+func (this *BigInt) GetMember(name string) (Object, error) {
+	return getMember(this, bigIntMethods, name)
+}
+
+func (this *BigInt) builtinMethods() methods {
+	return bigIntMethods
+}
+
+func (this *BigInt) True() bool {
+	return 0 != this.Value.Sign()
+}
+
+func (this *BigInt) getType() ObjectType {
+	return objectTypeBigInt
+}
+
+func (this *BigInt) asInteger() (int64, error) {
+	return 0, fmt.Errorf("bigint `%v` overflows int64", this.String())
+}
+
+func (this *BigInt) equal(other Object) error {
+	return other.equalBigInt(this)
+}
+
+func (this *BigInt) equalBigInt(other *BigInt) error {
+	if 0 != this.Value.Cmp(other.Value) {
+		return fmt.Errorf("value mismatch, this: %v, other: %v", this.Value, other.Value)
+	}
+	return nil
+}
+
+func (this *BigInt) calcInteger(op *token.Token, left *Integer) (Object, error) {
+	return calcBig(op, left, this)
+}
+
+func (this *BigInt) calcBigInt(op *token.Token, left *BigInt) (Object, error) {
+	return calcBig(op, left, this)
+}
+
+func (this *BigInt) calcBoolean(op *token.Token, left *Boolean) (Object, error) {
+	return this.calcInteger(op, toInteger(left.Value))
+}
+
+func (this *BigInt) calcNull(op *token.Token, left *Null) (Object, error) {
+	return infixNull(op, this, function.GetFunc())
+}
+
+// builtin
+func (this *BigInt) builtinNot(args Objects) (Object, error) {
+	argc := len(args)
+	if argc != 0 {
+		return False, fmt.Errorf("not() takes no argument (%v given), (`%v`)", argc, this.String())
+	}
+	return ToBoolean(!this.True()), nil
+}
+
+func (this *BigInt) builtinNeg(args Objects) (Object, error) {
+	argc := len(args)
+	if argc != 0 {
+		return NewInteger(0), fmt.Errorf("neg() takes no argument (%v given), (`%v`)", argc, this.String())
+	}
+	return NewBigInt(new(big.Int).Neg(this.Value)), nil
+}
+
+func (this *BigInt) builtinInt(args Objects) (Object, error) {
+	argc := len(args)
+	if argc != 0 {
+		return NewInteger(0), fmt.Errorf("int() takes no argument (%v given), (`%v`)", argc, this.String())
+	}
+	return this, nil
+}
+
+func (this *BigInt) builtinBitNot(args Objects) (Object, error) {
+	argc := len(args)
+	if argc != 0 {
+		return NewInteger(0), fmt.Errorf("bitnot() takes no argument (%v given), (`%v`)", argc, this.String())
+	}
+	return NewBigInt(new(big.Int).Not(this.Value)), nil
+}
+
+func toBig(v Object) *big.Int {
+	switch obj := v.(type) {
+	case *Integer:
+		return big.NewInt(obj.Value)
+	case *BigInt:
+		return obj.Value
+	}
+	return new(big.Int)
+}
+
+// calcBig : arbitrary precision arithmetic for integer & bigint, division truncates as int64 does
+// MaxBigIntBits : the longest bigint which `**` makes, in bits
+const MaxBigIntBits = 1 << 24
+
+func calcBig(op *token.Token, left Object, right Object) (Object, error) {
+	switch op.Type {
+	case token.AND:
+		if !left.True() {
+			return left, nil
+		}
+		return right, nil
+	case token.OR:
+		if left.True() {
+			return left, nil
+		}
+		return right, nil
+	}
+	l, r := toBig(left), toBig(right)
+	switch op.Type {
+	case token.ADD:
+		return NewBigInt(new(big.Int).Add(l, r)), nil
+	case token.SUB:
+		return NewBigInt(new(big.Int).Sub(l, r)), nil
+	case token.MUL:
+		return NewBigInt(new(big.Int).Mul(l, r)), nil
+	case token.DIV:
+		if 0 == r.Sign() {
+			return Nil, function.NewError(errDivisionByZero)
+		}
+		return NewBigInt(new(big.Int).Quo(l, r)), nil
+	case token.MOD:
+		if 0 == r.Sign() {
+			return Nil, function.NewError(errDivisionByZero)
+		}
+		return NewBigInt(new(big.Int).Rem(l, r)), nil
+	case token.LT:
+		return ToBoolean(l.Cmp(r) < 0), nil
+	case token.LEQ:
+		return ToBoolean(l.Cmp(r) <= 0), nil
+	case token.GT:
+		return ToBoolean(l.Cmp(r) > 0), nil
+	case token.GEQ:
+		return ToBoolean(l.Cmp(r) >= 0), nil
+	case token.EQ:
+		return ToBoolean(l.Cmp(r) == 0), nil
+	case token.NEQ:
+		return ToBoolean(l.Cmp(r) != 0), nil
+	case token.BITAND:
+		return NewBigInt(new(big.Int).And(l, r)), nil
+	case token.BITOR:
+		return NewBigInt(new(big.Int).Or(l, r)), nil
+	case token.BITXOR:
+		return NewBigInt(new(big.Int).Xor(l, r)), nil
+	case token.SHL, token.SHR:
+		n, err := bigCount(r, "shift count")
+		if nil != err {
+			return Nil, err
+		}
+		if op.TypeIs(token.SHL) {
+			return NewBigInt(new(big.Int).Lsh(l, n)), nil
+		}
+		return NewBigInt(new(big.Int).Rsh(l, n)), nil
+	case token.POW:
+		n, err := bigCount(r, "exponent")
+		if nil != err {
+			return Nil, err
+		}
+		// |l| >= 2 ** (BitLen - 1), so the result has at least (BitLen - 1) * n + 1 bits
+		if l.BitLen() > 1 && uint64(l.BitLen()-1)*uint64(n)+1 > MaxBigIntBits {
+			return Nil, fmt.Errorf("exponent too large: %v, result > %v bits", r, MaxBigIntBits)
+		}
+		return NewBigInt(new(big.Int).Exp(l, r, nil)), nil
+	default:
+		return Nil, unsupportedOp(function.GetFunc(), op, right)
+	}
+}
+
+func bigCount(v *big.Int, name string) (uint, error) {
+	if v.Sign() < 0 {
+		return 0, fmt.Errorf("negative %v: %v", name, v)
+	}
+	if !v.IsUint64() || v.Uint64() > math.MaxUint32 {
+		return 0, fmt.Errorf("%v too large: %v", name, v)
+	}
+	return uint(v.Uint64()), nil
+}
@@ -8,19 +8,14 @@ import (
 )
 
 func newBoolean(v bool) *Boolean {
-	obj := &Boolean{
+	return &Boolean{
 		Value: v,
 	}
-	obj.fns = objectBuiltins{
-		FnNot: obj.builtinNot,
-		FnNeg: obj.builtinNeg,
-		FnInt: obj.builtinInt,
-	}
-	return obj
 }
 
+// NewBoolean : True or False, which are shared
 func NewBoolean(v bool) Object {
-	return newBoolean(v)
+	return ToBoolean(v)
 }
 
 // Boolean : implement Object
@@ -29,6 +24,17 @@ type Boolean struct {
 	Value bool
 }
 
+// booleanMethods : builtin methods of Boolean
+var booleanMethods = methods{
+	FnNot: booleanMethod((*Boolean).builtinNot),
+	FnNeg: booleanMethod((*Boolean).builtinNeg),
+	FnInt: booleanMethod((*Boolean).builtinInt),
+}
+
+func booleanMethod(fn func(*Boolean, Objects) (Object, error)) method {
+	return func(this Object, args Objects) (Object, error) { return fn(this.(*Boolean), args) }
+}
+
 func (this *Boolean) String() string {
 	return fmt.Sprintf("%v", this.Value)
 }
@@ -46,11 +52,15 @@ func (this *Boolean) Calc(op *token.Token, right Object) (Object, error) {
 }
 
 func (this *Boolean) CallMember(name string, args Objects) (Object, error) {
-	return callMember(this, this.fns, name, args)
+	return callMember(this, booleanMethods, name, args)
 }
 
 func (this *Boolean) GetMember(name string) (Object, error) {
-	return getMember(this, this.fns, name)
+	return getMember(this, booleanMethods, name)
+}
+
+func (this *Boolean) builtinMethods() methods {
+	return booleanMethods
 }
 
 func (this *Boolean) True() bool {
@@ -122,13 +132,9 @@ func (this *Boolean) calcNull(op *token.Token, left *Null) (Object, error) {
 func (this *Boolean) builtinNot(args Objects) (Object, error) {
 	argc := len(args)
 	if argc != 0 {
-		return newBoolean(false), fmt.Errorf("not() takes no argument (%v given), (`%v`)", argc, this.String())
-	}
-	if this.Value {
-		return newBoolean(false), nil
-	} else {
-		return newBoolean(true), nil
+		return False, fmt.Errorf("not() takes no argument (%v given), (`%v`)", argc, this.String())
 	}
+	return ToBoolean(!this.Value), nil
 }
 
 func (this *Boolean) builtinNeg(args Objects) (Object, error) {
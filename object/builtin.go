@@ -8,14 +8,10 @@ import (
 )
 
 func NewBuiltin(fn BuiltinFunction, name string) Object {
-	obj := &Builtin{
+	return &Builtin{
 		Fn:   fn,
 		Name: name,
 	}
-	obj.fns = objectBuiltins{
-		FnNot: obj.builtinNot,
-	}
-	return obj
 }
 
 // Builtin : implement Object
@@ -38,11 +34,15 @@ func (this *Builtin) Call(args Objects) (Object, error) {
 }
 
 func (this *Builtin) CallMember(name string, args Objects) (Object, error) {
-	return callMember(this, this.fns, name, args)
+	return callMember(this, defaultMethods, name, args)
 }
 
 func (this *Builtin) GetMember(name string) (Object, error) {
-	return getMember(this, this.fns, name)
+	return getMember(this, defaultMethods, name)
+}
+
+func (this *Builtin) builtinMethods() methods {
+	return defaultMethods
 }
 
 func (this *Builtin) getType() ObjectType {
@@ -63,8 +63,3 @@ func (this *Builtin) equalBuiltin(other *Builtin) error {
 func (this *Builtin) calcBuiltin(op *token.Token, left *Builtin) (Object, error) {
 	return compare(function.GetFunc(), this, left, op)
 }
-
-// builtin
-func (this *Builtin) builtinNot(args Objects) (Object, error) {
-	return defaultNot(this, args)
-}
@@ -8,22 +8,19 @@ import (
 	"github.com/jobs-github/escript/token"
 )
 
-func NewByteFn(ins code.Instructions, locals int) *ByteFunc {
-	obj := &ByteFunc{Ins: ins, Locals: locals}
-	obj.fns = objectBuiltins{
-		FnNot: obj.builtinNot,
-	}
-	return obj
+func NewByteFn(ins code.Instructions, arity Arity, locals int) *ByteFunc {
+	return &ByteFunc{Ins: ins, Arity: arity, Locals: locals}
 }
 
-func NewByteFunc(ins code.Instructions, locals int) Object {
-	return NewByteFn(ins, locals)
+func NewByteFunc(ins code.Instructions, arity Arity, locals int) Object {
+	return NewByteFn(ins, arity, locals)
 }
 
 // ByteFunc : implement Object
 type ByteFunc struct {
 	defaultObject
 	Ins    code.Instructions
+	Arity  // leading locals filled by the caller, the rest arg follows the named args
 	Locals int
 }
 
@@ -36,11 +33,15 @@ func (this *ByteFunc) Calc(op *token.Token, right Object) (Object, error) {
 }
 
 func (this *ByteFunc) CallMember(name string, args Objects) (Object, error) {
-	return callMember(this, this.fns, name, args)
+	return callMember(this, defaultMethods, name, args)
 }
 
 func (this *ByteFunc) GetMember(name string) (Object, error) {
-	return getMember(this, this.fns, name)
+	return getMember(this, defaultMethods, name)
+}
+
+func (this *ByteFunc) builtinMethods() methods {
+	return defaultMethods
 }
 
 func (this *ByteFunc) AsByteFunc() (*ByteFunc, error) {
@@ -67,8 +68,3 @@ func (this *ByteFunc) equalByteFunc(other *ByteFunc) error {
 func (this *ByteFunc) calcByteFunc(op *token.Token, left *ByteFunc) (Object, error) {
 	return compare(function.GetFunc(), this, left, op)
 }
-
-// builtin
-func (this *ByteFunc) builtinNot(args Objects) (Object, error) {
-	return defaultNot(this, args)
-}
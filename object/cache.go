@@ -0,0 +1,23 @@
+package object
+
+// InlineCache : the builtin method which a call site resolved for the type of its latest receiver,
+// a receiver of the same type calls it without looking up the method or binding it to an ObjectFunc
+type InlineCache struct {
+	typ ObjectType
+	fn  method
+}
+
+// Call : obj.name(args) if name is a builtin method of the type of obj, ok is false otherwise
+// (e.g. obj is a struct, whose fields and methods come first), get the member and call it instead
+func (this *InlineCache) Call(obj Object, name string, args Objects) (r Object, ok bool, err error) {
+	t := obj.getType()
+	if nil == this.fn || t != this.typ {
+		fn, found := obj.builtinMethods()[name]
+		if !found {
+			return nil, false, nil
+		}
+		this.typ, this.fn = t, fn
+	}
+	r, err = this.fn(obj, args)
+	return r, true, err
+}
@@ -7,12 +7,11 @@ import (
 	"github.com/jobs-github/escript/token"
 )
 
-func NewClosure(fn *ByteFunc, frees Objects) *Closure {
-	obj := &Closure{Fn: fn, Free: frees}
-	obj.fns = objectBuiltins{
-		FnNot: obj.builtinNot,
-	}
-	return obj
+// Invoker : runs a closure from go code (e.g. operator hooks), provided by the vm which creates the closure
+type Invoker func(fn *Closure, args Objects) (Object, error)
+
+func NewClosure(fn *ByteFunc, frees Objects, invoke Invoker) *Closure {
+	return &Closure{Fn: fn, Free: frees, invoke: invoke}
 }
 
 // In summary
@@ -39,8 +38,9 @@ func NewClosure(fn *ByteFunc, frees Objects) *Closure {
 // Closure : implement Object
 type Closure struct {
 	defaultObject
-	Fn   *ByteFunc
-	Free Objects // env
+	Fn     *ByteFunc
+	Free   Objects // env
+	invoke Invoker
 }
 
 func (this *Closure) String() string {
@@ -51,12 +51,24 @@ func (this *Closure) Calc(op *token.Token, right Object) (Object, error) {
 	return right.calcClosure(op, this)
 }
 
+// Call : the vm calls a closure by itself, this is for go code
+func (this *Closure) Call(args Objects) (Object, error) {
+	if nil == this.invoke {
+		return Nil, errNotSupportCall
+	}
+	return this.invoke(this, args)
+}
+
 func (this *Closure) CallMember(name string, args Objects) (Object, error) {
-	return callMember(this, this.fns, name, args)
+	return callMember(this, defaultMethods, name, args)
 }
 
 func (this *Closure) GetMember(name string) (Object, error) {
-	return getMember(this, this.fns, name)
+	return getMember(this, defaultMethods, name)
+}
+
+func (this *Closure) builtinMethods() methods {
+	return defaultMethods
 }
 
 func (this *Closure) True() bool {
@@ -83,8 +95,3 @@ func (this *Closure) equalClosure(other *Closure) error {
 func (this *Closure) calcClosure(op *token.Token, left *Closure) (Object, error) {
 	return compare(function.GetFunc(), this, left, op)
 }
-
-// builtin
-func (this *Closure) builtinNot(args Objects) (Object, error) {
-	return defaultNot(this, args)
-}
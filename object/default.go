@@ -23,6 +23,12 @@ var (
 	errNotSupportEqualByteFunc   = errors.New("not support equalByteFunc func")
 	errNotSupportEqualClosure    = errors.New("not support equalClosure func")
 	errNotSupportEqualObjectFunc = errors.New("not support equalObjectFunc func")
+	errNotSupportEqualError      = errors.New("not support equalError func")
+	errNotSupportEqualBigInt     = errors.New("not support equalBigInt func")
+	errNotSupportEqualInterval   = errors.New("not support equalInterval func")
+	errNotSupportEqualStructType = errors.New("not support equalStructType func")
+	errNotSupportEqualStruct     = errors.New("not support equalStruct func")
+	errNotSupportEqualMethod     = errors.New("not support equalMethod func")
 
 	errInvalidOperation = errors.New("invalid operation")
 	errNotSupportCalc   = errors.New("not support calc func")
@@ -31,12 +37,11 @@ var (
 	errTypeIsNotClosure  = errors.New("type is not Closure")
 	errTypeIsNotInt      = errors.New("type is not Integer")
 	errTypeIsNotArray    = errors.New("type is not Array")
+	errTypeIsNotMethod   = errors.New("type is not Method")
 	errInvalidIndex      = errors.New("list index out of range")
 )
 
-type defaultObject struct {
-	fns objectBuiltins
-}
+type defaultObject struct{}
 
 func (this *defaultObject) Hash() (*HashKey, error) {
 	return nil, errNotSupportHash
@@ -54,12 +59,15 @@ func (this *defaultObject) Call(args Objects) (Object, error) {
 	return Nil, errNotSupportCall
 }
 
+// builtinMethods : nil if the members are not resolved by the type only, refer to InlineCache
+func (this *defaultObject) builtinMethods() methods {
+	return nil
+}
+
 func (this *defaultObject) True() bool {
 	return false
 }
 
-func (this *defaultObject) Incr() {}
-
 func (this *defaultObject) AsByteFunc() (*ByteFunc, error) {
 	return nil, errTypeIsNotByteFunc
 }
@@ -72,6 +80,10 @@ func (this *defaultObject) AsArray() (*Array, error) {
 	return nil, errTypeIsNotArray
 }
 
+func (this *defaultObject) AsMethod() (*Method, error) {
+	return nil, errTypeIsNotMethod
+}
+
 func (this *defaultObject) asInteger() (int64, error) {
 	return 0, errTypeIsNotInt
 }
@@ -167,3 +179,51 @@ func (this *defaultObject) calcClosure(op *token.Token, left *Closure) (Object,
 func (this *defaultObject) calcObjectFunc(op *token.Token, left *ObjectFunc) (Object, error) {
 	return notEqual(op)
 }
+
+func (this *defaultObject) equalError(other *Error) error {
+	return errNotSupportEqualError
+}
+
+func (this *defaultObject) calcError(op *token.Token, left *Error) (Object, error) {
+	return notEqual(op)
+}
+
+func (this *defaultObject) equalBigInt(other *BigInt) error {
+	return errNotSupportEqualBigInt
+}
+
+func (this *defaultObject) calcBigInt(op *token.Token, left *BigInt) (Object, error) {
+	return notEqual(op)
+}
+
+func (this *defaultObject) equalInterval(other *Interval) error {
+	return errNotSupportEqualInterval
+}
+
+func (this *defaultObject) calcInterval(op *token.Token, left *Interval) (Object, error) {
+	return notEqual(op)
+}
+
+func (this *defaultObject) equalStructType(other *StructType) error {
+	return errNotSupportEqualStructType
+}
+
+func (this *defaultObject) calcStructType(op *token.Token, left *StructType) (Object, error) {
+	return notEqual(op)
+}
+
+func (this *defaultObject) equalStruct(other *Struct) error {
+	return errNotSupportEqualStruct
+}
+
+func (this *defaultObject) calcStruct(op *token.Token, left *Struct) (Object, error) {
+	return notEqual(op)
+}
+
+func (this *defaultObject) equalMethod(other *Method) error {
+	return errNotSupportEqualMethod
+}
+
+func (this *defaultObject) calcMethod(op *token.Token, left *Method) (Object, error) {
+	return notEqual(op)
+}
@@ -0,0 +1,116 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/token"
+)
+
+func NewError(msg string) *Error {
+	return &Error{
+		Message: msg,
+	}
+}
+
+// ToError : convert any go error to error object, thrown error object is kept as it is
+func ToError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	for {
+		inner := errors.Unwrap(err)
+		if nil == inner {
+			break
+		}
+		err = inner
+	}
+	return NewError(err.Error())
+}
+
+// Error : implement Object & error
+type Error struct {
+	defaultObject
+	Message string
+}
+
+// errorMethods : builtin methods of Error
+var errorMethods = methods{
+	FnNot:     errorMethod((*Error).builtinNot),
+	FnMessage: errorMethod((*Error).builtinMessage),
+}
+
+func errorMethod(fn func(*Error, Objects) (Object, error)) method {
+	return func(this Object, args Objects) (Object, error) { return fn(this.(*Error), args) }
+}
+
+// Error : implement error, so that error object can be thrown
+func (this *Error) Error() string {
+	return this.Message
+}
+
+func (this *Error) String() string {
+	return this.Message
+}
+
+func (this *Error) Dump() (interface{}, error) {
+	return this.Message, nil
+}
+
+func (this *Error) Calc(op *token.Token, right Object) (Object, error) {
+	return right.calcError(op, this)
+}
+
+func (this *Error) CallMember(name string, args Objects) (Object, error) {
+	return callMember(this, errorMethods, name, args)
+}
+
+func (this *Error) GetMember(name string) (Object, error) {
+	return getMember(this, errorMethods, name)
+}
+
+func (this *Error) builtinMethods() methods {
+	return errorMethods
+}
+
+func (this *Error) True() bool {
+	return true
+}
+
+func (this *Error) getType() ObjectType {
+	return objectTypeError
+}
+
+func (this *Error) equal(other Object) error {
+	return other.equalError(this)
+}
+
+func (this *Error) equalError(other *Error) error {
+	if this.Message != other.Message {
+		return fmt.Errorf("message mismatch, this: %v, other: %v", this.Message, other.Message)
+	}
+	return nil
+}
+
+func (this *Error) calcError(op *token.Token, left *Error) (Object, error) {
+	return compare(function.GetFunc(), this, left, op)
+}
+
+// builtin
+func (this *Error) builtinNot(args Objects) (Object, error) {
+	argc := len(args)
+	if argc != 0 {
+		return Nil, fmt.Errorf("not() takes no argument (%v given), (`%v`)", argc, this.String())
+	}
+	return False, nil
+}
+
+func (this *Error) builtinMessage(args Objects) (Object, error) {
+	argc := len(args)
+	if argc != 0 {
+		return Nil, fmt.Errorf("message() takes no argument (%v given), (`%v`)", argc, this.String())
+	}
+	return NewString(this.Message), nil
+}
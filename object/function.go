@@ -7,22 +7,65 @@ import (
 	"github.com/jobs-github/escript/token"
 )
 
+// NewArity : exactly n args
+func NewArity(n int) Arity {
+	return Arity{Args: n, Required: n}
+}
+
+// Arity : number of args a function accepts
+type Arity struct {
+	Args     int  // named args, the rest arg excluded
+	Required int  // leading args without default value
+	Rest     bool // extra args are collected into an array
+}
+
+func (this *Arity) Accept(argc int) bool {
+	if argc < this.Required {
+		return false
+	}
+	return this.Rest || argc <= this.Args
+}
+
+func (this *Arity) String() string {
+	if this.Rest {
+		return fmt.Sprintf("%v+", this.Required)
+	}
+	if this.Required == this.Args {
+		return fmt.Sprintf("%v", this.Args)
+	}
+	return fmt.Sprintf("%v..%v", this.Required, this.Args)
+}
+
+// CannotSpread : only arrays and intervals can be spread into args
+func CannotSpread(v Object) error {
+	return fmt.Errorf("cannot spread %v `%v` into arguments", Typeof(v), v.String())
+}
+
+// TailCall : a call in tail position, made by the caller's loop instead of a nested call
+type TailCall struct {
+	Fn   Object
+	Args Objects
+}
+
+// EvalBody : returns either the result or the tail call to make
+type EvalBody func(env Env, argc int) (Object, *TailCall, error)
+
+// NewFunction : args holds the named args followed by the rest arg,
+// evalBody gets the number of args provided so that it can fill the missing ones
 func NewFunction(
 	name string,
 	args []string,
-	evalBody func(env Env) (Object, error),
+	arity Arity,
+	evalBody EvalBody,
 	env Env,
 ) Object {
-	obj := &Function{
+	return &Function{
 		Name:     name,
 		Args:     args,
+		Arity:    arity,
 		EvalBody: evalBody,
 		Env:      env,
 	}
-	obj.fns = objectBuiltins{
-		FnNot: obj.builtinNot,
-	}
-	return obj
 }
 
 // Function : implement Object
@@ -30,7 +73,8 @@ type Function struct {
 	defaultObject
 	Name     string
 	Args     []string
-	EvalBody func(env Env) (Object, error)
+	Arity    Arity
+	EvalBody EvalBody
 	Env      Env
 }
 
@@ -42,25 +86,63 @@ func (this *Function) Calc(op *token.Token, right Object) (Object, error) {
 	return right.calcFunction(op, this)
 }
 
+// Call : trampoline, tail calls between functions do not grow the go stack
 func (this *Function) Call(args Objects) (Object, error) {
-	if len(args) != len(this.Args) {
-		err := fmt.Errorf("%v args provided, but %v args required, (`%v`)", len(args), len(this.Args), this.String())
-		return Nil, err
+	fn := this
+	for {
+		r, tail, err := fn.call(args)
+		if nil != err {
+			return Nil, err
+		}
+		if nil == tail {
+			return r, nil
+		}
+		next, ok := tail.Fn.(*Function)
+		if !ok {
+			return tail.Fn.Call(tail.Args)
+		}
+		fn, args = next, tail.Args
 	}
-	innerEnv := newFunctionEnv(this.Env, this.Args, args)
-	evaluated, err := this.EvalBody(innerEnv)
+}
+
+func (this *Function) call(args Objects) (Object, *TailCall, error) {
+	innerEnv, err := this.bindArgs(args)
 	if nil != err {
-		return Nil, err
+		return Nil, nil, err
+	}
+	return this.EvalBody(innerEnv, len(args))
+}
+
+// bindArgs : kept apart from call, so that a recursion keeps a small go stack frame per call
+func (this *Function) bindArgs(args Objects) (Env, error) {
+	argc := len(args)
+	if !this.Arity.Accept(argc) {
+		err := fmt.Errorf("%v args provided, but %v args required, (`%v`)", argc, this.Arity.String(), this.String())
+		return nil, err
+	}
+	n := this.Arity.Args
+	if argc < n {
+		n = argc
 	}
-	return evaluated, nil
+	innerEnv := newFunctionEnv(this.Env, this.Args[:n], args[:n])
+	if this.Arity.Rest {
+		rest := make(Objects, argc-n)
+		copy(rest, args[n:])
+		innerEnv.Set(this.Args[this.Arity.Args], NewArray(rest))
+	}
+	return innerEnv, nil
 }
 
 func (this *Function) CallMember(name string, args Objects) (Object, error) {
-	return callMember(this, this.fns, name, args)
+	return callMember(this, defaultMethods, name, args)
 }
 
 func (this *Function) GetMember(name string) (Object, error) {
-	return getMember(this, this.fns, name)
+	return getMember(this, defaultMethods, name)
+}
+
+func (this *Function) builtinMethods() methods {
+	return defaultMethods
 }
 
 func (this *Function) getType() ObjectType {
@@ -81,8 +163,3 @@ func (this *Function) equalFunction(other *Function) error {
 func (this *Function) calcFunction(op *token.Token, left *Function) (Object, error) {
 	return compare(function.GetFunc(), this, left, op)
 }
-
-// builtin
-func (this *Function) builtinNot(args Objects) (Object, error) {
-	return defaultNot(this, args)
-}
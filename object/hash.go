@@ -2,6 +2,7 @@ package object
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -26,50 +27,120 @@ func (this *HashMap) set(k *HashKey, v *HashPair) {
 	(*this)[*k] = v
 }
 
-func (this *HashMap) keys() Objects {
-	arr := Objects{}
-	for _, v := range *this {
-		arr = append(arr, v.Key)
+func (this *HashMap) sortedKeys() HashKeys {
+	arr := HashKeys{}
+	for k, _ := range *this {
+		arr = append(arr, k)
 	}
 	sort.SliceStable(arr, func(i, j int) bool {
-		if r, err := arr[i].Calc(&token.Token{Type: token.LT}, arr[j]); nil != err {
+		l := (*this)[arr[i]].Key
+		r := (*this)[arr[j]].Key
+		if v, err := l.Calc(&token.Token{Type: token.LT}, r); nil != err {
 			return false
 		} else {
-			return r.True()
+			return v.True()
 		}
 	})
 	return arr
 }
 
+// HashKeys : keys of hash in insertion order
+type HashKeys []HashKey
+
+// NewHash : pairs without order are sorted by key
 func NewHash(pairs HashMap) Object {
-	obj := &Hash{
+	obj := newHash(pairs, pairs.sortedKeys())
+	return obj
+}
+
+// NewOrderedHash : empty hash, pairs are kept in insertion order by Set
+func NewOrderedHash() *Hash {
+	return newHash(HashMap{}, HashKeys{})
+}
+
+func newHash(pairs HashMap, keys HashKeys) *Hash {
+	return &Hash{
 		Pairs: pairs,
+		Keys:  keys,
 	}
-	obj.fns = objectBuiltins{
-		FnLen:   obj.builtinLen,
-		FnIndex: obj.builtinIndex,
-		FnNot:   obj.builtinNot,
-		FnKeys:  obj.builtinKeys,
-	}
-	return obj
 }
 
 // Hash : implement Object
 type Hash struct {
 	defaultObject
 	Pairs HashMap
+	Keys  HashKeys
+}
+
+// hashMethods : builtin methods of Hash
+var hashMethods = methods{
+	FnLen:      hashMethod((*Hash).builtinLen),
+	FnIndex:    hashMethod((*Hash).builtinIndex),
+	FnNot:      hashMethod((*Hash).builtinNot),
+	FnKeys:     hashMethod((*Hash).builtinKeys),
+	FnContains: hashMethod((*Hash).builtinContains),
+}
+
+func hashMethod(fn func(*Hash, Objects) (Object, error)) method {
+	return func(this Object, args Objects) (Object, error) { return fn(this.(*Hash), args) }
+}
+
+// Set : an existing key keeps its position, a new key goes last
+func (this *Hash) Set(key Object, val Object) error {
+	h, err := key.Hash()
+	if nil != err {
+		return err
+	}
+	if _, ok := this.Pairs.get(h); !ok {
+		this.Keys = append(this.Keys, *h)
+	}
+	this.Pairs.set(h, &HashPair{Key: key, Value: val})
+	return nil
+}
+
+// Get : value of key, false if key is absent or unhashable
+func (this *Hash) Get(key Object) (Object, bool) {
+	h, err := key.Hash()
+	if nil != err {
+		return Nil, false
+	}
+	pair, ok := this.Pairs.get(h)
+	if !ok {
+		return Nil, false
+	}
+	return pair.Value, true
+}
+
+// Items : pairs in insertion order
+func (this *Hash) Items() []*HashPair {
+	items := []*HashPair{}
+	for _, k := range this.Keys {
+		if v, ok := this.Pairs.get(&k); ok {
+			items = append(items, v)
+		}
+	}
+	return items
 }
 
 func (this *Hash) String() string {
+	s, _ := this.format(plainString)
+	return s
+}
+
+func (this *Hash) format(fn stringer) (string, error) {
 	var out bytes.Buffer
 	items := []string{}
-	for _, v := range this.Pairs {
-		items = append(items, fmt.Sprintf("%v: %v", v.Key.String(), v.Value.String()))
+	for _, v := range this.Items() {
+		s, err := fn(v.Value)
+		if nil != err {
+			return "", err
+		}
+		items = append(items, fmt.Sprintf("%v: %v", v.Key.String(), s))
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(items, ", "))
 	out.WriteString("}")
-	return out.String()
+	return out.String(), nil
 }
 
 func (this *Hash) Hash() (*HashKey, error) {
@@ -77,11 +148,11 @@ func (this *Hash) Hash() (*HashKey, error) {
 }
 
 func (this *Hash) Dump() (interface{}, error) {
-	m := map[string]interface{}{}
+	m := NewDumpMap()
 	if nil == this.Pairs || len(this.Pairs) < 1 {
 		return m, nil
 	}
-	for _, item := range this.Pairs {
+	for _, item := range this.Items() {
 		if !IsString(item.Key) {
 			err := fmt.Errorf("`%v` (%v) is not string", item.Key.String(), Typeof(item.Key))
 			return nil, err
@@ -90,7 +161,7 @@ func (this *Hash) Dump() (interface{}, error) {
 		if nil != err {
 			return nil, err
 		}
-		m[item.Key.String()] = v
+		m.Set(item.Key.String(), v)
 	}
 	return m, nil
 }
@@ -100,11 +171,15 @@ func (this *Hash) Calc(op *token.Token, right Object) (Object, error) {
 }
 
 func (this *Hash) CallMember(name string, args Objects) (Object, error) {
-	return callMember(this, this.fns, name, args)
+	return callMember(this, hashMethods, name, args)
 }
 
 func (this *Hash) GetMember(name string) (Object, error) {
-	return getMember(this, this.fns, name)
+	return getMember(this, hashMethods, name)
+}
+
+func (this *Hash) builtinMethods() methods {
+	return hashMethods
 }
 
 func (this *Hash) getType() ObjectType {
@@ -125,7 +200,7 @@ func (this *Hash) equalHash(other *Hash) error {
 		if valDst, ok := other.Pairs[k]; !ok {
 			return fmt.Errorf("other hash missing key `%v`", valSrc.Key.String())
 		} else {
-			if err := valDst.Value.equal(valSrc.Value); nil != err {
+			if err := valSrc.Value.equal(valDst.Value); nil != err {
 				return err
 			}
 		}
@@ -170,6 +245,19 @@ func (this *Hash) builtinNot(args Objects) (Object, error) {
 	}
 }
 
+func (this *Hash) builtinContains(args Objects) (Object, error) {
+	argc := len(args)
+	if argc != 1 {
+		return Nil, fmt.Errorf("contains() takes exactly one argument (%v given)", argc)
+	}
+	h, err := args[0].Hash()
+	if nil != err {
+		return Nil, fmt.Errorf("unhashable key `%v`", args[0].String())
+	}
+	_, ok := this.Pairs.get(h)
+	return ToBoolean(ok), nil
+}
+
 func (this *Hash) builtinKeys(args Objects) (Object, error) {
 	argc := len(args)
 	if argc != 0 {
@@ -178,5 +266,49 @@ func (this *Hash) builtinKeys(args Objects) (Object, error) {
 	if nil == this.Pairs || len(this.Pairs) < 1 {
 		return NewArray(Objects{}), nil
 	}
-	return NewArray(this.Pairs.keys()), nil
+	keys := Objects{}
+	for _, v := range this.Items() {
+		keys = append(keys, v.Key)
+	}
+	return NewArray(keys), nil
+}
+
+// DumpMap : json object which keeps the order of keys
+type DumpMap struct {
+	Keys   []string
+	Values map[string]interface{}
+}
+
+func NewDumpMap() *DumpMap {
+	return &DumpMap{Keys: []string{}, Values: map[string]interface{}{}}
+}
+
+func (this *DumpMap) Set(k string, v interface{}) {
+	if _, ok := this.Values[k]; !ok {
+		this.Keys = append(this.Keys, k)
+	}
+	this.Values[k] = v
+}
+
+func (this *DumpMap) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteString("{")
+	for i, k := range this.Keys {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		key, err := json.Marshal(k)
+		if nil != err {
+			return nil, err
+		}
+		val, err := json.Marshal(this.Values[k])
+		if nil != err {
+			return nil, err
+		}
+		out.Write(key)
+		out.WriteString(":")
+		out.Write(val)
+	}
+	out.WriteString("}")
+	return out.Bytes(), nil
 }
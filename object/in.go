@@ -0,0 +1,28 @@
+package object
+
+import "fmt"
+
+// Contains : `item in container`, or `item not in container` if not is set
+func Contains(container Object, item Object, not bool) (Object, error) {
+	var r Object
+	var err error
+	switch v := container.(type) {
+	case *Array:
+		r, err = v.builtinContains(Objects{item})
+	case *Hash:
+		r, err = v.builtinContains(Objects{item})
+	case *String:
+		r, err = v.builtinContains(Objects{item})
+	case *Interval:
+		r, err = v.builtinContains(Objects{item})
+	default:
+		return Nil, fmt.Errorf("`in` requires array, hash, string or interval, got %v (`%v`)", Typeof(container), container.String())
+	}
+	if nil != err {
+		return Nil, err
+	}
+	if not {
+		return ToBoolean(!r.True()), nil
+	}
+	return r, nil
+}
@@ -2,21 +2,35 @@ package object
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 
 	"github.com/jobs-github/escript/function"
 	"github.com/jobs-github/escript/token"
 )
 
+// the integers in [minSmallInteger, maxSmallInteger] are preallocated and shared,
+// an Integer is never modified once it is created
+const (
+	minSmallInteger = -128
+	maxSmallInteger = 1023
+)
+
+var smallIntegers = func() []*Integer {
+	r := make([]*Integer, maxSmallInteger-minSmallInteger+1)
+	for i := range r {
+		r[i] = &Integer{Value: int64(i + minSmallInteger)}
+	}
+	return r
+}()
+
 func newInteger(v int64) *Integer {
-	obj := &Integer{
-		Value: v,
+	if v >= minSmallInteger && v <= maxSmallInteger {
+		return smallIntegers[v-minSmallInteger]
 	}
-	obj.fns = objectBuiltins{
-		FnNot: obj.builtinNot,
-		FnNeg: obj.builtinNeg,
-		FnInt: obj.builtinInt,
+	return &Integer{
+		Value: v,
 	}
-	return obj
 }
 
 func NewInteger(v int64) Object {
@@ -29,6 +43,30 @@ type Integer struct {
 	Value int64
 }
 
+// integerMethods : builtin methods of Integer
+var integerMethods = methods{
+	FnNot:    integerMethod((*Integer).builtinNot),
+	FnNeg:    integerMethod((*Integer).builtinNeg),
+	FnInt:    integerMethod((*Integer).builtinInt),
+	FnBitNot: integerMethod((*Integer).builtinBitNot),
+}
+
+func integerMethod(fn func(*Integer, Objects) (Object, error)) method {
+	return func(this Object, args Objects) (Object, error) { return fn(this.(*Integer), args) }
+}
+
+// tokenAdd : the operator of Succ on bigint
+var tokenAdd = &token.Token{Type: token.ADD, Literal: "+"}
+
+// Succ : this + 1 as a new integer, promoted to bigint beyond int64 like `+`,
+// the integer itself may be shared (e.g. a constant) and is never modified
+func (this *Integer) Succ() (Object, error) {
+	if math.MaxInt64 == this.Value {
+		return calcBig(tokenAdd, this, newInteger(1))
+	}
+	return newInteger(this.Value + 1), nil
+}
+
 func (this *Integer) String() string {
 	return fmt.Sprintf("%v", this.Value)
 }
@@ -46,11 +84,15 @@ func (this *Integer) Calc(op *token.Token, right Object) (Object, error) {
 }
 
 func (this *Integer) CallMember(name string, args Objects) (Object, error) {
-	return callMember(this, this.fns, name, args)
+	return callMember(this, integerMethods, name, args)
 }
 
 func (this *Integer) GetMember(name string) (Object, error) {
-	return getMember(this, this.fns, name)
+	return getMember(this, integerMethods, name)
+}
+
+func (this *Integer) builtinMethods() methods {
+	return integerMethods
 }
 
 func (this *Integer) True() bool {
@@ -60,10 +102,6 @@ func (this *Integer) True() bool {
 	return true
 }
 
-func (this *Integer) Incr() {
-	this.Value++
-}
-
 func (this *Integer) getType() ObjectType {
 	return objectTypeInteger
 }
@@ -86,14 +124,17 @@ func (this *Integer) equalInteger(other *Integer) error {
 func (this *Integer) calcInteger(op *token.Token, left *Integer) (Object, error) {
 	switch op.Type {
 	case token.ADD:
-		return NewInteger(left.Value + this.Value), nil
+		return this.add(op, left)
 	case token.SUB:
-		return NewInteger(left.Value - this.Value), nil
+		return this.sub(op, left)
 	case token.MUL:
-		return NewInteger(left.Value * this.Value), nil
+		return this.mul(op, left)
 	case token.DIV:
-		return NewInteger(left.Value / this.Value), nil
+		return this.div(op, left)
 	case token.MOD:
+		if 0 == this.Value {
+			return Nil, function.NewError(errDivisionByZero)
+		}
 		return NewInteger(left.Value % this.Value), nil
 	case token.LT:
 		return ToBoolean(left.Value < this.Value), nil
@@ -111,11 +152,119 @@ func (this *Integer) calcInteger(op *token.Token, left *Integer) (Object, error)
 		return this.and(left)
 	case token.OR:
 		return this.or(left)
+	case token.BITAND:
+		return NewInteger(left.Value & this.Value), nil
+	case token.BITOR:
+		return NewInteger(left.Value | this.Value), nil
+	case token.BITXOR:
+		return NewInteger(left.Value ^ this.Value), nil
+	case token.SHL:
+		return this.shift(op, left)
+	case token.SHR:
+		return this.shift(op, left)
+	case token.POW:
+		return this.pow(op, left)
 	default:
 		return Nil, unsupportedOp(function.GetFunc(), op, this)
 	}
 }
 
+func (this *Integer) calcBigInt(op *token.Token, left *BigInt) (Object, error) {
+	return calcBig(op, left, this)
+}
+
+// add, sub, mul, div, shift & pow are promoted to bigint on int64 overflow
+func (this *Integer) add(op *token.Token, left *Integer) (Object, error) {
+	a, b := left.Value, this.Value
+	r := a + b
+	if (a >= 0 && b >= 0 && r < 0) || (a < 0 && b < 0 && r >= 0) {
+		return calcBig(op, left, this)
+	}
+	return NewInteger(r), nil
+}
+
+func (this *Integer) sub(op *token.Token, left *Integer) (Object, error) {
+	a, b := left.Value, this.Value
+	r := a - b
+	if (a >= 0 && b < 0 && r < 0) || (a < 0 && b > 0 && r >= 0) {
+		return calcBig(op, left, this)
+	}
+	return NewInteger(r), nil
+}
+
+func (this *Integer) mul(op *token.Token, left *Integer) (Object, error) {
+	r, ok := mulInt64(left.Value, this.Value)
+	if !ok {
+		return calcBig(op, left, this)
+	}
+	return NewInteger(r), nil
+}
+
+func (this *Integer) div(op *token.Token, left *Integer) (Object, error) {
+	if 0 == this.Value {
+		return Nil, function.NewError(errDivisionByZero)
+	}
+	if -1 == this.Value && math.MinInt64 == left.Value {
+		return calcBig(op, left, this)
+	}
+	return NewInteger(left.Value / this.Value), nil
+}
+
+func (this *Integer) shift(op *token.Token, left *Integer) (Object, error) {
+	if this.Value < 0 {
+		return Nil, fmt.Errorf("negative shift count: %v", this.Value)
+	}
+	if op.TypeIs(token.SHR) {
+		if this.Value > 63 {
+			return NewInteger(left.Value >> 63), nil
+		}
+		return NewInteger(left.Value >> uint64(this.Value)), nil
+	}
+	if this.Value > 62 {
+		return calcBig(op, left, this)
+	}
+	r := left.Value << uint64(this.Value)
+	if r>>uint64(this.Value) != left.Value {
+		return calcBig(op, left, this)
+	}
+	return NewInteger(r), nil
+}
+
+// pow : exponentiation by squaring
+func (this *Integer) pow(op *token.Token, left *Integer) (Object, error) {
+	if this.Value < 0 {
+		return Nil, fmt.Errorf("negative exponent: %v", this.Value)
+	}
+	r, base, exp := int64(1), left.Value, this.Value
+	ok := true
+	for exp > 0 {
+		if exp&1 == 1 {
+			if r, ok = mulInt64(r, base); !ok {
+				return calcBig(op, left, this)
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			if base, ok = mulInt64(base, base); !ok {
+				return calcBig(op, left, this)
+			}
+		}
+	}
+	return NewInteger(r), nil
+}
+
+// mulInt64 : false on overflow
+func mulInt64(a int64, b int64) (int64, bool) {
+	if 0 == a || 0 == b {
+		return 0, true
+	}
+	r := a * b
+	if r/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return r, true
+}
+
 func (this *Integer) calcBoolean(op *token.Token, left *Boolean) (Object, error) {
 	return this.calcInteger(op, toInteger(left.Value))
 }
@@ -142,13 +291,9 @@ func (this *Integer) or(left *Integer) (Object, error) {
 func (this *Integer) builtinNot(args Objects) (Object, error) {
 	argc := len(args)
 	if argc != 0 {
-		return newBoolean(false), fmt.Errorf("not() takes no argument (%v given), (`%v`)", argc, this.String())
-	}
-	if 0 == this.Value {
-		return newBoolean(true), nil
-	} else {
-		return newBoolean(false), nil
+		return False, fmt.Errorf("not() takes no argument (%v given), (`%v`)", argc, this.String())
 	}
+	return ToBoolean(0 == this.Value), nil
 }
 
 func (this *Integer) builtinNeg(args Objects) (Object, error) {
@@ -156,9 +301,20 @@ func (this *Integer) builtinNeg(args Objects) (Object, error) {
 	if argc != 0 {
 		return NewInteger(0), fmt.Errorf("neg() takes no argument (%v given), (`%v`)", argc, this.String())
 	}
+	if math.MinInt64 == this.Value {
+		return NewBigInt(new(big.Int).Neg(big.NewInt(this.Value))), nil
+	}
 	return NewInteger(-this.Value), nil
 }
 
+func (this *Integer) builtinBitNot(args Objects) (Object, error) {
+	argc := len(args)
+	if argc != 0 {
+		return NewInteger(0), fmt.Errorf("bitnot() takes no argument (%v given), (`%v`)", argc, this.String())
+	}
+	return NewInteger(^this.Value), nil
+}
+
 func (this *Integer) builtinInt(args Objects) (Object, error) {
 	argc := len(args)
 	if argc != 0 {
@@ -0,0 +1,257 @@
+package object
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/token"
+)
+
+// NewInterval : start..end (inclusive) or start..<end (exclusive), bounds must be integers
+func NewInterval(start Object, end Object, exclusive bool) (Object, error) {
+	s, err := start.asInteger()
+	if nil != err {
+		return Nil, fmt.Errorf("interval start must be integer, got `%v`", start.String())
+	}
+	e, err := end.asInteger()
+	if nil != err {
+		return Nil, fmt.Errorf("interval end must be integer, got `%v`", end.String())
+	}
+	return newInterval(s, e, exclusive), nil
+}
+
+// MaxIntervalItems : the longest interval which can be materialized as an array
+const MaxIntervalItems = 1 << 26
+
+func newInterval(start int64, end int64, exclusive bool) *Interval {
+	return &Interval{
+		Start:     start,
+		End:       end,
+		Exclusive: exclusive,
+	}
+}
+
+// Interval : implement Object, integers are computed on demand instead of being stored
+type Interval struct {
+	defaultObject
+	Start     int64
+	End       int64
+	Exclusive bool
+}
+
+// intervalMethods : builtin methods of Interval
+var intervalMethods = methods{
+	FnLen:      intervalMethod((*Interval).builtinLen),
+	FnIndex:    intervalMethod((*Interval).builtinIndex),
+	FnNot:      intervalMethod((*Interval).builtinNot),
+	FnFirst:    intervalMethod((*Interval).builtinFirst),
+	FnLast:     intervalMethod((*Interval).builtinLast),
+	FnSlice:    intervalMethod((*Interval).builtinSlice),
+	FnContains: intervalMethod((*Interval).builtinContains),
+}
+
+func intervalMethod(fn func(*Interval, Objects) (Object, error)) method {
+	return func(this Object, args Objects) (Object, error) { return fn(this.(*Interval), args) }
+}
+
+func (this *Interval) String() string {
+	if this.Exclusive {
+		return fmt.Sprintf("%v..<%v", this.Start, this.End)
+	}
+	return fmt.Sprintf("%v..%v", this.Start, this.End)
+}
+
+func (this *Interval) Calc(op *token.Token, right Object) (Object, error) {
+	return right.calcInterval(op, this)
+}
+
+func (this *Interval) CallMember(name string, args Objects) (Object, error) {
+	return callMember(this, intervalMethods, name, args)
+}
+
+func (this *Interval) GetMember(name string) (Object, error) {
+	return getMember(this, intervalMethods, name)
+}
+
+func (this *Interval) builtinMethods() methods {
+	return intervalMethods
+}
+
+func (this *Interval) True() bool {
+	_, ok := this.span()
+	return ok
+}
+
+// AsArray : materialize the interval, map/filter/reduce iterate it instead, refer to Iterate
+func (this *Interval) AsArray() (*Array, error) {
+	sz, err := MapLen(this)
+	if nil != err {
+		return nil, err
+	}
+	items := make(Objects, sz)
+	for i := int64(0); i < sz; i++ {
+		items[i] = NewInteger(this.Start + i)
+	}
+	return NewArray(items).(*Array), nil
+}
+
+// Len : number of integers in the interval, 0 if end is before start, error if it overflows int64
+func (this *Interval) Len() (int64, error) {
+	d, ok := this.span()
+	if !ok {
+		return 0, nil
+	}
+	if d >= math.MaxInt64 {
+		return 0, function.NewError(fmt.Errorf("interval `%v` is too long, len > %v", this.String(), int64(math.MaxInt64)))
+	}
+	return int64(d) + 1, nil
+}
+
+// span : distance from the first integer to the last one, false if the interval is empty
+func (this *Interval) span() (uint64, bool) {
+	last := this.End
+	if this.Exclusive {
+		if this.End <= this.Start {
+			return 0, false
+		}
+		last = this.End - 1
+	}
+	if last < this.Start {
+		return 0, false
+	}
+	return uint64(last) - uint64(this.Start), true
+}
+
+// Contains : whether v is an integer in the interval
+func (this *Interval) Contains(v int64) bool {
+	if v < this.Start {
+		return false
+	}
+	if this.Exclusive {
+		return v < this.End
+	}
+	return v <= this.End
+}
+
+func (this *Interval) getType() ObjectType {
+	return objectTypeInterval
+}
+
+func (this *Interval) equal(other Object) error {
+	return other.equalInterval(this)
+}
+
+func (this *Interval) equalInterval(other *Interval) error {
+	if this.Start != other.Start || this.End != other.End || this.Exclusive != other.Exclusive {
+		return fmt.Errorf("interval mismatch, this: %v, other: %v", this.String(), other.String())
+	}
+	return nil
+}
+
+func (this *Interval) calcInterval(op *token.Token, left *Interval) (Object, error) {
+	switch op.Type {
+	case token.EQ:
+		return ToBoolean(nil == this.equalInterval(left)), nil
+	case token.NEQ:
+		return ToBoolean(nil != this.equalInterval(left)), nil
+	}
+	return Nil, unsupportedOp(function.GetFunc(), op, this)
+}
+
+// builtin
+func (this *Interval) builtinLen(args Objects) (Object, error) {
+	argc := len(args)
+	if argc != 0 {
+		return Nil, fmt.Errorf("len() takes no argument (%v given), (`%v`)", argc, this.String())
+	}
+	sz, err := this.Len()
+	if nil != err {
+		return Nil, err
+	}
+	return NewInteger(sz), nil
+}
+
+func (this *Interval) builtinIndex(args Objects) (Object, error) {
+	argc := len(args)
+	if argc != 1 {
+		return Nil, fmt.Errorf("index() takes exactly one argument (%v given)", argc)
+	}
+	idx, err := args[0].asInteger()
+	if nil != err {
+		return Nil, err
+	}
+	return this.at(idx)
+}
+
+func (this *Interval) builtinNot(args Objects) (Object, error) {
+	argc := len(args)
+	if argc != 0 {
+		return Nil, fmt.Errorf("not() takes no argument (%v given), (`%v`)", argc, this.String())
+	}
+	return ToBoolean(!this.True()), nil
+}
+
+func (this *Interval) builtinFirst(args Objects) (Object, error) {
+	argc := len(args)
+	if argc != 0 {
+		return Nil, fmt.Errorf("first() takes exactly no argument (%v given)", argc)
+	}
+	return this.at(0)
+}
+
+func (this *Interval) builtinLast(args Objects) (Object, error) {
+	argc := len(args)
+	if argc != 0 {
+		return Nil, fmt.Errorf("last() takes exactly no argument (%v given)", argc)
+	}
+	return this.at(-1)
+}
+
+func (this *Interval) builtinSlice(args Objects) (Object, error) {
+	sz, err := this.Len()
+	if nil != err {
+		return Nil, err
+	}
+	idxs, err := sliceIndices(sz, args)
+	if nil != err {
+		return Nil, err
+	}
+	arr := make(Objects, len(idxs))
+	for i, idx := range idxs {
+		arr[i] = NewInteger(this.Start + idx)
+	}
+	return NewArray(arr), nil
+}
+
+func (this *Interval) builtinContains(args Objects) (Object, error) {
+	argc := len(args)
+	if argc != 1 {
+		return Nil, fmt.Errorf("contains() takes exactly one argument (%v given)", argc)
+	}
+	if !IsInteger(args[0]) {
+		return False, nil
+	}
+	v, _ := args[0].asInteger()
+	return ToBoolean(this.Contains(v)), nil
+}
+
+// at : the length may not fit in int64, so the offset is counted from the first or the last integer
+func (this *Interval) at(idx int64) (Object, error) {
+	d, ok := this.span()
+	if !ok {
+		return Nil, function.NewError(errIntervalEmpty)
+	}
+	off := uint64(idx)
+	if idx < 0 {
+		off = uint64(-(idx + 1))
+	}
+	if off > d {
+		return Nil, fmt.Errorf("list index out of range, idx: %v, len: %v", idx, d+1)
+	}
+	if idx < 0 {
+		off = d - off
+	}
+	// wraps around in uint64 but the result is in the interval
+	return NewInteger(int64(uint64(this.Start) + off)), nil
+}
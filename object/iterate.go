@@ -0,0 +1,58 @@
+package object
+
+import (
+	"fmt"
+
+	"github.com/jobs-github/escript/function"
+)
+
+// Iterate : call fn with the index and each item of an array or an interval,
+// the interval is iterated without being materialized
+func Iterate(v Object, fn func(i int64, item Object) error) error {
+	if r, ok := v.(*Interval); ok {
+		sz, err := r.Len()
+		if nil != err {
+			return err
+		}
+		for i := int64(0); i < sz; i++ {
+			if err := fn(i, NewInteger(r.Start+i)); nil != err {
+				return err
+			}
+		}
+		return nil
+	}
+	arr, err := v.AsArray()
+	if nil != err {
+		return err
+	}
+	for i, item := range arr.Items {
+		if err := fn(int64(i), item); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// ItemsLen : number of items of an array or an interval
+func ItemsLen(v Object) (int64, error) {
+	if r, ok := v.(*Interval); ok {
+		return r.Len()
+	}
+	arr, err := v.AsArray()
+	if nil != err {
+		return 0, err
+	}
+	return int64(len(arr.Items)), nil
+}
+
+// MapLen : number of items of the array which map makes of v, an interval is bounded by MaxIntervalItems
+func MapLen(v Object) (int64, error) {
+	sz, err := ItemsLen(v)
+	if nil != err {
+		return 0, err
+	}
+	if _, ok := v.(*Interval); ok && sz > MaxIntervalItems {
+		return 0, function.NewError(fmt.Errorf("interval `%v` is too long to be an array, max: %v", v.String(), MaxIntervalItems))
+	}
+	return sz, nil
+}
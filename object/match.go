@@ -0,0 +1,30 @@
+package object
+
+import "fmt"
+
+// Equals : strict equality used by match patterns, `1` does not equal `true` or `"1"`
+func Equals(a Object, b Object) bool {
+	return nil == a.equal(b)
+}
+
+// IsArrayOf : whether v is an array of exactly sz items, or at least sz items if rest
+func IsArrayOf(v Object, sz int, rest bool) bool {
+	arr, ok := v.(*Array)
+	if !ok {
+		return false
+	}
+	if rest {
+		return len(arr.Items) >= sz
+	}
+	return len(arr.Items) == sz
+}
+
+// NoMatch : no arm of a match expression accepts v
+func NoMatch(v Object) error {
+	return fmt.Errorf("no pattern matches `%v`", v.String())
+}
+
+// CannotDestruct : the shape of v does not fit the pattern of a binding
+func CannotDestruct(pattern string, v Object) error {
+	return fmt.Errorf("cannot destructure %v `%v` with `%v`", Typeof(v), v.String(), pattern)
+}
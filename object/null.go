@@ -8,11 +8,7 @@ import (
 )
 
 func newNull() *Null {
-	obj := &Null{}
-	obj.fns = objectBuiltins{
-		FnNot: obj.builtinNot,
-	}
-	return obj
+	return &Null{}
 }
 
 // Null : implement Object
@@ -20,6 +16,15 @@ type Null struct {
 	defaultObject
 }
 
+// nullMethods : builtin methods of Null
+var nullMethods = methods{
+	FnNot: nullMethod((*Null).builtinNot),
+}
+
+func nullMethod(fn func(*Null, Objects) (Object, error)) method {
+	return func(this Object, args Objects) (Object, error) { return fn(this.(*Null), args) }
+}
+
 func (this *Null) String() string {
 	return toString(objectTypeNull)
 }
@@ -33,11 +38,15 @@ func (this *Null) Calc(op *token.Token, right Object) (Object, error) {
 }
 
 func (this *Null) CallMember(name string, args Objects) (Object, error) {
-	return callMember(this, this.fns, name, args)
+	return callMember(this, nullMethods, name, args)
 }
 
 func (this *Null) GetMember(name string) (Object, error) {
-	return getMember(this, this.fns, name)
+	return getMember(this, nullMethods, name)
+}
+
+func (this *Null) builtinMethods() methods {
+	return nullMethods
 }
 
 func (this *Null) getType() ObjectType {
@@ -28,28 +28,43 @@ const (
 	objectTypeArray
 	objectTypeHash
 	objectTypeObjectFunc
+	objectTypeError
+	objectTypeBigInt
+	objectTypeInterval
+	objectTypeStructType
+	objectTypeStruct
+	objectTypeMethod
 )
 
 const (
-	TypeHash    = "hash"
-	TypeArray   = "array"
-	TypeBool    = "boolean"
-	TypeInt     = "integer"
-	TypeStr     = "string"
-	TypeBuiltin = "builtin"
+	TypeHash     = "hash"
+	TypeArray    = "array"
+	TypeBool     = "boolean"
+	TypeInt      = "integer"
+	TypeStr      = "string"
+	TypeBuiltin  = "builtin"
+	TypeError    = "error"
+	TypeBigInt   = "bigint"
+	TypeInterval = "interval"
+	TypeStruct   = "struct"
+	TypeMethod   = "method"
 )
 
 const (
-	FnLen   = "len"
-	FnIndex = "index"
-	FnNot   = "not"
-	FnNeg   = "neg"
-	FnInt   = "int"
-	FnFirst = "first"
-	FnLast  = "last"
-	FnTail  = "tail"
-	FnPush  = "push"
-	FnKeys  = "keys"
+	FnLen      = "len"
+	FnIndex    = "index"
+	FnNot      = "not"
+	FnNeg      = "neg"
+	FnInt      = "int"
+	FnFirst    = "first"
+	FnLast     = "last"
+	FnTail     = "tail"
+	FnPush     = "push"
+	FnKeys     = "keys"
+	FnSlice    = "slice"
+	FnMessage  = "message"
+	FnBitNot   = "bitnot"
+	FnContains = "contains"
 )
 
 var (
@@ -59,10 +74,13 @@ var (
 )
 
 var (
-	errStringEmpty = errors.New("string is empty")
-	errListEmpty   = errors.New("list is empty")
-	errArrayEmpty  = errors.New("array is empty")
-	errHashEmpty   = errors.New("hash is empty")
+	errStringEmpty   = errors.New("string is empty")
+	errListEmpty     = errors.New("list is empty")
+	errArrayEmpty    = errors.New("array is empty")
+	errHashEmpty     = errors.New("hash is empty")
+	errIntervalEmpty = errors.New("interval is empty")
+
+	errDivisionByZero = errors.New("integer division by zero")
 )
 
 var (
@@ -78,6 +96,12 @@ var (
 		objectTypeArray:      TypeArray,
 		objectTypeHash:       TypeHash,
 		objectTypeObjectFunc: "object_func",
+		objectTypeError:      TypeError,
+		objectTypeBigInt:     TypeBigInt,
+		objectTypeInterval:   TypeInterval,
+		objectTypeStructType: TypeStruct,
+		objectTypeStruct:     TypeStruct,
+		objectTypeMethod:     TypeMethod,
 	}
 )
 
@@ -85,6 +109,10 @@ func IsString(v Object) bool {
 	return v.getType() == objectTypeString
 }
 
+func IsHash(v Object) bool {
+	return v.getType() == objectTypeHash
+}
+
 func IsNull(v Object) bool {
 	return v.getType() == objectTypeNull
 }
@@ -101,20 +129,38 @@ func IsObjectFunc(v Object) bool {
 	return v.getType() == objectTypeObjectFunc
 }
 
+func IsError(v Object) bool {
+	return v.getType() == objectTypeError
+}
+
 func IsClosure(v Object) bool {
 	return v.getType() == objectTypeClosure
 }
 
+func IsStructType(v Object) bool {
+	return v.getType() == objectTypeStructType
+}
+
+func IsMethod(v Object) bool {
+	return v.getType() == objectTypeMethod
+}
+
 func IsCallable(v Object) bool {
 	t := v.getType()
 	return t == objectTypeFunction ||
+		t == objectTypeStructType ||
+		t == objectTypeMethod ||
 		t == objectTypeObjectFunc ||
 		t == objectTypeByteFunc ||
 		t == objectTypeClosure ||
 		t == objectTypeBuiltin
 }
 
+// Typeof : an instance of struct is typed by the name of its struct
 func Typeof(v Object) string {
+	if s, ok := v.(*Struct); ok {
+		return s.Type.Name
+	}
 	return toString(v.getType())
 }
 
@@ -152,12 +198,13 @@ type Object interface {
 	CallMember(name string, args Objects) (Object, error)
 	GetMember(name string) (Object, error)
 	True() bool
-	Incr()
 	AsByteFunc() (*ByteFunc, error)
 	AsClosure() (*Closure, error)
 	AsArray() (*Array, error)
+	AsMethod() (*Method, error)
 
 	getType() ObjectType
+	builtinMethods() methods
 	asInteger() (int64, error)
 	equal(other Object) error
 	equalInteger(other *Integer) error
@@ -171,6 +218,12 @@ type Object interface {
 	equalByteFunc(other *ByteFunc) error
 	equalClosure(other *Closure) error
 	equalObjectFunc(other *ObjectFunc) error
+	equalError(other *Error) error
+	equalBigInt(other *BigInt) error
+	equalInterval(other *Interval) error
+	equalStructType(other *StructType) error
+	equalStruct(other *Struct) error
+	equalMethod(other *Method) error
 	// calc
 	calcInteger(op *token.Token, left *Integer) (Object, error)
 	calcString(op *token.Token, left *String) (Object, error)
@@ -183,14 +236,23 @@ type Object interface {
 	calcByteFunc(op *token.Token, left *ByteFunc) (Object, error)
 	calcClosure(op *token.Token, left *Closure) (Object, error)
 	calcObjectFunc(op *token.Token, left *ObjectFunc) (Object, error)
+	calcError(op *token.Token, left *Error) (Object, error)
+	calcBigInt(op *token.Token, left *BigInt) (Object, error)
+	calcInterval(op *token.Token, left *Interval) (Object, error)
+	calcStructType(op *token.Token, left *StructType) (Object, error)
+	calcStruct(op *token.Token, left *Struct) (Object, error)
+	calcMethod(op *token.Token, left *Method) (Object, error)
 }
 
-type objectFn func(args Objects) (Object, error)
-type objectBuiltins map[string]objectFn
+// method : builtin method of a type, this is the receiver
+type method func(this Object, args Objects) (Object, error)
 
-func (this *objectBuiltins) get(name string) (objectFn, bool) {
-	v, ok := (*this)[name]
-	return v, ok
+// methods : builtin methods of a type, shared by all of its instances
+type methods map[string]method
+
+// defaultMethods : builtin methods of the types which support not() only
+var defaultMethods = methods{
+	FnNot: defaultNot,
 }
 
 type Objects []Object
@@ -287,12 +349,42 @@ func infixNull(op *token.Token, right Object, method string) (Object, error) {
 	}
 }
 
-func checkIdx(idx int64, sz int64) error {
+// stringer : the string form of an item of container, either plainString or ToString
+type stringer func(Object) (string, error)
+
+func plainString(v Object) (string, error) {
+	return v.String(), nil
+}
+
+// ToString : the string form of v, unlike v.String(), the error raised by __str__ of a struct
+// (also an item of array, hash or struct) is returned instead of falling back to the default form
+func ToString(v Object) (string, error) {
+	switch obj := v.(type) {
+	case *Array:
+		return obj.format(ToString)
+	case *Hash:
+		return obj.format(ToString)
+	case *Struct:
+		return obj.str()
+	}
+	return v.String(), nil
+}
+
+// normIdx : negative index counts from the end
+func normIdx(idx int64, sz int64) int64 {
 	if idx < 0 {
+		return idx + sz
+	}
+	return idx
+}
+
+// checkIdx : pos is idx counted from the start, idx is kept for the error as written by the user
+func checkIdx(idx int64, pos int64, sz int64) error {
+	if pos < 0 {
 		err := fmt.Errorf("list index out of range, idx: %v", idx)
 		return err
 	}
-	if idx > sz-1 {
+	if pos > sz-1 {
 		err := fmt.Errorf("list index out of range, idx: %v, len: %v", idx, sz)
 		return err
 	}
@@ -301,15 +393,16 @@ func checkIdx(idx int64, sz int64) error {
 
 func indexofArray(items Objects, idx int64) (Object, error) {
 	sz := int64(len(items))
-	if err := checkIdx(idx, sz); nil != err {
+	pos := normIdx(idx, sz)
+	if err := checkIdx(idx, pos, sz); nil != err {
 		return Nil, err
 	}
-	return items[idx], nil
+	return items[pos], nil
 }
 
 func setValue(items Objects, idx int64, v Object) (Object, error) {
 	sz := int64(len(items))
-	if err := checkIdx(idx, sz); nil != err {
+	if err := checkIdx(idx, idx, sz); nil != err {
 		return Nil, err
 	}
 	items[idx] = v
@@ -318,10 +411,121 @@ func setValue(items Objects, idx int64, v Object) (Object, error) {
 
 func indexofString(s string, idx int64) (Object, error) {
 	sz := int64(len(s))
-	if err := checkIdx(idx, sz); nil != err {
+	pos := normIdx(idx, sz)
+	if err := checkIdx(idx, pos, sz); nil != err {
 		return Nil, err
 	}
-	return NewString(s[idx : idx+1]), nil
+	return NewString(s[pos : pos+1]), nil
+}
+
+// sliceBound : null means the default bound
+func sliceBound(v Object, name string) (int64, bool, error) {
+	if nil == v || IsNull(v) {
+		return 0, false, nil
+	}
+	i, err := v.asInteger()
+	if nil != err {
+		return 0, false, fmt.Errorf("slice %v must be integer or null, got `%v` (%v)", name, v.String(), Typeof(v))
+	}
+	return i, true, nil
+}
+
+func clampIdx(idx int64, lower int64, upper int64) int64 {
+	if idx < lower {
+		return lower
+	}
+	if idx > upper {
+		return upper
+	}
+	return idx
+}
+
+// sliceIndices : python-style [start:end:step], returns the picked indexes
+func sliceIndices(sz int64, args Objects) ([]int64, error) {
+	argc := len(args)
+	if argc < 1 || argc > 3 {
+		return nil, fmt.Errorf("slice() takes 1 to 3 arguments (%v given)", argc)
+	}
+	bounds := [3]Object{}
+	copy(bounds[:], args)
+	start, hasStart, err := sliceBound(bounds[0], "start")
+	if nil != err {
+		return nil, err
+	}
+	end, hasEnd, err := sliceBound(bounds[1], "end")
+	if nil != err {
+		return nil, err
+	}
+	step, hasStep, err := sliceBound(bounds[2], "step")
+	if nil != err {
+		return nil, err
+	}
+	if !hasStep {
+		step = 1
+	}
+	if 0 == step {
+		return nil, errors.New("slice step cannot be zero")
+	}
+	idxs := []int64{}
+	if step > 0 {
+		if !hasStart {
+			start = 0
+		}
+		if !hasEnd {
+			end = sz
+		}
+		start = clampIdx(normIdx(start, sz), 0, sz)
+		end = clampIdx(normIdx(end, sz), 0, sz)
+		for i := start; i < end; i += step {
+			idxs = append(idxs, i)
+			// i + step may overflow
+			if step >= end-i {
+				break
+			}
+		}
+	} else {
+		if !hasStart {
+			start = sz - 1
+		} else {
+			start = clampIdx(normIdx(start, sz), -1, sz-1)
+		}
+		if !hasEnd {
+			end = -1
+		} else {
+			end = clampIdx(normIdx(end, sz), -1, sz-1)
+		}
+		for i := start; i > end; i += step {
+			idxs = append(idxs, i)
+			if step <= end-i {
+				break
+			}
+		}
+	}
+	return idxs, nil
+}
+
+func sliceArray(items Objects, args Objects) (Object, error) {
+	idxs, err := sliceIndices(int64(len(items)), args)
+	if nil != err {
+		return Nil, err
+	}
+	arr := make(Objects, len(idxs))
+	for i, idx := range idxs {
+		arr[i] = items[idx]
+	}
+	return NewArray(arr), nil
+}
+
+func sliceString(s string, args Objects) (Object, error) {
+	idxs, err := sliceIndices(int64(len(s)), args)
+	if nil != err {
+		return Nil, err
+	}
+	b := make([]byte, len(idxs))
+	for i, idx := range idxs {
+		b[i] = s[idx]
+	}
+	return NewString(string(b)), nil
 }
 
 func keyofHash(m HashMap, key Object) (Object, error) {
@@ -350,26 +554,28 @@ func notEqual(op *token.Token) (Object, error) {
 
 func compare(entry string, this Object, left Object, op *token.Token) (Object, error) {
 	switch op.Type {
-	case token.EQ:
-		return ToBoolean(nil == this.equal(left)), nil
-	case token.NEQ:
-		return ToBoolean(nil != this.equal(left)), nil
+	case token.EQ, token.NEQ:
+		err := left.equal(this)
+		if isHookError(err) {
+			return Nil, err
+		}
+		return ToBoolean((nil == err) == (token.EQ == op.Type)), nil
 	default:
 		return Nil, unsupportedOp(function.GetFunc(), op, this)
 	}
 }
 
-func callMember(this Object, fns objectBuiltins, name string, args Objects) (Object, error) {
-	fn, ok := fns.get(name)
+func callMember(this Object, fns methods, name string, args Objects) (Object, error) {
+	fn, ok := fns[name]
 	if !ok {
 		err := fmt.Errorf("no attribute '%v' in %v, (`%v`)", name, Typeof(this), this.String())
 		return Nil, err
 	}
-	return fn(args)
+	return fn(this, args)
 }
 
-func getMember(this Object, fns objectBuiltins, name string) (Object, error) {
-	fn, ok := fns.get(name)
+func getMember(this Object, fns methods, name string) (Object, error) {
+	fn, ok := fns[name]
 	if !ok {
 		err := fmt.Errorf("no attribute '%v' in %v, (`%v`)", name, Typeof(this), this.String())
 		return Nil, err
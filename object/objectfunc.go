@@ -7,15 +7,12 @@ import (
 	"github.com/jobs-github/escript/token"
 )
 
-func NewObjectFunc(obj Object, name string, fn objectFn) Object {
+func NewObjectFunc(obj Object, name string, fn method) Object {
 	f := &ObjectFunc{
 		Obj:  obj,
 		Name: name,
 		Fn:   fn,
 	}
-	f.fns = objectBuiltins{
-		FnNot: f.builtinNot,
-	}
 	return f
 }
 
@@ -24,7 +21,7 @@ type ObjectFunc struct {
 	defaultObject
 	Obj  Object
 	Name string
-	Fn   objectFn
+	Fn   method
 }
 
 func (this *ObjectFunc) String() string {
@@ -36,15 +33,19 @@ func (this *ObjectFunc) Calc(op *token.Token, right Object) (Object, error) {
 }
 
 func (this *ObjectFunc) Call(args Objects) (Object, error) {
-	return this.Fn(args)
+	return this.Fn(this.Obj, args)
 }
 
 func (this *ObjectFunc) CallMember(name string, args Objects) (Object, error) {
-	return callMember(this, this.fns, name, args)
+	return callMember(this, defaultMethods, name, args)
 }
 
 func (this *ObjectFunc) GetMember(name string) (Object, error) {
-	return getMember(this, this.fns, name)
+	return getMember(this, defaultMethods, name)
+}
+
+func (this *ObjectFunc) builtinMethods() methods {
+	return defaultMethods
 }
 
 func (this *ObjectFunc) getType() ObjectType {
@@ -68,8 +69,3 @@ func (this *ObjectFunc) equalObjectFunc(other *ObjectFunc) error {
 func (this *ObjectFunc) calcObjectFunc(op *token.Token, left *ObjectFunc) (Object, error) {
 	return compare(function.GetFunc(), this, left, op)
 }
-
-// builtin
-func (this *ObjectFunc) builtinNot(args Objects) (Object, error) {
-	return defaultNot(this, args)
-}
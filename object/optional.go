@@ -0,0 +1,43 @@
+package object
+
+// OptionalIndex : left?.[idx], null when left is null or idx is absent
+func OptionalIndex(left Object, idx Object) (Object, error) {
+	switch v := left.(type) {
+	case *Null:
+		return Nil, nil
+	case *Hash:
+		k, err := idx.Hash()
+		if nil != err {
+			return Nil, err
+		}
+		if pair, ok := v.Pairs.get(k); ok {
+			return pair.Value, nil
+		}
+		return Nil, nil
+	case *Array:
+		i, ok, err := optionalIdx(idx, int64(len(v.Items)))
+		if nil != err || !ok {
+			return Nil, err
+		}
+		return v.Items[i], nil
+	case *String:
+		i, ok, err := optionalIdx(idx, int64(len(v.Value)))
+		if nil != err || !ok {
+			return Nil, err
+		}
+		return NewString(v.Value[i : i+1]), nil
+	}
+	return left.CallMember(FnIndex, Objects{idx})
+}
+
+func optionalIdx(idx Object, sz int64) (int64, bool, error) {
+	i, err := idx.asInteger()
+	if nil != err {
+		return 0, false, err
+	}
+	pos := normIdx(i, sz)
+	if nil != checkIdx(i, pos, sz) {
+		return 0, false, nil
+	}
+	return pos, true, nil
+}
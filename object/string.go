@@ -1,24 +1,32 @@
 package object
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/jobs-github/escript/function"
 	"github.com/jobs-github/escript/token"
 )
 
 func NewString(v string) Object {
-	obj := &String{
+	return &String{
 		Value: v,
 	}
-	obj.fns = objectBuiltins{
-		FnLen:   obj.builtinLen,
-		FnIndex: obj.builtinIndex,
-		FnNot:   obj.builtinNot,
-		FnInt:   obj.builtinInt,
+}
+
+// Concat : join the string form of each object, used by the template string
+func Concat(items Objects) (Object, error) {
+	var out strings.Builder
+	for _, v := range items {
+		s, err := ToString(v)
+		if nil != err {
+			return Nil, err
+		}
+		out.WriteString(s)
 	}
-	return obj
+	return NewString(out.String()), nil
 }
 
 // String : implement Object
@@ -27,6 +35,20 @@ type String struct {
 	Value string
 }
 
+// stringMethods : builtin methods of String
+var stringMethods = methods{
+	FnLen:      stringMethod((*String).builtinLen),
+	FnIndex:    stringMethod((*String).builtinIndex),
+	FnNot:      stringMethod((*String).builtinNot),
+	FnInt:      stringMethod((*String).builtinInt),
+	FnSlice:    stringMethod((*String).builtinSlice),
+	FnContains: stringMethod((*String).builtinContains),
+}
+
+func stringMethod(fn func(*String, Objects) (Object, error)) method {
+	return func(this Object, args Objects) (Object, error) { return fn(this.(*String), args) }
+}
+
 func (this *String) String() string {
 	return this.Value
 }
@@ -44,11 +66,15 @@ func (this *String) Calc(op *token.Token, right Object) (Object, error) {
 }
 
 func (this *String) CallMember(name string, args Objects) (Object, error) {
-	return callMember(this, this.fns, name, args)
+	return callMember(this, stringMethods, name, args)
 }
 
 func (this *String) GetMember(name string) (Object, error) {
-	return getMember(this, this.fns, name)
+	return getMember(this, stringMethods, name)
+}
+
+func (this *String) builtinMethods() methods {
+	return stringMethods
 }
 
 func (this *String) True() bool {
@@ -136,6 +162,22 @@ func (this *String) builtinIndex(args Objects) (Object, error) {
 	return indexofString(this.Value, idx)
 }
 
+func (this *String) builtinSlice(args Objects) (Object, error) {
+	return sliceString(this.Value, args)
+}
+
+func (this *String) builtinContains(args Objects) (Object, error) {
+	argc := len(args)
+	if argc != 1 {
+		return Nil, fmt.Errorf("contains() takes exactly one argument (%v given)", argc)
+	}
+	sub, ok := args[0].(*String)
+	if !ok {
+		return Nil, fmt.Errorf("contains() on string requires a string, got `%v`", args[0].String())
+	}
+	return ToBoolean(strings.Contains(this.Value, sub.Value)), nil
+}
+
 func (this *String) builtinNot(args Objects) (Object, error) {
 	argc := len(args)
 	if argc != 0 {
@@ -155,6 +197,9 @@ func (this *String) builtinInt(args Objects) (Object, error) {
 	}
 	v, err := strconv.ParseInt(this.Value, 10, 64)
 	if nil != err {
+		if errors.Is(err, strconv.ErrRange) {
+			return ParseInteger(this.Value, 10)
+		}
 		return Nil, err
 	}
 	return NewInteger(v), nil
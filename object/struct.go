@@ -0,0 +1,405 @@
+package object
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/token"
+)
+
+// NewStructType : `struct Name { fields }`, methods are defined later by DefineMethod
+func NewStructType(name string, fields []string) *StructType {
+	return &StructType{
+		Name:    name,
+		Fields:  fields,
+		Methods: map[string]Object{},
+	}
+}
+
+// DefineMethod : `func (recv Name) method(args) {...}`, fn takes the receiver as its first arg
+func DefineMethod(t Object, name string, fn Object) error {
+	st, ok := t.(*StructType)
+	if !ok {
+		return fmt.Errorf("cannot define method `%v` on %v `%v`", name, Typeof(t), t.String())
+	}
+	return st.define(name, fn)
+}
+
+// StructType : implement Object, calling it constructs an instance with the fields in order
+type StructType struct {
+	defaultObject
+	Name    string
+	Fields  []string
+	Methods map[string]Object // Function or Closure
+}
+
+func (this *StructType) define(name string, fn Object) error {
+	if this.fieldIndex(name) >= 0 {
+		return fmt.Errorf("struct %v has both field and method named `%v`", this.Name, name)
+	}
+	this.Methods[name] = fn
+	return nil
+}
+
+func (this *StructType) fieldIndex(name string) int {
+	for i, field := range this.Fields {
+		if field == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (this *StructType) String() string {
+	return fmt.Sprintf("struct %v { %v }", this.Name, strings.Join(this.Fields, ", "))
+}
+
+func (this *StructType) Calc(op *token.Token, right Object) (Object, error) {
+	return right.calcStructType(op, this)
+}
+
+func (this *StructType) Call(args Objects) (Object, error) {
+	argc := len(args)
+	sz := len(this.Fields)
+	if argc != sz {
+		return Nil, fmt.Errorf("%v() takes exactly %v arguments (%v given)", this.Name, sz, argc)
+	}
+	values := make(Objects, sz)
+	copy(values, args)
+	return newStruct(this, values), nil
+}
+
+func (this *StructType) CallMember(name string, args Objects) (Object, error) {
+	return callMember(this, defaultMethods, name, args)
+}
+
+func (this *StructType) GetMember(name string) (Object, error) {
+	return getMember(this, defaultMethods, name)
+}
+
+func (this *StructType) builtinMethods() methods {
+	return defaultMethods
+}
+
+func (this *StructType) True() bool {
+	return true
+}
+
+func (this *StructType) getType() ObjectType {
+	return objectTypeStructType
+}
+
+func (this *StructType) equal(other Object) error {
+	return other.equalStructType(this)
+}
+
+func (this *StructType) equalStructType(other *StructType) error {
+	if this != other {
+		return fmt.Errorf("struct mismatch, this: %v, other: %v", this.Name, other.Name)
+	}
+	return nil
+}
+
+func (this *StructType) calcStructType(op *token.Token, left *StructType) (Object, error) {
+	return compare(function.GetFunc(), this, left, op)
+}
+
+// operator hooks, methods of StructType consulted by Struct
+const (
+	hookAdd   = "__add__"
+	hookSub   = "__sub__"
+	hookMul   = "__mul__"
+	hookDiv   = "__div__"
+	hookMod   = "__mod__"
+	hookEq    = "__eq__"
+	hookLt    = "__lt__"
+	hookIndex = "__index__"
+	hookStr   = "__str__"
+)
+
+var arithHooks = map[token.TokenType]string{
+	token.ADD: hookAdd,
+	token.SUB: hookSub,
+	token.MUL: hookMul,
+	token.DIV: hookDiv,
+	token.MOD: hookMod,
+}
+
+func newStruct(t *StructType, values Objects) *Struct {
+	return &Struct{
+		Type:   t,
+		Values: values,
+	}
+}
+
+// Struct : implement Object, an instance of StructType
+type Struct struct {
+	defaultObject
+	Type   *StructType
+	Values Objects // one value per field
+}
+
+// String : the error of __str__ cannot be returned here, refer to ToString
+func (this *Struct) String() string {
+	if r, ok, err := this.hook(hookStr, this); ok && nil == err {
+		return r.String()
+	}
+	s, _ := this.format(plainString)
+	return s
+}
+
+// format : the default form, the fields in declaration order
+func (this *Struct) format(fn stringer) (string, error) {
+	var out bytes.Buffer
+	items := []string{}
+	for i, field := range this.Type.Fields {
+		s, err := fn(this.Values[i])
+		if nil != err {
+			return "", err
+		}
+		items = append(items, fmt.Sprintf("%v: %v", field, s))
+	}
+	out.WriteString(this.Type.Name)
+	out.WriteString("{")
+	out.WriteString(strings.Join(items, ", "))
+	out.WriteString("}")
+	return out.String(), nil
+}
+
+// str : the result of __str__ if defined, otherwise the default form
+func (this *Struct) str() (string, error) {
+	r, ok, err := this.hook(hookStr, this)
+	if !ok {
+		return this.format(ToString)
+	}
+	if nil != err {
+		return "", err
+	}
+	return ToString(r)
+}
+
+func (this *Struct) Hash() (*HashKey, error) {
+	return nil, unsupported(function.GetFunc(), this)
+}
+
+// Dump : fields in declaration order
+func (this *Struct) Dump() (interface{}, error) {
+	m := NewDumpMap()
+	for i, field := range this.Type.Fields {
+		v, err := this.Values[i].Dump()
+		if nil != err {
+			return nil, err
+		}
+		m.Set(field, v)
+	}
+	return m, nil
+}
+
+// hookError : an error raised by a hook called by equal, which is returned instead of a mismatch
+type hookError struct {
+	err error
+}
+
+func (this *hookError) Error() string {
+	return this.err.Error()
+}
+
+func (this *hookError) Unwrap() error {
+	return this.err
+}
+
+func isHookError(err error) bool {
+	var e *hookError
+	return errors.As(err, &e)
+}
+
+// hook : call the hook method of the type if defined, args includes the receiver
+func (this *Struct) hook(name string, args ...Object) (Object, bool, error) {
+	fn, ok := this.Type.Methods[name]
+	if !ok {
+		return Nil, false, nil
+	}
+	r, err := fn.Call(args)
+	return r, true, err
+}
+
+// hookBool : call the hook method and take the truth of its result
+func (this *Struct) hookBool(name string, not bool, args ...Object) (Object, bool, error) {
+	r, ok, err := this.hook(name, args...)
+	if !ok || nil != err {
+		return Nil, ok, err
+	}
+	return ToBoolean(r.True() != not), true, nil
+}
+
+// calcHook : only the hooks of the left operand are consulted,
+// `>`, `<=` and `>=` are derived from `__lt__` and `!=` from `__eq__`
+func (this *Struct) calcHook(op *token.Token, right Object) (Object, bool, error) {
+	if name, ok := arithHooks[op.Type]; ok {
+		return this.hook(name, this, right)
+	}
+	switch op.Type {
+	case token.EQ:
+		return this.hookBool(hookEq, false, this, right)
+	case token.NEQ:
+		return this.hookBool(hookEq, true, this, right)
+	case token.LT:
+		return this.hookBool(hookLt, false, this, right)
+	case token.GT:
+		return this.hookBool(hookLt, false, right, this)
+	case token.LEQ:
+		return this.hookBool(hookLt, true, right, this)
+	case token.GEQ:
+		return this.hookBool(hookLt, true, this, right)
+	default:
+		return Nil, false, nil
+	}
+}
+
+func (this *Struct) Calc(op *token.Token, right Object) (Object, error) {
+	if r, ok, err := this.calcHook(op, right); ok {
+		return r, err
+	}
+	return right.calcStruct(op, this)
+}
+
+// CallMember : a method is called with the instance as the receiver, a field is called as a function
+func (this *Struct) CallMember(name string, args Objects) (Object, error) {
+	if name == FnIndex && len(args) == 1 {
+		if r, ok, err := this.hook(hookIndex, this, args[0]); ok {
+			return r, err
+		}
+	}
+	fn, ok := this.Member(name)
+	if !ok {
+		return callMember(this, defaultMethods, name, args)
+	}
+	return fn.Call(args)
+}
+
+// GetMember : field, method bound to the instance or builtin, in order
+func (this *Struct) GetMember(name string) (Object, error) {
+	if fn, ok := this.Member(name); ok {
+		return fn, nil
+	}
+	return getMember(this, defaultMethods, name)
+}
+
+// Member : field or method bound to the instance, false if name is neither of them
+func (this *Struct) Member(name string) (Object, bool) {
+	if i := this.Type.fieldIndex(name); i >= 0 {
+		return this.Values[i], true
+	}
+	if fn, ok := this.Type.Methods[name]; ok {
+		return NewMethod(this, fn), true
+	}
+	return nil, false
+}
+
+func (this *Struct) True() bool {
+	return true
+}
+
+func (this *Struct) getType() ObjectType {
+	return objectTypeStruct
+}
+
+// equal : the __eq__ hook if defined, so that the equality of arrays and hashes and `in` agree with `==`
+func (this *Struct) equal(other Object) error {
+	r, ok, err := this.hookBool(hookEq, false, this, other)
+	if !ok {
+		return other.equalStruct(this)
+	}
+	if nil != err {
+		return &hookError{err}
+	}
+	if !r.True() {
+		return fmt.Errorf("%v of %v mismatch, other: %v", hookEq, this.Type.Name, Typeof(other))
+	}
+	return nil
+}
+
+func (this *Struct) equalStruct(other *Struct) error {
+	if this.Type != other.Type {
+		return fmt.Errorf("struct mismatch, this: %v, other: %v", this.Type.Name, other.Type.Name)
+	}
+	for i, v := range this.Values {
+		if err := v.equal(other.Values[i]); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *Struct) calcStruct(op *token.Token, left *Struct) (Object, error) {
+	return compare(function.GetFunc(), this, left, op)
+}
+
+func NewMethod(recv Object, fn Object) *Method {
+	return &Method{
+		Recv: recv,
+		Fn:   fn,
+	}
+}
+
+// Method : implement Object, a method bound to its receiver
+type Method struct {
+	defaultObject
+	Recv Object
+	Fn   Object
+}
+
+func (this *Method) String() string {
+	return fmt.Sprintf("<method of %v>", this.Recv.String())
+}
+
+func (this *Method) Calc(op *token.Token, right Object) (Object, error) {
+	return right.calcMethod(op, this)
+}
+
+// Call : vm calls a Closure by itself, refer to virtualMachine.bindMethod
+func (this *Method) Call(args Objects) (Object, error) {
+	return this.Fn.Call(append(Objects{this.Recv}, args...))
+}
+
+func (this *Method) CallMember(name string, args Objects) (Object, error) {
+	return callMember(this, defaultMethods, name, args)
+}
+
+func (this *Method) GetMember(name string) (Object, error) {
+	return getMember(this, defaultMethods, name)
+}
+
+func (this *Method) builtinMethods() methods {
+	return defaultMethods
+}
+
+func (this *Method) True() bool {
+	return true
+}
+
+func (this *Method) AsMethod() (*Method, error) {
+	return this, nil
+}
+
+func (this *Method) getType() ObjectType {
+	return objectTypeMethod
+}
+
+func (this *Method) equal(other Object) error {
+	return other.equalMethod(this)
+}
+
+func (this *Method) equalMethod(other *Method) error {
+	if err := other.Fn.equal(this.Fn); nil != err {
+		return err
+	}
+	return other.Recv.equal(this.Recv)
+}
+
+func (this *Method) calcMethod(op *token.Token, left *Method) (Object, error) {
+	return compare(function.GetFunc(), this, left, op)
+}
@@ -24,6 +24,10 @@ var (
 		FnTail,
 		FnPush,
 		FnKeys,
+		FnSlice,
+		FnMessage,
+		FnBitNot,
+		FnContains,
 	}
 )
 
@@ -34,3 +38,13 @@ func Resolve(idx int) string {
 func Traverse(cb func(i int, name string)) {
 	objectSymbolTable.traverse(cb)
 }
+
+// IsObjectFn : name of builtin method shared by objects
+func IsObjectFn(name string) bool {
+	for _, fn := range objectSymbolTable {
+		if fn == name {
+			return true
+		}
+	}
+	return false
+}
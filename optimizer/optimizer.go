@@ -0,0 +1,188 @@
+package optimizer
+
+import (
+	"github.com/jobs-github/escript/ast"
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+)
+
+// Optimize : rewrite node in place before it is compiled,
+// constant arithmetic, comparisons and string concatenation are folded,
+// conditional expressions with constant conditions are pruned,
+// names bound only once in node by `const name = literal` are inlined.
+// An expression which fails (e.g. `1 / 0`) is kept, so that the error is raised when it runs.
+func Optimize(node ast.Node) error {
+	counter := newOptimizer(nil)
+	if err := node.Do(counter); nil != err {
+		return function.NewError(err)
+	}
+	if err := node.Do(newOptimizer(counter.bindings)); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+// newOptimizer : bindings is nil for the first pass, which only counts the bindings of names
+func newOptimizer(bindings map[string]int) *optimizer {
+	this := &optimizer{
+		bindings: bindings,
+		counting: nil == bindings,
+		scope:    newScope(nil),
+	}
+	if this.counting {
+		this.bindings = map[string]int{}
+	}
+	return this
+}
+
+// optimizer : implement ast.Visitor, an expression is replaced by setting result in its Do
+type optimizer struct {
+	bindings map[string]int // times of every name is bound in the whole node
+	counting bool
+	scope    *scope
+	result   ast.Expression
+}
+
+// expr : the optimized e, e itself or its replacement
+func (this *optimizer) expr(e ast.Expression) (ast.Expression, error) {
+	if nil == e {
+		return nil, nil
+	}
+	this.result = nil
+	if err := e.Do(this); nil != err {
+		return nil, function.NewError(err)
+	}
+	r := this.result
+	this.result = nil
+	if nil == r {
+		return e, nil
+	}
+	return r, nil
+}
+
+// exprs : optimize the items in place
+func (this *optimizer) exprs(items ast.ExpressionSlice) error {
+	for i, item := range items {
+		r, err := this.expr(item)
+		if nil != err {
+			return function.NewError(err)
+		}
+		items[i] = r
+	}
+	return nil
+}
+
+// operand : the array or body of loops, an identifier is kept as the compiler expects it
+func (this *optimizer) operand(e ast.Expression) (ast.Expression, error) {
+	if _, ok := e.(*ast.Identifier); ok {
+		return e, nil
+	}
+	return this.expr(e)
+}
+
+// bind : names are counted by the first pass, and shadow the inlined consts of outer scopes
+func (this *optimizer) bind(names ...string) {
+	for _, name := range names {
+		if this.counting {
+			this.bindings[name]++
+		}
+	}
+}
+
+// fold : replace e by the literal of its value, e is kept if it fails or the value has no literal
+func (this *optimizer) fold(e ast.Expression) {
+	if this.counting {
+		return
+	}
+	v, err := e.Eval(object.NewEnv(nil))
+	if nil != err {
+		return
+	}
+	if r := literalOf(v); nil != r {
+		r.SetPos(e.Pos())
+		this.result = r
+	}
+}
+
+func (this *optimizer) enterScope() {
+	this.scope = newScope(this.scope)
+}
+
+func (this *optimizer) leaveScope() {
+	this.scope = this.scope.outer
+}
+
+// isLiteral : null, boolean, integer or string literal
+func isLiteral(e ast.Expression) bool {
+	switch e.(type) {
+	case *ast.Null, *ast.Boolean, *ast.Integer, *ast.String:
+		return true
+	}
+	return false
+}
+
+// literalOf : nil if v has no literal
+func literalOf(v object.Object) ast.Expression {
+	switch v := v.(type) {
+	case *object.Null:
+		return ast.NewNull()
+	case *object.Boolean:
+		r := ast.NewBoolean()
+		r.Value = v.Value
+		return r
+	case *object.Integer:
+		r := ast.NewInteger()
+		r.Value = v.Value
+		return r
+	case *object.BigInt:
+		r := ast.NewInteger()
+		r.Big = v.Value
+		return r
+	case *object.String:
+		r := ast.NewString()
+		r.Value = v.Value
+		return r
+	}
+	return nil
+}
+
+// clone : every use of an inlined const is a node of its own
+func clone(e ast.Expression) ast.Expression {
+	switch v := e.(type) {
+	case *ast.Null:
+		return ast.NewNull()
+	case *ast.Boolean:
+		r := *v
+		return &r
+	case *ast.Integer:
+		r := *v
+		return &r
+	case *ast.String:
+		r := *v
+		return &r
+	}
+	return e
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{outer: outer, m: map[string]ast.Expression{}}
+}
+
+// scope : literals of the consts which can be inlined
+type scope struct {
+	outer *scope
+	m     map[string]ast.Expression
+}
+
+func (this *scope) get(name string) (ast.Expression, bool) {
+	for s := this; nil != s; s = s.outer {
+		if v, ok := s.m[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func (this *scope) set(name string, v ast.Expression) {
+	this.m[name] = v
+}
@@ -0,0 +1,56 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/jobs-github/escript/ast"
+	"github.com/jobs-github/escript/parser"
+)
+
+func parseProgram(t *testing.T, code string) ast.Node {
+	p, err := parser.New(code)
+	if nil != err {
+		t.Fatal(err)
+	}
+	program, err := p.ParseProgram()
+	if nil != err {
+		t.Fatal(err)
+	}
+	return program
+}
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`(1 > 0) ? (1 + 1) : (10 % 3)`, `2`},
+		{`(1 < 0) ? 1 : f()`, `f()`},
+		{`-(2 ** 3) + ~0`, `-9`},
+		{`!true == false`, `true`},
+		{`"a" + "b" + str(1)`, `(ab + str(1))`},
+		{"`x${1 + 2}y`", `x3y`},
+		{`9223372036854775807 + 1`, `9223372036854775808`},
+		{`const a = 2; const b = a * 3; func f(x) { x + b }; f(a)`, `const a = 2;const b = 6;func ff(x){(x + 6)};f(2)`},
+		{`const a = 1; a ? "x" : "y"`, `const a = 1;x`},
+		{`const s = "abc"; s.len()`, `const s = abc;abc.len()`},
+		{`const n = 2; map([1], func(i, x) { x * n })`, `const n = 2;map([1],func (i, x){(x * 2)})`},
+		// not inlined: bound more than once, or the value is not a literal
+		{`const a = 1; func f(a) { a }; a`, `const a = 1;func ff(a){a};a`},
+		{`const a = 1; const a = 2; a`, `const a = 1;const a = 2;a`},
+		{`const a = 1; match 2 { a => a, _ => 0 }`, `const a = 1;match 2 { a => a, _ => 0 }`},
+		{`const a = [1]; a[0]`, `const a = [1];(a[0])`},
+		// failures are left to runtime
+		{`1 / 0`, `(1 / 0)`},
+		{`"a" - 1`, `(a - 1)`},
+	}
+	for _, tt := range tests {
+		program := parseProgram(t, tt.input)
+		if err := Optimize(program); nil != err {
+			t.Fatalf("`%v` err: %v", tt.input, err)
+		}
+		if program.String() != tt.expected {
+			t.Fatalf("`%v` expect `%v`, got `%v`", tt.input, tt.expected, program.String())
+		}
+	}
+}
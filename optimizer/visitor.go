@@ -0,0 +1,390 @@
+package optimizer
+
+import (
+	"github.com/jobs-github/escript/ast"
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+)
+
+func (this *optimizer) DoProgram(v *ast.Program) error {
+	for _, stmt := range v.Stmts {
+		if err := stmt.Do(this); nil != err {
+			return function.NewError(err)
+		}
+	}
+	return nil
+}
+
+func (this *optimizer) DoConst(v *ast.ConstStmt) error {
+	r, err := this.expr(v.Value)
+	if nil != err {
+		return function.NewError(err)
+	}
+	v.Value = r
+	if nil != v.Pattern {
+		this.bind(v.Pattern.Names()...)
+		return nil
+	}
+	this.bind(v.Name.Value)
+	if !this.counting && 1 == this.bindings[v.Name.Value] && isLiteral(v.Value) {
+		this.scope.set(v.Name.Value, v.Value)
+	}
+	return nil
+}
+
+func (this *optimizer) DoBlock(v *ast.BlockStmt) error {
+	this.enterScope()
+	defer this.leaveScope()
+	return v.Stmt.Do(this)
+}
+
+func (this *optimizer) DoExpr(v *ast.ExpressionStmt) error {
+	r, err := this.expr(v.Expr)
+	if nil != err {
+		return function.NewError(err)
+	}
+	v.Expr = r
+	return nil
+}
+
+func (this *optimizer) DoLoop(v *ast.LoopExpr) error {
+	var err error
+	if v.Cnt, err = this.expr(v.Cnt); nil != err {
+		return function.NewError(err)
+	}
+	if v.Body, err = this.operand(v.Body); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+func (this *optimizer) DoMap(v *ast.MapExpr) error {
+	var err error
+	if v.Arr, err = this.operand(v.Arr); nil != err {
+		return function.NewError(err)
+	}
+	if v.Body, err = this.operand(v.Body); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+func (this *optimizer) DoReduce(v *ast.ReduceExpr) error {
+	var err error
+	if v.Arr, err = this.operand(v.Arr); nil != err {
+		return function.NewError(err)
+	}
+	if v.Body, err = this.operand(v.Body); nil != err {
+		return function.NewError(err)
+	}
+	if v.Init, err = this.expr(v.Init); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+func (this *optimizer) DoFilter(v *ast.FilterExpr) error {
+	var err error
+	if v.Arr, err = this.operand(v.Arr); nil != err {
+		return function.NewError(err)
+	}
+	if v.Body, err = this.operand(v.Body); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+func (this *optimizer) DoRange(v *ast.RangeExpr) error {
+	var err error
+	if v.Cnt, err = this.expr(v.Cnt); nil != err {
+		return function.NewError(err)
+	}
+	if v.Body, err = this.operand(v.Body); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+func (this *optimizer) DoFunction(v *ast.FunctionStmt) error {
+	this.bind(v.Name.Value)
+	return v.Value.Do(this)
+}
+
+func (this *optimizer) DoStruct(v *ast.StructStmt) error {
+	this.bind(v.Name.Value)
+	return nil
+}
+
+func (this *optimizer) DoMethod(v *ast.MethodStmt) error {
+	this.enterScope()
+	defer this.leaveScope()
+	this.bind(v.Recv.Value)
+	return v.Value.Do(this)
+}
+
+func (this *optimizer) DoPrefix(v *ast.PrefixExpr) error {
+	r, err := this.expr(v.Right)
+	if nil != err {
+		return function.NewError(err)
+	}
+	v.Right = r
+	if isLiteral(v.Right) {
+		this.fold(v)
+	}
+	return nil
+}
+
+func (this *optimizer) DoInfix(v *ast.InfixExpr) error {
+	var err error
+	if v.Left, err = this.expr(v.Left); nil != err {
+		return function.NewError(err)
+	}
+	if v.Right, err = this.expr(v.Right); nil != err {
+		return function.NewError(err)
+	}
+	if isLiteral(v.Left) && isLiteral(v.Right) {
+		this.fold(v)
+	}
+	return nil
+}
+
+func (this *optimizer) DoIdent(v *ast.Identifier) error {
+	if this.counting {
+		return nil
+	}
+	if lit, ok := this.scope.get(v.Value); ok {
+		r := clone(lit)
+		r.SetPos(v.Pos())
+		this.result = r
+	}
+	return nil
+}
+
+func (this *optimizer) DoSymbol(v *ast.SymbolExpr) error {
+	return nil
+}
+
+func (this *optimizer) DoConditional(v *ast.ConditionalExpr) error {
+	var err error
+	if v.Cond, err = this.expr(v.Cond); nil != err {
+		return function.NewError(err)
+	}
+	if v.Yes, err = this.expr(v.Yes); nil != err {
+		return function.NewError(err)
+	}
+	if v.No, err = this.expr(v.No); nil != err {
+		return function.NewError(err)
+	}
+	if this.counting || !isLiteral(v.Cond) {
+		return nil
+	}
+	cond, err := v.Cond.Eval(object.NewEnv(nil))
+	if nil != err {
+		return nil
+	}
+	if cond.True() {
+		this.result = v.Yes
+	} else {
+		this.result = v.No
+	}
+	return nil
+}
+
+func (this *optimizer) DoNullish(v *ast.NullishExpr) error {
+	var err error
+	if v.Left, err = this.expr(v.Left); nil != err {
+		return function.NewError(err)
+	}
+	if v.Right, err = this.expr(v.Right); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+func (this *optimizer) DoIn(v *ast.InExpr) error {
+	var err error
+	if v.Left, err = this.expr(v.Left); nil != err {
+		return function.NewError(err)
+	}
+	if v.Right, err = this.expr(v.Right); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+func (this *optimizer) DoInterval(v *ast.IntervalExpr) error {
+	var err error
+	if v.Start, err = this.expr(v.Start); nil != err {
+		return function.NewError(err)
+	}
+	if v.End, err = this.expr(v.End); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+// DoMatch : literal patterns are kept, the names of patterns are bound in the arms
+func (this *optimizer) DoMatch(v *ast.MatchExpr) error {
+	var err error
+	if v.Value, err = this.expr(v.Value); nil != err {
+		return function.NewError(err)
+	}
+	for _, arm := range v.Arms {
+		if err := this.doArm(arm); nil != err {
+			return function.NewError(err)
+		}
+	}
+	return nil
+}
+
+func (this *optimizer) doArm(arm *ast.MatchArm) error {
+	this.enterScope()
+	defer this.leaveScope()
+	this.bind(arm.Pattern.Names()...)
+	var err error
+	if arm.Guard, err = this.expr(arm.Guard); nil != err {
+		return function.NewError(err)
+	}
+	if arm.Body, err = this.expr(arm.Body); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+func (this *optimizer) DoFn(v *ast.Function) error {
+	this.enterScope()
+	defer this.leaveScope()
+	if "" != v.Lambda {
+		this.bind(v.Lambda)
+	}
+	for _, arg := range v.Args {
+		this.bind(arg.Value)
+	}
+	for _, p := range v.Params {
+		this.bind(p.Names()...)
+	}
+	if nil != v.Rest {
+		this.bind(v.Rest.Value)
+	}
+	if err := this.exprs(v.Defaults); nil != err {
+		return function.NewError(err)
+	}
+	return v.Body.Do(this)
+}
+
+func (this *optimizer) DoCall(v *ast.Call) error {
+	var err error
+	if v.Func, err = this.operand(v.Func); nil != err {
+		return function.NewError(err)
+	}
+	return this.exprs(v.Args)
+}
+
+func (this *optimizer) DoSpread(v *ast.SpreadExpr) error {
+	r, err := this.expr(v.Value)
+	if nil != err {
+		return function.NewError(err)
+	}
+	v.Value = r
+	return nil
+}
+
+func (this *optimizer) DoCallMember(v *ast.CallMember) error {
+	var err error
+	if v.Left, err = this.expr(v.Left); nil != err {
+		return function.NewError(err)
+	}
+	return this.exprs(v.Args)
+}
+
+func (this *optimizer) DoObjectMember(v *ast.ObjectMember) error {
+	r, err := this.expr(v.Left)
+	if nil != err {
+		return function.NewError(err)
+	}
+	v.Left = r
+	return nil
+}
+
+func (this *optimizer) DoIndex(v *ast.IndexExpr) error {
+	var err error
+	if v.Left, err = this.expr(v.Left); nil != err {
+		return function.NewError(err)
+	}
+	if v.Index, err = this.expr(v.Index); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+func (this *optimizer) DoSlice(v *ast.SliceExpr) error {
+	var err error
+	if v.Left, err = this.expr(v.Left); nil != err {
+		return function.NewError(err)
+	}
+	if v.Start, err = this.expr(v.Start); nil != err {
+		return function.NewError(err)
+	}
+	if v.End, err = this.expr(v.End); nil != err {
+		return function.NewError(err)
+	}
+	if v.Step, err = this.expr(v.Step); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+func (this *optimizer) DoTry(v *ast.TryExpr) error {
+	if err := v.Try.Do(this); nil != err {
+		return function.NewError(err)
+	}
+	this.enterScope()
+	defer this.leaveScope()
+	if nil != v.Name {
+		this.bind(v.Name.Value)
+	}
+	return v.Catch.Do(this)
+}
+
+func (this *optimizer) DoNull(v *ast.Null) error       { return nil }
+func (this *optimizer) DoInteger(v *ast.Integer) error { return nil }
+func (this *optimizer) DoBoolean(v *ast.Boolean) error { return nil }
+func (this *optimizer) DoString(v *ast.String) error   { return nil }
+
+func (this *optimizer) DoTemplate(v *ast.TemplateExpr) error {
+	if err := this.exprs(v.Parts); nil != err {
+		return function.NewError(err)
+	}
+	for _, part := range v.Parts {
+		if !isLiteral(part) {
+			return nil
+		}
+	}
+	this.fold(v)
+	return nil
+}
+
+func (this *optimizer) DoArray(v *ast.Array) error {
+	return this.exprs(v.Items)
+}
+
+// DoHash : the pairs are rebuilt as the keys may be replaced
+func (this *optimizer) DoHash(v *ast.Hash) error {
+	keys := ast.ExpressionSlice{}
+	pairs := ast.ExpressionMap{}
+	for _, k := range v.OrderedKeys() {
+		key, err := this.expr(k)
+		if nil != err {
+			return function.NewError(err)
+		}
+		val, err := this.expr(v.Pairs[k])
+		if nil != err {
+			return function.NewError(err)
+		}
+		keys = append(keys, key)
+		pairs[key] = val
+	}
+	v.Keys = keys
+	v.Pairs = pairs
+	return nil
+}
@@ -18,24 +18,28 @@ func newExprParser(s scanner, p Parser) exprParser {
 
 	return &exprParserImpl{
 		m: map[token.TokenType]tokenDecoder{
-			token.SYMBOL: &symbolExpr{s},
-			token.IDENT:  &identifier{s},
-			token.INT:    &integer{s},
-			token.STRING: &stringExpr{s},
-			token.TRUE:   bd,
-			token.FALSE:  bd,
-			token.NULL:   &null{s},
-			token.NOT:    pd,
-			token.SUB:    pd,
-			token.LPAREN: &lparen{s, p},
-			token.LBRACK: &lbrack{s, p},
-			token.LBRACE: &lbrace{s, p},
-			token.FUNC:   &lambdaFunction{s, p},
-			token.LOOP:   &loopExpr{s, p},
-			token.MAP:    &mapExpr{s, p},
-			token.REDUCE: &reduceExpr{s, p},
-			token.FILTER: &filterExpr{s, p},
-			token.RANGE:  &rangeExpr{s, p},
+			token.SYMBOL:   &symbolExpr{s},
+			token.IDENT:    &identifier{s},
+			token.INT:      &integer{s},
+			token.STRING:   &stringExpr{s},
+			token.TEMPLATE: &templateExpr{s},
+			token.TRUE:     bd,
+			token.FALSE:    bd,
+			token.NULL:     &null{s},
+			token.NOT:      pd,
+			token.SUB:      pd,
+			token.BITNOT:   pd,
+			token.LPAREN:   &lparen{s, p},
+			token.LBRACK:   &lbrack{s, p},
+			token.LBRACE:   &lbrace{s, p},
+			token.FUNC:     &lambdaFunction{s, p},
+			token.LOOP:     &loopExpr{s, p},
+			token.MAP:      &mapExpr{s, p},
+			token.REDUCE:   &reduceExpr{s, p},
+			token.FILTER:   &filterExpr{s, p},
+			token.RANGE:    &rangeExpr{s, p},
+			token.TRY:      &tryExpr{s, p},
+			token.MATCH:    &matchExpr{s, p},
 		},
 	}
 }
@@ -126,6 +130,15 @@ func (this *stringExpr) decode() (ast.Expression, error) {
 	return this.s.NewString(), nil
 }
 
+// templateExpr : implement tokenDecoder
+type templateExpr struct {
+	s scanner
+}
+
+func (this *templateExpr) decode() (ast.Expression, error) {
+	return this.s.NewTemplate()
+}
+
 // boolean : implement tokenDecoder
 type boolean struct {
 	s scanner
@@ -227,7 +240,7 @@ func (this *lbrace) decode() (ast.Expression, error) {
 		if nil != err {
 			return nil, function.NewError(err)
 		}
-		h.Pairs[key] = val
+		h.Set(key, val)
 		if this.eof() {
 			break
 		}
@@ -348,3 +361,43 @@ func (this *rangeExpr) decode() (ast.Expression, error) {
 	expr.Body = body
 	return expr, nil
 }
+
+// tryExpr : implement tokenDecoder
+type tryExpr struct {
+	s scanner
+	p Parser
+}
+
+// try { ... } catch (e) { ... }
+func (this *tryExpr) decode() (ast.Expression, error) {
+	expr := ast.NewTry()
+	if err := this.s.ExpectPeek(token.LBRACE); nil != err {
+		return nil, function.NewError(err)
+	}
+	var err error
+	expr.Try, err = this.p.ParseBlockStmt()
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	if err := this.s.ExpectPeek(token.CATCH); nil != err {
+		return nil, function.NewError(err)
+	}
+	if nil == this.s.PeekIs(token.LPAREN) {
+		this.s.NextToken()
+		if err := this.s.ExpectPeek(token.IDENT); nil != err {
+			return nil, function.NewError(err)
+		}
+		expr.Name = this.s.GetIdentifier()
+		if err := this.s.ExpectPeek(token.RPAREN); nil != err {
+			return nil, function.NewError(err)
+		}
+	}
+	if err := this.s.ExpectPeek(token.LBRACE); nil != err {
+		return nil, function.NewError(err)
+	}
+	expr.Catch, err = this.p.ParseBlockStmt()
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	return expr, nil
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/jobs-github/escript/function"
 	"github.com/jobs-github/escript/token"
@@ -18,14 +19,22 @@ type lexerImpl struct {
 	position     int
 	nextPosition int
 	ch           byte
+	line         int // line of ch, starts from 1
+	col          int // column of ch, starts from 1
+	comments     []*token.Comment
 }
 
 func newLexer(input string) Lexer {
-	l := &lexerImpl{input: input}
+	l := &lexerImpl{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
+// Tokenize : tokens with positions, comments are kept as the leading trivia of the next token
+func Tokenize(code string) ([]*token.Token, error) {
+	return newLexer(code).Parse()
+}
+
 func newToken(tokenType token.TokenType, ch byte) *token.Token {
 	return &token.Token{Type: tokenType, Literal: string(ch)}
 }
@@ -55,6 +64,19 @@ func (this *lexerImpl) endofComment() bool {
 	return this.ch == '*' && this.peekChar() == '/'
 }
 
+func (this *lexerImpl) startofLineComment() bool {
+	return this.ch == '/' && this.peekChar() == '/'
+}
+
+func (this *lexerImpl) startofShebang() bool {
+	return 0 == this.position && this.ch == '#' && this.peekChar() == '!'
+}
+
+func (this *lexerImpl) errorf(line int, col int, format string, a ...interface{}) error {
+	err := fmt.Errorf("%v, line: %v, col: %v", fmt.Sprintf(format, a...), line, col)
+	return function.NewError(err)
+}
+
 func (this *lexerImpl) skipWhitespace() {
 	this.readChar()
 
@@ -67,35 +89,55 @@ func (this *lexerImpl) skipWhitespace() {
 	}
 }
 
-func (this *lexerImpl) skipComment() {
+func (this *lexerImpl) addComment(start int, line int, col int) {
+	this.comments = append(this.comments, &token.Comment{
+		Text: this.input[start:this.position],
+		Line: line,
+		Col:  col,
+	})
+}
+
+func (this *lexerImpl) skipComment() error {
+	start, line, col := this.position, this.line, this.col
 	this.readChar()
 	this.readChar()
 
-	if this.eof() {
-		return
-	}
-
 	for !this.endofComment() {
-		this.readChar()
-
 		if this.eof() {
-			return
+			return this.errorf(line, col, "unterminated comment")
 		}
+		this.readChar()
 	}
 	this.readChar()
 	this.readChar()
+	this.addComment(start, line, col)
+	return nil
+}
+
+// skipLineComment : `// ...` or the `#!` shebang, ends before the line break
+func (this *lexerImpl) skipLineComment() {
+	start, line, col := this.position, this.line, this.col
+	for this.ch != '\n' && !this.eof() {
+		this.readChar()
+	}
+	this.addComment(start, line, col)
 }
 
-func (this *lexerImpl) skip() {
+func (this *lexerImpl) skip() error {
 	for {
 		if isWhitespace(this.ch) {
 			this.skipWhitespace()
 		} else if this.startofComment() {
-			this.skipComment()
+			if err := this.skipComment(); nil != err {
+				return err
+			}
+		} else if this.startofLineComment() || this.startofShebang() {
+			this.skipLineComment()
 		} else {
 			break
 		}
 	}
+	return nil
 }
 
 func (this *lexerImpl) twoCharToken(tokenType token.TokenType, expectedNextChar byte, tokenType2 token.TokenType, literal string) *token.Token {
@@ -107,6 +149,56 @@ func (this *lexerImpl) twoCharToken(tokenType token.TokenType, expectedNextChar
 	}
 }
 
+// optionalToken : ? ?? ?.
+func (this *lexerImpl) optionalToken() *token.Token {
+	switch this.peekChar() {
+	case '?':
+		this.readChar()
+		return &token.Token{Type: token.NULLISH, Literal: "??"}
+	case '.':
+		this.readChar()
+		return &token.Token{Type: token.QPERIOD, Literal: "?."}
+	default:
+		return newToken(token.QUESTION, this.ch)
+	}
+}
+
+// periodToken : . .. ..< ...
+func (this *lexerImpl) periodToken() *token.Token {
+	if '.' != this.peekChar() {
+		return newToken(token.PERIOD, this.ch)
+	}
+	this.readChar()
+	switch this.peekChar() {
+	case '<':
+		this.readChar()
+		return &token.Token{Type: token.DOTDOTLT, Literal: "..<"}
+	case '.':
+		this.readChar()
+		return &token.Token{Type: token.ELLIPSIS, Literal: token.Ellipsis}
+	}
+	return &token.Token{Type: token.DOTDOT, Literal: ".."}
+}
+
+// notIn : `not` followed by blanks on the same line and the `in` keyword
+func (this *lexerImpl) notIn() bool {
+	i := this.position
+	for i < len(this.input) && (' ' == this.input[i] || '\t' == this.input[i]) {
+		i++
+	}
+	if !strings.HasPrefix(this.input[i:], token.In) {
+		return false
+	}
+	i += len(token.In)
+	if i < len(this.input) && (isLetter(this.input[i]) || isDigit(this.input[i])) {
+		return false
+	}
+	for this.position < i {
+		this.readChar()
+	}
+	return true
+}
+
 func (this *lexerImpl) Parse() ([]*token.Token, error) {
 	toks := []*token.Token{}
 	for {
@@ -127,9 +219,23 @@ func (this *lexerImpl) eof() bool {
 }
 
 func (this *lexerImpl) nextToken() (*token.Token, error) {
-	var tok *token.Token
-	this.skip()
+	if err := this.skip(); nil != err {
+		return newToken(token.ILLEGAL, this.ch), err
+	}
+	line, col := this.line, this.col
+	tok, err := this.readToken()
+	if nil != err {
+		return tok, err
+	}
+	tok.Line = line
+	tok.Col = col
+	tok.Comments = this.comments
+	this.comments = nil
+	return tok, nil
+}
 
+func (this *lexerImpl) readToken() (*token.Token, error) {
+	var tok *token.Token
 	if this.eof() {
 		return &token.Token{Type: token.EOF, Literal: ""}, nil
 	}
@@ -142,17 +248,41 @@ func (this *lexerImpl) nextToken() (*token.Token, error) {
 			tok = &token.Token{Type: token.STRING, Literal: s}
 		}
 	case '&':
-		tok = this.twoCharToken(token.ILLEGAL, '&', token.AND, "&&")
+		tok = this.twoCharToken(token.BITAND, '&', token.AND, "&&")
 	case '|':
-		tok = this.twoCharToken(token.ILLEGAL, '|', token.OR, "||")
+		tok = this.twoCharToken(token.BITOR, '|', token.OR, "||")
+	case '*':
+		tok = this.twoCharToken(token.MUL, '*', token.POW, "**")
 	case '=':
-		tok = this.twoCharToken(token.ASSIGN, '=', token.EQ, "==")
+		if '>' == this.peekChar() {
+			tok = this.twoCharToken(token.ASSIGN, '>', token.ARROW, "=>")
+		} else {
+			tok = this.twoCharToken(token.ASSIGN, '=', token.EQ, "==")
+		}
 	case '!':
 		tok = this.twoCharToken(token.NOT, '=', token.NEQ, "!=")
 	case '<':
-		tok = this.twoCharToken(token.LT, '=', token.LEQ, "<=")
+		if '<' == this.peekChar() {
+			tok = this.twoCharToken(token.LT, '<', token.SHL, "<<")
+		} else {
+			tok = this.twoCharToken(token.LT, '=', token.LEQ, "<=")
+		}
 	case '>':
-		tok = this.twoCharToken(token.GT, '=', token.GEQ, ">=")
+		if '>' == this.peekChar() {
+			tok = this.twoCharToken(token.GT, '>', token.SHR, ">>")
+		} else {
+			tok = this.twoCharToken(token.GT, '=', token.GEQ, ">=")
+		}
+	case '`':
+		if s, err := this.readTemplate(); nil != err {
+			return newToken(token.ILLEGAL, this.ch), function.NewError(err)
+		} else {
+			tok = &token.Token{Type: token.TEMPLATE, Literal: s}
+		}
+	case '?':
+		tok = this.optionalToken()
+	case '.':
+		tok = this.periodToken()
 	case '$':
 		this.readChar()
 		if isLetter(this.ch) {
@@ -168,6 +298,9 @@ func (this *lexerImpl) nextToken() (*token.Token, error) {
 		} else {
 			if isLetter(this.ch) {
 				literal := this.readIdentifier()
+				if literal == "not" && this.notIn() {
+					return &token.Token{Type: token.NOTIN, Literal: token.NotIn}, nil
+				}
 				return &token.Token{Type: token.LookupIdent(literal), Literal: literal}, nil
 			} else if isDigit(this.ch) {
 				return &token.Token{Type: token.INT, Literal: this.readNumber()}, nil
@@ -206,23 +339,38 @@ func (this *lexerImpl) checkEscape(ch byte) bool {
 }
 
 func (this *lexerImpl) readString() (string, error) {
-	start := this.position + 1
+	start, line, col := this.position+1, this.line, this.col
 	for {
 		this.readChar()
+		if this.eof() {
+			return "", this.errorf(line, col, "unterminated string")
+		}
 		if this.ch == '"' {
 			break
 		}
 		if this.ch == '\\' {
 			this.readChar()
 			if !this.checkEscape(this.ch) {
-				err := fmt.Errorf("unexpected escape, pos: %v, %v", this.position, this.input[this.position:])
-				return "", function.NewError(err)
+				return "", this.errorf(this.line, this.col, "unexpected escape `\\%c`", this.ch)
 			}
 		}
 	}
 	return this.input[start:this.position], nil
 }
 
+// readTemplate : the raw content between backticks, `${}` is split by the parser
+func (this *lexerImpl) readTemplate() (string, error) {
+	start := this.position + 1
+	end, err := templateEnd(this.input, start)
+	if nil != err {
+		return "", this.errorf(this.line, this.col, "%v", err)
+	}
+	for this.position < end {
+		this.readChar()
+	}
+	return this.input[start:end], nil
+}
+
 func (this *lexerImpl) peekChar() byte {
 	if this.nextPosition >= len(this.input) {
 		return 0
@@ -232,6 +380,11 @@ func (this *lexerImpl) peekChar() byte {
 }
 
 func (this *lexerImpl) readChar() {
+	if this.ch == '\n' {
+		this.line++
+		this.col = 0
+	}
+	this.col++
 	if this.nextPosition >= len(this.input) {
 		this.ch = 0
 	} else {
@@ -17,6 +17,10 @@ type Parser interface {
 	ParseIndexExpression(left ast.Expression) (ast.Expression, error)
 	ParseMemberExpression(left ast.Expression) (ast.Expression, error)
 	ParseConditionalExpression(left ast.Expression) (ast.Expression, error)
+	ParseNullishExpression(left ast.Expression) (ast.Expression, error)
+	ParseOptionalExpression(left ast.Expression) (ast.Expression, error)
+	ParseInExpression(left ast.Expression) (ast.Expression, error)
+	ParseIntervalExpression(left ast.Expression) (ast.Expression, error)
 
 	ParseStmt(endTok token.TokenType) (ast.Statement, error)
 	ParseBlockStmt() (*ast.BlockStmt, error)
@@ -25,6 +29,14 @@ type Parser interface {
 }
 
 func New(code string) (Parser, error) {
+	p, err := newParser(code)
+	if nil == p {
+		return nil, err
+	}
+	return p, nil
+}
+
+func newParser(code string) (*parserImpl, error) {
 	l := newLexer(code)
 	s, err := newScanner(l)
 	if nil == s {
@@ -62,19 +74,45 @@ func newInfixDecoders(p Parser) infixDecoderMap {
 		token.GEQ:      p.ParseInfixExpression,
 		token.AND:      p.ParseInfixExpression,
 		token.OR:       p.ParseInfixExpression,
+		token.BITAND:   p.ParseInfixExpression,
+		token.BITOR:    p.ParseInfixExpression,
+		token.BITXOR:   p.ParseInfixExpression,
+		token.SHL:      p.ParseInfixExpression,
+		token.SHR:      p.ParseInfixExpression,
+		token.POW:      p.ParseInfixExpression,
 		token.LPAREN:   p.ParseCallExpression,
 		token.LBRACK:   p.ParseIndexExpression,
 		token.PERIOD:   p.ParseMemberExpression,
 		token.QUESTION: p.ParseConditionalExpression,
+		token.NULLISH:  p.ParseNullishExpression,
+		token.QPERIOD:  p.ParseOptionalExpression,
+		token.IN:       p.ParseInExpression,
+		token.NOTIN:    p.ParseInExpression,
+		token.DOTDOT:   p.ParseIntervalExpression,
+		token.DOTDOTLT: p.ParseIntervalExpression,
 	}
 }
 
 func (this *parserImpl) ParseStmt(endTok token.TokenType) (ast.Statement, error) {
-	return this.sp.Decode(this.s.CurTokenType(), endTok)
+	pos := this.s.CurPos()
+	stmt, err := this.sp.Decode(this.s.CurTokenType(), endTok)
+	if nil != err {
+		return nil, err
+	}
+	setPos(stmt, pos)
+	return stmt, nil
+}
+
+// setPos : a node keeps the first position it is given, e.g. `(a + b)` is at `a + b`
+func setPos(node ast.Node, pos ast.Position) {
+	if !node.Pos().Valid() {
+		node.SetPos(pos)
+	}
 }
 
 func (this *parserImpl) ParseProgram() (ast.Node, error) {
 	program := &ast.Program{Stmts: ast.StatementSlice{}}
+	decls := declarations{}
 	for !this.s.Eof() {
 		// need to skip ;
 		if nil == this.s.CurrentIs(token.SEMICOLON) {
@@ -86,6 +124,9 @@ func (this *parserImpl) ParseProgram() (ast.Node, error) {
 		if nil != err {
 			return nil, function.NewError(err)
 		}
+		if err := decls.add(stmt); nil != err {
+			return nil, function.NewError(err)
+		}
 		program.Stmts = append(program.Stmts, stmt)
 		this.s.NextToken()
 	}
@@ -107,10 +148,12 @@ func (this *parserImpl) ParseBlockStmt() (*ast.BlockStmt, error) {
 }
 
 func (this *parserImpl) ParseExpression(precedence int) (ast.Expression, error) {
+	pos := this.s.CurPos()
 	leftExpr, err := this.ep.Decode(this.s.CurTokenType())
 	if nil != err {
 		return nil, function.NewError(err)
 	}
+	setPos(leftExpr, pos)
 
 	for nil != this.s.PeekIs(token.SEMICOLON) && precedence < this.s.PeekPrecedence() {
 		fn, ok := this.im[this.s.PeekTokenType()]
@@ -118,10 +161,12 @@ func (this *parserImpl) ParseExpression(precedence int) (ast.Expression, error)
 			return leftExpr, nil
 		}
 		this.s.NextToken()
+		pos := this.s.CurPos()
 		expr, err := fn(leftExpr)
 		if nil != err {
 			return nil, function.NewError(err)
 		}
+		setPos(expr, pos)
 		leftExpr = expr
 	}
 	return leftExpr, nil
@@ -130,6 +175,10 @@ func (this *parserImpl) ParseExpression(precedence int) (ast.Expression, error)
 func (this *parserImpl) ParseInfixExpression(left ast.Expression) (ast.Expression, error) {
 	expr := this.s.NewInfix(left)
 	preced := this.s.CurPrecedence()
+	if expr.Op.TypeIs(token.POW) {
+		// right associative, 2 ** 3 ** 2 == 2 ** (3 ** 2)
+		preced--
+	}
 	this.s.NextToken()
 	right, err := this.ParseExpression(preced)
 	if nil != err {
@@ -141,7 +190,7 @@ func (this *parserImpl) ParseInfixExpression(left ast.Expression) (ast.Expressio
 
 func (this *parserImpl) ParseCallExpression(left ast.Expression) (ast.Expression, error) {
 	expr := this.s.NewCall(left)
-	args, err := this.ParseExpressions(token.RPAREN)
+	args, err := this.parseCallArgs()
 	if nil != err {
 		return nil, function.NewError(err)
 	}
@@ -150,19 +199,70 @@ func (this *parserImpl) ParseCallExpression(left ast.Expression) (ast.Expression
 }
 
 func (this *parserImpl) ParseIndexExpression(left ast.Expression) (ast.Expression, error) {
-	expr := this.s.NewIndex(left)
+	return this.parseIndexExpression(left, false)
+}
+
+func (this *parserImpl) parseIndexExpression(left ast.Expression, optional bool) (ast.Expression, error) {
 	this.s.NextToken()
+	// a[:end]
+	if nil == this.s.CurrentIs(token.COLON) {
+		if optional {
+			return nil, function.NewError(errOptionalSlice)
+		}
+		return this.parseSliceExpression(left, nil)
+	}
 	idx, err := this.ParseExpression(PRECED_LOWEST)
 	if nil != err {
 		return nil, function.NewError(err)
 	}
+	// a[start:end:step]
+	if nil == this.s.PeekIs(token.COLON) {
+		if optional {
+			return nil, function.NewError(errOptionalSlice)
+		}
+		this.s.NextToken()
+		return this.parseSliceExpression(left, idx)
+	}
 	if err := this.s.ExpectPeek(token.RBRACK); nil != err {
 		return nil, function.NewError(err)
 	}
+	expr := this.s.NewIndex(left)
 	expr.Index = idx
+	expr.Optional = optional
 	return expr, nil
 }
 
+// parseSliceExpression : current token is the first colon
+func (this *parserImpl) parseSliceExpression(left ast.Expression, start ast.Expression) (ast.Expression, error) {
+	expr := this.s.NewSlice(left)
+	expr.Start = start
+	end, err := this.parseSliceBound()
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	expr.End = end
+	if nil == this.s.PeekIs(token.COLON) {
+		this.s.NextToken()
+		step, err := this.parseSliceBound()
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		expr.Step = step
+	}
+	if err := this.s.ExpectPeek(token.RBRACK); nil != err {
+		return nil, function.NewError(err)
+	}
+	return expr, nil
+}
+
+func (this *parserImpl) parseSliceBound() (ast.Expression, error) {
+	if nil == this.s.PeekIs(token.COLON) || nil == this.s.PeekIs(token.RBRACK) {
+		return nil, nil
+	}
+	this.s.NextToken()
+	return this.ParseExpression(PRECED_LOWEST)
+}
+
 func (this *parserImpl) isCallMemeber() bool {
 	return this.s.ExpectPeek2(token.IDENT, token.LPAREN)
 }
@@ -171,8 +271,9 @@ func (this *parserImpl) isObjectMember() bool {
 	return nil == this.s.PeekIs(token.IDENT) && nil != this.s.Peek2Is(token.LPAREN)
 }
 
-func (this *parserImpl) parseCallMemberExpression(left ast.Expression) (ast.Expression, error) {
+func (this *parserImpl) parseCallMemberExpression(left ast.Expression, optional bool) (ast.Expression, error) {
 	expr := this.s.NewCallMember(left)
+	expr.Optional = optional
 	this.s.NextToken()
 
 	expr.Func = this.s.GetIdentifier()
@@ -185,24 +286,76 @@ func (this *parserImpl) parseCallMemberExpression(left ast.Expression) (ast.Expr
 	return expr, nil
 }
 
-func (this *parserImpl) parseObjectMemberExpression(left ast.Expression) (ast.Expression, error) {
+func (this *parserImpl) parseObjectMemberExpression(left ast.Expression, optional bool) (ast.Expression, error) {
 	expr := this.s.NewObjectMember(left)
+	expr.Optional = optional
 	this.s.NextToken()
 	expr.Member = this.s.GetIdentifier()
 	return expr, nil
 }
 
 func (this *parserImpl) ParseMemberExpression(left ast.Expression) (ast.Expression, error) {
+	return this.parseMemberExpression(left, false)
+}
+
+func (this *parserImpl) parseMemberExpression(left ast.Expression, optional bool) (ast.Expression, error) {
 	if this.isCallMemeber() {
-		return this.parseCallMemberExpression(left)
+		return this.parseCallMemberExpression(left, optional)
 	} else if this.isObjectMember() {
-		return this.parseObjectMemberExpression(left)
+		return this.parseObjectMemberExpression(left, optional)
 	} else {
 		err := fmt.Errorf("unknown pattern, %v", this.s.String())
 		return nil, function.NewError(err)
 	}
 }
 
+// ParseOptionalExpression : a?.b, a?.[k], a?.m()
+func (this *parserImpl) ParseOptionalExpression(left ast.Expression) (ast.Expression, error) {
+	if nil == this.s.PeekIs(token.LBRACK) {
+		this.s.NextToken()
+		return this.parseIndexExpression(left, true)
+	}
+	return this.parseMemberExpression(left, true)
+}
+
+func (this *parserImpl) ParseNullishExpression(left ast.Expression) (ast.Expression, error) {
+	expr := this.s.NewNullish(left)
+	preced := this.s.CurPrecedence()
+	this.s.NextToken()
+	right, err := this.ParseExpression(preced)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	expr.Right = right
+	return expr, nil
+}
+
+// ParseInExpression : x in arr, x not in arr
+func (this *parserImpl) ParseInExpression(left ast.Expression) (ast.Expression, error) {
+	expr := this.s.NewIn(left)
+	preced := this.s.CurPrecedence()
+	this.s.NextToken()
+	right, err := this.ParseExpression(preced)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	expr.Right = right
+	return expr, nil
+}
+
+// ParseIntervalExpression : start..end, start..<end
+func (this *parserImpl) ParseIntervalExpression(left ast.Expression) (ast.Expression, error) {
+	expr := this.s.NewInterval(left)
+	preced := this.s.CurPrecedence()
+	this.s.NextToken()
+	end, err := this.ParseExpression(preced)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	expr.End = end
+	return expr, nil
+}
+
 func (this *parserImpl) ParseConditionalExpression(left ast.Expression) (ast.Expression, error) {
 	expr := this.s.NewConditional(left)
 	this.s.NextToken()
@@ -223,6 +376,38 @@ func (this *parserImpl) ParseConditionalExpression(left ast.Expression) (ast.Exp
 	return expr, nil
 }
 
+// parseCallArgs : like ParseExpressions, an arg may be spread by `...`
+func (this *parserImpl) parseCallArgs() (ast.ExpressionSlice, error) {
+	args := ast.ExpressionSlice{}
+	if nil == this.s.PeekIs(token.RPAREN) {
+		this.s.NextToken()
+		return args, nil
+	}
+	for {
+		this.s.NextToken()
+		spread := nil == this.s.CurrentIs(token.ELLIPSIS)
+		if spread {
+			this.s.NextToken()
+		}
+		expr, err := this.ParseExpression(PRECED_LOWEST)
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		if spread {
+			expr = &ast.SpreadExpr{Value: expr}
+		}
+		args = append(args, expr)
+		if nil != this.s.PeekIs(token.COMMA) {
+			break
+		}
+		this.s.NextToken()
+	}
+	if err := this.s.ExpectPeek(token.RPAREN); nil != err {
+		return nil, function.NewError(err)
+	}
+	return args, nil
+}
+
 func (this *parserImpl) ParseExpressions(endTok token.TokenType) (ast.ExpressionSlice, error) {
 	args := ast.ExpressionSlice{}
 	if nil == this.s.PeekIs(endTok) {
@@ -1,10 +1,13 @@
 package parser
 
 import (
+	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/jobs-github/escript/ast"
+	"github.com/jobs-github/escript/token"
 )
 
 func parseProgram(t *testing.T, p Parser) *ast.Program {
@@ -362,6 +365,45 @@ func TestOpPrecedParsing(t *testing.T) {
 		{"a + add(b * c) + d", "((a + add((b * c))) + d)"},
 		{"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))", "add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))"},
 		{"add(a + b + c * d / f + g)", "add((((a + b) + ((c * d) / f)) + g))"},
+		{"a[1:2]", "(a[1:2])"},
+		{"a[:b + 1]", "(a[:(b + 1)])"},
+		{"a[1:]", "(a[1:])"},
+		{"a[:]", "(a[:])"},
+		{"a[::-1]", "(a[::(-1)])"},
+		{"a[-2::2][0]", "((a[(-2)::2])[0])"},
+		{"a[b ? 1 : 2:]", "(a[(b) ? (1) : (2):])"},
+		{"try { a + 1 } catch (e) { e }", "try {(a + 1)} catch (e) {e}"},
+		{"try { a } catch { 0 } + 1", "(try {a} catch {0} + 1)"},
+		{"a & b | c ^ d", "((a & b) | (c ^ d))"},
+		{"a | b && c", "((a | b) && c)"},
+		{"a & b == c", "(a & (b == c))"},
+		{"a << 1 + b", "(a << (1 + b))"},
+		{"a < b << 1", "(a < (b << 1))"},
+		{"a ** b ** c", "(a ** (b ** c))"},
+		{"-a ** b", "(-(a ** b))"},
+		{"a * b ** c", "(a * (b ** c))"},
+		{"~a & b", "((~a) & b)"},
+		{"a ?? b", "(a ?? b)"},
+		{"a ?? b ?? c", "((a ?? b) ?? c)"},
+		{"a || b ?? c", "((a || b) ?? c)"},
+		{"a ?? b ? c : d", "((a ?? b)) ? (c) : (d)"},
+		{"a?.b", "a?.b"},
+		{"a?.[k] + 1", "((a?.[k]) + 1)"},
+		{"a?.b?.c", "a?.b?.c"},
+		{"a?.len() ?? 0", "(a?.len() ?? 0)"},
+		{"a ? b : c", "(a) ? (b) : (c)"},
+		{"a in b", "(a in b)"},
+		{"a not in b", "(a not in b)"},
+		{"a in b && c not in d", "((a in b) && (c not in d))"},
+		{"a + 1 in b", "((a + 1) in b)"},
+		{"a in b == c", "((a in b) == c)"},
+		{"a..b", "(a..b)"},
+		{"a..<b", "(a..<b)"},
+		{"a + 1..b * 2", "((a + 1)..(b * 2))"},
+		{"a << 1..b", "(a << (1..b))"},
+		{"x in 1..<n", "(x in (1..<n))"},
+		{"a.not()", "a.not()"},
+		{"not", "not"},
 	}
 	for _, tt := range cases {
 		p, err := New(tt.input)
@@ -542,6 +584,434 @@ func TestHashParsing(t *testing.T) {
 	}
 }
 
+func TestHashOrderParsing(t *testing.T) {
+	input := `{"z": 1, "a": 2, "m": {"y": 3, "b": 4}}`
+	want := `{z:1, a:2, m:{y:3, b:4}}`
+
+	p, err := New(input)
+	if nil != err {
+		t.Fatal(err)
+	}
+	program := parseProgram(t, p)
+	if str := program.String(); want != str {
+		t.Fatalf("expected %v, got %v", want, str)
+	}
+	b1, err := json.Marshal(program.Encode())
+	if nil != err {
+		t.Fatal(err)
+	}
+	node, err := ast.Decode(b1)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if str := node.String(); want != str {
+		t.Fatalf("expected %v, got %v", want, str)
+	}
+	b2, err := json.Marshal(node.Encode())
+	if nil != err {
+		t.Fatal(err)
+	}
+	if string(b1) != string(b2) {
+		t.Fatalf("expected %v, got %v", string(b1), string(b2))
+	}
+}
+
+func TestNullSafeParsing(t *testing.T) {
+	input := `h?.a?.[1] ?? h?.b.len() ?? h?.c?.len()`
+	want := `(((h?.a?.[1]) ?? h?.b.len()) ?? h?.c?.len())`
+
+	p, err := New(input)
+	if nil != err {
+		t.Fatal(err)
+	}
+	program := parseProgram(t, p)
+	if str := program.String(); want != str {
+		t.Fatalf("expected %v, got %v", want, str)
+	}
+	b, err := json.Marshal(program.Encode())
+	if nil != err {
+		t.Fatal(err)
+	}
+	node, err := ast.Decode(b)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if str := node.String(); want != str {
+		t.Fatalf("expected %v, got %v", want, str)
+	}
+	p, err = New(`a?.[1:2]`)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if _, err := p.ParseProgram(); nil == err {
+		t.Fatal("optional slice expect error")
+	}
+}
+
+func TestInParsing(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`x in [1, 2]`, `(x in [1, 2])`},
+		{`x not in {"a": 1}`, `(x not in {a:1})`},
+		{`x in 1..10`, `(x in (1..10))`},
+		{`x not	in 1..<10`, `(x not in (1..<10))`},
+	}
+	for _, tt := range tests {
+		p, err := New(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := parseProgram(t, p)
+		if str := program.String(); tt.want != str {
+			t.Fatalf("expected %v, got %v", tt.want, str)
+		}
+		b, err := json.Marshal(program.Encode())
+		if nil != err {
+			t.Fatal(err)
+		}
+		node, err := ast.Decode(b)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if str := node.String(); tt.want != str {
+			t.Fatalf("expected %v, got %v", tt.want, str)
+		}
+	}
+}
+
+func TestMatchParsing(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`match x { 1 => a, _ => b }`, `match x { 1 => a, _ => b }`},
+		{`match x { "a" | "b" => 1, -1 | null => 2, }`, `match x { "a" | "b" => 1, -1 | null => 2 }`},
+		{`match x { [a, [b, _]] if a > b => a + b }`, `match x { [a, [b, _]] if (a > b) => (a + b) }`},
+		{`match x { {"type": t, "r": [r]} => t }`, `match x { {"type": t, "r": [r]} => t }`},
+		{`match x + 1 { [] | {} => true }`, `match (x + 1) { [] | {} => true }`},
+		{`match x { {k: 1, name, age: [a, ...b]} => a }`, `match x { {"k": 1, "name": name, "age": [a, ...b]} => a }`},
+		{`match x { [..._] => 0 }`, `match x { [..._] => 0 }`},
+	}
+	for _, tt := range tests {
+		p, err := New(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := parseProgram(t, p)
+		if str := program.String(); tt.want != str {
+			t.Fatalf("expected %v, got %v", tt.want, str)
+		}
+		b, err := json.Marshal(program.Encode())
+		if nil != err {
+			t.Fatal(err)
+		}
+		node, err := ast.Decode(b)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if str := node.String(); tt.want != str {
+			t.Fatalf("expected %v, got %v", tt.want, str)
+		}
+	}
+	for _, code := range []string{
+		`match x { }`,
+		`match x { [a, a] => a }`,
+		`match x { a | 1 => a }`,
+		`match x { 1 => }`,
+		`match x { {[1]: 1} => 1 }`,
+		`match x { [...a, b] => 1 }`,
+		`match x { [...[a]] => 1 }`,
+	} {
+		p, err := New(code)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if _, err := p.ParseProgram(); nil == err {
+			t.Fatalf("`%v` expect error", code)
+		}
+	}
+}
+
+func TestDestructParsing(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`const [a, b, ...rest] = arr;`, `const [a, b, ...rest] = arr;`},
+		{`const {name, age: years} = person;`, `const {"name": name, "age": years} = person;`},
+		{`const {"a": [_, x]} = v;`, `const {"a": [_, x]} = v;`},
+		{`func([a, b], {c}, d) { a }`, `func ([a, b], {"c": c}, d){a}`},
+		{`func f([h, ...t]) { h };`, `func ff([h, ...t]){h};`},
+	}
+	for _, tt := range tests {
+		p, err := New(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := parseProgram(t, p)
+		if str := program.String(); tt.want != str {
+			t.Fatalf("expected %v, got %v", tt.want, str)
+		}
+		b, err := json.Marshal(program.Encode())
+		if nil != err {
+			t.Fatal(err)
+		}
+		node, err := ast.Decode(b)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if str := node.String(); tt.want != str {
+			t.Fatalf("expected %v, got %v", tt.want, str)
+		}
+	}
+	for _, code := range []string{
+		`const [a, a] = x;`,
+		`const {str} = x;`,
+		`const [...a, b] = x;`,
+		`const 1 = x;`,
+		`func([a], {a: [b, b]}) { a }`,
+	} {
+		p, err := New(code)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if _, err := p.ParseProgram(); nil == err {
+			t.Fatalf("`%v` expect error", code)
+		}
+	}
+}
+
+func TestFunctionArgsParsing(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`func(x, y = 10) { x }`, `func (x, y = 10){x}`},
+		{`func(x, y = x + 1, ...rest) { x }`, `func (x, y = (x + 1), ...rest){x}`},
+		{`func(...rest) { rest }`, `func (...rest){rest}`},
+		{`func([a, b] = [1, 2]) { a }`, `func ([a, b] = [1, 2]){a}`},
+		{`f(...a, b, ...[1, 2])`, `f(...a, b, ...[1, 2])`},
+		{`f(...a + b)`, `f(...(a + b))`},
+	}
+	for _, tt := range tests {
+		p, err := New(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := parseProgram(t, p)
+		if str := program.String(); tt.want != str {
+			t.Fatalf("expected %v, got %v", tt.want, str)
+		}
+		b, err := json.Marshal(program.Encode())
+		if nil != err {
+			t.Fatal(err)
+		}
+		node, err := ast.Decode(b)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if str := node.String(); tt.want != str {
+			t.Fatalf("expected %v, got %v", tt.want, str)
+		}
+	}
+	for _, code := range []string{
+		`func(x = 1, y) { x }`,
+		`func(...a, b) { a }`,
+		`func(...) { 1 }`,
+		`[...a]`,
+		`x.push(...a)`,
+	} {
+		p, err := New(code)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if _, err := p.ParseProgram(); nil == err {
+			t.Fatalf("`%v` expect error", code)
+		}
+	}
+}
+
+func TestStructParsing(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`struct Point { x, y };`, `struct Point { x, y };`},
+		{`struct Empty {}`, `struct Empty {  };`},
+		{`func (p Point) dist(o, k = 1) { p.x - o.x }`, `func (p Point) dist(o, k = 1){(p.x - o.x)};`},
+		{`func (x) { x }(1)`, `func (x){x}(1)`},
+	}
+	for _, tt := range tests {
+		p, err := New(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := parseProgram(t, p)
+		if str := program.String(); tt.want != str {
+			t.Fatalf("expected %v, got %v", tt.want, str)
+		}
+		b, err := json.Marshal(program.Encode())
+		if nil != err {
+			t.Fatal(err)
+		}
+		node, err := ast.Decode(b)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if str := node.String(); tt.want != str {
+			t.Fatalf("expected %v, got %v", tt.want, str)
+		}
+	}
+	for _, code := range []string{
+		`struct { x }`,
+		`struct P { x, x }`,
+		`struct P { 1 }`,
+		`struct str { x }`,
+		`func (p P) { p }`,
+		`func (p P q) f() { p }`,
+	} {
+		p, err := New(code)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if _, err := p.ParseProgram(); nil == err {
+			t.Fatalf("`%v` expect error", code)
+		}
+	}
+	for _, tt := range []struct {
+		input string
+		want  string
+	}{
+		{`struct P { x, y }; struct P { x }`, "duplicate struct `P`"},
+		{`struct P { x }; func (p P) f() { 1 }; func (p P) f() { 2 }`, "duplicate method `f` of struct P"},
+	} {
+		p, err := New(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if _, err := p.ParseProgram(); nil == err || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("`%v` expect error `%v`, got %v", tt.input, tt.want, err)
+		}
+	}
+	for _, code := range []string{
+		`struct P { x }; struct Q { x }; func (p P) f() { 1 }; func (q Q) f() { 2 }; func (p P) g() { 3 }`,
+	} {
+		p, err := New(code)
+		if nil != err {
+			t.Fatal(err)
+		}
+		parseProgram(t, p)
+	}
+}
+
+func TestAnnotationParsing(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`const n: int = 1;`, `const n: int = 1;`},
+		{`const a: array<hash<int>> = [];`, `const a: array<hash<int>> = [];`},
+		{`const a: array<array<array<int>>> = [];`, `const a: array<array<array<int>>> = [];`},
+		{`const [a, b]: array<int> = [1, 2];`, `const [a, b]: array<int> = [1, 2];`},
+		{`func f(x: int, y: string = "a"): bool { x }`, `func ff(x: int, y: string = a): bool{x};`},
+		{`func(x, ...rest: array<int>): null { x }`, `func (x, ...rest: array<int>): null{x}`},
+		{`func([a, b]: array<int>, f: func) { a }`, `func ([a, b]: array<int>, f: func){a}`},
+		{`func (p Point) dist(o: Point): int { p.x - o.x }`, `func (p Point) dist(o: Point): int{(p.x - o.x)};`},
+		{`(a > b) ? 1 : 2`, `((a > b)) ? (1) : (2)`},
+	}
+	for _, tt := range tests {
+		p, err := New(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := parseProgram(t, p)
+		if str := program.String(); tt.want != str {
+			t.Fatalf("expected %v, got %v", tt.want, str)
+		}
+		b, err := json.Marshal(program.Encode())
+		if nil != err {
+			t.Fatal(err)
+		}
+		node, err := ast.Decode(b)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if str := node.String(); tt.want != str {
+			t.Fatalf("expected %v, got %v", tt.want, str)
+		}
+	}
+	for _, code := range []string{
+		`const n: = 1`,
+		`const n: array<int = 1`,
+		`const n: array<int>> = 1`,
+		`func f(x:) { x }`,
+		`func f(x): { x }`,
+	} {
+		p, err := New(code)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if _, err := p.ParseProgram(); nil == err {
+			t.Fatalf("`%v` expect error", code)
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	p, err := New("const a = 1;\nf(a,\n  b + 1)")
+	if nil != err {
+		t.Fatal(err)
+	}
+	program := parseProgram(t, p)
+	if pos := program.Stmts[0].Pos(); pos.Line != 1 || pos.Col != 1 {
+		t.Fatalf("unexpected position: %v", pos)
+	}
+	call := program.Stmts[1].(*ast.ExpressionStmt).Expr.(*ast.Call)
+	if pos := call.Func.Pos(); pos.Line != 2 || pos.Col != 1 {
+		t.Fatalf("unexpected position: %v", pos)
+	}
+	if pos := call.Args[1].Pos(); pos.Line != 3 || pos.Col != 5 {
+		t.Fatalf("unexpected position: %v", pos)
+	}
+}
+
+func TestTemplateParsing(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"`abc`", "`abc`"},
+		{"`a${b}c`", "`a${b}c`"},
+		{"`${a + b * c}`", "`${(a + (b * c))}`"},
+		{"`${h[\"k\"]}-${f(1)}`", "`${(h[k])}-${f(1)}`"},
+		{"`\\${a} \\``", "`\\${a} \\``"},
+		{"`x` + 1", "(`x` + 1)"},
+	}
+	for _, tt := range tests {
+		p, err := New(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := parseProgram(t, p)
+		if str := program.String(); tt.want != str {
+			t.Fatalf("expected %v, got %v", tt.want, str)
+		}
+		b, err := json.Marshal(program.Encode())
+		if nil != err {
+			t.Fatal(err)
+		}
+		node, err := ast.Decode(b)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if str := node.String(); tt.want != str {
+			t.Fatalf("expected %v, got %v", tt.want, str)
+		}
+	}
+}
+
 func TestHashExprParsing(t *testing.T) {
 	input := `{"k1": 1 + 1, "k2": 100 - 90, "k3": 30 / 10}`
 
@@ -581,3 +1051,83 @@ func TestHashExprParsing(t *testing.T) {
 		fn(v)
 	}
 }
+
+func TestComments(t *testing.T) {
+	input := "#!/usr/bin/env escript\n" +
+		"// line comment\n" +
+		"const a = 1; /* block\n comment */ const b = a // tail\n" +
+		"/ 2;"
+	toks, err := Tokenize(input)
+	if nil != err {
+		t.Fatal(err)
+	}
+	types := []token.TokenType{}
+	for _, tok := range toks {
+		types = append(types, tok.Type)
+	}
+	want := []token.TokenType{
+		token.CONST, token.IDENT, token.ASSIGN, token.INT, token.SEMICOLON,
+		token.CONST, token.IDENT, token.ASSIGN, token.IDENT, token.DIV, token.INT, token.SEMICOLON,
+		token.EOF,
+	}
+	if !reflect.DeepEqual(want, types) {
+		t.Fatalf("expected %v, got %v", want, types)
+	}
+	comments := toks[0].Comments
+	if len(comments) != 2 || comments[0].Text != "#!/usr/bin/env escript" || comments[1].Text != "// line comment" {
+		t.Fatalf("unexpected comments: %v", comments)
+	}
+	if c := comments[1]; c.Line != 2 || c.Col != 1 {
+		t.Fatalf("unexpected comment position: %v:%v", c.Line, c.Col)
+	}
+	if tok := toks[0]; tok.Line != 3 || tok.Col != 1 {
+		t.Fatalf("unexpected token position: %v:%v", tok.Line, tok.Col)
+	}
+	if c := toks[5].Comments; len(c) != 1 || c[0].Text != "/* block\n comment */" || c[0].Line != 3 || c[0].Col != 14 {
+		t.Fatalf("unexpected block comment: %v", c)
+	}
+	if tok := toks[9]; tok.Line != 5 || tok.Col != 1 || len(tok.Comments) != 1 || tok.Comments[0].Text != "// tail" {
+		t.Fatalf("unexpected trailing comment: %v", tok)
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"const a = 1;\n/* open", "unterminated comment, line: 2, col: 1"},
+		{"const a = \"abc;\nconst b = 2;", "unterminated string, line: 1, col: 11"},
+		{"\"a\\qb\"", "unexpected escape `\\q`, line: 1, col: 4"},
+		{"1 +\n `abc", "unterminated template string, line: 2, col: 2"},
+	}
+	for _, tt := range tests {
+		_, err := New(tt.input)
+		if nil == err {
+			t.Fatalf("`%v` expect error", tt.input)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("expected %v, got %v", tt.want, err)
+		}
+	}
+}
+
+func TestOperatorEncoding(t *testing.T) {
+	input := "~a & b ** 2 << 1 | !c ^ -d >> e + 123456789012345678901234567890"
+	p, err := New(input)
+	if nil != err {
+		t.Fatal(err)
+	}
+	program := parseProgram(t, p)
+	b, err := json.Marshal(program.Encode())
+	if nil != err {
+		t.Fatal(err)
+	}
+	node, err := ast.Decode(b)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if want, got := program.String(), node.String(); want != got {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
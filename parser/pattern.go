@@ -0,0 +1,292 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jobs-github/escript/ast"
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/token"
+)
+
+var (
+	errEmptyMatch = errors.New("match requires at least one arm")
+)
+
+// parsePattern : p1 | p2 | ..., current token is the first token of the pattern
+func parsePattern(s scanner, p Parser) (ast.Pattern, error) {
+	first, err := parseSinglePattern(s, p)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	if nil != s.PeekIs(token.BITOR) {
+		return first, nil
+	}
+	alts := ast.PatternSlice{first}
+	for nil == s.PeekIs(token.BITOR) {
+		s.NextToken()
+		s.NextToken()
+		alt, err := parseSinglePattern(s, p)
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		alts = append(alts, alt)
+	}
+	for _, alt := range alts {
+		if len(alt.Names()) > 0 {
+			err := fmt.Errorf("alternative pattern `%v` cannot bind names", alt.String())
+			return nil, function.NewError(err)
+		}
+	}
+	return &ast.AltPattern{Alts: alts}, nil
+}
+
+func parseSinglePattern(s scanner, p Parser) (ast.Pattern, error) {
+	switch s.CurTokenType() {
+	case token.IDENT:
+		return parseBindPattern(s), nil
+	case token.INT, token.SUB, token.STRING, token.TRUE, token.FALSE, token.NULL:
+		lit, err := parseLiteral(s)
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		return &ast.LiteralPattern{Value: lit}, nil
+	case token.LBRACK:
+		return parseArrayPattern(s, p)
+	case token.LBRACE:
+		return parseHashPattern(s, p)
+	}
+	err := fmt.Errorf("unexpected %v in pattern, %v", token.ToString(s.CurTokenType()), s.String())
+	return nil, function.NewError(err)
+}
+
+// parseBindPattern : `_` or a name, current token is IDENT
+func parseBindPattern(s scanner) ast.Pattern {
+	name := s.GetIdentifier()
+	if ast.Wildcard == name.Value {
+		return &ast.WildcardPattern{}
+	}
+	return &ast.BindPattern{Name: name}
+}
+
+// parseLiteral : integer (optionally negative), string, boolean or null
+func parseLiteral(s scanner) (ast.Expression, error) {
+	switch s.CurTokenType() {
+	case token.INT:
+		return s.NewInteger()
+	case token.SUB:
+		if err := s.ExpectPeek(token.INT); nil != err {
+			return nil, function.NewError(err)
+		}
+		v, err := s.NewInteger()
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		if nil != v.Big {
+			v.Big.Neg(v.Big)
+		} else {
+			v.Value = -v.Value
+		}
+		return v, nil
+	case token.STRING:
+		return s.NewString(), nil
+	case token.TRUE, token.FALSE:
+		return s.NewBoolean(), nil
+	case token.NULL:
+		return ast.NewNull(), nil
+	}
+	err := fmt.Errorf("expected literal, got %v, %v", token.ToString(s.CurTokenType()), s.String())
+	return nil, function.NewError(err)
+}
+
+// [p1, p2, ...rest]
+func parseArrayPattern(s scanner, p Parser) (ast.Pattern, error) {
+	r := &ast.ArrayPattern{Elems: ast.PatternSlice{}}
+	if nil == s.PeekIs(token.RBRACK) {
+		s.NextToken()
+		return r, nil
+	}
+	for {
+		s.NextToken()
+		if token.ELLIPSIS == s.CurTokenType() {
+			// the rest must be the last element
+			if err := s.ExpectPeek(token.IDENT); nil != err {
+				return nil, function.NewError(err)
+			}
+			r.Rest = parseBindPattern(s)
+			break
+		}
+		elem, err := parsePattern(s, p)
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		r.Elems = append(r.Elems, elem)
+		if nil != s.PeekIs(token.COMMA) {
+			break
+		}
+		s.NextToken()
+	}
+	if err := s.ExpectPeek(token.RBRACK); nil != err {
+		return nil, function.NewError(err)
+	}
+	return r, nil
+}
+
+// {"k1": p1, k2: p2, k3, ...}, a name as key is a string, `k3` is short for `k3: k3`
+func parseHashPattern(s scanner, p Parser) (ast.Pattern, error) {
+	r := &ast.HashPattern{Keys: ast.ExpressionSlice{}, Values: ast.PatternSlice{}}
+	if nil == s.PeekIs(token.RBRACE) {
+		s.NextToken()
+		return r, nil
+	}
+	for {
+		s.NextToken()
+		key, val, err := parseHashPatternItem(s, p)
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		r.Keys = append(r.Keys, key)
+		r.Values = append(r.Values, val)
+		if nil != s.PeekIs(token.COMMA) {
+			break
+		}
+		s.NextToken()
+	}
+	if err := s.ExpectPeek(token.RBRACE); nil != err {
+		return nil, function.NewError(err)
+	}
+	return r, nil
+}
+
+func parseHashPatternItem(s scanner, p Parser) (ast.Expression, ast.Pattern, error) {
+	var key ast.Expression
+	if token.IDENT == s.CurTokenType() {
+		key = s.NewString()
+		if nil != s.PeekIs(token.COLON) {
+			return key, parseBindPattern(s), nil
+		}
+	} else {
+		var err error
+		key, err = parseLiteral(s)
+		if nil != err {
+			return nil, nil, function.NewError(err)
+		}
+	}
+	if err := s.ExpectPeek(token.COLON); nil != err {
+		return nil, nil, function.NewError(err)
+	}
+	s.NextToken()
+	val, err := parsePattern(s, p)
+	if nil != err {
+		return nil, nil, function.NewError(err)
+	}
+	return key, val, nil
+}
+
+// checkNames : a name can be bound only once in a pattern
+func checkNames(pattern ast.Pattern) error {
+	m := map[string]bool{}
+	for _, name := range pattern.Names() {
+		if m[name] {
+			return fmt.Errorf("duplicate binding `%v` in pattern `%v`", name, pattern.String())
+		}
+		m[name] = true
+	}
+	return nil
+}
+
+// parseDestructPattern : array or hash pattern of const and function arguments
+func parseDestructPattern(s scanner, p Parser) (ast.Pattern, error) {
+	var pattern ast.Pattern
+	var err error
+	switch s.CurTokenType() {
+	case token.LBRACK:
+		pattern, err = parseArrayPattern(s, p)
+	case token.LBRACE:
+		pattern, err = parseHashPattern(s, p)
+	default:
+		err = fmt.Errorf("expected array or hash pattern, got %v, %v", token.ToString(s.CurTokenType()), s.String())
+	}
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	if err := checkNames(pattern); nil != err {
+		return nil, function.NewError(err)
+	}
+	for _, name := range pattern.Names() {
+		if err := checkBuiltin(name); nil != err {
+			return nil, function.NewError(err)
+		}
+	}
+	return pattern, nil
+}
+
+// pattern [if guard] => body
+func parseMatchArm(s scanner, p Parser) (*ast.MatchArm, error) {
+	arm := &ast.MatchArm{}
+	var err error
+	arm.Pattern, err = parsePattern(s, p)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	if err := checkNames(arm.Pattern); nil != err {
+		return nil, function.NewError(err)
+	}
+	if nil == s.PeekIs(token.IF) {
+		s.NextToken()
+		s.NextToken()
+		arm.Guard, err = p.ParseExpression(PRECED_LOWEST)
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+	}
+	if err := s.ExpectPeek(token.ARROW); nil != err {
+		return nil, function.NewError(err)
+	}
+	s.NextToken()
+	arm.Body, err = p.ParseExpression(PRECED_LOWEST)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	return arm, nil
+}
+
+// matchExpr : implement tokenDecoder
+type matchExpr struct {
+	s scanner
+	p Parser
+}
+
+// match v { pattern [if guard] => body, ... }
+func (this *matchExpr) decode() (ast.Expression, error) {
+	expr := ast.NewMatch()
+	var err error
+	this.s.NextToken()
+	expr.Value, err = this.p.ParseExpression(PRECED_LOWEST)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	if err := this.s.ExpectPeek(token.LBRACE); nil != err {
+		return nil, function.NewError(err)
+	}
+	expr.Arms = ast.MatchArms{}
+	for nil != this.s.PeekIs(token.RBRACE) {
+		this.s.NextToken()
+		arm, err := parseMatchArm(this.s, this.p)
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		expr.Arms = append(expr.Arms, arm)
+		if nil != this.s.PeekIs(token.COMMA) {
+			break
+		}
+		this.s.NextToken()
+	}
+	if err := this.s.ExpectPeek(token.RBRACE); nil != err {
+		return nil, function.NewError(err)
+	}
+	if len(expr.Arms) < 1 {
+		return nil, function.NewError(errEmptyMatch)
+	}
+	return expr, nil
+}
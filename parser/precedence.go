@@ -6,18 +6,25 @@ const (
 	_ int = iota
 	PRECED_LOWEST
 	PRECED_QUESTION // ?
+	PRECED_NULLISH  // ??
 	PRECED_OR       // ||
 	PRECED_AND      // &&
+	PRECED_BITOR    // |
+	PRECED_BITXOR   // ^
+	PRECED_BITAND   // &
 	PRECED_EQ       // ==
 	PRECED_NEQ      // !=
-	PRECED_LT       // < > >= <=
+	PRECED_LT       // < > >= <= in, not in
+	PRECED_SHIFT    // << >>
+	PRECED_RANGE    // .. ..<
 	PRECED_ADD      // +
 	PRECED_MUL      // *
-	PRECED_PREFIX   // -x !x
+	PRECED_PREFIX   // -x !x ~x
+	PRECED_POW      // ** (right associative)
 	PRECED_CALL     // myFn(x)
 	PRECED_HIGHEST
 	PRECED_INDEX  = PRECED_HIGHEST // array[index]
-	PRECED_PERIOD = PRECED_HIGHEST // s.len()
+	PRECED_PERIOD = PRECED_HIGHEST // s.len() s?.len()
 )
 
 var (
@@ -35,10 +42,22 @@ var (
 		token.GEQ:      PRECED_LT,
 		token.AND:      PRECED_AND,
 		token.OR:       PRECED_OR,
+		token.BITOR:    PRECED_BITOR,
+		token.BITXOR:   PRECED_BITXOR,
+		token.BITAND:   PRECED_BITAND,
+		token.SHL:      PRECED_SHIFT,
+		token.SHR:      PRECED_SHIFT,
+		token.POW:      PRECED_POW,
 		token.LPAREN:   PRECED_CALL,
 		token.LBRACK:   PRECED_INDEX,
 		token.PERIOD:   PRECED_PERIOD,
 		token.QUESTION: PRECED_QUESTION,
+		token.NULLISH:  PRECED_NULLISH,
+		token.QPERIOD:  PRECED_PERIOD,
+		token.IN:       PRECED_LT,
+		token.NOTIN:    PRECED_LT,
+		token.DOTDOT:   PRECED_RANGE,
+		token.DOTDOTLT: PRECED_RANGE,
 	}
 )
 
@@ -3,6 +3,7 @@ package parser
 import (
 	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 
 	"github.com/jobs-github/escript/ast"
@@ -11,7 +12,8 @@ import (
 )
 
 var (
-	errNoTok = errors.New("no valid token")
+	errNoTok         = errors.New("no valid token")
+	errOptionalSlice = errors.New("optional chaining does not support slice")
 )
 
 type scanner interface {
@@ -19,15 +21,21 @@ type scanner interface {
 	NewPrefix() *ast.PrefixExpr
 	NewInfix(left ast.Expression) *ast.InfixExpr
 	NewIndex(left ast.Expression) *ast.IndexExpr
+	NewSlice(left ast.Expression) *ast.SliceExpr
 	NewCallMember(left ast.Expression) *ast.CallMember
 	NewObjectMember(left ast.Expression) *ast.ObjectMember
+	NewNullish(left ast.Expression) *ast.NullishExpr
+	NewIn(left ast.Expression) *ast.InExpr
+	NewInterval(start ast.Expression) *ast.IntervalExpr
 	NewCall(left ast.Expression) *ast.Call
 	NewConditional(left ast.Expression) *ast.ConditionalExpr
 	NewFunction() *ast.FunctionStmt
 	NewBoolean() *ast.Boolean
 	NewInteger() (*ast.Integer, error)
 	NewString() *ast.String
+	NewTemplate() (*ast.TemplateExpr, error)
 	NewSymbol() *ast.SymbolExpr
+	ParseAnnotation() (*ast.TypeExpr, error)
 
 	Clone() scanner
 	String() string
@@ -36,6 +44,7 @@ type scanner interface {
 	Eof() bool
 	PeekPrecedence() int
 	CurPrecedence() int
+	CurPos() ast.Position
 	CurTokenType() token.TokenType
 	PeekTokenType() token.TokenType
 	NextToken()
@@ -93,11 +102,14 @@ func (this *scannerImpl) ParseFunction(lambda bool, p Parser) (*ast.Function, er
 	if err := this.ExpectPeek(token.LPAREN); nil != err {
 		return nil, function.NewError(err)
 	}
-	args, err := this.parseArgs()
+	if err := this.parseArgs(p, fn); nil != err {
+		return nil, function.NewError(err)
+	}
+	returns, err := this.ParseAnnotation()
 	if nil != err {
 		return nil, function.NewError(err)
 	}
-	fn.Args = args
+	fn.Returns = returns
 	if err := this.ExpectPeek(token.LBRACE); nil != err {
 		return nil, function.NewError(err)
 	}
@@ -109,27 +121,132 @@ func (this *scannerImpl) ParseFunction(lambda bool, p Parser) (*ast.Function, er
 	return fn, nil
 }
 
-func (this *scannerImpl) parseArgs() (ast.IdentifierSlice, error) {
-	args := ast.IdentifierSlice{}
+func (this *scannerImpl) parseArgs(p Parser, fn *ast.Function) error {
+	fn.Args = ast.IdentifierSlice{}
 	if err := this.PeekIs(token.RPAREN); nil == err {
 		this.NextToken()
-		return args, nil
+		return nil
 	}
-	this.NextToken()
-	ident := this.GetIdentifier()
-	args = append(args, ident)
-
-	for nil == this.PeekIs(token.COMMA) {
+	params := ast.PatternSlice{}
+	defaults := ast.ExpressionSlice{}
+	types := []*ast.TypeExpr{}
+	destructed := false
+	hasDefault := false
+	annotated := false
+	for {
 		this.NextToken()
+		if this.curTok.TypeIs(token.ELLIPSIS) {
+			// the rest must be the last arg
+			if err := this.ExpectPeek(token.IDENT); nil != err {
+				return function.NewError(err)
+			}
+			fn.Rest = this.GetIdentifier()
+			t, err := this.ParseAnnotation()
+			if nil != err {
+				return function.NewError(err)
+			}
+			annotated = annotated || nil != t
+			types = append(types, t)
+			break
+		}
+		param, err := this.parseArg(p, fn)
+		if nil != err {
+			return function.NewError(err)
+		}
+		if _, ok := param.(*ast.BindPattern); !ok {
+			destructed = true
+		}
+		t, err := this.ParseAnnotation()
+		if nil != err {
+			return function.NewError(err)
+		}
+		annotated = annotated || nil != t
+		types = append(types, t)
+		var value ast.Expression
+		if nil == this.PeekIs(token.ASSIGN) {
+			this.NextToken()
+			this.NextToken()
+			value, err = p.ParseExpression(PRECED_LOWEST)
+			if nil != err {
+				return function.NewError(err)
+			}
+			hasDefault = true
+		} else if hasDefault {
+			err := fmt.Errorf("arg `%v` without default value follows arg with default value", param.String())
+			return function.NewError(err)
+		}
+		params = append(params, param)
+		defaults = append(defaults, value)
+		if nil != this.PeekIs(token.COMMA) {
+			break
+		}
 		this.NextToken()
-		ident := this.GetIdentifier()
-		args = append(args, ident)
 	}
 
 	if err := this.ExpectPeek(token.RPAREN); nil != err {
-		return nil, function.NewError(err)
+		return function.NewError(err)
+	}
+	if destructed {
+		fn.Params = params
+	}
+	if hasDefault {
+		fn.Defaults = defaults
+	}
+	if annotated {
+		fn.Types = types
+	}
+	return nil
+}
+
+// ParseAnnotation : `: type` after the current token, nil if there is no colon
+func (this *scannerImpl) ParseAnnotation() (*ast.TypeExpr, error) {
+	if nil != this.PeekIs(token.COLON) {
+		return nil, nil
+	}
+	this.NextToken()
+	// array<hash<int>>, the closing `>>` is lexed as one token
+	chain := []*ast.TypeExpr{}
+	for {
+		this.NextToken()
+		if !this.curTok.TypeIs(token.IDENT) && !this.curTok.TypeIs(token.FUNC) && !this.curTok.TypeIs(token.NULL) {
+			err := fmt.Errorf("expected type, got %v instead, line: %v, col: %v", token.ToString(this.curTok.Type), this.curTok.Line, this.curTok.Col)
+			return nil, function.NewError(err)
+		}
+		chain = append(chain, &ast.TypeExpr{Name: this.curTok.Literal})
+		if nil != this.PeekIs(token.LT) {
+			break
+		}
+		this.NextToken()
+	}
+	for open := len(chain) - 1; open > 0; {
+		if nil == this.PeekIs(token.GT) {
+			open--
+		} else if nil == this.PeekIs(token.SHR) && open > 1 {
+			open -= 2
+		} else {
+			return nil, function.NewError(this.ExpectPeek(token.GT))
+		}
+		this.NextToken()
 	}
-	return args, nil
+	for i := len(chain) - 1; i > 0; i-- {
+		chain[i-1].Elem = chain[i]
+	}
+	return chain[0], nil
+}
+
+// parseArg : a name or a destructuring pattern, which is bound to a hidden arg
+func (this *scannerImpl) parseArg(p Parser, fn *ast.Function) (ast.Pattern, error) {
+	if this.curTok.TypeIs(token.LBRACK) || this.curTok.TypeIs(token.LBRACE) {
+		pattern, err := parseDestructPattern(this, p)
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		fn.Args = append(fn.Args, &ast.Identifier{Value: ast.ArgName(len(fn.Args))})
+		return pattern, nil
+	}
+	ident := this.GetIdentifier()
+	fn.Args = append(fn.Args, ident)
+	return &ast.BindPattern{Name: ident}, nil
 }
 
 func (this *scannerImpl) NewPrefix() *ast.PrefixExpr {
@@ -147,6 +264,10 @@ func (this *scannerImpl) NewIndex(left ast.Expression) *ast.IndexExpr {
 	return &ast.IndexExpr{Left: left}
 }
 
+func (this *scannerImpl) NewSlice(left ast.Expression) *ast.SliceExpr {
+	return &ast.SliceExpr{Left: left}
+}
+
 func (this *scannerImpl) NewCallMember(left ast.Expression) *ast.CallMember {
 	return &ast.CallMember{Left: left}
 }
@@ -155,6 +276,18 @@ func (this *scannerImpl) NewObjectMember(left ast.Expression) *ast.ObjectMember
 	return &ast.ObjectMember{Left: left}
 }
 
+func (this *scannerImpl) NewNullish(left ast.Expression) *ast.NullishExpr {
+	return &ast.NullishExpr{Left: left}
+}
+
+func (this *scannerImpl) NewIn(left ast.Expression) *ast.InExpr {
+	return &ast.InExpr{Left: left, Not: this.curTok.TypeIs(token.NOTIN)}
+}
+
+func (this *scannerImpl) NewInterval(start ast.Expression) *ast.IntervalExpr {
+	return &ast.IntervalExpr{Start: start, Exclusive: this.curTok.TypeIs(token.DOTDOTLT)}
+}
+
 func (this *scannerImpl) NewCall(left ast.Expression) *ast.Call {
 	return &ast.Call{Func: left}
 }
@@ -175,6 +308,11 @@ func (this *scannerImpl) NewInteger() (*ast.Integer, error) {
 	expr := &ast.Integer{}
 	val, err := strconv.ParseInt(this.curTok.Literal, 0, 64)
 	if nil != err {
+		// literal out of the int64 range is a bigint
+		if v, ok := new(big.Int).SetString(this.curTok.Literal, 0); ok && errors.Is(err, strconv.ErrRange) {
+			expr.Big = v
+			return expr, nil
+		}
 		err := fmt.Errorf("could not parse %v as integer", this.curTok.Literal)
 		return nil, function.NewError(err)
 	}
@@ -186,6 +324,14 @@ func (this *scannerImpl) NewString() *ast.String {
 	return &ast.String{Value: this.curTok.Literal}
 }
 
+func (this *scannerImpl) NewTemplate() (*ast.TemplateExpr, error) {
+	parts, err := parseTemplate(this.curTok.Literal)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	return &ast.TemplateExpr{Parts: parts}, nil
+}
+
 func (this *scannerImpl) NewSymbol() *ast.SymbolExpr {
 	return &ast.SymbolExpr{Value: this.curTok.Literal}
 }
@@ -224,6 +370,10 @@ func (this *scannerImpl) CurPrecedence() int {
 	return getPrecedence(this.curTok)
 }
 
+func (this *scannerImpl) CurPos() ast.Position {
+	return ast.Position{Line: this.curTok.Line, Col: this.curTok.Col}
+}
+
 func (this *scannerImpl) CurTokenType() token.TokenType {
 	return this.curTok.Type
 }
@@ -251,7 +401,7 @@ func (this *scannerImpl) ExpectPeek(t token.TokenType) error {
 		this.NextToken()
 		return nil
 	}
-	err := fmt.Errorf("expected next token to be %v, got %v instead", token.ToString(t), token.ToString(this.peekTok.Type))
+	err := fmt.Errorf("expected next token to be %v, got %v instead, line: %v, col: %v", token.ToString(t), token.ToString(this.peekTok.Type), this.peekTok.Line, this.peekTok.Col)
 	return function.NewError(err)
 }
 
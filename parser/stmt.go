@@ -21,9 +21,11 @@ func newStmtParser(
 		scanner:         s,
 		p:               p,
 		functionDecoder: &functionStmt{s, p},
+		methodDecoder:   &methodStmt{s, p},
 		exprDecoder:     &exprStmt{s, p},
 		m: map[token.TokenType]stmtDecoder{
-			token.CONST: &constStmt{s, p},
+			token.CONST:  &constStmt{s, p},
+			token.STRUCT: &structStmt{s, p},
 		},
 	}
 }
@@ -37,6 +39,7 @@ type stmtParserImpl struct {
 	scanner         scanner
 	p               Parser
 	functionDecoder stmtDecoder
+	methodDecoder   stmtDecoder
 	exprDecoder     stmtDecoder
 	m               map[token.TokenType]stmtDecoder
 }
@@ -48,6 +51,8 @@ func (this *stmtParserImpl) Decode(t token.TokenType, endTok token.TokenType) (a
 	} else {
 		if this.isFunctionStmt() {
 			return this.functionDecoder.decode(endTok)
+		} else if this.isMethodStmt() {
+			return this.methodDecoder.decode(endTok)
 		} else {
 			return this.exprDecoder.decode(endTok)
 		}
@@ -58,6 +63,17 @@ func (this *stmtParserImpl) isFunctionStmt() bool {
 	return this.scanner.ExpectCur2(token.FUNC, token.IDENT)
 }
 
+// isMethodStmt : `func (recv Type)`, which is not a function literal
+func (this *stmtParserImpl) isMethodStmt() bool {
+	if !this.scanner.ExpectCur2(token.FUNC, token.LPAREN) {
+		return false
+	}
+	s := this.scanner.Clone()
+	s.NextToken()
+	s.NextToken()
+	return s.ExpectCur2(token.IDENT, token.IDENT)
+}
+
 // constStmt : implement stmtDecoder
 type constStmt struct {
 	s scanner
@@ -66,15 +82,27 @@ type constStmt struct {
 
 func (this *constStmt) decode(endTok token.TokenType) (ast.Statement, error) {
 	stmt := ast.NewConst()
-	if err := this.s.ExpectPeek(token.IDENT); nil != err {
-		return nil, function.NewError(err)
+	if nil == this.s.PeekIs(token.LBRACK) || nil == this.s.PeekIs(token.LBRACE) {
+		this.s.NextToken()
+		pattern, err := parseDestructPattern(this.s, this.p)
+		if nil != err {
+			return nil, function.NewError(err)
+		}
+		stmt.Pattern = pattern
+	} else {
+		if err := this.s.ExpectPeek(token.IDENT); nil != err {
+			return nil, function.NewError(err)
+		}
+		stmt.Name = this.s.GetIdentifier()
+		if err := checkBuiltin(stmt.Name.Value); nil != err {
+			return nil, function.NewError(err)
+		}
 	}
-	stmt.Name = this.s.GetIdentifier()
-
-	if builtin.IsBuiltin(stmt.Name.Value) {
-		err := fmt.Errorf("`%v` is built-in function", stmt.Name.Value)
+	t, err := this.s.ParseAnnotation()
+	if nil != err {
 		return nil, function.NewError(err)
 	}
+	stmt.Type = t
 
 	if err := this.s.ExpectPeek(token.ASSIGN); nil != err {
 		return nil, function.NewError(err)
@@ -86,7 +114,7 @@ func (this *constStmt) decode(endTok token.TokenType) (ast.Statement, error) {
 	if nil != err {
 		return nil, function.NewError(err)
 	}
-	if fn, err := expr.AsFunction(); nil == err {
+	if fn, err := expr.AsFunction(); nil == err && nil != stmt.Name {
 		fn.Lambda = stmt.Name.Value
 	}
 	stmt.Value = expr
@@ -97,6 +125,13 @@ func (this *constStmt) decode(endTok token.TokenType) (ast.Statement, error) {
 	return stmt, nil
 }
 
+func checkBuiltin(name string) error {
+	if builtin.IsBuiltin(name) {
+		return fmt.Errorf("`%v` is built-in function", name)
+	}
+	return nil
+}
+
 // exprStmt : implement stmtDecoder
 type exprStmt struct {
 	s scanner
@@ -136,3 +171,104 @@ func (this *functionStmt) decode(endTok token.TokenType) (ast.Statement, error)
 	}
 	return stmt, nil
 }
+
+// structStmt : implement stmtDecoder
+type structStmt struct {
+	s scanner
+	p Parser
+}
+
+// struct Name { field, ... }
+func (this *structStmt) decode(endTok token.TokenType) (ast.Statement, error) {
+	stmt := ast.NewStruct()
+	if err := this.s.ExpectPeek(token.IDENT); nil != err {
+		return nil, function.NewError(err)
+	}
+	stmt.Name = this.s.GetIdentifier()
+	if err := checkBuiltin(stmt.Name.Value); nil != err {
+		return nil, function.NewError(err)
+	}
+	if err := this.s.ExpectPeek(token.LBRACE); nil != err {
+		return nil, function.NewError(err)
+	}
+	stmt.Fields = ast.IdentifierSlice{}
+	m := map[string]bool{}
+	for nil != this.s.PeekIs(token.RBRACE) {
+		if err := this.s.ExpectPeek(token.IDENT); nil != err {
+			return nil, function.NewError(err)
+		}
+		field := this.s.GetIdentifier()
+		if m[field.Value] {
+			err := fmt.Errorf("duplicate field `%v` in struct %v", field.Value, stmt.Name.Value)
+			return nil, function.NewError(err)
+		}
+		m[field.Value] = true
+		stmt.Fields = append(stmt.Fields, field)
+		if nil != this.s.PeekIs(token.COMMA) {
+			break
+		}
+		this.s.NextToken()
+	}
+	if err := this.s.ExpectPeek(token.RBRACE); nil != err {
+		return nil, function.NewError(err)
+	}
+	for !this.s.StmtEnd(endTok) {
+		this.s.NextToken()
+	}
+	return stmt, nil
+}
+
+// declarations : a struct and a method of it are declared once in a program
+type declarations map[string]bool
+
+func (this declarations) add(stmt ast.Statement) error {
+	switch v := stmt.(type) {
+	case *ast.StructStmt:
+		if this[v.Name.Value] {
+			return fmt.Errorf("duplicate struct `%v`", v.Name.Value)
+		}
+		this[v.Name.Value] = true
+	case *ast.MethodStmt:
+		// not a valid name of struct, so it never collides with one
+		key := v.Type.Value + "." + v.Value.Name
+		if this[key] {
+			return fmt.Errorf("duplicate method `%v` of struct %v", v.Value.Name, v.Type.Value)
+		}
+		this[key] = true
+	}
+	return nil
+}
+
+// methodStmt : implement stmtDecoder
+type methodStmt struct {
+	s scanner
+	p Parser
+}
+
+// func (recv Type) name(args) {...}
+func (this *methodStmt) decode(endTok token.TokenType) (ast.Statement, error) {
+	stmt := ast.NewMethod()
+	this.s.NextToken()
+	this.s.NextToken()
+	stmt.Recv = this.s.GetIdentifier()
+	if err := checkBuiltin(stmt.Recv.Value); nil != err {
+		return nil, function.NewError(err)
+	}
+	this.s.NextToken()
+	stmt.Type = this.s.GetIdentifier()
+	if err := this.s.ExpectPeek(token.RPAREN); nil != err {
+		return nil, function.NewError(err)
+	}
+	if err := this.s.ExpectPeek(token.IDENT); nil != err {
+		return nil, function.NewError(err)
+	}
+	fn, err := this.s.ParseFunction(false, this.p)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	stmt.Value = fn
+	for !this.s.StmtEnd(endTok) {
+		this.s.NextToken()
+	}
+	return stmt, nil
+}
@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/jobs-github/escript/ast"
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/token"
+)
+
+var (
+	errUnterminatedTemplate = errors.New("unterminated template string")
+	errUnterminatedString   = errors.New("unterminated string")
+	errUnterminatedTemplExp = errors.New("unterminated template expression")
+	errEmptyTemplateExpr    = errors.New("empty template expression")
+)
+
+// templateEnd : i is the index after the opening backtick, returns the index of the closing one
+func templateEnd(s string, i int) (int, error) {
+	for i < len(s) {
+		switch s[i] {
+		case '\\':
+			i += 2
+		case '`':
+			return i, nil
+		case '$':
+			if i+1 < len(s) && s[i+1] == '{' {
+				end, err := templateExprEnd(s, i+2)
+				if nil != err {
+					return 0, err
+				}
+				i = end + 1
+			} else {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	return 0, errUnterminatedTemplate
+}
+
+// templateExprEnd : i is the index after `${`, returns the index of the matching `}`
+func templateExprEnd(s string, i int) (int, error) {
+	depth := 0
+	for i < len(s) {
+		switch s[i] {
+		case '"':
+			end, err := stringEnd(s, i+1)
+			if nil != err {
+				return 0, err
+			}
+			i = end + 1
+		case '`':
+			end, err := templateEnd(s, i+1)
+			if nil != err {
+				return 0, err
+			}
+			i = end + 1
+		case '{':
+			depth++
+			i++
+		case '}':
+			if 0 == depth {
+				return i, nil
+			}
+			depth--
+			i++
+		default:
+			i++
+		}
+	}
+	return 0, errUnterminatedTemplExp
+}
+
+func stringEnd(s string, i int) (int, error) {
+	for i < len(s) {
+		switch s[i] {
+		case '\\':
+			i += 2
+		case '"':
+			return i, nil
+		default:
+			i++
+		}
+	}
+	return 0, errUnterminatedString
+}
+
+// parseTemplate : split the raw template into string literals and `${}` expressions
+func parseTemplate(raw string) (ast.ExpressionSlice, error) {
+	parts := ast.ExpressionSlice{}
+	var text bytes.Buffer
+	flush := func() {
+		if text.Len() > 0 {
+			parts = append(parts, &ast.String{Value: text.String()})
+			text.Reset()
+		}
+	}
+	for i := 0; i < len(raw); {
+		ch := raw[i]
+		if ch == '\\' && i+1 < len(raw) {
+			// \` and \$ are template escapes, others are kept as the string literal does
+			if next := raw[i+1]; next == '`' || next == '$' {
+				text.WriteByte(next)
+			} else {
+				text.WriteString(raw[i : i+2])
+			}
+			i += 2
+			continue
+		}
+		if ch == '$' && i+1 < len(raw) && raw[i+1] == '{' {
+			end, err := templateExprEnd(raw, i+2)
+			if nil != err {
+				return nil, function.NewError(err)
+			}
+			expr, err := parseTemplateExpr(raw[i+2 : end])
+			if nil != err {
+				return nil, function.NewError(err)
+			}
+			flush()
+			parts = append(parts, expr)
+			i = end + 1
+			continue
+		}
+		text.WriteByte(ch)
+		i++
+	}
+	flush()
+	return parts, nil
+}
+
+func parseTemplateExpr(code string) (ast.Expression, error) {
+	p, err := newParser(code)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	if p.s.Eof() {
+		return nil, function.NewError(errEmptyTemplateExpr)
+	}
+	expr, err := p.ParseExpression(PRECED_LOWEST)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	if nil != p.s.PeekIs(token.EOF) {
+		err := fmt.Errorf("unexpected token in template expression, %v", p.s.String())
+		return nil, function.NewError(err)
+	}
+	return expr, nil
+}
@@ -0,0 +1,168 @@
+package regvm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jobs-github/escript/code"
+)
+
+// Opcode : instruction of the register vm, the operands are registers of the frame (1 byte),
+// indexes of constants, globals or positions to jump to (2 bytes)
+type Opcode byte
+
+const (
+	OpUndefined Opcode = iota
+	OpLoadConst
+	OpLoadNull
+	OpLoadTrue
+	OpLoadFalse
+	OpMove
+	OpGetGlobal
+	OpSetGlobal
+	OpGetFree
+	OpGetLambda
+	OpGetBuiltin
+	OpSymbol
+	OpInfix
+	OpInfixConst
+	OpPrefix
+	OpIn
+	OpNotIn
+	OpInterval
+	OpJump
+	OpJumpWhenFalse
+	OpJumpWhenNull
+	OpJumpWhenNotNull
+	OpCmpJump
+	OpCall
+	OpTailCall
+	OpCallMember
+	OpReturn
+	OpClosure
+	OpArray
+	OpHash
+	OpConcat
+	OpIndex
+	OpIndexOptional
+	OpSlice
+	OpGetMember
+	OpLoop
+	OpMap
+	OpReduce
+	OpFilter
+	OpRange
+	OpPlaceholder
+)
+
+// R(x) is the register x, K(x) the constant x, G(x) the global x
+var definitions = map[Opcode]*code.Definition{
+	OpLoadConst:       {Name: "OpLoadConst", OperandWidths: []int{1, 2}},           // R(a) = K(b)
+	OpLoadNull:        {Name: "OpLoadNull", OperandWidths: []int{1}},               // R(a) = null
+	OpLoadTrue:        {Name: "OpLoadTrue", OperandWidths: []int{1}},               // R(a) = true
+	OpLoadFalse:       {Name: "OpLoadFalse", OperandWidths: []int{1}},              // R(a) = false
+	OpMove:            {Name: "OpMove", OperandWidths: []int{1, 1}},                // R(a) = R(b)
+	OpGetGlobal:       {Name: "OpGetGlobal", OperandWidths: []int{1, 2}},           // R(a) = G(b)
+	OpSetGlobal:       {Name: "OpSetGlobal", OperandWidths: []int{2, 1}},           // G(a) = R(b)
+	OpGetFree:         {Name: "OpGetFree", OperandWidths: []int{1, 1}},             // R(a) = free b of the closure
+	OpGetLambda:       {Name: "OpGetLambda", OperandWidths: []int{1}},              // R(a) = the closure itself
+	OpGetBuiltin:      {Name: "OpGetBuiltin", OperandWidths: []int{1, 1}},          // R(a) = builtin b
+	OpSymbol:          {Name: "OpSymbol", OperandWidths: []int{1, 2}},              // R(a) = symbol named K(b)
+	OpInfix:           {Name: "OpInfix", OperandWidths: []int{1, 1, 1, 1}},         // R(b) = R(c) a R(d), a is code.OpAdd, code.OpLt...
+	OpInfixConst:      {Name: "OpInfixConst", OperandWidths: []int{1, 1, 1, 2}},    // R(b) = R(c) a K(d)
+	OpPrefix:          {Name: "OpPrefix", OperandWidths: []int{1, 1, 1}},           // R(b) = a R(c), a is code.OpNot, code.OpNeg or code.OpBitNot
+	OpIn:              {Name: "OpIn", OperandWidths: []int{1, 1, 1}},               // R(a) = R(b) in R(c)
+	OpNotIn:           {Name: "OpNotIn", OperandWidths: []int{1, 1, 1}},            // R(a) = R(b) not in R(c)
+	OpInterval:        {Name: "OpInterval", OperandWidths: []int{1, 1, 1, 1}},      // R(a) = R(b)..R(c), exclusive if d is 1
+	OpJump:            {Name: "OpJump", OperandWidths: []int{2}},                   // to a
+	OpJumpWhenFalse:   {Name: "OpJumpWhenFalse", OperandWidths: []int{1, 2}},       // to b if R(a) is false
+	OpJumpWhenNull:    {Name: "OpJumpWhenNull", OperandWidths: []int{1, 2}},        // to b if R(a) is null
+	OpJumpWhenNotNull: {Name: "OpJumpWhenNotNull", OperandWidths: []int{1, 2}},     // to b if R(a) is not null
+	OpCmpJump:         {Name: "OpCmpJump", OperandWidths: []int{1, 1, 1, 2}},       // to d if R(b) a R(c) is false
+	OpCall:            {Name: "OpCall", OperandWidths: []int{1, 1, 1}},             // R(a) = R(b)(R(b+1)...R(b+c))
+	OpTailCall:        {Name: "OpTailCall", OperandWidths: []int{1, 1}},            // return R(a)(R(a+1)...R(a+b))
+	OpCallMember:      {Name: "OpCallMember", OperandWidths: []int{1, 1, 1, 2, 2}}, // R(a) = R(b).K(d)(R(b+1)...R(b+c)), e is the inline cache
+	OpReturn:          {Name: "OpReturn", OperandWidths: []int{1}},                 // return R(a)
+	OpClosure:         {Name: "OpClosure", OperandWidths: []int{1, 2, 1, 1}},       // R(a) = closure of K(b), the frees are R(c)...R(c+d-1)
+	OpArray:           {Name: "OpArray", OperandWidths: []int{1, 1, 1}},            // R(a) = [R(b)...R(b+c-1)]
+	OpHash:            {Name: "OpHash", OperandWidths: []int{1, 1, 1}},             // R(a) = {R(b): R(b+1)...}, c pairs
+	OpConcat:          {Name: "OpConcat", OperandWidths: []int{1, 1, 1}},           // R(a) = `R(b)...R(b+c-1)`
+	OpIndex:           {Name: "OpIndex", OperandWidths: []int{1, 1, 1}},            // R(a) = R(b)[R(c)]
+	OpIndexOptional:   {Name: "OpIndexOptional", OperandWidths: []int{1, 1, 1}},    // R(a) = R(b)?[R(c)]
+	OpSlice:           {Name: "OpSlice", OperandWidths: []int{1, 1}},               // R(a) = R(b)[R(b+1):R(b+2):R(b+3)]
+	OpGetMember:       {Name: "OpGetMember", OperandWidths: []int{1, 1, 2}},        // R(a) = R(b).K(c)
+	OpLoop:            {Name: "OpLoop", OperandWidths: []int{1, 1, 1}},             // R(a) = loop(R(b), R(c))
+	OpMap:             {Name: "OpMap", OperandWidths: []int{1, 1, 1}},              // R(a) = map(R(b), R(c))
+	OpReduce:          {Name: "OpReduce", OperandWidths: []int{1, 1, 1, 1}},        // R(a) = reduce(R(b), R(c), R(d))
+	OpFilter:          {Name: "OpFilter", OperandWidths: []int{1, 1, 1}},           // R(a) = filter(R(b), R(c))
+	OpRange:           {Name: "OpRange", OperandWidths: []int{1, 1, 1}},            // R(a) = range(R(b), R(c))
+}
+
+func Lookup(op Opcode) (*code.Definition, error) {
+	def, ok := definitions[op]
+	if !ok {
+		return nil, fmt.Errorf("opcode %v undefined", op)
+	}
+	return def, nil
+}
+
+func Make(op Opcode, operands ...int) (code.Instructions, error) {
+	def, err := Lookup(op)
+	if nil != err {
+		return nil, err
+	}
+	if len(operands) != len(def.OperandWidths) {
+		return nil, fmt.Errorf("%v: %v operands provided, but %v required", def.Name, len(operands), len(def.OperandWidths))
+	}
+	r := code.Instructions{byte(op)}
+	for i, operand := range operands {
+		switch w := def.OperandWidths[i]; w {
+		case 1:
+			if operand < 0 || operand > 0xff {
+				return nil, fmt.Errorf("%w, %v: operand %v overflows 1 byte", ErrUnsupported, def.Name, operand)
+			}
+			r = append(r, byte(operand))
+		case 2:
+			if operand < 0 || operand > 0xffff {
+				return nil, fmt.Errorf("%w, %v: operand %v overflows 2 bytes", ErrUnsupported, def.Name, operand)
+			}
+			var b [2]byte
+			binary.BigEndian.PutUint16(b[:], uint16(operand))
+			r = append(r, b[:]...)
+		}
+	}
+	return r, nil
+}
+
+// Disassemble : one instruction per line
+func Disassemble(ins code.Instructions) string {
+	var out bytes.Buffer
+	for i := 0; i < len(ins); {
+		def, err := Lookup(Opcode(ins[i]))
+		if nil != err {
+			fmt.Fprintf(&out, "ERROR: %04d %v\n", i, err)
+			break
+		}
+		width := 0
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		if i+1+width > len(ins) {
+			fmt.Fprintf(&out, "ERROR: %04d %v truncated\n", i, def.Name)
+			break
+		}
+		operands, err := code.DecodeOperands(def, ins[i+1:])
+		if nil != err {
+			fmt.Fprintf(&out, "ERROR: %04d %v\n", i, err)
+			break
+		}
+		fmt.Fprintf(&out, "%04d %v", i, def.Name)
+		for _, v := range operands.Value {
+			fmt.Fprintf(&out, " %v", v)
+		}
+		out.WriteString("\n")
+		i += 1 + width
+	}
+	return out.String()
+}
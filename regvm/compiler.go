@@ -0,0 +1,353 @@
+package regvm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/jobs-github/escript/ast"
+	"github.com/jobs-github/escript/builtin"
+	"github.com/jobs-github/escript/code"
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+)
+
+// ErrUnsupported : the node is out of the subset the register vm compiles,
+// run it by the stack vm instead
+var ErrUnsupported = errors.New("unsupported by the register vm")
+
+func unsupported(node ast.Node) error {
+	return fmt.Errorf("%w, (`%v`)", ErrUnsupported, node.String())
+}
+
+// MaxInlineCaches : a program with more call sites of members is run by the stack vm
+const MaxInlineCaches = 0x10000
+
+// Bytecode : the compiled program, Main is run with no args
+type Bytecode struct {
+	Main      *object.ByteFunc
+	Constants object.Objects
+	Globals   int
+	Caches    int // inline caches of OpCallMember
+}
+
+// Compile : compile node into register code, which is a subset of escript,
+// errors.Is(err, ErrUnsupported) if node uses struct, match, try, spread, destructuring,
+// default or rest args, or an operand overflows (e.g. more than 256 registers)
+func Compile(node ast.Node) (*Bytecode, error) {
+	c := &compiler{
+		consts:   object.Objects{},
+		globals:  map[string]int{},
+		builtins: map[string]int{},
+	}
+	builtin.Traverse(func(i int, name string) { c.builtins[name] = i })
+	c.fn = newFuncScope(nil, "")
+	// R(0) holds the value of the latest statement
+	c.fn.alloc()
+	if _, err := c.emit(OpLoadNull, 0); nil != err {
+		return nil, function.NewError(err)
+	}
+	if err := node.Do(c); nil != err {
+		return nil, function.NewError(err)
+	}
+	if _, err := c.emit(OpReturn, 0); nil != err {
+		return nil, function.NewError(err)
+	}
+	main := object.NewByteFunc(c.fn.ins, object.NewArity(0), c.fn.max).(*object.ByteFunc)
+	return &Bytecode{Main: main, Constants: c.consts, Globals: c.nGlobals, Caches: c.caches}, nil
+}
+
+func newFuncScope(outer *funcScope, lambda string) *funcScope {
+	return &funcScope{
+		outer:   outer,
+		lambda:  lambda,
+		locals:  map[string]int{},
+		freeIdx: map[string]int{},
+		ins:     code.Instructions{},
+	}
+}
+
+// funcScope : registers and instructions of the function being compiled,
+// the args are the leading registers, the temporaries follow
+type funcScope struct {
+	outer   *funcScope
+	lambda  string
+	locals  map[string]int // name => register
+	frees   []string
+	freeIdx map[string]int
+	next    int // the first free register
+	max     int
+	ins     code.Instructions
+}
+
+func (this *funcScope) alloc() int {
+	r := this.next
+	this.next++
+	if this.next > this.max {
+		this.max = this.next
+	}
+	return r
+}
+
+// captures : name is a local, free or lambda of the function, which a closure within it captures
+func (this *funcScope) captures(name string) bool {
+	if nil == this.outer {
+		return false
+	}
+	if _, ok := this.locals[name]; ok {
+		return true
+	}
+	if name == this.lambda {
+		return true
+	}
+	if _, ok := this.freeIdx[name]; ok {
+		return true
+	}
+	if this.outer.captures(name) {
+		this.freeIdx[name] = len(this.frees)
+		this.frees = append(this.frees, name)
+		return true
+	}
+	return false
+}
+
+// compiler : implement ast.Visitor, every expression is stored to the register dst
+type compiler struct {
+	consts      object.Objects
+	globals     map[string]int
+	nGlobals    int
+	builtins    map[string]int
+	caches      int
+	fn          *funcScope
+	dst         int
+	unsupported error
+}
+
+func (this *compiler) emit(op Opcode, operands ...int) (int, error) {
+	ins, err := Make(op, operands...)
+	if nil != err {
+		return -1, function.NewError(err)
+	}
+	pos := len(this.fn.ins)
+	this.fn.ins = append(this.fn.ins, ins...)
+	return pos, nil
+}
+
+// patch : the last operand of the jump at pos is the current position
+func (this *compiler) patch(pos int) error {
+	def, err := Lookup(Opcode(this.fn.ins[pos]))
+	if nil != err {
+		return function.NewError(err)
+	}
+	end := pos + 1
+	for _, w := range def.OperandWidths {
+		end += w
+	}
+	if len(this.fn.ins) > 0xffff {
+		return fmt.Errorf("%w, jump to %v overflows 2 bytes", ErrUnsupported, len(this.fn.ins))
+	}
+	binary.BigEndian.PutUint16(this.fn.ins[end-2:], uint16(len(this.fn.ins)))
+	return nil
+}
+
+func (this *compiler) addConst(obj object.Object) int {
+	this.consts = append(this.consts, obj)
+	return len(this.consts) - 1
+}
+
+// addInlineCache : slot of the inline cache of a call site
+func (this *compiler) addInlineCache(node ast.Node) (int, error) {
+	if this.caches >= MaxInlineCaches {
+		return -1, unsupported(node)
+	}
+	this.caches++
+	return this.caches - 1, nil
+}
+
+func (this *compiler) defineGlobal(name string) int {
+	idx := this.nGlobals
+	this.nGlobals++
+	this.globals[name] = idx
+	return idx
+}
+
+// expr : compile e into the register dst, the temporaries are released after it,
+// the first unsupported error is passed up as it is, since wrapping it per level
+// of a deeply nested expression (e.g. a generated sum) costs quadratic memory
+func (this *compiler) expr(e ast.Expression, dst int) error {
+	return this.exprWith(e, dst, func() error { return e.Do(this) })
+}
+
+// exprWith : compile e into dst by do, refer to expr
+func (this *compiler) exprWith(e ast.Expression, dst int, do func() error) error {
+	saved, next := this.dst, this.fn.next
+	this.dst = dst
+	err := do()
+	this.dst, this.fn.next = saved, next
+	if errors.Is(err, ErrUnsupported) {
+		if nil == this.unsupported {
+			this.unsupported = err
+		}
+		return this.unsupported
+	}
+	return err
+}
+
+// operand : the register of a local is used as it is, other expressions are compiled into a temporary
+func (this *compiler) operand(e ast.Expression) (int, error) {
+	if ident, ok := e.(*ast.Identifier); ok {
+		if r, ok := this.fn.locals[ident.Value]; ok {
+			return r, nil
+		}
+	}
+	r := this.fn.alloc()
+	if err := this.expr(e, r); nil != err {
+		return -1, function.NewError(err)
+	}
+	return r, nil
+}
+
+// operands : compile items into consecutive registers, return the first one
+func (this *compiler) operands(items ast.ExpressionSlice) (int, error) {
+	base := this.fn.next
+	regs := make([]int, len(items))
+	for i := range items {
+		regs[i] = this.fn.alloc()
+	}
+	for i, item := range items {
+		if nil == item {
+			if _, err := this.emit(OpLoadNull, regs[i]); nil != err {
+				return -1, function.NewError(err)
+			}
+			continue
+		}
+		if err := this.expr(item, regs[i]); nil != err {
+			return -1, function.NewError(err)
+		}
+	}
+	return base, nil
+}
+
+func (this *compiler) load(name string, dst int) error {
+	if r, ok := this.fn.locals[name]; ok {
+		if r == dst {
+			return nil
+		}
+		_, err := this.emit(OpMove, dst, r)
+		return err
+	}
+	if name == this.fn.lambda {
+		_, err := this.emit(OpGetLambda, dst)
+		return err
+	}
+	if this.fn.captures(name) {
+		_, err := this.emit(OpGetFree, dst, this.fn.freeIdx[name])
+		return err
+	}
+	if idx, ok := this.globals[name]; ok {
+		_, err := this.emit(OpGetGlobal, dst, idx)
+		return err
+	}
+	if idx, ok := this.builtins[name]; ok {
+		_, err := this.emit(OpGetBuiltin, dst, idx)
+		return err
+	}
+	return fmt.Errorf("undefined symbol `%v`", name)
+}
+
+// bind : top level names are globals, the others are registers
+func (this *compiler) bind(name string, value ast.Expression) error {
+	if nil != this.fn.outer {
+		r := this.fn.alloc()
+		this.fn.locals[name] = r
+		return this.expr(value, r)
+	}
+	idx := this.defineGlobal(name)
+	if err := this.expr(value, 0); nil != err {
+		return function.NewError(err)
+	}
+	if _, err := this.emit(OpSetGlobal, idx, 0); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+// literalConst : index of the constant if e is an integer or string literal
+func (this *compiler) literalConst(e ast.Expression) (int, bool) {
+	switch v := e.(type) {
+	case *ast.Integer:
+		return this.addConst(v.Object()), true
+	case *ast.String:
+		return this.addConst(object.NewString(v.Value)), true
+	}
+	return -1, false
+}
+
+var comparisons = map[code.Opcode]bool{
+	code.OpLt:  true,
+	code.OpGt:  true,
+	code.OpEq:  true,
+	code.OpNeq: true,
+	code.OpLeq: true,
+	code.OpGeq: true,
+}
+
+// jumpWhenFalse : a comparison is fused with the jump, return the position to patch
+func (this *compiler) jumpWhenFalse(cond ast.Expression) (int, error) {
+	next := this.fn.next
+	defer func() { this.fn.next = next }()
+	if v, ok := cond.(*ast.InfixExpr); ok {
+		if op, err := code.InfixCode(v.Op.Type); nil == err && comparisons[op] {
+			left, err := this.operand(v.Left)
+			if nil != err {
+				return -1, function.NewError(err)
+			}
+			right, err := this.operand(v.Right)
+			if nil != err {
+				return -1, function.NewError(err)
+			}
+			return this.emit(OpCmpJump, int(op), left, right, 0)
+		}
+	}
+	r, err := this.operand(cond)
+	if nil != err {
+		return -1, function.NewError(err)
+	}
+	return this.emit(OpJumpWhenFalse, r, 0)
+}
+
+// tail : the expression in tail position of a function body, a call reuses the frame
+func (this *compiler) tail(e ast.Expression) error {
+	switch v := e.(type) {
+	case *ast.Call:
+		if ast.HasSpread(v.Args) {
+			return unsupported(v)
+		}
+		base, err := this.operands(append(ast.ExpressionSlice{v.Func}, v.Args...))
+		if nil != err {
+			return function.NewError(err)
+		}
+		_, err = this.emit(OpTailCall, base, len(v.Args))
+		return err
+	case *ast.ConditionalExpr:
+		pos, err := this.jumpWhenFalse(v.Cond)
+		if nil != err {
+			return function.NewError(err)
+		}
+		if err := this.tail(v.Yes); nil != err {
+			return function.NewError(err)
+		}
+		if err := this.patch(pos); nil != err {
+			return function.NewError(err)
+		}
+		return this.tail(v.No)
+	}
+	next := this.fn.next
+	defer func() { this.fn.next = next }()
+	r, err := this.operand(e)
+	if nil != err {
+		return function.NewError(err)
+	}
+	_, err = this.emit(OpReturn, r)
+	return err
+}
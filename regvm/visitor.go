@@ -0,0 +1,515 @@
+package regvm
+
+import (
+	"github.com/jobs-github/escript/ast"
+	"github.com/jobs-github/escript/code"
+	"github.com/jobs-github/escript/function"
+	"github.com/jobs-github/escript/object"
+)
+
+func (this *compiler) DoProgram(v *ast.Program) error {
+	for _, stmt := range v.Stmts {
+		if err := stmt.Do(this); nil != err {
+			return function.NewError(err)
+		}
+	}
+	return nil
+}
+
+func (this *compiler) DoConst(v *ast.ConstStmt) error {
+	if nil != v.Pattern {
+		return unsupported(v)
+	}
+	return this.bind(v.Name.Value, v.Value)
+}
+
+func (this *compiler) DoBlock(v *ast.BlockStmt) error {
+	return unsupported(v)
+}
+
+func (this *compiler) DoExpr(v *ast.ExpressionStmt) error {
+	return this.expr(v.Expr, 0)
+}
+
+func (this *compiler) DoLoop(v *ast.LoopExpr) error {
+	return this.doIterate(OpLoop, v.Cnt, v.Body)
+}
+
+func (this *compiler) DoMap(v *ast.MapExpr) error {
+	return this.doIterate(OpMap, v.Arr, v.Body)
+}
+
+func (this *compiler) DoFilter(v *ast.FilterExpr) error {
+	return this.doIterate(OpFilter, v.Arr, v.Body)
+}
+
+func (this *compiler) DoRange(v *ast.RangeExpr) error {
+	return this.doIterate(OpRange, v.Cnt, v.Body)
+}
+
+// doIterate : R(dst) = op(R(arr), R(body)), the body is called by the vm
+func (this *compiler) doIterate(op Opcode, arr ast.Expression, body ast.Expression) error {
+	dst := this.dst
+	a, err := this.operand(arr)
+	if nil != err {
+		return function.NewError(err)
+	}
+	fn, err := this.operand(body)
+	if nil != err {
+		return function.NewError(err)
+	}
+	_, err = this.emit(op, dst, a, fn)
+	return err
+}
+
+func (this *compiler) DoReduce(v *ast.ReduceExpr) error {
+	dst := this.dst
+	arr, err := this.operand(v.Arr)
+	if nil != err {
+		return function.NewError(err)
+	}
+	init, err := this.operand(v.Init)
+	if nil != err {
+		return function.NewError(err)
+	}
+	fn, err := this.operand(v.Body)
+	if nil != err {
+		return function.NewError(err)
+	}
+	_, err = this.emit(OpReduce, dst, arr, fn, init)
+	return err
+}
+
+func (this *compiler) DoFunction(v *ast.FunctionStmt) error {
+	return this.bind(v.Name.Value, v.Value)
+}
+
+func (this *compiler) DoStruct(v *ast.StructStmt) error {
+	return unsupported(v)
+}
+
+func (this *compiler) DoMethod(v *ast.MethodStmt) error {
+	return unsupported(v)
+}
+
+func (this *compiler) DoPrefix(v *ast.PrefixExpr) error {
+	dst := this.dst
+	op, err := code.PrefixCode(v.Op.Type)
+	if nil != err {
+		return function.NewError(err)
+	}
+	r, err := this.operand(v.Right)
+	if nil != err {
+		return function.NewError(err)
+	}
+	_, err = this.emit(OpPrefix, int(op), dst, r)
+	return err
+}
+
+func (this *compiler) DoInfix(v *ast.InfixExpr) error {
+	dst := this.dst
+	op, err := code.InfixCode(v.Op.Type)
+	if nil != err {
+		return function.NewError(err)
+	}
+	left, err := this.operand(v.Left)
+	if nil != err {
+		return function.NewError(err)
+	}
+	if idx, ok := this.literalConst(v.Right); ok {
+		_, err = this.emit(OpInfixConst, int(op), dst, left, idx)
+		return err
+	}
+	right, err := this.operand(v.Right)
+	if nil != err {
+		return function.NewError(err)
+	}
+	_, err = this.emit(OpInfix, int(op), dst, left, right)
+	return err
+}
+
+func (this *compiler) DoIdent(v *ast.Identifier) error {
+	return this.load(v.Value, this.dst)
+}
+
+func (this *compiler) DoSymbol(v *ast.SymbolExpr) error {
+	_, err := this.emit(OpSymbol, this.dst, this.addConst(object.NewString(v.Value)))
+	return err
+}
+
+// DoConditional : the comparison of cond is fused with the jump
+//
+//	     OpCmpJump--------|
+//	     Yes              |
+//	|----OpJump           |
+//	|    No<--------------|
+//	|--->...
+func (this *compiler) DoConditional(v *ast.ConditionalExpr) error {
+	dst := this.dst
+	posFalse, err := this.jumpWhenFalse(v.Cond)
+	if nil != err {
+		return function.NewError(err)
+	}
+	if err := this.expr(v.Yes, dst); nil != err {
+		return function.NewError(err)
+	}
+	posEnd, err := this.emit(OpJump, 0)
+	if nil != err {
+		return function.NewError(err)
+	}
+	if err := this.patch(posFalse); nil != err {
+		return function.NewError(err)
+	}
+	if err := this.expr(v.No, dst); nil != err {
+		return function.NewError(err)
+	}
+	return this.patch(posEnd)
+}
+
+func (this *compiler) DoNullish(v *ast.NullishExpr) error {
+	dst := this.dst
+	if err := this.expr(v.Left, dst); nil != err {
+		return function.NewError(err)
+	}
+	pos, err := this.emit(OpJumpWhenNotNull, dst, 0)
+	if nil != err {
+		return function.NewError(err)
+	}
+	if err := this.expr(v.Right, dst); nil != err {
+		return function.NewError(err)
+	}
+	return this.patch(pos)
+}
+
+func (this *compiler) DoIn(v *ast.InExpr) error {
+	dst := this.dst
+	left, err := this.operand(v.Left)
+	if nil != err {
+		return function.NewError(err)
+	}
+	right, err := this.operand(v.Right)
+	if nil != err {
+		return function.NewError(err)
+	}
+	op := OpIn
+	if v.Not {
+		op = OpNotIn
+	}
+	_, err = this.emit(op, dst, left, right)
+	return err
+}
+
+func (this *compiler) DoInterval(v *ast.IntervalExpr) error {
+	dst := this.dst
+	start, err := this.operand(v.Start)
+	if nil != err {
+		return function.NewError(err)
+	}
+	end, err := this.operand(v.End)
+	if nil != err {
+		return function.NewError(err)
+	}
+	exclusive := 0
+	if v.Exclusive {
+		exclusive = 1
+	}
+	_, err = this.emit(OpInterval, dst, start, end, exclusive)
+	return err
+}
+
+func (this *compiler) DoMatch(v *ast.MatchExpr) error {
+	return unsupported(v)
+}
+
+// DoFn : the frees are loaded into consecutive registers and merged with the function by OpClosure
+func (this *compiler) DoFn(v *ast.Function) error {
+	if nil != v.Params || nil != v.Defaults || nil != v.Rest {
+		return unsupported(v)
+	}
+	body, ok := v.Body.Stmt.(*ast.ExpressionStmt)
+	if !ok {
+		return unsupported(v)
+	}
+	dst := this.dst
+	outer := this.fn
+	this.fn = newFuncScope(outer, v.Lambda)
+	for _, arg := range v.Args {
+		this.fn.locals[arg.Value] = this.fn.alloc()
+	}
+	err := this.tail(body.Expr)
+	inner := this.fn
+	this.fn = outer
+	if nil != err {
+		return function.NewError(err)
+	}
+	if inner.max < 1 {
+		inner.max = 1
+	}
+	fn := object.NewByteFunc(inner.ins, v.Arity(), inner.max)
+	idx := this.addConst(fn)
+	frees := make(ast.ExpressionSlice, len(inner.frees))
+	for i, name := range inner.frees {
+		ident := ast.NewIdent()
+		ident.Value = name
+		frees[i] = ident
+	}
+	base, err := this.operands(frees)
+	if nil != err {
+		return function.NewError(err)
+	}
+	_, err = this.emit(OpClosure, dst, idx, base, len(frees))
+	return err
+}
+
+func (this *compiler) DoCall(v *ast.Call) error {
+	if ast.HasSpread(v.Args) {
+		return unsupported(v)
+	}
+	dst := this.dst
+	base, err := this.operands(append(ast.ExpressionSlice{v.Func}, v.Args...))
+	if nil != err {
+		return function.NewError(err)
+	}
+	_, err = this.emit(OpCall, dst, base, len(v.Args))
+	return err
+}
+
+func (this *compiler) DoSpread(v *ast.SpreadExpr) error {
+	return function.NewError(ast.ErrSpread)
+}
+
+// DoCallMember : left?.fn(args) is null if left is null
+func (this *compiler) DoCallMember(v *ast.CallMember) error {
+	jumps, err := this.linkCallMember(v)
+	if nil != err {
+		return err
+	}
+	return this.chainEnd(jumps)
+}
+
+func (this *compiler) DoObjectMember(v *ast.ObjectMember) error {
+	jumps, err := this.linkObjectMember(v)
+	if nil != err {
+		return err
+	}
+	return this.chainEnd(jumps)
+}
+
+func (this *compiler) DoIndex(v *ast.IndexExpr) error {
+	jumps, err := this.linkIndex(v)
+	if nil != err {
+		return err
+	}
+	return this.chainEnd(jumps)
+}
+
+// link : compile a link of a member chain (a.b, a.f(), a[k]) into dst, the jumps of ?. on a null left are returned,
+// the outermost link loads null into its dst at them, so the rest of the chain is null
+func (this *compiler) link(e ast.Expression, dst int) ([]int, error) {
+	var jumps []int
+	err := this.exprWith(e, dst, func() error {
+		var err error
+		switch v := e.(type) {
+		case *ast.ObjectMember:
+			jumps, err = this.linkObjectMember(v)
+		case *ast.CallMember:
+			jumps, err = this.linkCallMember(v)
+		case *ast.IndexExpr:
+			jumps, err = this.linkIndex(v)
+		default:
+			err = e.Do(this)
+		}
+		return err
+	})
+	return jumps, err
+}
+
+// linkOperands : compile the left of a link and items into consecutive registers like operands
+func (this *compiler) linkOperands(left ast.Expression, optional bool, items ast.ExpressionSlice) (int, []int, error) {
+	base := this.fn.next
+	for i := 0; i <= len(items); i++ {
+		this.fn.alloc()
+	}
+	jumps, err := this.link(left, base)
+	if nil != err {
+		return -1, nil, function.NewError(err)
+	}
+	if jumps, err = this.jumpWhenNull(jumps, base, optional); nil != err {
+		return -1, nil, function.NewError(err)
+	}
+	for i, item := range items {
+		if err := this.expr(item, base+1+i); nil != err {
+			return -1, nil, function.NewError(err)
+		}
+	}
+	return base, jumps, nil
+}
+
+// linkOperand : the register of a local is used as it is, refer to operand
+func (this *compiler) linkOperand(left ast.Expression, optional bool) (int, []int, error) {
+	if ident, ok := left.(*ast.Identifier); ok {
+		if r, ok := this.fn.locals[ident.Value]; ok {
+			jumps, err := this.jumpWhenNull(nil, r, optional)
+			return r, jumps, err
+		}
+	}
+	return this.linkOperands(left, optional, nil)
+}
+
+// jumpWhenNull : the null left of left?.x in r jumps to the end of the chain
+func (this *compiler) jumpWhenNull(jumps []int, r int, optional bool) ([]int, error) {
+	if !optional {
+		return jumps, nil
+	}
+	pos, err := this.emit(OpJumpWhenNull, r, 0)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	return append(jumps, pos), nil
+}
+
+// chainEnd : the outermost link of a chain
+func (this *compiler) chainEnd(jumps []int) error {
+	if 0 == len(jumps) {
+		return nil
+	}
+	posEnd, err := this.emit(OpJump, 0)
+	if nil != err {
+		return function.NewError(err)
+	}
+	for _, pos := range jumps {
+		if err := this.patch(pos); nil != err {
+			return function.NewError(err)
+		}
+	}
+	if _, err := this.emit(OpLoadNull, this.dst); nil != err {
+		return function.NewError(err)
+	}
+	return this.patch(posEnd)
+}
+
+func (this *compiler) linkCallMember(v *ast.CallMember) ([]int, error) {
+	if ast.HasSpread(v.Args) {
+		return nil, unsupported(v)
+	}
+	dst := this.dst
+	base, jumps, err := this.linkOperands(v.Left, v.Optional, v.Args)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	name := this.addConst(object.NewString(v.Func.Value))
+	slot, err := this.addInlineCache(v)
+	if nil != err {
+		return nil, err
+	}
+	if _, err := this.emit(OpCallMember, dst, base, len(v.Args), name, slot); nil != err {
+		return nil, function.NewError(err)
+	}
+	return jumps, nil
+}
+
+// linkObjectMember : left?.member resolves the member as left.member does
+func (this *compiler) linkObjectMember(v *ast.ObjectMember) ([]int, error) {
+	dst := this.dst
+	left, jumps, err := this.linkOperand(v.Left, v.Optional)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	if _, err := this.emit(OpGetMember, dst, left, this.addConst(object.NewString(v.Member.Value))); nil != err {
+		return nil, function.NewError(err)
+	}
+	return jumps, nil
+}
+
+func (this *compiler) linkIndex(v *ast.IndexExpr) ([]int, error) {
+	dst := this.dst
+	left, jumps, err := this.linkOperand(v.Left, v.Optional)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	idx, err := this.operand(v.Index)
+	if nil != err {
+		return nil, function.NewError(err)
+	}
+	op := OpIndex
+	if v.Optional {
+		op = OpIndexOptional
+	}
+	if _, err := this.emit(op, dst, left, idx); nil != err {
+		return nil, function.NewError(err)
+	}
+	return jumps, nil
+}
+
+func (this *compiler) DoSlice(v *ast.SliceExpr) error {
+	dst := this.dst
+	base, err := this.operands(append(ast.ExpressionSlice{v.Left}, v.Bounds()...))
+	if nil != err {
+		return function.NewError(err)
+	}
+	_, err = this.emit(OpSlice, dst, base)
+	return err
+}
+
+func (this *compiler) DoTry(v *ast.TryExpr) error {
+	return unsupported(v)
+}
+
+func (this *compiler) DoNull(v *ast.Null) error {
+	_, err := this.emit(OpLoadNull, this.dst)
+	return err
+}
+
+func (this *compiler) DoInteger(v *ast.Integer) error {
+	_, err := this.emit(OpLoadConst, this.dst, this.addConst(v.Object()))
+	return err
+}
+
+func (this *compiler) DoBoolean(v *ast.Boolean) error {
+	op := OpLoadFalse
+	if v.Value {
+		op = OpLoadTrue
+	}
+	_, err := this.emit(op, this.dst)
+	return err
+}
+
+func (this *compiler) DoString(v *ast.String) error {
+	_, err := this.emit(OpLoadConst, this.dst, this.addConst(object.NewString(v.Value)))
+	return err
+}
+
+func (this *compiler) DoTemplate(v *ast.TemplateExpr) error {
+	dst := this.dst
+	base, err := this.operands(v.Parts)
+	if nil != err {
+		return function.NewError(err)
+	}
+	_, err = this.emit(OpConcat, dst, base, len(v.Parts))
+	return err
+}
+
+func (this *compiler) DoArray(v *ast.Array) error {
+	dst := this.dst
+	base, err := this.operands(v.Items)
+	if nil != err {
+		return function.NewError(err)
+	}
+	_, err = this.emit(OpArray, dst, base, len(v.Items))
+	return err
+}
+
+// DoHash : the pairs are in consecutive registers in literal order
+func (this *compiler) DoHash(v *ast.Hash) error {
+	dst := this.dst
+	items := ast.ExpressionSlice{}
+	for _, k := range v.OrderedKeys() {
+		items = append(items, k, v.Pairs[k])
+	}
+	base, err := this.operands(items)
+	if nil != err {
+		return function.NewError(err)
+	}
+	_, err = this.emit(OpHash, dst, base, len(v.Pairs))
+	return err
+}
@@ -0,0 +1,430 @@
+package regvm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jobs-github/escript/builtin"
+	"github.com/jobs-github/escript/code"
+	"github.com/jobs-github/escript/object"
+	"github.com/jobs-github/escript/token"
+)
+
+// RegistersSize : registers of all the frames on the call stack
+const RegistersSize = 65536
+
+var (
+	errStackOverflow = errors.New("stack overflow")
+	errNotCallable   = errors.New("not callable")
+)
+
+var (
+	infixTokens = map[code.Opcode]*token.Token{}
+	prefixFns   = map[code.Opcode]string{
+		code.OpNot:    object.FnNot,
+		code.OpNeg:    object.FnNeg,
+		code.OpBitNot: object.FnBitNot,
+	}
+)
+
+func init() {
+	for op := code.OpUndefined; op < code.OpPlaceholder; op++ {
+		if t, err := code.InfixToken(op); nil == err {
+			infixTokens[op] = t
+		}
+	}
+}
+
+type VM interface {
+	Run(s object.Symbols) (object.Object, error)
+}
+
+func New(b *Bytecode) VM {
+	return &virtualMachine{
+		b:         b,
+		constants: b.Constants,
+		globals:   make(object.Objects, b.Globals),
+		regs:      make(object.Objects, RegistersSize),
+		caches:    make([]object.InlineCache, b.Caches),
+	}
+}
+
+// virtualMachine : implement VM, a frame is a window of regs starting at its base,
+// a call from go code (e.g. a builtin) runs a nested loop
+type virtualMachine struct {
+	b         *Bytecode
+	constants object.Objects
+	globals   object.Objects
+	regs      object.Objects
+	top       int // the first register which is not used by a frame
+	symbols   object.Symbols
+	caches    []object.InlineCache
+}
+
+// Run : the result is the value of the latest statement
+func (this *virtualMachine) Run(s object.Symbols) (object.Object, error) {
+	this.symbols = s
+	this.top = 0
+	main := object.NewClosure(this.b.Main, object.Objects{}, this.invoke)
+	return this.invoke(main, object.Objects{})
+}
+
+// invoke : implement object.Invoker
+func (this *virtualMachine) invoke(fn *object.Closure, args object.Objects) (object.Object, error) {
+	if !fn.Fn.Accept(len(args)) {
+		return nil, wrongArgs(fn, len(args))
+	}
+	base := this.top
+	if base+fn.Fn.Locals > len(this.regs) {
+		return nil, errStackOverflow
+	}
+	copy(this.regs[base:], args)
+	this.top = base + fn.Fn.Locals
+	r, err := this.run(fn, base)
+	this.top = base
+	return r, err
+}
+
+func wrongArgs(fn *object.Closure, argc int) error {
+	return fmt.Errorf("wrong number of arguments: want=%v, got=%v", fn.Fn.Arity.String(), argc)
+}
+
+// call : closures of the vm are run directly, other objects are called as go code
+func (this *virtualMachine) call(fn object.Object, args object.Objects) (object.Object, error) {
+	if cl, ok := fn.(*object.Closure); ok {
+		return this.invoke(cl, args)
+	}
+	if !object.IsCallable(fn) {
+		return nil, errNotCallable
+	}
+	return fn.Call(args)
+}
+
+func u16(b []byte) int {
+	return int(b[0])<<8 | int(b[1])
+}
+
+func (this *virtualMachine) run(fn *object.Closure, base int) (object.Object, error) {
+	ins := fn.Fn.Ins
+	regs := this.regs[base:this.top]
+	for ip := 0; ip < len(ins); {
+		switch Opcode(ins[ip]) {
+		case OpLoadConst:
+			regs[ins[ip+1]] = this.constants[u16(ins[ip+2:])]
+			ip += 4
+		case OpLoadNull:
+			regs[ins[ip+1]] = object.Nil
+			ip += 2
+		case OpLoadTrue:
+			regs[ins[ip+1]] = object.True
+			ip += 2
+		case OpLoadFalse:
+			regs[ins[ip+1]] = object.False
+			ip += 2
+		case OpMove:
+			regs[ins[ip+1]] = regs[ins[ip+2]]
+			ip += 3
+		case OpGetGlobal:
+			regs[ins[ip+1]] = this.globals[u16(ins[ip+2:])]
+			ip += 4
+		case OpSetGlobal:
+			this.globals[u16(ins[ip+1:])] = regs[ins[ip+3]]
+			ip += 4
+		case OpGetFree:
+			regs[ins[ip+1]] = fn.Free[ins[ip+2]]
+			ip += 3
+		case OpGetLambda:
+			regs[ins[ip+1]] = fn
+			ip += 2
+		case OpGetBuiltin:
+			regs[ins[ip+1]] = builtin.Resolve(int(ins[ip+2]))
+			ip += 3
+		case OpSymbol:
+			r, err := this.symbol(u16(ins[ip+2:]))
+			if nil != err {
+				return nil, err
+			}
+			regs[ins[ip+1]] = r
+			ip += 4
+		case OpInfix:
+			r, err := regs[ins[ip+3]].Calc(infixTokens[code.Opcode(ins[ip+1])], regs[ins[ip+4]])
+			if nil != err {
+				return nil, err
+			}
+			regs[ins[ip+2]] = r
+			ip += 5
+		case OpInfixConst:
+			r, err := regs[ins[ip+3]].Calc(infixTokens[code.Opcode(ins[ip+1])], this.constants[u16(ins[ip+4:])])
+			if nil != err {
+				return nil, err
+			}
+			regs[ins[ip+2]] = r
+			ip += 6
+		case OpPrefix:
+			r, err := regs[ins[ip+3]].CallMember(prefixFns[code.Opcode(ins[ip+1])], object.Objects{})
+			if nil != err {
+				return nil, err
+			}
+			regs[ins[ip+2]] = r
+			ip += 4
+		case OpIn, OpNotIn:
+			r, err := object.Contains(regs[ins[ip+3]], regs[ins[ip+2]], OpNotIn == Opcode(ins[ip]))
+			if nil != err {
+				return nil, err
+			}
+			regs[ins[ip+1]] = r
+			ip += 4
+		case OpInterval:
+			r, err := object.NewInterval(regs[ins[ip+2]], regs[ins[ip+3]], 1 == ins[ip+4])
+			if nil != err {
+				return nil, err
+			}
+			regs[ins[ip+1]] = r
+			ip += 5
+		case OpJump:
+			ip = u16(ins[ip+1:])
+		case OpJumpWhenFalse:
+			if !regs[ins[ip+1]].True() {
+				ip = u16(ins[ip+2:])
+			} else {
+				ip += 4
+			}
+		case OpJumpWhenNull:
+			if object.IsNull(regs[ins[ip+1]]) {
+				ip = u16(ins[ip+2:])
+			} else {
+				ip += 4
+			}
+		case OpJumpWhenNotNull:
+			if !object.IsNull(regs[ins[ip+1]]) {
+				ip = u16(ins[ip+2:])
+			} else {
+				ip += 4
+			}
+		case OpCmpJump:
+			r, err := regs[ins[ip+2]].Calc(infixTokens[code.Opcode(ins[ip+1])], regs[ins[ip+3]])
+			if nil != err {
+				return nil, err
+			}
+			if !r.True() {
+				ip = u16(ins[ip+4:])
+			} else {
+				ip += 6
+			}
+		case OpCall:
+			b, c := int(ins[ip+2]), int(ins[ip+3])
+			r, err := this.call(regs[b], regs[b+1:b+1+c])
+			if nil != err {
+				return nil, err
+			}
+			regs[ins[ip+1]] = r
+			ip += 4
+		case OpTailCall:
+			a, b := int(ins[ip+1]), int(ins[ip+2])
+			cl, ok := regs[a].(*object.Closure)
+			if !ok {
+				return this.call(regs[a], regs[a+1:a+1+b])
+			}
+			if !cl.Fn.Accept(b) {
+				return nil, wrongArgs(cl, b)
+			}
+			// the frame is reused by the callee
+			if base+cl.Fn.Locals > len(this.regs) {
+				return nil, errStackOverflow
+			}
+			copy(this.regs[base:], regs[a+1:a+1+b])
+			this.top = base + cl.Fn.Locals
+			regs = this.regs[base:this.top]
+			fn, ins, ip = cl, cl.Fn.Ins, 0
+		case OpCallMember:
+			b, c := int(ins[ip+2]), int(ins[ip+3])
+			r, err := this.callMember(regs[b], this.constants[u16(ins[ip+4:])].String(), regs[b+1:b+1+c], u16(ins[ip+6:]))
+			if nil != err {
+				return nil, err
+			}
+			regs[ins[ip+1]] = r
+			ip += 8
+		case OpReturn:
+			return regs[ins[ip+1]], nil
+		case OpClosure:
+			c, d := int(ins[ip+4]), int(ins[ip+5])
+			frees := make(object.Objects, d)
+			copy(frees, regs[c:c+d])
+			f := this.constants[u16(ins[ip+2:])].(*object.ByteFunc)
+			regs[ins[ip+1]] = object.NewClosure(f, frees, this.invoke)
+			ip += 6
+		case OpArray:
+			b, c := int(ins[ip+2]), int(ins[ip+3])
+			items := make(object.Objects, c)
+			copy(items, regs[b:b+c])
+			regs[ins[ip+1]] = object.NewArray(items)
+			ip += 4
+		case OpHash:
+			b, c := int(ins[ip+2]), int(ins[ip+3])
+			h := object.NewOrderedHash()
+			for i := b; i < b+c*2; i += 2 {
+				if err := h.Set(regs[i], regs[i+1]); nil != err {
+					return nil, err
+				}
+			}
+			regs[ins[ip+1]] = h
+			ip += 4
+		case OpConcat:
+			b, c := int(ins[ip+2]), int(ins[ip+3])
+			r, err := object.Concat(regs[b : b+c])
+			if nil != err {
+				return nil, err
+			}
+			regs[ins[ip+1]] = r
+			ip += 4
+		case OpIndex:
+			r, err := regs[ins[ip+2]].CallMember(object.FnIndex, object.Objects{regs[ins[ip+3]]})
+			if nil != err {
+				return nil, err
+			}
+			regs[ins[ip+1]] = r
+			ip += 4
+		case OpIndexOptional:
+			r, err := object.OptionalIndex(regs[ins[ip+2]], regs[ins[ip+3]])
+			if nil != err {
+				return nil, err
+			}
+			regs[ins[ip+1]] = r
+			ip += 4
+		case OpSlice:
+			b := int(ins[ip+2])
+			r, err := regs[b].CallMember(object.FnSlice, object.Objects{regs[b+1], regs[b+2], regs[b+3]})
+			if nil != err {
+				return nil, err
+			}
+			regs[ins[ip+1]] = r
+			ip += 3
+		case OpGetMember:
+			r, err := regs[ins[ip+2]].GetMember(this.constants[u16(ins[ip+3:])].String())
+			if nil != err {
+				return nil, err
+			}
+			regs[ins[ip+1]] = r
+			ip += 5
+		case OpLoop, OpRange:
+			r, err := this.doCount(regs[ins[ip+2]], regs[ins[ip+3]], OpRange == Opcode(ins[ip]))
+			if nil != err {
+				return nil, err
+			}
+			regs[ins[ip+1]] = r
+			ip += 4
+		case OpMap, OpFilter:
+			r, err := this.doMap(regs[ins[ip+2]], regs[ins[ip+3]], OpFilter == Opcode(ins[ip]))
+			if nil != err {
+				return nil, err
+			}
+			regs[ins[ip+1]] = r
+			ip += 4
+		case OpReduce:
+			r, err := this.doReduce(regs[ins[ip+2]], regs[ins[ip+3]], regs[ins[ip+4]])
+			if nil != err {
+				return nil, err
+			}
+			regs[ins[ip+1]] = r
+			ip += 5
+		default:
+			return nil, fmt.Errorf("%04d unknown opcode %v", ip, ins[ip])
+		}
+	}
+	return object.Nil, nil
+}
+
+// callMember : a builtin method is called through the inline cache, other members are got and called
+func (this *virtualMachine) callMember(recv object.Object, name string, args object.Objects, slot int) (object.Object, error) {
+	r, ok, err := this.caches[slot].Call(recv, name, args)
+	if ok || nil != err {
+		return r, err
+	}
+	fn, err := recv.GetMember(name)
+	if nil != err {
+		return nil, err
+	}
+	return this.call(fn, args)
+}
+
+func (this *virtualMachine) symbol(idx int) (object.Object, error) {
+	key := this.constants[idx].String()
+	cb, ok := this.symbols[key]
+	if !ok {
+		return nil, fmt.Errorf("symbol `%v` missing", key)
+	}
+	return cb()
+}
+
+// doCount : loop calls fn for 0...cnt-1, range collects the results
+func (this *virtualMachine) doCount(cnt object.Object, fn object.Object, collect bool) (object.Object, error) {
+	n, err := object.ToInteger(cnt)
+	if nil != err {
+		return nil, err
+	}
+	if !object.IsCallable(fn) {
+		return nil, errNotCallable
+	}
+	r := object.Objects{}
+	for i := int64(0); i < n; i++ {
+		v, err := this.call(fn, object.Objects{object.NewInteger(i)})
+		if nil != err {
+			return nil, err
+		}
+		if collect {
+			r = append(r, v)
+		}
+	}
+	if !collect {
+		return object.Nil, nil
+	}
+	return object.NewArray(r), nil
+}
+
+// doMap : map collects the results of fn(i, item), filter collects the items which fn(i, item) is true
+func (this *virtualMachine) doMap(arr object.Object, fn object.Object, filter bool) (object.Object, error) {
+	sz := int64(0)
+	if !filter {
+		n, err := object.MapLen(arr)
+		if nil != err {
+			return nil, err
+		}
+		sz = n
+	}
+	if !object.IsCallable(fn) {
+		return nil, errNotCallable
+	}
+	r := make(object.Objects, 0, sz)
+	if err := object.Iterate(arr, func(i int64, item object.Object) error {
+		v, err := this.call(fn, object.Objects{object.NewInteger(i), item})
+		if nil != err {
+			return err
+		}
+		if !filter {
+			r = append(r, v)
+		} else if v.True() {
+			r = append(r, item)
+		}
+		return nil
+	}); nil != err {
+		return nil, err
+	}
+	return object.NewArray(r), nil
+}
+
+func (this *virtualMachine) doReduce(arr object.Object, fn object.Object, acc object.Object) (object.Object, error) {
+	if !object.IsCallable(fn) {
+		return nil, errNotCallable
+	}
+	if err := object.Iterate(arr, func(_ int64, item object.Object) error {
+		v, err := this.call(fn, object.Objects{acc, item})
+		if nil != err {
+			return err
+		}
+		acc = v
+		return nil
+	}); nil != err {
+		return nil, err
+	}
+	return acc, nil
+}
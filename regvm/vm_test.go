@@ -0,0 +1,173 @@
+package regvm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jobs-github/escript/ast"
+	"github.com/jobs-github/escript/object"
+	"github.com/jobs-github/escript/parser"
+)
+
+func parse(t *testing.T, input string) ast.Node {
+	p, err := parser.New(input)
+	if nil != err {
+		t.Fatal(err)
+	}
+	r, err := p.ParseProgram()
+	if nil != err {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func run(t *testing.T, input string, s object.Symbols) object.Object {
+	b, err := Compile(parse(t, input))
+	if nil != err {
+		t.Fatalf("input: %v, err: %v", input, err)
+	}
+	r, err := New(b).Run(s)
+	if nil != err {
+		t.Fatalf("input: %v, err: %v", input, err)
+	}
+	return r
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`1 + 2 * 3`, "7"},
+		{`const a = 1; const b = a + 1; a - b`, "-1"},
+		{`!true`, "false"},
+		{`-(1 + 2)`, "-3"},
+		{`(1 < 2) ? "yes" : "no"`, "yes"},
+		{`const a = 3; (a > 5) ? 1 : ((a == 3) ? 2 : 3)`, "2"},
+		{`func fib(x) { (x < 2) ? x : fib(x - 1) + fib(x - 2) }; fib(15)`, "610"},
+		{`func sum(n, acc) { (n == 0) ? acc : sum(n - 1, acc + n) }; sum(10000, 0)`, "50005000"},
+		{`func add(a) { func(b) { func(c) { a + b + c } } }; add(1)(2)(3)`, "6"},
+		{`const n = 10; func f() { n * 2 }; f()`, "20"},
+		{`map([1, 2, 3], func(i, x) { x * x })`, "[1, 4, 9]"},
+		{`filter([1, 2, 3, 4], func(i, x) { x % 2 == 0 })`, "[2, 4]"},
+		{`reduce([1, 2, 3, 4], func(acc, x) { acc + x }, 0)`, "10"},
+		{`range(3, func(i) { i * 10 })`, "[0, 10, 20]"},
+		{`const k = 2; map([1, 2], func(i, x) { x * k })`, "[2, 4]"},
+		{`const a = [1, 2, 3]; a[1] + a.len()`, "5"},
+		{`[1, 2, 3, 4][1:3]`, "[2, 3]"},
+		{`{"a": 1, "b": 2}["b"]`, "2"},
+		{`const h = {"a": {"b": [1, 2]}}; h?.["a"]?.["b"]?.[1]`, "2"},
+		{`const x = null; x?.y ?? "none"`, "none"},
+		{`const x = null; x?.[0] ?? x?.len()`, "null"},
+		{`const x = null; x?.y.z[0] ?? "none"`, "none"},
+		{`const h = {"a": null}; [h["a"]?.b.c(1), h?.len()]`, "[null, 1]"},
+		{`2 in [1, 2, 3]`, "true"},
+		{`4 not in 1..<4`, "true"},
+		{"const n = 2; `n = ${n + 1}`", "n = 3"},
+		{`str(1) + type("")`, "1string"},
+		{`$sym + 1`, "42"},
+	}
+	s := object.Symbols{"sym": func() (object.Object, error) { return object.NewInteger(41), nil }}
+	for i, tt := range tests {
+		if r := run(t, tt.input, s); tt.want != r.String() {
+			t.Fatalf("i: %v, want: %v, got: %v", i, tt.want, r)
+		}
+	}
+}
+
+func TestRunError(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`func f(x) { x }; f(1, 2)`, "wrong number of arguments"},
+		{`const a = 1; a(1)`, "not callable"},
+		{`$missing`, "missing"},
+		{`func f(x) { f(x + 1) + 1 }; f(0)`, "stack overflow"},
+	}
+	for i, tt := range tests {
+		b, err := Compile(parse(t, tt.input))
+		if nil != err {
+			t.Fatalf("i: %v, err: %v", i, err)
+		}
+		if _, err := New(b).Run(nil); nil == err || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("i: %v, expect error `%v`, got: %v", i, tt.want, err)
+		}
+	}
+}
+
+// TestRerun : the same compiled program runs repeatedly, the integers in the constant pool stay intact
+func TestRerun(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`reduce(range(2000, func(i) { i }), func(acc, x) { acc + x }, 0)`, "1999000"},
+		{`const a = range(300, func(i) { i }); reduce(map(a, func(i, x) { x * 2 }), func(acc, x) { acc + x }, 0)`, "89700"},
+		{`reduce(range(50, func(i) { reduce(range(i, func(j) { j }), func(acc, x) { acc + x }, 0) }), func(acc, x) { acc + x }, 0)`, "19600"},
+		{`filter(range(1000, func(i) { i }), func(i, x) { x % 7 == 0 }).len()`, "143"},
+		{`const n = 0; loop(100, func(i) { i + n }); range(3, func(i) { i + n })`, "[0, 1, 2]"},
+	}
+	for i, tt := range tests {
+		b, err := Compile(parse(t, tt.input))
+		if nil != err {
+			t.Fatal(err)
+		}
+		before := fmt.Sprint(b.Constants)
+		for round := 0; round < 5; round++ {
+			r, err := New(b).Run(nil)
+			if nil != err {
+				t.Fatal(err)
+			}
+			if tt.want != r.String() {
+				t.Fatalf("i: %v, round: %v, want: %v, got: %v", i, round, tt.want, r)
+			}
+		}
+		if after := fmt.Sprint(b.Constants); before != after {
+			t.Fatalf("i: %v, constants modified, before: %v, after: %v", i, before, after)
+		}
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	tests := []string{
+		`struct P { x }; P(1)`,
+		`match 1 { 1 => 2, _ => 3 }`,
+		`try { 1 } catch (e) { 2 }`,
+		`func f(x, y = 1) { x + y }; f(1)`,
+		`func f(...xs) { xs }; f(1)`,
+		`const [a, b] = [1, 2]; a`,
+		`func f(x, y) { x + y }; f(...[1, 2])`,
+	}
+	// more than 256 registers
+	tests = append(tests, "func f(x) { x"+strings.Repeat(" + (1 + x", 300)+strings.Repeat(")", 300)+" }; f(1)")
+	for i, input := range tests {
+		if _, err := Compile(parse(t, input)); !errors.Is(err, ErrUnsupported) {
+			t.Fatalf("i: %v, expect ErrUnsupported, got: %v", i, err)
+		}
+	}
+	if _, err := Compile(parse(t, `x + 1`)); nil == err || errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expect undefined symbol, got: %v", err)
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	b, err := Compile(parse(t, `const a = 1; (a < 2) ? a + 1 : a`))
+	if nil != err {
+		t.Fatal(err)
+	}
+	s := Disassemble(b.Main.Ins)
+	for _, want := range []string{"OpLoadConst 0 0", "OpSetGlobal 0 0", "OpCmpJump", "OpInfixConst", "OpReturn 0"} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("expect `%v` in\n%v", want, s)
+		}
+	}
+	if _, err := Make(OpLoadConst, 256, 0); nil == err {
+		t.Fatal("expect overflow error")
+	}
+	if _, err := Make(OpLoadConst, 0, 0x10000); nil == err {
+		t.Fatal("expect overflow error")
+	}
+}
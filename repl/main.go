@@ -3,16 +3,21 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"strings"
 
 	"github.com/jobs-github/escript/ast"
+	"github.com/jobs-github/escript/checker"
 	"github.com/jobs-github/escript/compiler"
 	"github.com/jobs-github/escript/function"
 	"github.com/jobs-github/escript/object"
+	"github.com/jobs-github/escript/optimizer"
 	"github.com/jobs-github/escript/parser"
+	"github.com/jobs-github/escript/regvm"
 	"github.com/jobs-github/escript/vm"
 )
 
@@ -20,6 +25,13 @@ const (
 	useVM = true
 )
 
+// optimize : `--no-opt` compiles the AST as it is parsed and skips the peephole pass, e.g. `--no-opt --disasm x.es`
+var optimize = true
+
+// register : `--reg` runs the scripts on the register vm, the code out of its subset runs on the stack vm,
+// refer to regvm.Compile
+var register = false
+
 func newEval() Eval {
 	if useVM {
 		return newState()
@@ -29,12 +41,24 @@ func newEval() Eval {
 }
 
 func main() {
+	for len(os.Args) > 1 && (os.Args[1] == "--no-opt" || os.Args[1] == "--reg") {
+		if os.Args[1] == "--no-opt" {
+			optimize = false
+		} else {
+			register = true
+		}
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
 	argc := len(os.Args)
 	e := newEval()
 	if argc == 1 {
 		e.Repl(os.Stdin, os.Stdout)
 	} else if argc == 2 {
-		e.EvalScript(os.Args[1])
+		if strings.HasSuffix(os.Args[1], compiler.SuffixBytecode) {
+			evalBytecode(os.Args[1])
+		} else {
+			e.EvalScript(os.Args[1])
+		}
 	} else {
 		if os.Args[1] == "--dump" {
 			if s, err := e.DumpAst(os.Args[2]); nil != err {
@@ -46,6 +70,23 @@ func main() {
 			if argc == 3 {
 				e.EvalJson(os.Args[2])
 			}
+		} else if os.Args[1] == "--compile" {
+			if err := compileScript(os.Args[2]); nil != err {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		} else if os.Args[1] == "--disasm" {
+			if s, err := disasmScript(os.Args[2]); nil != err {
+				fmt.Println(err)
+				os.Exit(1)
+			} else {
+				fmt.Print(s)
+			}
+		} else if os.Args[1] == "--check" {
+			if err := checkScript(os.Args[2]); nil != err {
+				fmt.Println(err)
+				os.Exit(1)
+			}
 		}
 	}
 }
@@ -140,6 +181,124 @@ func evalCode(code string, fn evalNode) {
 	}
 }
 
+// checkScript : type errors of the script, the script is not run
+func checkScript(path string) error {
+	b, err := loadCode(path)
+	if nil != err {
+		return function.NewError(err)
+	}
+	program, err := LoadAst(function.BytesToString(b))
+	if nil != err {
+		return function.NewError(err)
+	}
+	return checker.Check(program)
+}
+
+// compileProgram : compile a whole script, the lines of Repl are not optimized
+// as the consts may be redefined by the later lines
+func compileProgram(program ast.Node) (compiler.Compiler, error) {
+	if err := optimizeProgram(program); nil != err {
+		return nil, function.NewError(err)
+	}
+	return compileOptimized(program)
+}
+
+func optimizeProgram(program ast.Node) error {
+	if !optimize {
+		return nil
+	}
+	return optimizer.Optimize(program)
+}
+
+// compileOptimized : compile the program which optimizeProgram is applied to
+func compileOptimized(program ast.Node) (compiler.Compiler, error) {
+	c := compiler.Make(compiler.NewSymbolTable(nil), object.Objects{})
+	if err := c.Compile(program); nil != err {
+		return nil, function.NewError(err)
+	}
+	if optimize {
+		if err := c.Peephole(); nil != err {
+			return nil, function.NewError(err)
+		}
+	}
+	return c, nil
+}
+
+// compileScript : write the bytecode of x.es to x.esc, which runs without the compiler
+func compileScript(path string) error {
+	b, err := loadCode(path)
+	if nil != err {
+		return function.NewError(err)
+	}
+	program, err := LoadAst(function.BytesToString(b))
+	if nil != err {
+		return function.NewError(err)
+	}
+	c, err := compileProgram(program)
+	if nil != err {
+		return function.NewError(err)
+	}
+	b, err = compiler.Marshal(c.Bytecode(), c.Constants())
+	if nil != err {
+		return function.NewError(err)
+	}
+	dst := strings.TrimSuffix(path, ast.Suffix) + compiler.SuffixBytecode
+	if err := ioutil.WriteFile(dst, b, 0644); nil != err {
+		return function.NewError(err)
+	}
+	return nil
+}
+
+// disasmScript : listing of the bytecode of x.es, or x.esc without the names of symbols
+func disasmScript(path string) (string, error) {
+	if strings.HasSuffix(path, compiler.SuffixBytecode) {
+		b, err := function.LoadFile(path)
+		if nil != err {
+			return "", function.NewError(err)
+		}
+		bytecode, consts, err := compiler.Unmarshal(b)
+		if nil != err {
+			return "", function.NewError(err)
+		}
+		return compiler.Disassemble(bytecode, consts, nil), nil
+	}
+	b, err := loadCode(path)
+	if nil != err {
+		return "", function.NewError(err)
+	}
+	program, err := LoadAst(function.BytesToString(b))
+	if nil != err {
+		return "", function.NewError(err)
+	}
+	c, err := compileProgram(program)
+	if nil != err {
+		return "", function.NewError(err)
+	}
+	return compiler.Disassemble(c.Bytecode(), c.Constants(), c.Debug()), nil
+}
+
+// evalBytecode : run x.esc on vm
+func evalBytecode(path string) {
+	b, err := function.LoadFile(path)
+	if nil != err {
+		fmt.Println(err.Error())
+		return
+	}
+	bytecode, consts, err := compiler.Unmarshal(b)
+	if nil != err {
+		fmt.Println(err.Error())
+		return
+	}
+	machine := vm.Make(bytecode, consts, vm.NewGlobals())
+	if err := machine.Run(nil); nil != err {
+		fmt.Println(err.Error())
+		return
+	}
+	if val := machine.LastPopped(); nil != val && !object.IsNull(val) {
+		fmt.Print(val.String())
+	}
+}
+
 func dumpAst(path string) (string, error) {
 	b, err := loadCode(path)
 	if nil != err {
@@ -253,14 +412,23 @@ func (this *virtualMachine) Repl(in io.Reader, out io.Writer) {
 }
 
 func (this *virtualMachine) eval(program ast.Node) (object.Object, error) {
-	consts := object.Objects{}
-	globals := vm.NewGlobals()
-	st := compiler.NewSymbolTable(nil)
-	c := compiler.Make(st, consts)
-	if err := c.Compile(program); nil != err {
+	if err := optimizeProgram(program); nil != err {
+		return object.Nil, function.NewError(err)
+	}
+	if register {
+		b, err := regvm.Compile(program)
+		if nil == err {
+			return regvm.New(b).Run(nil)
+		}
+		if !errors.Is(err, regvm.ErrUnsupported) {
+			return object.Nil, function.NewError(err)
+		}
+	}
+	c, err := compileOptimized(program)
+	if nil != err {
 		return object.Nil, function.NewError(err)
 	}
-	machine := vm.Make(c.Bytecode(), c.Constants(), globals)
+	machine := vm.Make(c.Bytecode(), c.Constants(), vm.NewGlobals())
 	if err := machine.Run(nil); nil != err {
 		return object.Nil, function.NewError(err)
 	}
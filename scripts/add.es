@@ -0,0 +1,2 @@
+func add(x, y = 1) { x + y };
+add(2)
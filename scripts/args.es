@@ -0,0 +1,10 @@
+const greet = func(name, greeting = "hello", ...tags) { `${greeting} ${name} ${tags}` };
+println(greet("bob"));
+println(greet("bob", "hi", "vip", "new"));
+const args = ["ann", "hey"];
+println(greet(...args, ...1..2));
+func sum(acc, ...xs) { (xs.len() == 0) ? acc : sum(acc + xs[0], ...xs[1:]) };
+println(sum(0, 1, 2, 3, 4));
+const point = func([x, y] = [0, 0], scale = 1) { [x * scale, y * scale] };
+println(point());
+println(point([1, 2], 10));
@@ -0,0 +1,6 @@
+#!/usr/bin/env escript
+// line comment
+/* block
+   comment */
+const n = 8; // trailing comment
+println(n / 2);
@@ -0,0 +1,8 @@
+const [first, second, ...others] = [1, 2, 3, 4];
+println(`${first} ${second} ${others}`);
+const {name, age: years} = {"name": "bob", "age": 30};
+println(`${name} is ${years}`);
+const dist = func([x1, y1], [x2, y2]) { (x2 - x1) * (x2 - x1) + (y2 - y1) * (y2 - y1) };
+println(dist([0, 0], [3, 4]));
+const users = [{"name": "ann", "roles": ["admin", "dev"]}, {"name": "joe", "roles": ["dev"]}];
+println(map(users, func(i, {name, roles: [main, ...rest]}) { `${name}:${main}+${rest.len()}` }));
@@ -0,0 +1,16 @@
+struct Vec { x, y };
+
+func (v Vec) __add__(o) { Vec(v.x + o.x, v.y + o.y) };
+func (v Vec) __mul__(k) { Vec(v.x * k, v.y * k) };
+func (v Vec) __eq__(o) { (type(o) == "Vec") ? (v.x == o.x && v.y == o.y) : false };
+func (v Vec) __lt__(o) { v.x * v.x + v.y * v.y < o.x * o.x + o.y * o.y };
+func (v Vec) __index__(i) { (i == 0) ? v.x : v.y };
+func (v Vec) __str__() { `(${v.x}, ${v.y})` };
+
+const a = Vec(1, 2);
+const b = Vec(3, 4);
+println(a + b);
+println(a * 3);
+println(a == Vec(1, 2));
+println(a > b);
+println(b[1]);
@@ -0,0 +1,10 @@
+const countries = ["US", "CA", "MX"];
+const order = {"country": "CA", "age": 34, "tags": {"vip": true}};
+println(order["country"] in countries);
+println("debug" not in order["tags"]);
+println(order["age"] in 18..<65);
+println("bc" in "abcd");
+const adults = 18..120;
+println(`${adults.len()} ${adults.first()} ${adults.last()} ${adults[2:5]}`);
+println(map(1..5, func(i, x) { x * x }));
+println(reduce(1..<101, func(acc, x) { acc + x }, 0));
@@ -0,0 +1,19 @@
+const area = func(shape) {
+    match shape {
+        {"type": "circle", "r": r} => 3 * r * r,
+        {"type": "rect", "size": [w, h]} => w * h,
+        {"type": t} => throw(`unknown shape ${t}`),
+    }
+};
+println(area({"type": "rect", "size": [3, 4]}));
+println(area({"type": "circle", "r": 2}));
+const sign = func(n) { match n { 0 => "zero", n if n < 0 => "neg", _ => "pos" } };
+println(map([-3, 0, 7], func(i, x) { sign(x) }));
+const weekday = func(d) {
+    match d {
+        "sat" | "sun" => false,
+        "mon" | "tue" | "wed" | "thu" | "fri" => true,
+        _ => null,
+    }
+};
+println(`${weekday("sun")} ${weekday("wed")} ${weekday("x")}`);
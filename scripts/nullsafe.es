@@ -0,0 +1,5 @@
+const cfg = loads("{\"user\":{\"name\":\"jobs\",\"tags\":[\"a\"]},\"extra\":null}");
+println(cfg?.["user"]?.["name"] ?? "anonymous");
+println(cfg?.["extra"]?.["name"].len() ?? "anonymous");
+println(cfg?.["user"]?.["tags"]?.[3] ?? "none");
+println(cfg?.["missing"]?.len() ?? 0);
@@ -0,0 +1,17 @@
+struct Point { x, y };
+
+func (p Point) dist2() {
+    p.x * p.x + p.y * p.y;
+};
+func (p Point) add(o, scale = 1) {
+    Point(p.x + o.x * scale, p.y + o.y * scale);
+};
+
+const a = Point(3, 4);
+println(a);
+println(a.x);
+println(a.dist2());
+println(a.add(Point(1, 1), 10));
+println(a == Point(3, 4));
+println(type(a));
+println(dumps(a));
@@ -0,0 +1,5 @@
+const name = "escript";
+const h = {"v": 3};
+println(`hello ${name}, v = ${h["v"] * 2}`);
+println(`${[1, 2, 3].len()} items: ${map([1, 2, 3], func(i, x) { `<${x}>` })}`);
+println(sprintf("%05d|%x|%t", 42, 255, true));
@@ -0,0 +1,9 @@
+const h = {"k1": 1};
+const v = try { h["k2"] } catch (e) { -1 };
+println(v);
+
+func check(x) {
+    x > 0 ? x : throw(error(sprintf("invalid value: %v", x)))
+};
+println(try { check(0) } catch (e) { e.message() });
+println(try { "abc".int() } catch { 0 });
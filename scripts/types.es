@@ -0,0 +1,12 @@
+struct Point { x, y };
+
+func (p Point) scale(k: int): Point { Point(p.x * k, p.y * k) };
+
+func add(x: int, y: int = 1): int { x + y };
+
+func count(sep: string, ...items: array<string>): int { items.len() };
+
+const nums: array<int> = [1, 2, 3];
+const p: Point = Point(1, 2).scale(add(nums[0]));
+println(p);
+println(count(", ", "a", "b", "c"));
@@ -12,6 +12,7 @@ const (
 	IDENT
 	INT
 	STRING
+	TEMPLATE
 	//literal_end
 
 	//operator_beg
@@ -30,6 +31,13 @@ const (
 	GEQ       // >=
 	AND       // &&
 	OR        // ||
+	BITAND    // &
+	BITOR     // |
+	BITXOR    // ^
+	BITNOT    // ~
+	SHL       // <<
+	SHR       // >>
+	POW       // **
 	COMMA     // ,
 	PERIOD    // .
 	SEMICOLON // ;
@@ -41,6 +49,13 @@ const (
 	LBRACK    // [
 	RBRACK    // ]
 	QUESTION  // ?
+	NULLISH   // ??
+	QPERIOD   // ?.
+	DOTDOT    // ..
+	DOTDOTLT  // ..<
+	NOTIN     // not in
+	ARROW     // =>
+	ELLIPSIS  // ...
 	//operator_end
 
 	//keyword_beg
@@ -55,41 +70,63 @@ const (
 	FILTER
 	RANGE
 	SYMBOL
+	TRY
+	CATCH
+	IN
+	MATCH
+	IF
+	STRUCT
 	//keyword_end
 )
 
 const (
-	Const  = "const"
-	Func   = "func"
-	Null   = "null"
-	True   = "true"
-	False  = "false"
-	Loop   = "loop"
-	Map    = "map"
-	Reduce = "reduce"
-	Filter = "filter"
-	Range  = "range"
-	Symbol = "$"
+	Const    = "const"
+	Func     = "func"
+	Null     = "null"
+	True     = "true"
+	False    = "false"
+	Loop     = "loop"
+	Map      = "map"
+	Reduce   = "reduce"
+	Filter   = "filter"
+	Range    = "range"
+	Symbol   = "$"
+	Try      = "try"
+	Catch    = "catch"
+	In       = "in"
+	NotIn    = "not in"
+	Match    = "match"
+	If       = "if"
+	Struct   = "struct"
+	Ellipsis = "..."
 )
 
 var (
 	// prefix
-	Not = &Token{NOT, "!"}
-	Neg = &Token{SUB, "-"}
+	Not = &Token{Type: NOT, Literal: "!"}
+	Neg = &Token{Type: SUB, Literal: "-"}
 	// infix
-	Add = &Token{ADD, "+"}
-	Sub = &Token{SUB, "-"}
-	Mul = &Token{MUL, "*"}
-	Div = &Token{DIV, "/"}
-	Mod = &Token{MOD, "%"}
-	Lt  = &Token{LT, "<"}
-	Gt  = &Token{GT, ">"}
-	Eq  = &Token{EQ, "=="}
-	Neq = &Token{NEQ, "!="}
-	Leq = &Token{LEQ, "<="}
-	Geq = &Token{GEQ, ">="}
-	And = &Token{AND, "&&"}
-	Or  = &Token{OR, "||"}
+	Add = &Token{Type: ADD, Literal: "+"}
+	Sub = &Token{Type: SUB, Literal: "-"}
+	Mul = &Token{Type: MUL, Literal: "*"}
+	Div = &Token{Type: DIV, Literal: "/"}
+	Mod = &Token{Type: MOD, Literal: "%"}
+	Lt  = &Token{Type: LT, Literal: "<"}
+	Gt  = &Token{Type: GT, Literal: ">"}
+	Eq  = &Token{Type: EQ, Literal: "=="}
+	Neq = &Token{Type: NEQ, Literal: "!="}
+	Leq = &Token{Type: LEQ, Literal: "<="}
+	Geq = &Token{Type: GEQ, Literal: ">="}
+	And = &Token{Type: AND, Literal: "&&"}
+	Or  = &Token{Type: OR, Literal: "||"}
+	// bitwise
+	BitNot = &Token{Type: BITNOT, Literal: "~"}
+	BitAnd = &Token{Type: BITAND, Literal: "&"}
+	BitOr  = &Token{Type: BITOR, Literal: "|"}
+	BitXor = &Token{Type: BITXOR, Literal: "^"}
+	Shl    = &Token{Type: SHL, Literal: "<<"}
+	Shr    = &Token{Type: SHR, Literal: ">>"}
+	Pow    = &Token{Type: POW, Literal: "**"}
 )
 
 var (
@@ -107,17 +144,30 @@ var (
 		">=": GEQ,
 		"&&": AND,
 		"||": OR,
+		"&":  BITAND,
+		"|":  BITOR,
+		"^":  BITXOR,
+		"<<": SHL,
+		">>": SHR,
+		"**": POW,
 		"(":  LPAREN,
 		"[":  LBRACK,
 		".":  PERIOD,
 		"?":  QUESTION,
 	}
+	prefixTokens = map[string]TokenType{
+		"!": NOT,
+		"-": SUB,
+		"~": BITNOT,
+	}
 	tokenTypes = map[byte]TokenType{
 		'+': ADD,
 		'-': SUB,
 		'*': MUL,
 		'/': DIV,
 		'%': MOD,
+		'^': BITXOR,
+		'~': BITNOT,
 		',': COMMA,
 		'.': PERIOD,
 		';': SEMICOLON,
@@ -142,6 +192,12 @@ var (
 		Filter: FILTER,
 		Range:  RANGE,
 		Symbol: SYMBOL,
+		Try:    TRY,
+		Catch:  CATCH,
+		In:     IN,
+		Match:  MATCH,
+		If:     IF,
+		Struct: STRUCT,
 	}
 
 	tokenTypeStrings = map[TokenType]string{
@@ -149,6 +205,7 @@ var (
 		IDENT:     "IDENT",
 		INT:       "INT",
 		STRING:    "STRING",
+		TEMPLATE:  "TEMPLATE",
 		LT:        "LT",
 		GT:        "GT",
 		ASSIGN:    "ASSIGN",
@@ -164,6 +221,13 @@ var (
 		GEQ:       "GEQ",
 		AND:       "AND",
 		OR:        "OR",
+		BITAND:    "BITAND",
+		BITOR:     "BITOR",
+		BITXOR:    "BITXOR",
+		BITNOT:    "BITNOT",
+		SHL:       "SHL",
+		SHR:       "SHR",
+		POW:       "POW",
 		COMMA:     "COMMA",
 		PERIOD:    "PERIOD",
 		SEMICOLON: "SEMICOLON",
@@ -175,6 +239,13 @@ var (
 		LBRACK:    "LBRACK",
 		RBRACK:    "RBRACK",
 		QUESTION:  "QUESTION",
+		NULLISH:   "NULLISH",
+		QPERIOD:   "QPERIOD",
+		DOTDOT:    "DOTDOT",
+		DOTDOTLT:  "DOTDOTLT",
+		NOTIN:     "NOTIN",
+		ARROW:     "ARROW",
+		ELLIPSIS:  "ELLIPSIS",
 		TRUE:      "TRUE",
 		FALSE:     "FALSE",
 		NULL:      "NULL",
@@ -186,6 +257,12 @@ var (
 		FILTER:    "FILTER",
 		RANGE:     "RANGE",
 		SYMBOL:    "SYMBOL",
+		TRY:       "TRY",
+		CATCH:     "CATCH",
+		IN:        "IN",
+		MATCH:     "MATCH",
+		IF:        "IF",
+		STRUCT:    "STRUCT",
 	}
 )
 
@@ -210,8 +287,18 @@ func LookupIdent(ident string) TokenType {
 }
 
 type Token struct {
-	Type    TokenType
-	Literal string
+	Type     TokenType
+	Literal  string
+	Line     int        // starts from 1, 0 means unknown
+	Col      int        // starts from 1, 0 means unknown
+	Comments []*Comment // leading trivia
+}
+
+// Comment : `/* */`, `//` or the `#!` shebang, with the delimiters
+type Comment struct {
+	Text string
+	Line int
+	Col  int
 }
 
 func (this *Token) TypeIs(t TokenType) bool {
@@ -243,6 +330,14 @@ func GetInfixToken(s string) (*Token, error) {
 	return &Token{Type: tt, Literal: s}, nil
 }
 
+func GetPrefixToken(s string) (*Token, error) {
+	tt, ok := prefixTokens[s]
+	if !ok {
+		return nil, fmt.Errorf("not prefix token: %v", s)
+	}
+	return &Token{Type: tt, Literal: s}, nil
+}
+
 func Bool(v bool) string {
 	if v {
 		return True
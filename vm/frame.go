@@ -7,8 +7,8 @@ import (
 )
 
 func NewCallFrame(b compiler.Bytecode, frameSize int) CallFrame {
-	fn := object.NewByteFn(b.Instructions(), 0)
-	mainFrame := NewFrame(object.NewClosure(fn, nil), 0)
+	fn := object.NewByteFn(b.Instructions(), object.NewArity(0), 0)
+	mainFrame := NewFrame(object.NewClosure(fn, nil, nil), 0, 0)
 	frames := make([]*Frame, frameSize)
 	frames[0] = mainFrame
 	return &callFrame{
@@ -17,22 +17,32 @@ func NewCallFrame(b compiler.Bytecode, frameSize int) CallFrame {
 	}
 }
 
-func NewFrame(fn *object.Closure, basePointer int) *Frame {
+func NewFrame(fn *object.Closure, basePointer int, argc int) *Frame {
 	return &Frame{
-		fn: fn,
-		ip: -1,
-		bp: basePointer,
+		fn:   fn,
+		ip:   -1,
+		bp:   basePointer,
+		argc: argc,
 	}
 }
 
+// handler : entry of the exception-handler table
+type handler struct {
+	catch int // => bytecode of catch block
+	sp    int // => stack when entering try block
+}
+
 type Frame struct {
-	fn *object.Closure
-	ip int // => bytecode
-	bp int // => stack
+	fn       *object.Closure
+	ip       int // => bytecode
+	bp       int // => stack
+	argc     int // args provided by the caller
+	handlers []*handler
 }
 
 func (this *Frame) reset() {
 	this.ip = -1
+	this.handlers = nil
 }
 
 type CallFrame interface {
@@ -45,8 +55,13 @@ type CallFrame interface {
 	incr()
 	incrby(sz int)
 	current() *Frame
-	push(f *Frame)
+	push(f *Frame) error
 	pop() *Frame
+	pushHandler(h *handler)
+	popHandler()
+	unwind(floor int) *handler
+	depth() int
+	restore(depth int)
 }
 
 // callFrame : implement CallFrame
@@ -92,12 +107,52 @@ func (this *callFrame) current() *Frame {
 	return this.frames[this.frameIndex-1]
 }
 
-func (this *callFrame) push(f *Frame) {
+func (this *callFrame) push(f *Frame) error {
+	if this.frameIndex >= len(this.frames) {
+		return errStackOverflow
+	}
 	this.frames[this.frameIndex] = f
 	this.frameIndex++
+	return nil
 }
 
 func (this *callFrame) pop() *Frame {
 	this.frameIndex--
 	return this.frames[this.frameIndex]
 }
+
+func (this *callFrame) pushHandler(h *handler) {
+	f := this.current()
+	f.handlers = append(f.handlers, h)
+}
+
+func (this *callFrame) popHandler() {
+	f := this.current()
+	f.handlers = f.handlers[:len(f.handlers)-1]
+}
+
+// unwind : pop frames above floor until one has a handler, return nil if there is none
+func (this *callFrame) unwind(floor int) *handler {
+	idx := this.frameIndex
+	for idx > floor && len(this.frames[idx-1].handlers) < 1 {
+		idx--
+	}
+	if idx <= floor {
+		return nil
+	}
+	this.frameIndex = idx
+	f := this.current()
+	h := f.handlers[len(f.handlers)-1]
+	f.handlers = f.handlers[:len(f.handlers)-1]
+	return h
+}
+
+// depth : number of frames
+func (this *callFrame) depth() int {
+	return this.frameIndex
+}
+
+// restore : drop the frames above depth
+func (this *callFrame) restore(depth int) {
+	this.frameIndex = depth
+}
@@ -13,13 +13,14 @@ import (
 
 const (
 	StackSize   = 2048
-	GlobalsSize = 65536
+	GlobalsSize = compiler.MaxGlobals
 	MaxFrames   = 1024
 )
 
 var (
 	errStackOverflow = errors.New("stack overflow")
 	errNotCallable   = errors.New("not callable")
+	errUndefined     = errors.New("undefined value")
 )
 
 func NewGlobals() object.Objects {
@@ -61,33 +62,60 @@ type virtualMachine struct {
 	ip        int
 	ins       code.Instructions
 	symbols   object.Symbols
+	caught    object.Object // error caught by the latest catch block
+	caches    []object.InlineCache
+	wide      int // operands left of the instruction after OpWide
 }
 
-func (this *virtualMachine) decodeUint16() uint16 {
-	return code.DecodeUint16(this.ins[this.ip+1:])
-}
-
-func (this *virtualMachine) decodeUint8() uint8 {
-	return code.DecodeUint8(this.ins[this.ip+1:])
+// fetch1 : next operand of 1 byte, 2 bytes after OpWide
+func (this *virtualMachine) fetch1() int {
+	if this.wide > 0 {
+		return this.fetchWide(2)
+	}
+	v := int(code.DecodeUint8(this.ins[this.ip+1:]))
+	this.ip++
+	this.frames.incr()
+	return v
 }
 
-func (this *virtualMachine) fetchUint16() uint16 {
-	v := this.decodeUint16()
+// fetch2 : next operand of 2 bytes, 4 bytes after OpWide
+func (this *virtualMachine) fetch2() int {
+	if this.wide > 0 {
+		return this.fetchWide(4)
+	}
+	v := int(code.DecodeUint16(this.ins[this.ip+1:]))
+	this.ip += 2
 	this.frames.incrby(2)
 	return v
 }
 
-func (this *virtualMachine) fetchUint8() uint8 {
-	v := this.decodeUint8()
-	this.frames.incr()
+func (this *virtualMachine) fetchWide(width int) int {
+	this.wide--
+	v := 0
+	if 4 == width {
+		v = int(code.DecodeUint32(this.ins[this.ip+1:]))
+	} else {
+		v = int(code.DecodeUint16(this.ins[this.ip+1:]))
+	}
+	this.ip += width
+	this.frames.incrby(width)
 	return v
 }
 
-func (this *virtualMachine) fetchClosure() (uint16, int) {
-	idx := this.fetchUint16()
-	frees := code.DecodeUint8(this.ins[this.ip+3:])
+// doWide : exec the instruction after OpWide, whose operands are fetched twice as wide,
+// every instruction fetches its operands before it calls into anything else
+func (this *virtualMachine) doWide() error {
 	this.frames.incr()
-	return idx, int(frees)
+	this.ip = this.frames.ip()
+	op := code.Opcode(this.ins[this.ip])
+	def, err := code.LookupWide(op)
+	if nil != err {
+		return err
+	}
+	this.wide = len(def.OperandWidths)
+	err = this.exec(op)
+	this.wide = 0
+	return err
 }
 
 func (this *virtualMachine) Run(s object.Symbols) error {
@@ -98,208 +126,473 @@ func (this *virtualMachine) Run(s object.Symbols) error {
 		this.ip = this.frames.ip()
 		this.ins = this.frames.instructions()
 		op := code.Opcode(this.ins[this.ip])
-		switch op {
-		case code.OpConst:
-			{
-				idx := this.fetchUint16()
-				err := this.push(this.constants[idx])
-				if nil != err {
-					return err
-				}
-			}
-		case code.OpSymbol:
-			{
-				if err := this.doSymbol(); nil != err {
-					return err
-				}
-			}
-		case code.OpSetGlobal:
-			{
-				idx := this.fetchUint16()
-				this.globals[idx] = this.pop() // bind
-			}
-		case code.OpGetGlobal:
-			{
-				idx := this.fetchUint16()
-				// resolve
-				if err := this.push(this.globals[idx]); nil != err {
-					return err
-				}
-			}
-		case code.OpSetLocal: // pop the stack and fill the hole
-			{
-				localIndex := this.fetchUint8()
-				idx := this.frames.basePointer() + int(localIndex)
-				this.stack[idx] = this.pop()
-			}
-		case code.OpGetLocal:
-			{
-				localIndex := this.fetchUint8()
-				idx := this.frames.basePointer() + int(localIndex)
-				if err := this.push(this.stack[idx]); nil != err {
-					return err
-				}
-			}
-		case code.OpIncLocal:
-			{
-				localIndex := this.fetchUint8()
-				idx := this.frames.basePointer() + int(localIndex)
-				this.stack[idx].Incr()
-			}
-		case code.OpJump:
-			{
-				pos := this.decodeUint16()
-				// in a loop that increments ip with each iteration
-				// we need to set ip to the offset right before the one we want
-				this.frames.jmp(int(pos - 1))
-			}
-		case code.OpJumpWhenFalse:
-			{
-				pos := this.fetchUint16()
-				cond := this.pop()
-				if !cond.True() {
-					this.frames.jmp(int(pos - 1))
-				}
-			}
-		case code.OpArrayLen:
-			{
-				if err := this.doArrayLen(); nil != err {
-					return err
-				}
-			}
-		case code.OpArrayNew:
-			{
-				if err := this.doArrayNew(); nil != err {
-					return err
-				}
-			}
-		case code.OpArrayReserve:
-			{
-				if err := this.doArrayReserve(); nil != err {
-					return err
-				}
-			}
-		case code.OpArrayAppend:
-			{
-				if err := this.doArrayAppend(); nil != err {
-					return err
-				}
-			}
-		case code.OpArraySet:
-			{
-				if err := this.doArraySet(); nil != err {
-					return err
-				}
-			}
-		case code.OpGetBuiltin: // pair with OpCall
-			{
-				if err := this.doGetBuiltin(); nil != err {
-					return err
-				}
-			}
-		case code.OpGetObjectFn: // pair with OpCall
-			{
-				if err := this.doGetObjectFn(); nil != err {
-					return err
-				}
-			}
-		case code.OpGetFree:
-			{
-				if err := this.doGetFree(); nil != err {
-					return err
-				}
-			}
-		case code.OpGetLambda:
-			{
-				if err := this.push(this.frames.current().fn); nil != err {
-					return err
-				}
-			}
-		case code.OpClosure: // pair with OpCall
-			{
-				if err := this.doClosure(); nil != err {
-					return err
-				}
-			}
-		case code.OpCall:
-			{
-				if err := this.doCall(); nil != err {
-					return err
-				}
-			}
-		case code.OpReturn:
-			{
-				if err := this.doReturn(); nil != err {
-					return err
-				}
-			}
-		case code.OpArray:
-			{
-				if err := this.doArray(); nil != err {
-					return err
-				}
-			}
-		case code.OpHash:
-			{
-				if err := this.doHash(); nil != err {
-					return err
-				}
-			}
-		case code.OpPop:
-			{
+		if err := this.exec(op); nil != err {
+			if !this.doRecover(err, 0) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// invoke : call fn from go code (e.g. operator hooks) in the middle of an instruction,
+// the frames pushed by the call are executed here until it returns, the stack and the frames
+// are restored when it fails, since the go code may go on after the error (e.g. equal)
+func (this *virtualMachine) invoke(fn *object.Closure, args object.Objects) (r object.Object, err error) {
+	ip, ins, sp := this.ip, this.ins, this.sp
+	floor := this.frames.depth()
+	defer func() {
+		this.ip, this.ins = ip, ins
+		if nil != err {
+			this.sp = sp
+			this.frames.restore(floor)
+		}
+	}()
+	if err := this.push(fn); nil != err {
+		return nil, err
+	}
+	for _, arg := range args {
+		if err := this.push(arg); nil != err {
+			return nil, err
+		}
+	}
+	if err := this.call(len(args)); nil != err {
+		return nil, err
+	}
+	for this.frames.depth() > floor {
+		this.frames.incr()
+		this.ip = this.frames.ip()
+		this.ins = this.frames.instructions()
+		op := code.Opcode(this.ins[this.ip])
+		if err := this.exec(op); nil != err {
+			// catch blocks out of the call are left to the caller
+			if !this.doRecover(err, floor) {
+				return nil, err
+			}
+		}
+	}
+	return this.pop(), nil
+}
+
+// doRecover : jump to the nearest catch block above floor, unwind frames if necessary
+func (this *virtualMachine) doRecover(err error, floor int) bool {
+	h := this.frames.unwind(floor)
+	if nil == h {
+		return false
+	}
+	this.sp = h.sp
+	this.caught = object.ToError(err)
+	this.frames.jmp(h.catch - 1)
+	return true
+}
+
+func (this *virtualMachine) exec(op code.Opcode) error {
+	switch op {
+	case code.OpConst:
+		{
+			idx := this.fetch2()
+			err := this.push(this.constants[idx])
+			if nil != err {
+				return err
+			}
+		}
+	case code.OpSymbol:
+		{
+			if err := this.doSymbol(); nil != err {
+				return err
+			}
+		}
+	case code.OpSetGlobal:
+		{
+			idx := this.fetch2()
+			this.globals[idx] = this.pop() // bind
+		}
+	case code.OpGetGlobal:
+		{
+			idx := this.fetch2()
+			// resolve
+			if err := this.push(this.globals[idx]); nil != err {
+				return err
+			}
+		}
+	case code.OpSetLocal: // pop the stack and fill the hole
+		{
+			localIndex := this.fetch1()
+			idx := this.frames.basePointer() + localIndex
+			this.stack[idx] = this.pop()
+		}
+	case code.OpGetLocal:
+		{
+			localIndex := this.fetch1()
+			idx := this.frames.basePointer() + localIndex
+			if err := this.push(this.stack[idx]); nil != err {
+				return err
+			}
+		}
+	case code.OpIncLocal:
+		{
+			localIndex := this.fetch1()
+			idx := this.frames.basePointer() + localIndex
+			// the counter may be shared, the slot is rebound to a new integer instead of modifying it
+			var r object.Object
+			var err error
+			switch i := this.stack[idx].(type) {
+			case *object.Integer:
+				r, err = i.Succ()
+			case *object.BigInt:
+				r, err = i.Succ()
+			default:
+				return fmt.Errorf("OpIncLocal: local %v is %v, not an integer", localIndex, object.Typeof(this.stack[idx]))
+			}
+			if nil != err {
+				return err
+			}
+			this.stack[idx] = r
+		}
+	case code.OpJump:
+		{
+			pos := this.fetch2()
+			// in a loop that increments ip with each iteration
+			// we need to set ip to the offset right before the one we want
+			this.frames.jmp(pos - 1)
+		}
+	case code.OpJumpWhenFalse:
+		{
+			pos := this.fetch2()
+			cond := this.pop()
+			if !cond.True() {
+				this.frames.jmp(pos - 1)
+			}
+		}
+	case code.OpJumpWhenNull: // keep the null as the result
+		{
+			pos := this.fetch2()
+			if object.IsNull(this.top()) {
+				this.frames.jmp(pos - 1)
+			}
+		}
+	case code.OpJumpWhenNotNull: // keep the non-null as the result
+		{
+			pos := this.fetch2()
+			if !object.IsNull(this.top()) {
+				this.frames.jmp(pos - 1)
+			} else {
 				this.pop()
 			}
-		case code.OpTrue:
-			{
-				if err := this.push(object.True); nil != err {
-					return err
-				}
-			}
-		case code.OpFalse:
-			{
-				if err := this.push(object.False); nil != err {
-					return err
-				}
-			}
-		case code.OpNull:
-			{
-				if err := this.push(object.Nil); nil != err {
-					return err
-				}
-			}
-		case code.OpNot:
-			{
-				if err := this.doPrefix(object.FnNot); nil != err {
-					return err
-				}
-			}
-		case code.OpNeg:
-			{
-				if err := this.doPrefix(object.FnNeg); nil != err {
-					return err
-				}
-			}
-		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod,
-			code.OpLt, code.OpGt, code.OpEq, code.OpNeq, code.OpLeq, code.OpGeq,
-			code.OpAnd, code.OpOr:
-			{
-				if err := this.doInfix(op); nil != err {
-					return err
-				}
-			}
-		case code.OpIndex:
-			{
-				if err := this.doIndex(); nil != err {
-					return err
-				}
+		}
+	case code.OpArrayLen:
+		{
+			if err := this.doArrayLen(); nil != err {
+				return err
+			}
+		}
+	case code.OpArrayNew:
+		{
+			if err := this.doArrayNew(); nil != err {
+				return err
+			}
+		}
+	case code.OpArrayReserve:
+		{
+			if err := this.doArrayReserve(); nil != err {
+				return err
+			}
+		}
+	case code.OpArrayAppend:
+		{
+			if err := this.doArrayAppend(); nil != err {
+				return err
+			}
+		}
+	case code.OpArraySet:
+		{
+			if err := this.doArraySet(); nil != err {
+				return err
+			}
+		}
+	case code.OpGetBuiltin: // pair with OpCall
+		{
+			if err := this.doGetBuiltin(); nil != err {
+				return err
+			}
+		}
+	case code.OpGetObjectFn: // pair with OpCall
+		{
+			if err := this.doGetObjectFn(); nil != err {
+				return err
+			}
+		}
+	case code.OpGetFree:
+		{
+			if err := this.doGetFree(); nil != err {
+				return err
+			}
+		}
+	case code.OpGetLambda:
+		{
+			this.fetch1() // the closure being run, refer to compiler.ScopeLambda
+			if err := this.push(this.frames.current().fn); nil != err {
+				return err
+			}
+		}
+	case code.OpClosure: // pair with OpCall
+		{
+			if err := this.doClosure(); nil != err {
+				return err
+			}
+		}
+	case code.OpCall:
+		{
+			if err := this.doCall(); nil != err {
+				return err
 			}
 		}
+	case code.OpCallSpread:
+		{
+			if err := this.doCallSpread(); nil != err {
+				return err
+			}
+		}
+	case code.OpTailCall:
+		{
+			if err := this.tailCall(this.fetch1()); nil != err {
+				return err
+			}
+		}
+	case code.OpTailCallSpread:
+		{
+			argc, err := this.spread(this.fetch1())
+			if nil != err {
+				return err
+			}
+			if err := this.tailCall(argc); nil != err {
+				return err
+			}
+		}
+	case code.OpGetMember:
+		{
+			if err := this.doGetMember(); nil != err {
+				return err
+			}
+		}
+	case code.OpMethod:
+		{
+			if err := this.doMethod(); nil != err {
+				return err
+			}
+		}
+	case code.OpArgMissing:
+		{
+			i := this.fetch1()
+			if err := this.push(object.ToBoolean(this.frames.current().argc <= i)); nil != err {
+				return err
+			}
+		}
+	case code.OpReturn:
+		{
+			if err := this.doReturn(); nil != err {
+				return err
+			}
+		}
+	case code.OpArray:
+		{
+			if err := this.doArray(); nil != err {
+				return err
+			}
+		}
+	case code.OpConcat:
+		{
+			if err := this.doConcat(); nil != err {
+				return err
+			}
+		}
+	case code.OpHash:
+		{
+			if err := this.doHash(); nil != err {
+				return err
+			}
+		}
+	case code.OpPop:
+		{
+			this.pop()
+		}
+	case code.OpTrue:
+		{
+			if err := this.push(object.True); nil != err {
+				return err
+			}
+		}
+	case code.OpFalse:
+		{
+			if err := this.push(object.False); nil != err {
+				return err
+			}
+		}
+	case code.OpNull:
+		{
+			if err := this.push(object.Nil); nil != err {
+				return err
+			}
+		}
+	case code.OpNot:
+		{
+			if err := this.doPrefix(object.FnNot); nil != err {
+				return err
+			}
+		}
+	case code.OpNeg:
+		{
+			if err := this.doPrefix(object.FnNeg); nil != err {
+				return err
+			}
+		}
+	case code.OpBitNot:
+		{
+			if err := this.doPrefix(object.FnBitNot); nil != err {
+				return err
+			}
+		}
+	case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod,
+		code.OpLt, code.OpGt, code.OpEq, code.OpNeq, code.OpLeq, code.OpGeq,
+		code.OpAnd, code.OpOr,
+		code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShl, code.OpShr, code.OpPow:
+		{
+			if err := this.doInfix(op); nil != err {
+				return err
+			}
+		}
+	case code.OpAddConst:
+		{
+			if err := this.doInfixConst(code.OpAdd); nil != err {
+				return err
+			}
+		}
+	case code.OpSubConst:
+		{
+			if err := this.doInfixConst(code.OpSub); nil != err {
+				return err
+			}
+		}
+	case code.OpGetLocal2:
+		{
+			if err := this.doGetLocal2(); nil != err {
+				return err
+			}
+		}
+	case code.OpCmpJump:
+		{
+			if err := this.doCmpJump(); nil != err {
+				return err
+			}
+		}
+	case code.OpCallBuiltin:
+		{
+			if err := this.doCallBuiltin(); nil != err {
+				return err
+			}
+		}
+	case code.OpCallObjectFn:
+		{
+			if err := this.doCallObjectFn(); nil != err {
+				return err
+			}
+		}
+	case code.OpIndex:
+		{
+			if err := this.doIndex(); nil != err {
+				return err
+			}
+		}
+	case code.OpIn, code.OpNotIn:
+		{
+			if err := this.doIn(op == code.OpNotIn); nil != err {
+				return err
+			}
+		}
+	case code.OpInterval:
+		{
+			if err := this.doInterval(); nil != err {
+				return err
+			}
+		}
+	case code.OpMatchValue:
+		{
+			lit := this.pop()
+			v := this.pop()
+			if err := this.push(object.ToBoolean(object.Equals(lit, v))); nil != err {
+				return err
+			}
+		}
+	case code.OpMatchArray:
+		{
+			sz := this.fetch2()
+			rest := this.fetch1()
+			v := this.pop()
+			if err := this.push(object.ToBoolean(object.IsArrayOf(v, sz, 1 == rest))); nil != err {
+				return err
+			}
+		}
+	case code.OpMatchHash:
+		{
+			v := this.pop()
+			if err := this.push(object.ToBoolean(object.IsHash(v))); nil != err {
+				return err
+			}
+		}
+	case code.OpMatchTable:
+		{
+			this.doMatchTable()
+		}
+	case code.OpNoMatch:
+		{
+			return object.NoMatch(this.pop())
+		}
+	case code.OpDestructFail:
+		{
+			idx := this.fetch2()
+			pattern := this.constants[idx].(*object.String)
+			return object.CannotDestruct(pattern.Value, this.pop())
+		}
+	case code.OpIndexOptional:
+		{
+			if err := this.doIndexOptional(); nil != err {
+				return err
+			}
+		}
+	case code.OpSlice:
+		{
+			if err := this.doSlice(); nil != err {
+				return err
+			}
+		}
+	case code.OpWide:
+		{
+			if err := this.doWide(); nil != err {
+				return err
+			}
+		}
+	case code.OpTry:
+		{
+			pos := this.fetch2()
+			this.frames.pushHandler(&handler{catch: pos, sp: this.sp})
+		}
+	case code.OpEndTry:
+		{
+			this.frames.popHandler()
+		}
+	case code.OpCatch:
+		{
+			if err := this.push(this.caught); nil != err {
+				return err
+			}
+			this.caught = nil
+		}
 	}
 	return nil
 }
 
 func (this *virtualMachine) doSymbol() error {
-	idx := this.fetchUint16()
+	idx := this.fetch2()
 	key := this.constants[idx].String()
 	cb, ok := this.symbols[key]
 	if !ok {
@@ -315,27 +608,36 @@ func (this *virtualMachine) doSymbol() error {
 	return nil
 }
 
+// doArrayLen : an interval is not materialized, refer to object.Iterate
 func (this *virtualMachine) doArrayLen() error {
-	arr, err := this.pop().AsArray()
+	sz, err := object.ItemsLen(this.pop())
 	if nil != err {
 		return err
 	}
-	if err := this.push(object.NewInteger(int64(len(arr.Items)))); nil != err {
+	if err := this.push(object.NewInteger(sz)); nil != err {
 		return err
 	}
 	return nil
 }
 
 func (this *virtualMachine) doArrayNew() error {
-	flag := this.fetchUint8()
-	arr, err := this.pop().AsArray()
-	if nil != err {
+	flag := this.fetch1()
+	v := this.pop()
+	if err := this.push(v); nil != err {
 		return err
 	}
-	if err := this.push(arr); nil != err {
+	if arr, ok := v.(*object.Array); ok {
+		return this.push(arr.New(uint8(flag)))
+	}
+	// an interval, the items are read by index
+	sz, err := object.MapLen(v)
+	if nil != err {
 		return err
 	}
-	if err := this.push(arr.New(flag)); nil != err {
+	if 0 == flag {
+		sz = 0
+	}
+	if err := this.push(object.NewArray(make(object.Objects, sz))); nil != err {
 		return err
 	}
 	return nil
@@ -347,6 +649,9 @@ func (this *virtualMachine) doArrayReserve() error {
 	if nil != err {
 		return err
 	}
+	if cnt < 0 {
+		return function.NewError(fmt.Errorf("range with negative count %v", cnt))
+	}
 	if err := this.push(v); nil != err {
 		return err
 	}
@@ -358,8 +663,8 @@ func (this *virtualMachine) doArrayReserve() error {
 }
 
 func (this *virtualMachine) doArrayAppend() error {
-	localIndex := this.fetchUint8()
-	idx := this.frames.basePointer() + int(localIndex)
+	localIndex := this.fetch1()
+	idx := this.frames.basePointer() + localIndex
 	arr, err := this.stack[idx].AsArray()
 	if nil != err {
 		return err
@@ -373,8 +678,9 @@ func (this *virtualMachine) doArrayAppend() error {
 }
 
 func (this *virtualMachine) doArraySet() error {
-	localIndex := this.fetchUint8()
-	idx := this.frames.basePointer() + int(localIndex)
+	localIndex := this.fetch1()
+	this.fetch1() // not used, refer to compiler.DoMap
+	idx := this.frames.basePointer() + localIndex
 	arr, err := this.stack[idx].AsArray()
 	if nil != err {
 		return err
@@ -388,8 +694,8 @@ func (this *virtualMachine) doArraySet() error {
 }
 
 func (this *virtualMachine) doGetBuiltin() error {
-	idx := this.fetchUint8()
-	builtinFn := builtin.Resolve(int(idx))
+	idx := this.fetch1()
+	builtinFn := builtin.Resolve(idx)
 	// object.Builtin
 	if err := this.push(builtinFn); nil != err {
 		return err
@@ -398,9 +704,9 @@ func (this *virtualMachine) doGetBuiltin() error {
 }
 
 func (this *virtualMachine) doGetObjectFn() error {
-	idx := this.fetchUint8()
+	idx := this.fetch1()
 	obj := this.pop()
-	fn := object.Resolve(int(idx))
+	fn := object.Resolve(idx)
 	r, err := obj.GetMember(fn)
 	if nil != err {
 		return err
@@ -413,7 +719,7 @@ func (this *virtualMachine) doGetObjectFn() error {
 }
 
 func (this *virtualMachine) doGetFree() error {
-	idx := this.fetchUint8()
+	idx := this.fetch1()
 	fn := this.frames.current().fn
 	if err := this.push(fn.Free[idx]); nil != err {
 		return err
@@ -423,9 +729,8 @@ func (this *virtualMachine) doGetFree() error {
 
 func (this *virtualMachine) doClosure() error {
 	// exec after a lot OpGetFree, refer to visitor.DoFn
-	// idx := this.fetchUint16()
-	// frees := int(this.fetchUint8(ip+2, ins))
-	idx, frees := this.fetchClosure()
+	idx := this.fetch2()
+	frees := this.fetch1()
 	fn, err := this.constants[idx].AsByteFunc()
 	if nil != err {
 		return err
@@ -437,23 +742,59 @@ func (this *virtualMachine) doClosure() error {
 	}
 	// clean up the stack
 	this.sp = this.sp - frees
-	if err := this.push(object.NewClosure(fn, freeSymbols)); nil != err {
+	if err := this.push(object.NewClosure(fn, freeSymbols, this.invoke)); nil != err {
 		return err
 	}
 	return nil
 }
 
 func (this *virtualMachine) doCall() error {
-	args := this.fetchUint8()
-	obj := this.stack[this.sp-1-int(args)]
+	return this.call(this.fetch1())
+}
+
+func (this *virtualMachine) doCallSpread() error {
+	argc, err := this.spread(this.fetch1())
+	if nil != err {
+		return err
+	}
+	return this.call(argc)
+}
+
+// spread : every arg is pushed as an array, flatten them and return the number of args
+func (this *virtualMachine) spread(n int) (int, error) {
+	segments := make(object.Objects, n)
+	copy(segments, this.stack[this.sp-n:this.sp])
+	this.sp = this.sp - n
+	argc := 0
+	for _, seg := range segments {
+		arr, err := seg.AsArray()
+		if nil != err {
+			return 0, object.CannotSpread(seg)
+		}
+		for _, item := range arr.Items {
+			if err := this.push(item); nil != err {
+				return 0, err
+			}
+		}
+		argc += len(arr.Items)
+	}
+	return argc, nil
+}
 
-	if object.IsBuiltin(obj) || object.IsObjectFunc(obj) {
-		arguments := this.stack[this.sp-int(args) : this.sp]
+func (this *virtualMachine) call(args int) error {
+	args, err := this.bindMethod(args)
+	if nil != err {
+		return err
+	}
+	obj := this.stack[this.sp-1-args]
+
+	if object.IsBuiltin(obj) || object.IsObjectFunc(obj) || object.IsStructType(obj) {
+		arguments := this.stack[this.sp-args : this.sp]
 		r, err := obj.Call(arguments)
 		if nil != err {
 			return err
 		}
-		this.sp = this.sp - int(args) - 1
+		this.sp = this.sp - args - 1
 		if err := this.push(r); nil != err {
 			return err
 		}
@@ -462,13 +803,17 @@ func (this *virtualMachine) doCall() error {
 
 	if object.IsClosure(obj) {
 		fn, _ := obj.AsClosure()
-		if args != uint8(fn.Fn.Locals) {
-			err := fmt.Errorf("wrong number of arguments: want=%v, got=%v", fn.Fn.Locals, args)
+		if err := checkArgs(fn, args); nil != err {
 			return err
 		}
-		frame := NewFrame(fn, this.sp-int(args))
+		frame := NewFrame(fn, this.sp-args, args)
+		if fn.Fn.Rest {
+			this.packRest(frame.bp, fn.Fn.Args, args)
+		}
 		// set env
-		this.frames.push(frame)
+		if err := this.frames.push(frame); nil != err {
+			return err
+		}
 		this.sp = frame.bp + fn.Fn.Locals // reserverd for local bindings
 		return nil
 	}
@@ -476,6 +821,66 @@ func (this *virtualMachine) doCall() error {
 	return errNotCallable
 }
 
+// tailCall : a closure reuses the current frame, the callee and its args are moved to
+// the place of the current function on the stack, other objects are called as usual
+func (this *virtualMachine) tailCall(args int) error {
+	args, err := this.bindMethod(args)
+	if nil != err {
+		return err
+	}
+	obj := this.stack[this.sp-1-args]
+	if !object.IsClosure(obj) {
+		return this.call(args)
+	}
+	fn, _ := obj.AsClosure()
+	if err := checkArgs(fn, args); nil != err {
+		return err
+	}
+	frame := this.frames.current()
+	copy(this.stack[frame.bp-1:], this.stack[this.sp-1-args:this.sp])
+	frame.fn = fn
+	frame.argc = args
+	frame.reset()
+	if fn.Fn.Rest {
+		this.packRest(frame.bp, fn.Fn.Args, args)
+	}
+	this.sp = frame.bp + fn.Fn.Locals // reserverd for local bindings
+	return nil
+}
+
+// bindMethod : a method of closure is called as the closure with the receiver inserted before the args
+func (this *virtualMachine) bindMethod(args int) (int, error) {
+	m, err := this.stack[this.sp-1-args].AsMethod()
+	if nil != err || !object.IsClosure(m.Fn) {
+		return args, nil
+	}
+	if err := this.push(object.Nil); nil != err {
+		return 0, err
+	}
+	first := this.sp - 1 - args
+	copy(this.stack[first+1:this.sp], this.stack[first:this.sp-1])
+	this.stack[first] = m.Recv
+	this.stack[first-1] = m.Fn
+	return args + 1, nil
+}
+
+func checkArgs(fn *object.Closure, args int) error {
+	if !fn.Fn.Accept(args) {
+		return fmt.Errorf("wrong number of arguments: want=%v, got=%v", fn.Fn.Arity.String(), args)
+	}
+	return nil
+}
+
+// packRest : collect the args after the named ones into an array, which is the rest arg
+func (this *virtualMachine) packRest(bp int, named int, argc int) {
+	rest := object.Objects{}
+	if argc > named {
+		rest = make(object.Objects, argc-named)
+		copy(rest, this.stack[bp+named:bp+argc])
+	}
+	this.stack[bp+named] = object.NewArray(rest)
+}
+
 func (this *virtualMachine) doReturn() error {
 	returnValue := this.pop()
 	// recover env
@@ -489,28 +894,24 @@ func (this *virtualMachine) doReturn() error {
 }
 
 func (this *virtualMachine) doHash() error {
-	sz := int(this.fetchUint16())
-	h := object.HashMap{}
-	for i := 0; i < sz; i++ {
-		v := this.pop()
-		k := this.pop()
-
-		pair := object.HashPair{Key: k, Value: v}
-
-		key, err := k.Hash()
-		if nil != err {
+	sz := this.fetch2()
+	h := object.NewOrderedHash()
+	// pairs are pushed in literal order
+	start := this.sp - sz*2
+	for i := start; i < this.sp; i += 2 {
+		if err := h.Set(this.stack[i], this.stack[i+1]); nil != err {
 			return err
 		}
-		h[*key] = &pair
 	}
-	if err := this.push(object.NewHash(h)); nil != err {
+	this.sp = start
+	if err := this.push(h); nil != err {
 		return err
 	}
 	return nil
 }
 
 func (this *virtualMachine) doArray() error {
-	sz := int(this.fetchUint16())
+	sz := this.fetch2()
 	arr := make(object.Objects, sz)
 	for i := 0; i < sz; i++ {
 		arr[sz-i-1] = this.pop()
@@ -522,6 +923,51 @@ func (this *virtualMachine) doArray() error {
 	return nil
 }
 
+func (this *virtualMachine) doConcat() error {
+	sz := this.fetch2()
+	r, err := object.Concat(this.stack[this.sp-sz : this.sp])
+	if nil != err {
+		return err
+	}
+	this.sp -= sz
+	return this.push(r)
+}
+
+func (this *virtualMachine) doIn(not bool) error {
+	right := this.pop()
+	left := this.pop()
+	if r, err := object.Contains(right, left, not); nil != err {
+		return err
+	} else {
+		return this.push(r)
+	}
+}
+
+func (this *virtualMachine) doInterval() error {
+	exclusive := this.fetch1()
+	end := this.pop()
+	start := this.pop()
+	if r, err := object.NewInterval(start, end, exclusive == 1); nil != err {
+		return err
+	} else {
+		return this.push(r)
+	}
+}
+
+// doMatchTable : pop the value and jump to its arm if found, otherwise keep it and jump to the default
+func (this *virtualMachine) doMatchTable() {
+	idx := this.fetch2()
+	posDefault := this.fetch2()
+	table := this.constants[idx].(*object.Hash)
+	if target, ok := table.Get(this.top()); ok {
+		this.pop()
+		pos, _ := object.ToInteger(target)
+		this.frames.jmp(int(pos - 1))
+		return
+	}
+	this.frames.jmp(posDefault - 1)
+}
+
 func (this *virtualMachine) doPrefix(fn string) error {
 	right := this.pop()
 	if r, err := right.CallMember(fn, object.Objects{}); nil != err {
@@ -542,6 +988,48 @@ func (this *virtualMachine) doIndex() error {
 	}
 }
 
+func (this *virtualMachine) doIndexOptional() error {
+	idx := this.pop()
+	left := this.pop()
+	if r, err := object.OptionalIndex(left, idx); nil != err {
+		return err
+	} else {
+		return this.push(r)
+	}
+}
+
+func (this *virtualMachine) doGetMember() error {
+	idx := this.fetch2()
+	name := this.constants[idx].String()
+	left := this.pop()
+	r, err := left.GetMember(name)
+	if nil != err {
+		return err
+	}
+	return this.push(r)
+}
+
+func (this *virtualMachine) doMethod() error {
+	idx := this.fetch2()
+	name := this.constants[idx].String()
+	fn := this.pop()
+	t := this.pop()
+	return object.DefineMethod(t, name, fn)
+}
+
+func (this *virtualMachine) doSlice() error {
+	step := this.pop()
+	end := this.pop()
+	start := this.pop()
+	left := this.pop()
+	if r, err := left.CallMember(object.FnSlice, object.Objects{start, end, step}); nil != err {
+		return err
+	} else {
+		this.push(r)
+		return nil
+	}
+}
+
 func (this *virtualMachine) doInfix(op code.Opcode) error {
 	t, err := code.InfixToken(op)
 	if nil != err {
@@ -557,6 +1045,102 @@ func (this *virtualMachine) doInfix(op code.Opcode) error {
 	return nil
 }
 
+// doInfixConst : the right operand is the constant, refer to compiler.Peephole
+func (this *virtualMachine) doInfixConst(op code.Opcode) error {
+	idx := this.fetch2()
+	t, err := code.InfixToken(op)
+	if nil != err {
+		return err
+	}
+	left := this.pop()
+	r, err := left.Calc(t, this.constants[idx])
+	if nil != err {
+		return err
+	}
+	this.push(r)
+	return nil
+}
+
+func (this *virtualMachine) doGetLocal2() error {
+	bp := this.frames.basePointer()
+	a := code.DecodeUint8(this.ins[this.ip+1:])
+	b := code.DecodeUint8(this.ins[this.ip+2:])
+	this.frames.incrby(2)
+	if err := this.push(this.stack[bp+int(a)]); nil != err {
+		return err
+	}
+	return this.push(this.stack[bp+int(b)])
+}
+
+// doCmpJump : compare the operands and jump when the result is false
+func (this *virtualMachine) doCmpJump() error {
+	op := code.Opcode(code.DecodeUint8(this.ins[this.ip+1:]))
+	pos := code.DecodeUint16(this.ins[this.ip+2:])
+	this.frames.incrby(3)
+	t, err := code.InfixToken(op)
+	if nil != err {
+		return err
+	}
+	right := this.pop()
+	left := this.pop()
+	r, err := left.Calc(t, right)
+	if nil != err {
+		return err
+	}
+	if !r.True() {
+		this.frames.jmp(int(pos - 1))
+	}
+	return nil
+}
+
+// doCallBuiltin : the args are on the stack, the builtin is not
+func (this *virtualMachine) doCallBuiltin() error {
+	idx := code.DecodeUint8(this.ins[this.ip+1:])
+	argc := int(code.DecodeUint8(this.ins[this.ip+2:]))
+	this.frames.incrby(2)
+	r, err := builtin.Resolve(int(idx)).Call(this.stack[this.sp-argc : this.sp])
+	if nil != err {
+		return err
+	}
+	this.sp = this.sp - argc
+	return this.push(r)
+}
+
+// doCallObjectFn : the receiver and the args are on the stack, a builtin method is called through
+// the inline cache of the instruction, other members (e.g. fields of struct) are got and called as OpCall
+func (this *virtualMachine) doCallObjectFn() error {
+	idx := code.DecodeUint8(this.ins[this.ip+1:])
+	argc := int(code.DecodeUint8(this.ins[this.ip+2:]))
+	slot := int(code.DecodeUint16(this.ins[this.ip+3:]))
+	this.frames.incrby(4)
+	name := object.Resolve(int(idx))
+	recv := this.stack[this.sp-1-argc]
+	r, ok, err := this.inlineCache(slot).Call(recv, name, this.stack[this.sp-argc:this.sp])
+	if nil != err {
+		return err
+	}
+	if !ok {
+		fn, err := recv.GetMember(name)
+		if nil != err {
+			return err
+		}
+		this.stack[this.sp-1-argc] = fn
+		return this.call(argc)
+	}
+	this.sp = this.sp - argc - 1
+	return this.push(r)
+}
+
+// inlineCache : the caches are allocated on demand, refer to compiler.MaxInlineCaches
+func (this *virtualMachine) inlineCache(slot int) *object.InlineCache {
+	if slot >= len(this.caches) {
+		caches := make([]object.InlineCache, slot+1)
+		copy(caches, this.caches)
+		this.caches = caches
+	}
+	return &this.caches[slot]
+}
+
 func (this *virtualMachine) StackTop() object.Object {
 	if this.sp == 0 {
 		return nil
@@ -572,6 +1156,10 @@ func (this *virtualMachine) push(o object.Object) error {
 	if this.sp >= StackSize {
 		return function.NewError(errStackOverflow)
 	}
+	if nil == o {
+		// a global or local read before it is set, which only a malformed bytecode does
+		return function.NewError(errUndefined)
+	}
 
 	this.stack[this.sp] = o
 	this.sp++
@@ -2,6 +2,8 @@ package vm
 
 import (
 	"fmt"
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/jobs-github/escript/ast"
@@ -168,6 +170,20 @@ func TestIntegerArithmetic(t *testing.T) {
 		{"case_14", "-10", -10},
 		{"case_15", "-50 + 100 + -50", 0},
 		{"case_16", "(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"case_17", "12 & 10", 8},
+		{"case_18", "12 | 10", 14},
+		{"case_19", "12 ^ 10", 6},
+		{"case_20", "~0", -1},
+		{"case_21", "3 << 4", 48},
+		{"case_22", "48 >> 4", 3},
+		{"case_23", "3 ** 4", 81},
+		{"case_24", "2 ** 3 ** 2", 512},
+		{"case_25", "-2 ** 2", -4},
+		{"case_26", "1 + 2 << 1", 6},
+		{"case_27", "7 ** 0", 1},
+		{"case_28", "(9223372036854775807 + 1) - 1", 9223372036854775807},
+		{"case_29", "(2 ** 63) / 2", 4611686018427387904},
+		{"case_30", "-9223372036854775808 / -1 / 2", 4611686018427387904},
 	}
 	runVmTests(t, tests)
 }
@@ -196,6 +212,9 @@ func TestBooleanExpr(t *testing.T) {
 		{"case_20", "!!true", true},
 		{"case_21", "!!false", false},
 		{"case_22", "!!5", true},
+		{"case_23", "2 in [1, 2]", true},
+		{"case_24", "3 not in [1, 2]", true},
+		{"case_25", "10 in 1..<10", false},
 	}
 	runVmTests(t, tests)
 }
@@ -211,6 +230,29 @@ func TestConditional(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestMatch(t *testing.T) {
+	tests := []vmTestCase{
+		{"case_1", `match 2 { 1 => 10, 2 | 3 => 20, _ => 30 }`, 20},
+		{"case_2", `match 4 { 1 => 10, 2 | 3 => 20, _ => 30 }`, 30},
+		{"case_3", `match [1, 2] { [x, y] if x > y => x, [x, y] => y }`, 2},
+		{"case_4", `match {"a": [3]} { {"a": [n]} => n }`, 3},
+		{"case_5", `const f = func(v) { match v { [x] => func() { x } } }; f([7])()`, 7},
+		{"case_6", `const x = 1; match 5 { x => x }; x`, 1},
+	}
+	runVmTests(t, tests)
+}
+
+func TestStruct(t *testing.T) {
+	tests := []vmTestCase{
+		{"case_1", `struct P { x, y }; P(1, 2).y`, 2},
+		{"case_2", `struct P { x }; func (p P) f(a, b) { p.x * 100 + a * 10 + b }; P(1).f(2, 3)`, 123},
+		{"case_3", `struct P { x }; func (p P) f(...a) { p.x + a.len() }; P(1).f(2, 3)`, 3},
+		{"case_4", `struct P { x }; func (p P) f() { p.x }; const g = func(q) { q.f() }; g(P(7))`, 7},
+		{"case_5", `struct P { x }; func (p P) f() { p.x }; const g = func(q) { q.f }; g(P(8))()`, 8},
+	}
+	runVmTests(t, tests)
+}
+
 func TestGlobalConstStmt(t *testing.T) {
 	tests := []vmTestCase{
 		{"case_1", "const one = 1; one;", 1},
@@ -248,6 +290,9 @@ func TestHash(t *testing.T) {
 		{"case_1", "{}", testHashType{}},
 		{"case_2", "{1:2,2:3}", testHashType{*h1: 2, *h2: 3}},
 		{"case_3", "{1 + 1 : 2 * 2, 3 + 3 : 4 * 4}", testHashType{*h2: 4, *h6: 16}},
+		{"case_4", `str({"z": 1, "a": 2, "m": 3})`, `{z: 1, a: 2, m: 3}`},
+		{"case_5", `str({"z": 1, "a": 2, "m": 3}.keys())`, `[z, a, m]`},
+		{"case_6", `dumps({"z": 1, "a": {"y": 2, "b": 3}})`, `{"z":1,"a":{"y":2,"b":3}}`},
 	}
 	runVmTests(t, tests)
 }
@@ -258,6 +303,16 @@ func TestIndexExpr(t *testing.T) {
 		{"case_2", "[1,2,3][0 + 2]", 3},
 		{"case_3", "[[1,1,1]][0][0]", 1},
 		{"case_4", "{1:1, 2:2}[1]", 1},
+		{"case_slice_1", "[1,2,3,4,5][1:3]", []int{2, 3}},
+		{"case_slice_2", "[1,2,3,4,5][:-2]", []int{1, 2, 3}},
+		{"case_slice_3", "[1,2,3,4,5][::2]", []int{1, 3, 5}},
+		{"case_slice_4", "[1,2,3,4,5][::-1]", []int{5, 4, 3, 2, 1}},
+		{"case_slice_5", "[1,2,3,4,5][-1:0:-2]", []int{5, 3}},
+		{"case_slice_6", "[1,2,3][10:]", []int{}},
+		{"case_slice_7", `"hello"[1:4]`, "ell"},
+		{"case_slice_8", `"hello"[::-1]`, "olleh"},
+		{"case_neg_1", "[1,2,3][-1]", 3},
+		{"case_neg_2", `"hello"[-2]`, "l"},
 		{"case_5", "{1:1, 2:2}[2]", 2},
 	}
 	runVmTests(t, tests)
@@ -317,6 +372,22 @@ func TestCall(t *testing.T) {
 			`,
 			15,
 		},
+		{
+			"case_7",
+			`
+			func add(x, y = 5) { x + y };
+			add(10);
+			`,
+			15,
+		},
+		{
+			"case_8",
+			`
+			func add(x, ...ys) { reduce(ys, func(acc, y) { acc + y }, x) };
+			add(...[1, 2], 3, ...4..5);
+			`,
+			15,
+		},
 	}
 	runVmTests(t, tests)
 }
@@ -415,3 +486,92 @@ func TestFib(t *testing.T) {
 	}
 	runVmTests(t, tests)
 }
+
+// TestRerun : the loop counters start from the integers in the constant pool, which must stay intact
+// when the same compiled program runs again
+func TestRerun(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`reduce(range(2000, func(i) { i }), func(acc, x) { acc + x }, 0)`, "1999000"},
+		{`const a = range(300, func(i) { i }); reduce(map(a, func(i, x) { x * 2 }), func(acc, x) { acc + x }, 0)`, "89700"},
+		{`reduce(range(50, func(i) { reduce(range(i, func(j) { j }), func(acc, x) { acc + x }, 0) }), func(acc, x) { acc + x }, 0)`, "19600"},
+		{`filter(range(1000, func(i) { i }), func(i, x) { x % 7 == 0 }).len()`, "143"},
+		{`const n = 0; loop(100, func(i) { i + n }); range(3, func(i) { i + n })`, "[0, 1, 2]"},
+	}
+	for _, peephole := range []bool{false, true} {
+		for i, tt := range tests {
+			c := compiler.New()
+			if err := c.Compile(parse(t, tt.input)); nil != err {
+				t.Fatal(err)
+			}
+			if peephole {
+				if err := c.Peephole(); nil != err {
+					t.Fatal(err)
+				}
+			}
+			consts := c.Constants()
+			before := fmt.Sprint(consts)
+			for round := 0; round < 5; round++ {
+				vm := New(c.Bytecode(), consts)
+				if err := vm.Run(nil); nil != err {
+					t.Fatal(err)
+				}
+				if r := vm.LastPopped().String(); tt.want != r {
+					t.Fatalf("i: %v, peephole: %v, round: %v, want: %v, got: %v", i, peephole, round, tt.want, r)
+				}
+			}
+			if after := fmt.Sprint(consts); before != after {
+				t.Fatalf("i: %v, peephole: %v, constants modified, before: %v, after: %v", i, peephole, before, after)
+			}
+		}
+	}
+}
+
+// TestInvokeError : a closure called from go code leaves the stack and the frames as they were when it fails
+func TestInvokeError(t *testing.T) {
+	c := compiler.New()
+	if err := c.Compile(parse(t, `const f = func(x) { (x > 0) ? [1, 2][x + [][0]] : x }; f`)); nil != err {
+		t.Fatal(err)
+	}
+	vm := New(c.Bytecode(), c.Constants())
+	if err := vm.Run(nil); nil != err {
+		t.Fatal(err)
+	}
+	fn := vm.LastPopped()
+	impl := vm.(*virtualMachine)
+	sp, depth := impl.sp, impl.frames.depth()
+	if _, err := fn.Call(object.Objects{object.NewInteger(1)}); nil == err {
+		t.Fatal("expect error")
+	}
+	if sp != impl.sp || depth != impl.frames.depth() {
+		t.Fatalf("expect sp: %v, depth: %v, got sp: %v, depth: %v", sp, depth, impl.sp, impl.frames.depth())
+	}
+	r, err := fn.Call(object.Objects{object.NewInteger(0)})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := testIntegerObject(0, r); nil != err {
+		t.Fatal(err)
+	}
+}
+
+// TestIncLocal : the loop counter is stepped past int64 as `+` does, more than once
+func TestIncLocal(t *testing.T) {
+	c := compiler.New()
+	if err := c.Compile(parse(t, `loop(9223372036854775807 + 3, func(i) { (i == 9223372036854775807 + 2) ? throw(str(i)) : null })`)); nil != err {
+		t.Fatal(err)
+	}
+	// the counter starts from the constant 0
+	consts := c.Constants()
+	for i, v := range consts {
+		if n, ok := v.(*object.Integer); ok && 0 == n.Value {
+			consts[i] = object.NewInteger(math.MaxInt64 - 1)
+		}
+	}
+	err := New(c.Bytecode(), consts).Run(nil)
+	if nil == err || !strings.Contains(err.Error(), "9223372036854775809") {
+		t.Fatalf("expect the counter 9223372036854775809, got %v", err)
+	}
+}